@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/mark3labs/mcphost/internal/acp"
+	"github.com/mark3labs/mcphost/internal/agent"
+)
+
+// runACPMode answers the prompts of an editor speaking the agent client
+// protocol on stdin and stdout, until the editor closes stdin or ctx is
+// cancelled. Nothing else may be written to stdout meanwhile.
+func runACPMode(ctx context.Context, mcpAgent *agent.Agent) error {
+	if err := configWatch.start(ctx, mcpAgent, func(notice string) {
+		log.Printf("config: %s", notice)
+	}); err != nil {
+		return err
+	}
+
+	return acp.New(acp.Config{
+		Agent:         mcpAgent,
+		MessageWindow: messageWindow,
+	}).Run(ctx, os.Stdin, os.Stdout)
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent [name]",
+	Short: "Run a named agent definition",
+	Long: `Run a named agent definition from ~/.config/mcphost/agents/<name>.yaml.
+
+An agent definition bundles a model, system prompt, MCP servers and tool
+permissions into a reusable persona. It accepts the same keys as a script's
+frontmatter. Without a name, the available agents are listed.
+
+Examples:
+  mcphost agent
+  mcphost agent reviewer
+  mcphost agent reviewer -p "Review the last commit"`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return listAgents()
+		}
+		ctx, cancel := withSignalHandling(context.Background())
+		defer cancel()
+		return runAgentMode(ctx, args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+}
+
+// runAgentMode runs the named agent definition, whose settings take
+// precedence over flags and the config file like a script's frontmatter
+func runAgentMode(ctx context.Context, name string) error {
+	agentConfig, err := config.LoadAgent(name)
+	if err != nil {
+		return err
+	}
+	return runWithScriptConfig(ctx, agentConfig)
+}
+
+// listAgents prints the names of the available agent definitions
+func listAgents() error {
+	names, err := config.ListAgents()
+	if err != nil {
+		return err
+	}
+
+	dir, err := config.AgentsDir()
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		fmt.Printf("No agents defined. Add agent definitions to %s\n", dir)
+		return nil
+	}
+
+	fmt.Printf("Agents in %s:\n", dir)
+	for _, name := range names {
+		fmt.Printf("  %s\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcphost/internal/citations"
+	"github.com/mark3labs/mcphost/internal/share"
+	"github.com/mark3labs/mcphost/internal/transcript"
+	"github.com/mark3labs/mcphost/internal/ui"
+)
+
+// lastAnswer is the latest answer of the session with the sources listed
+// under it, for /save-last and /open
+var lastAnswer struct {
+	message *schema.Message
+	sources []citations.Source
+}
+
+// recordAnswer remembers an answer and, with --output-file, writes it to
+// the file, replacing the previous answer
+func recordAnswer(response *schema.Message, sources []citations.Source) error {
+	lastAnswer.message = response
+	lastAnswer.sources = sources
+	if outputFile == "" {
+		return nil
+	}
+	return writeAnswer(outputFile, citations.WithFooter(response.Content, sources))
+}
+
+// writeAnswer writes an answer to a file as Markdown
+func writeAnswer(path, answer string) error {
+	if err := os.WriteFile(path, []byte(strings.TrimRight(answer, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write answer to %s: %v", path, err)
+	}
+	return nil
+}
+
+// latestAnswer returns the last answer in the history, with its sources if
+// it is the latest answer of the session
+func latestAnswer(messages []*schema.Message) (string, []citations.Source, bool) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Role != schema.Assistant || len(msg.ToolCalls) > 0 || strings.TrimSpace(msg.Content) == "" {
+			continue
+		}
+		if msg == lastAnswer.message {
+			return msg.Content, lastAnswer.sources, true
+		}
+		return msg.Content, nil, true
+	}
+	return "", nil, false
+}
+
+// handleAnswerCommand handles /save-last and /open, returning whether input
+// was one of them
+func handleAnswerCommand(input string, cli *ui.CLI, messages []*schema.Message, modelName string) bool {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || (fields[0] != "/save-last" && fields[0] != "/open") {
+		return false
+	}
+
+	answer, sources, ok := latestAnswer(messages)
+	if !ok {
+		cli.DisplayError(fmt.Errorf("there is no answer yet"))
+		return true
+	}
+
+	switch fields[0] {
+	case "/save-last":
+		if len(fields) != 2 {
+			cli.DisplayError(fmt.Errorf("usage: /save-last <path>"))
+			return true
+		}
+		path := expandHome(fields[1])
+		if err := writeAnswer(path, citations.WithFooter(answer, sources)); err != nil {
+			cli.DisplayError(err)
+			return true
+		}
+		cli.DisplayInfo(fmt.Sprintf("Answer saved to %s", path))
+	case "/open":
+		if len(fields) != 1 {
+			cli.DisplayError(fmt.Errorf("usage: /open"))
+			return true
+		}
+		path, err := renderAnswerPage(answer, sources, modelName)
+		if err != nil {
+			cli.DisplayError(err)
+			return true
+		}
+		if err := openInBrowser(path); err != nil {
+			cli.DisplayError(fmt.Errorf("failed to open %s: %v", path, err))
+			return true
+		}
+		cli.DisplayInfo(fmt.Sprintf("Opened %s", path))
+	}
+	return true
+}
+
+// renderAnswerPage renders an answer to a temporary HTML page, which is
+// left for the browser to read
+func renderAnswerPage(answer string, sources []citations.Source, modelName string) (string, error) {
+	now := time.Now()
+	page, err := share.RenderHTML(share.Session{
+		Title: "mcphost answer",
+		Model: modelName,
+		Time:  now,
+		Events: []transcript.Event{
+			{Time: now, Type: "assistant", Content: answer, Model: modelName, Sources: sources},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.CreateTemp("", "mcphost-answer-*.html")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	if _, err := file.Write(page); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write %s: %v", file.Name(), err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", file.Name(), err)
+	}
+	return file.Name(), nil
+}
+
+// openInBrowser opens a file in the default browser without waiting for it
+func openInBrowser(path string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", path)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+	default:
+		cmd = exec.Command("xdg-open", path)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// expandHome expands a leading ~ in a path to the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
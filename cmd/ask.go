@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// modelPrefix starts a prompt that is sent to another model for one turn
+const modelPrefix = "@model:"
+
+// parseModelOverride splits a prompt sent to another model for one turn,
+// "@model:<provider:model> <prompt>" or "/ask <provider:model> <prompt>",
+// into the model and the prompt. ok is false for other prompts.
+func parseModelOverride(input string) (modelString, prompt string, ok bool, err error) {
+	trimmed := strings.TrimSpace(input)
+	var rest string
+	switch {
+	case strings.HasPrefix(trimmed, modelPrefix):
+		rest = strings.TrimPrefix(trimmed, modelPrefix)
+	case trimmed == "/ask" || strings.HasPrefix(trimmed, "/ask "):
+		rest = strings.TrimSpace(strings.TrimPrefix(trimmed, "/ask"))
+	default:
+		return "", "", false, nil
+	}
+
+	modelString = rest
+	if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+		modelString, prompt = rest[:i], strings.TrimSpace(rest[i:])
+	}
+	if !strings.Contains(modelString, ":") || prompt == "" {
+		return "", "", true, fmt.Errorf("usage: /ask <provider:model> <prompt> or @model:<provider:model> <prompt>")
+	}
+	return modelString, prompt, true, nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/bot"
+	"github.com/spf13/cobra"
+)
+
+var (
+	botPlatform       string
+	botToken          string
+	botAdmins         []string
+	botRateLimit      int
+	botSessionTimeout time.Duration
+)
+
+// botTokenEnv maps each platform to the environment variable holding its
+// bot token
+var botTokenEnv = map[string]string{
+	"discord":  "DISCORD_BOT_TOKEN",
+	"telegram": "TELEGRAM_BOT_TOKEN",
+}
+
+var botCmd = &cobra.Command{
+	Use:   "bot",
+	Short: "Run mcphost as a Discord or Telegram bot",
+	Long: `Run mcphost as a chat bot on Discord or Telegram.
+
+Each channel or chat has its own conversation, which is forgotten after
+--session-timeout without messages or when someone sends /reset. On Discord
+the bot answers direct messages and messages that mention it.
+
+Tools that need approval (all tools not in a server's autoApprove list) can
+only be used by the user IDs given with --admins.
+
+The bot token is read from --token, DISCORD_BOT_TOKEN or TELEGRAM_BOT_TOKEN.
+
+Examples:
+  mcphost bot --platform telegram
+  mcphost bot --platform discord --admins 123456789012345678 --rate-limit 5
+  mcphost bot --platform discord --agent reviewer`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env, ok := botTokenEnv[botPlatform]
+		if !ok {
+			return fmt.Errorf("invalid --platform %q (expected discord or telegram)", botPlatform)
+		}
+		if botToken == "" {
+			botToken = os.Getenv(env)
+		}
+		if botToken == "" {
+			return fmt.Errorf("no bot token: set %s or use --token", env)
+		}
+
+		ctx, cancel := withSignalHandling(context.Background())
+		defer cancel()
+		return runMCPHost(ctx)
+	},
+}
+
+func init() {
+	botCmd.Flags().StringVar(&botPlatform, "platform", "", "chat platform (discord or telegram)")
+	botCmd.Flags().StringVar(&botToken, "token", "", "bot token (default from DISCORD_BOT_TOKEN or TELEGRAM_BOT_TOKEN)")
+	botCmd.Flags().StringSliceVar(&botAdmins, "admins", nil, "user IDs allowed to use tools that are not auto-approved")
+	botCmd.Flags().IntVar(&botRateLimit, "rate-limit", 10, "messages each user may send per minute (0 for no limit)")
+	botCmd.Flags().DurationVar(&botSessionTimeout, "session-timeout", time.Hour, "forget a channel's conversation after this long without messages")
+	botCmd.MarkFlagRequired("platform")
+
+	rootCmd.AddCommand(botCmd)
+}
+
+// runBotMode answers chat messages with the agent until ctx is cancelled
+func runBotMode(ctx context.Context, mcpAgent *agent.Agent) error {
+	var platform bot.Platform
+	switch botPlatform {
+	case "discord":
+		platform = bot.NewDiscord(botToken)
+	case "telegram":
+		platform = bot.NewTelegram(botToken)
+	default:
+		return fmt.Errorf("invalid --platform %q (expected discord or telegram)", botPlatform)
+	}
+
+	// Nobody can confirm tool calls in a chat, so tools that aren't
+	// auto-approved are reserved for admins
+	mcpAgent.Permissions().SetConfirm(true)
+
+	if err := configWatch.start(ctx, mcpAgent, func(notice string) {
+		log.Printf("config: %s", notice)
+	}); err != nil {
+		return err
+	}
+
+	return bot.New(bot.Config{
+		Agent:          mcpAgent,
+		MessageWindow:  messageWindow,
+		SessionTimeout: botSessionTimeout,
+		RateLimit:      botRateLimit,
+		Admins:         botAdmins,
+	}).Run(ctx, platform)
+}
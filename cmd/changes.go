@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcphost/internal/changes"
+	"github.com/mark3labs/mcphost/internal/sandbox"
+	"github.com/mark3labs/mcphost/internal/ui"
+)
+
+// handleChangesCommand handles /changes, /diff and /revert, which review the
+// files changed by tool calls. It reports whether input was one of them.
+func handleChangesCommand(input string, cli *ui.CLI, tracker *changes.Tracker) bool {
+	command, rest, _ := strings.Cut(strings.TrimSpace(input), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch command {
+	case "/changes":
+		list := tracker.Changes()
+		if len(list) == 0 {
+			cli.DisplayInfo("No files changed by tools.")
+			return true
+		}
+		cli.DisplayInfo("## Changed Files\n\n```\n" + sandbox.Summary(list) +
+			"\n```\n\nUse /diff <file> to view a change and /revert <file> to undo it.")
+	case "/diff":
+		// The rest of the line is the path, which may contain spaces
+		name := unquotePath(rest)
+		if name == "" {
+			cli.DisplayError(fmt.Errorf("usage: /diff <file>"))
+			return true
+		}
+		diff, err := tracker.Diff(name)
+		if err != nil {
+			cli.DisplayError(err)
+			return true
+		}
+		if diff == "" {
+			cli.DisplayInfo(fmt.Sprintf("%s is unchanged.", name))
+			return true
+		}
+		cli.DisplayInfo(fmt.Sprintf("```diff\n%s```", diff))
+	case "/revert":
+		names, err := splitPaths(rest)
+		if err != nil {
+			cli.DisplayError(err)
+			return true
+		}
+		if len(names) == 0 {
+			cli.DisplayError(fmt.Errorf("usage: /revert <file>..."))
+			return true
+		}
+		for _, name := range names {
+			if err := tracker.Revert(name); err != nil {
+				cli.DisplayError(err)
+				continue
+			}
+			cli.DisplayInfo(fmt.Sprintf("Reverted %s.", name))
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// unquotePath removes the quotes around a path, if any
+func unquotePath(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// splitPaths splits paths at spaces outside single or double quotes.
+// Backslashes are kept, as they separate Windows paths.
+func splitPaths(s string) ([]string, error) {
+	var (
+		paths  []string
+		path   strings.Builder
+		inPath bool
+		quote  rune
+	)
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				path.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inPath = r, true
+		case r == ' ' || r == '\t':
+			if inPath {
+				paths = append(paths, path.String())
+				path.Reset()
+				inPath = false
+			}
+		default:
+			path.WriteRune(r)
+			inPath = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inPath {
+		paths = append(paths, path.String())
+	}
+	return paths, nil
+}
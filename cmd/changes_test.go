@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitPaths(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{input: "", want: nil},
+		{input: "a.go  b.go", want: []string{"a.go", "b.go"}},
+		{input: `"my file.go" b.go`, want: []string{"my file.go", "b.go"}},
+		{input: `'it"s.txt'`, want: []string{`it"s.txt`}},
+		{input: `C:\src\main.go`, want: []string{`C:\src\main.go`}},
+		{input: `"open.go`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := splitPaths(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("splitPaths(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitPaths(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/paths"
+	"github.com/mark3labs/mcphost/internal/transcript"
+)
+
+// exitCodeCrash is the exit code after a crash, EX_SOFTWARE from sysexits.h
+const exitCodeCrash = 70
+
+// crashEvents is how many of the last session events a crash report shows
+const crashEvents = 20
+
+// crashContext is what a crash report is written from: the session's
+// events and the configuration
+var crashContext struct {
+	mu     sync.Mutex
+	tw     *transcript.Writer
+	config *config.Config
+	once   sync.Once
+}
+
+// watchForCrashes sets the session and configuration that crash reports are
+// written from
+func watchForCrashes(tw *transcript.Writer, mcpConfig *config.Config) {
+	crashContext.mu.Lock()
+	defer crashContext.mu.Unlock()
+	crashContext.tw = tw
+	crashContext.config = mcpConfig
+}
+
+// recoverCrash is deferred at the start of the main goroutine and of each
+// goroutine cmd starts. It handles a panic as a crash.
+func recoverCrash() {
+	if value := recover(); value != nil {
+		crash(value, debug.Stack())
+	}
+}
+
+// crashOnPanic handles a panic that the agent recovered during a run as a
+// crash
+func crashOnPanic(err error) {
+	var panicked *agent.PanicError
+	if errors.As(err, &panicked) {
+		crash(panicked.Value, panicked.Stack)
+	}
+}
+
+// crash saves the session and writes a crash report to crash in the data
+// directory, then runs the shutdown hooks and exits. Only the first crash is
+// reported; others wait for the exit.
+func crash(value any, stack []byte) {
+	crashContext.once.Do(func() {
+		crashContext.mu.Lock()
+		tw, mcpConfig := crashContext.tw, crashContext.config
+		crashContext.mu.Unlock()
+
+		fmt.Fprintf(os.Stderr, "\nmcphost crashed: %v\n", value)
+		secrets := []string{anthropicAPIKey, openaiAPIKey, googleAPIKey, azureAPIKey}
+		report, session, err := writeCrashReport(value, stack, tw.Events(), mcpConfig, secrets...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write crash report: %v\n%s", err, stack)
+		} else {
+			fmt.Fprintf(os.Stderr, "Crash report: %s\n", report)
+			if session != "" {
+				fmt.Fprintf(os.Stderr, "The session was saved to %s; view it with: mcphost render %s\n", session, session)
+			}
+		}
+
+		runShutdownHooks()
+		os.Exit(exitCodeCrash)
+	})
+}
+
+// writeCrashReport writes a crash report and, if there are any events, the
+// session as a JSON lines transcript, and returns their paths. The secrets,
+// e.g. API keys given as flags, are replaced wherever they appear in the
+// report.
+func writeCrashReport(value any, stack []byte, events []transcript.Event, mcpConfig *config.Config, secrets ...string) (report, session string, err error) {
+	dir, err := paths.Data("crash")
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", fmt.Errorf("failed to create %s: %v", dir, err)
+	}
+	base := filepath.Join(dir, fmt.Sprintf("crash-%s-%d", time.Now().Format("20060102-150405"), os.Getpid()))
+
+	if len(events) > 0 {
+		session = base + ".jsonl"
+		sessionFile, err := transcript.Open(session)
+		if err != nil {
+			return "", "", err
+		}
+		sessionFile.Append(events...)
+		if err := sessionFile.Close(); err != nil {
+			return "", "", fmt.Errorf("failed to write %s: %v", session, err)
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "mcphost crash report\n\n")
+	fmt.Fprintf(&b, "Time:    %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "Command: %s\n", strings.Join(os.Args, " "))
+	fmt.Fprintf(&b, "Go:      %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if session != "" {
+		fmt.Fprintf(&b, "Session: %s\n", session)
+	}
+	fmt.Fprintf(&b, "\nPanic: %v\n\n%s\n", value, stack)
+
+	if len(events) > crashEvents {
+		events = events[len(events)-crashEvents:]
+	}
+	fmt.Fprintf(&b, "\nLast %d events:\n\n", len(events))
+	last := transcript.NewWriter(&b, transcript.FormatText)
+	last.Append(events...)
+
+	fmt.Fprintf(&b, "\nConfiguration, with secrets redacted:\n\n")
+	if mcpConfig != nil {
+		b.WriteString(redactedConfig(mcpConfig))
+	}
+
+	text := b.String()
+	for _, secret := range secrets {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, "[redacted]")
+		}
+	}
+
+	report = base + ".txt"
+	if err := os.WriteFile(report, []byte(text), 0600); err != nil {
+		return "", "", fmt.Errorf("failed to write %s: %v", report, err)
+	}
+	return report, session, nil
+}
+
+// secretKey matches configuration keys whose values are secrets, and the
+// names of command line flags and URL query parameters
+var secretKey = regexp.MustCompile(`(?i)(key|token|secret|password|auth|credential)`)
+
+// secretValue matches the prefixes of common API keys and tokens, which
+// MCP servers are sometimes given as plain arguments
+var secretValue = regexp.MustCompile(`^(sk-|sk_|ghp_|gho_|ghs_|github_pat_|glpat-|xox[abpr]-|AKIA)`)
+
+// redactedConfig returns the configuration as indented JSON with API keys,
+// tokens, headers and environment variables replaced, as well as secrets in
+// MCP server arguments and URL query strings
+func redactedConfig(mcpConfig *config.Config) string {
+	data, err := json.Marshal(mcpConfig)
+	if err != nil {
+		return fmt.Sprintf("(failed to encode configuration: %v)\n", err)
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Sprintf("(failed to encode configuration: %v)\n", err)
+	}
+	data, err = json.MarshalIndent(redact(value), "", "  ")
+	if err != nil {
+		return fmt.Sprintf("(failed to encode configuration: %v)\n", err)
+	}
+	return string(data) + "\n"
+}
+
+// redact replaces the secrets in a decoded JSON value
+func redact(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, item := range v {
+			switch {
+			case k == "env" || k == "headers":
+				v[k] = redactAll(item)
+			case k == "args":
+				v[k] = redactArgs(item)
+			case secretKey.MatchString(k) && !strings.HasSuffix(k, "Env") && item != "" && isString(item):
+				v[k] = "[redacted]"
+			default:
+				v[k] = redact(item)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+	case string:
+		return redactURL(v)
+	}
+	return value
+}
+
+// redactArgs replaces the secrets in the arguments of an MCP server
+// command: values of flags named like secrets, whether given as
+// --flag=value or as the next argument, NAME=value assignments, values
+// that look like API keys, and URL query parameters
+func redactArgs(value any) any {
+	args, ok := value.([]any)
+	if !ok {
+		return redact(value)
+	}
+	secretFlag := false
+	for i, item := range args {
+		arg, ok := item.(string)
+		if !ok {
+			continue
+		}
+		name, _, assigned := strings.Cut(arg, "=")
+		switch {
+		case secretFlag:
+			args[i] = "[redacted]"
+		case assigned && secretKey.MatchString(name) && !strings.Contains(name, "/"):
+			args[i] = name + "=[redacted]"
+		case secretValue.MatchString(arg):
+			args[i] = "[redacted]"
+		default:
+			args[i] = redactURL(arg)
+		}
+		secretFlag = !secretFlag && !assigned && strings.HasPrefix(arg, "-") && secretKey.MatchString(arg)
+	}
+	return args
+}
+
+// redactURL replaces the password and the secret query parameters of a
+// URL, such as ?api_key=. Other strings are returned unchanged.
+func redactURL(s string) string {
+	if !strings.Contains(s, "://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return s
+	}
+	changed := false
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "redacted")
+		changed = true
+	}
+	query := u.Query()
+	for name := range query {
+		if secretKey.MatchString(name) {
+			query.Set(name, "redacted")
+			changed = true
+		}
+	}
+	if !changed {
+		return s
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func isString(value any) bool {
+	_, ok := value.(string)
+	return ok
+}
+
+// redactAll replaces the values of environment variables and headers,
+// keeping their names
+func redactAll(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for k := range v {
+			v[k] = "[redacted]"
+		}
+	case []any:
+		for i, item := range v {
+			// Headers of MCP servers are "Name: value" strings
+			if s, ok := item.(string); ok {
+				if name, _, found := strings.Cut(s, ":"); found {
+					v[i] = name + ": [redacted]"
+					continue
+				}
+			}
+			v[i] = "[redacted]"
+		}
+	}
+	return value
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/spf13/cobra"
+)
+
+var (
+	graphMode   bool
+	graphFormat string
+	graphOutput string
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Export the agent graph as a Mermaid or DOT diagram",
+	Long: `Export the compiled agent graph, with its nodes, edges, branches and the
+tools of the Tools node, for documentation and debugging. The MCP servers
+are started to list their tools, but no model is called.
+
+Branches, such as the model deciding between calling tools and answering,
+are drawn as dashed edges.
+
+Formats:
+  mermaid  a Mermaid flowchart, which GitHub renders in Markdown (default)
+  dot      a Graphviz DOT graph, e.g. for dot -Tsvg
+
+Examples:
+  mcphost graph
+  mcphost graph --format dot | dot -Tsvg -o agent.svg
+  mcphost graph --return-direct search -o agent.mmd`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if graphFormat != "mermaid" && graphFormat != "dot" {
+			return fmt.Errorf("invalid format %q (use mermaid or dot)", graphFormat)
+		}
+		graphMode = true
+
+		ctx, cancel := withSignalHandling(context.Background())
+		defer cancel()
+		return runMCPHost(ctx)
+	},
+}
+
+func init() {
+	graphCmd.Flags().StringVarP(&graphFormat, "format", "f", "mermaid", "diagram format: mermaid or dot")
+	graphCmd.Flags().StringVarP(&graphOutput, "output", "o", "", "file to write the diagram to (default stdout)")
+	rootCmd.AddCommand(graphCmd)
+}
+
+// runGraphMode writes the diagram of the agent's graph
+func runGraphMode(mcpAgent *agent.Agent) error {
+	diagram, err := mcpAgent.GraphDiagram(graphFormat)
+	if err != nil {
+		return err
+	}
+
+	if graphOutput == "" || graphOutput == "-" {
+		_, err = fmt.Fprint(os.Stdout, diagram)
+		return err
+	}
+	if err := os.WriteFile(graphOutput, []byte(diagram), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", graphOutput, err)
+	}
+	return nil
+}
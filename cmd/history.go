@@ -0,0 +1,361 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/transcript"
+	"github.com/mark3labs/mcphost/internal/ui"
+)
+
+// droppedMessages are the messages of the current conversation that the
+// message window removed from the context, oldest first, for /history full
+var droppedMessages []*schema.Message
+
+// pruneMessages keeps the last window messages of the history, plus any
+// messages pinned with agent.SetMessagePinned before them in their original order, and returns the
+// messages kept and the messages dropped
+func pruneMessages(messages []*schema.Message, window int) (kept, dropped []*schema.Message) {
+	if len(messages) <= window {
+		return messages, nil
+	}
+
+	cut := len(messages) - window
+	for _, msg := range messages[:cut] {
+		if agent.MessagePinned(msg) {
+			kept = append(kept, msg)
+		} else {
+			dropped = append(dropped, msg)
+		}
+	}
+	return append(kept, messages[cut:]...), dropped
+}
+
+// pruneHistory prunes the history of the current conversation to the
+// message window. The dropped messages are kept for /history full, and a
+// notice says how many there were, so it's clear why the model no longer
+// knows about them.
+func pruneHistory(cli *ui.CLI, messages []*schema.Message) []*schema.Message {
+	kept, dropped := pruneMessages(messages, messageWindow)
+	if len(dropped) == 0 {
+		return kept
+	}
+	droppedMessages = append(droppedMessages, dropped...)
+	cli.DisplayNotice(fmt.Sprintf("%d older messages dropped from the context (--message-window %d) · /history full", len(dropped), messageWindow))
+	return kept
+}
+
+// handleHistoryCommand handles the slash commands that rewrite the
+// conversation history, returning the new history and whether input was
+// such a command
+func handleHistoryCommand(ctx context.Context, input string, mcpAgent *agent.Agent, cli *ui.CLI, tw *transcript.Writer, modelName string, messages []*schema.Message) ([]*schema.Message, bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return messages, false
+	}
+
+	switch fields[0] {
+	case "/retry":
+		// Resend the last prompt, dropping any answer to it
+		i := lastUserMessage(messages)
+		if i < 0 {
+			cli.DisplayError(fmt.Errorf("there is no prompt to retry"))
+			return messages, true
+		}
+		messages = messages[:i+1]
+		cli.DisplayUserMessage(messages[i].Content)
+		return runInteractiveTurn(ctx, mcpAgent, cli, tw, modelName, messages), true
+	case "/regenerate":
+		// Discard the last answer and ask for a new one, optionally at a
+		// different temperature
+		if len(messages) == 0 || messages[len(messages)-1].Role != schema.Assistant {
+			cli.DisplayError(fmt.Errorf("there is no answer to regenerate"))
+			return messages, true
+		}
+		turnAgent := mcpAgent
+		if len(fields) > 1 {
+			temperature, err := strconv.ParseFloat(fields[1], 32)
+			if err != nil || temperature < 0 {
+				cli.DisplayError(fmt.Errorf("usage: /regenerate [temperature]"))
+				return messages, true
+			}
+			turnAgent = mcpAgent.WithTemperature(float32(temperature))
+		}
+		return runInteractiveTurn(ctx, turnAgent, cli, tw, modelName, messages[:len(messages)-1]), true
+	case "/history":
+		options, err := parseHistoryOptions(fields[1:])
+		if err != nil {
+			cli.DisplayError(err)
+			return messages, true
+		}
+		entries := historyEntries(messages, options)
+		if len(entries) == 0 && options.search != "" {
+			cli.DisplayInfo(fmt.Sprintf("No messages match %q", options.search))
+			return messages, true
+		}
+		cli.DisplayHistory(entries, options.tools)
+		return messages, true
+	case "/pin", "/unpin":
+		var msg *schema.Message
+		if len(fields) > 1 {
+			n, err := strconv.Atoi(fields[1])
+			if err == nil {
+				msg = historyMessage(messages, n)
+			}
+		} else if fields[0] == "/pin" {
+			msg = historyMessage(messages, countHistoryMessages(messages))
+		}
+		if msg == nil {
+			cli.DisplayError(fmt.Errorf("usage: %s <n> where n is a message number from /history", fields[0]))
+			return messages, true
+		}
+		if fields[0] == "/pin" {
+			agent.SetMessagePinned(msg, true)
+			cli.DisplayInfo("Pinned message: " + previewMessage(msg))
+		} else {
+			agent.SetMessagePinned(msg, false)
+			cli.DisplayInfo("Unpinned message: " + previewMessage(msg))
+		}
+		return messages, true
+	case "/pins":
+		if len(fields) > 1 && fields[1] == "clear" {
+			for _, msg := range messages {
+				agent.SetMessagePinned(msg, false)
+			}
+			cli.DisplayInfo("Unpinned all messages")
+			return messages, true
+		}
+		var list strings.Builder
+		list.WriteString("## Pinned Messages\n\n")
+		n, pinned := 0, 0
+		for _, msg := range messages {
+			if msg.Role != schema.User && msg.Role != schema.Assistant {
+				continue
+			}
+			n++
+			if agent.MessagePinned(msg) {
+				pinned++
+				list.WriteString(fmt.Sprintf("- `#%d` %s\n", n, previewMessage(msg)))
+			}
+		}
+		if pinned == 0 {
+			list.WriteString("No messages are pinned. Use `/pin <n>` with a number from `/history`.")
+		}
+		cli.DisplayInfo(list.String())
+		return messages, true
+	case "/undo":
+		n := 1
+		if len(fields) > 1 {
+			parsed, err := strconv.Atoi(fields[1])
+			if err != nil || parsed < 1 {
+				cli.DisplayError(fmt.Errorf("usage: /undo [n]"))
+				return messages, true
+			}
+			n = parsed
+		}
+		updated, removed := undoExchanges(messages, n)
+		if removed == 0 {
+			cli.DisplayError(fmt.Errorf("there is nothing to undo"))
+			return messages, true
+		}
+		cli.DisplayInfo(fmt.Sprintf("Removed the last %d exchange(s) from the conversation", removed))
+		return updated, true
+	default:
+		return messages, false
+	}
+}
+
+// historyOptions are the arguments of /history
+type historyOptions struct {
+	// full includes the messages dropped from the context
+	full bool
+	// tools shows the tool calls of each answer
+	tools bool
+	// last limits the history to its last messages, if not 0
+	last int
+	// search limits the history to messages containing it
+	search string
+}
+
+// parseHistoryOptions parses the arguments of /history: full, tools,
+// last <n> and search <term>, in any order except that the search term
+// takes the rest of the line
+func parseHistoryOptions(args []string) (historyOptions, error) {
+	usage := fmt.Errorf("usage: /history [full] [tools] [last <n>] [search <term>]")
+	var options historyOptions
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "full":
+			options.full = true
+		case "tools":
+			options.tools = true
+		case "last":
+			if i+1 == len(args) {
+				return options, usage
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 1 {
+				return options, usage
+			}
+			options.last = n
+			i++
+		case "search":
+			options.search = strings.Join(args[i+1:], " ")
+			if options.search == "" {
+				return options, usage
+			}
+			return options, nil
+		default:
+			return options, usage
+		}
+	}
+	return options, nil
+}
+
+// historyEntries returns the user and assistant messages of the history as
+// /history shows them. Messages keep their numbers when some are left out.
+func historyEntries(messages []*schema.Message, options historyOptions) []ui.HistoryEntry {
+	var entries []ui.HistoryEntry
+	add := func(msg *schema.Message, number int) {
+		// Models are recorded as provider:model, and shown without the
+		// provider like live answers
+		_, model, _ := strings.Cut(agent.MessageModel(msg), ":")
+		entry := ui.HistoryEntry{
+			Message:   msg,
+			Number:    number,
+			Pinned:    agent.MessagePinned(msg),
+			Time:      agent.MessageTime(msg),
+			Model:     model,
+			ToolCalls: historyToolCalls(agent.MessageToolCalls(msg)),
+		}
+		if options.search == "" || entryMatches(entry, options.search, options.tools) {
+			entries = append(entries, entry)
+		}
+	}
+
+	if options.full {
+		for _, msg := range droppedMessages {
+			if msg.Role == schema.User || msg.Role == schema.Assistant {
+				add(msg, 0)
+			}
+		}
+	}
+	n := 0
+	for _, msg := range messages {
+		if msg.Role != schema.User && msg.Role != schema.Assistant {
+			continue
+		}
+		n++
+		add(msg, n)
+	}
+
+	if options.last > 0 && len(entries) > options.last {
+		entries = entries[len(entries)-options.last:]
+	}
+	return entries
+}
+
+// entryMatches reports whether a history entry contains term, ignoring
+// case. With tools its tool calls are searched too.
+func entryMatches(entry ui.HistoryEntry, term string, tools bool) bool {
+	term = strings.ToLower(term)
+	contains := func(s string) bool {
+		return strings.Contains(strings.ToLower(s), term)
+	}
+	if contains(entry.Message.Content) {
+		return true
+	}
+	if tools {
+		for _, call := range entry.ToolCalls {
+			if contains(call.Name) || contains(call.Args) || contains(call.Result) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// historyToolCalls converts the tool calls recorded on an answer for
+// /history tools
+func historyToolCalls(records []agent.ToolCallRecord) []ui.HistoryToolCall {
+	var calls []ui.HistoryToolCall
+	for _, record := range records {
+		calls = append(calls, ui.HistoryToolCall{
+			Name:    record.Name,
+			Args:    record.Args,
+			Result:  record.Result,
+			IsError: record.IsError,
+			Time:    record.Time,
+		})
+	}
+	return calls
+}
+
+// undoExchanges removes the last n exchanges from the history and returns the
+// new history and the number of exchanges removed. An exchange starts at a
+// user message and includes everything after it up to the next one, so
+// assistant tool calls and their tool results are always removed together.
+func undoExchanges(messages []*schema.Message, n int) ([]*schema.Message, int) {
+	removed := 0
+	end := len(messages)
+	for removed < n {
+		i := lastUserMessage(messages[:end])
+		if i < 0 {
+			break
+		}
+		end = i
+		removed++
+	}
+	return messages[:end], removed
+}
+
+// historyMessage returns the nth (from 1) user or assistant message of the
+// history, as numbered by /history, or nil if there is no such message
+func historyMessage(messages []*schema.Message, n int) *schema.Message {
+	for _, msg := range messages {
+		if msg.Role != schema.User && msg.Role != schema.Assistant {
+			continue
+		}
+		n--
+		if n == 0 {
+			return msg
+		}
+	}
+	return nil
+}
+
+// countHistoryMessages returns the number of user and assistant messages in
+// the history
+func countHistoryMessages(messages []*schema.Message) int {
+	count := 0
+	for _, msg := range messages {
+		if msg.Role == schema.User || msg.Role == schema.Assistant {
+			count++
+		}
+	}
+	return count
+}
+
+// previewMessage returns the start of a message's first line
+func previewMessage(msg *schema.Message) string {
+	preview, _, _ := strings.Cut(strings.TrimSpace(msg.Content), "\n")
+	if len([]rune(preview)) > 60 {
+		preview = string([]rune(preview)[:60]) + "..."
+	}
+	return fmt.Sprintf("%s: %s", msg.Role, preview)
+}
+
+// lastUserMessage returns the index of the last user message in the
+// history, or -1 if there is none
+func lastUserMessage(messages []*schema.Message) int {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == schema.User {
+			return i
+		}
+	}
+	return -1
+}
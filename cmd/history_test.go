@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcphost/internal/agent"
+)
+
+func TestPruneMessages(t *testing.T) {
+	m1 := schema.UserMessage("1")
+	m2 := schema.AssistantMessage("2", nil)
+	m3 := schema.UserMessage("3")
+	m4 := schema.AssistantMessage("4", nil)
+	m5 := schema.UserMessage("5")
+	history := []*schema.Message{m1, m2, m3, m4, m5}
+
+	// The pin is kept by copies of a message
+	pinned := schema.UserMessage("pinned")
+	agent.SetMessagePinned(pinned, true)
+	copied := *pinned
+
+	tests := []struct {
+		name   string
+		window int
+		// history defaults to the five messages
+		history     []*schema.Message
+		pinned      []*schema.Message
+		want        []*schema.Message
+		wantDropped []*schema.Message
+	}{
+		{
+			name:   "within the window",
+			window: 5,
+			want:   history,
+		},
+		{
+			name:        "last messages kept",
+			window:      2,
+			want:        []*schema.Message{m4, m5},
+			wantDropped: []*schema.Message{m1, m2, m3},
+		},
+		{
+			name:        "pinned messages kept in order",
+			window:      2,
+			pinned:      []*schema.Message{m3, m1},
+			want:        []*schema.Message{m1, m3, m4, m5},
+			wantDropped: []*schema.Message{m2},
+		},
+		{
+			name:        "pinned message within the window",
+			window:      2,
+			pinned:      []*schema.Message{m5},
+			want:        []*schema.Message{m4, m5},
+			wantDropped: []*schema.Message{m1, m2, m3},
+		},
+		{
+			name:        "copied pinned message",
+			window:      1,
+			history:     []*schema.Message{m1, &copied, m2},
+			want:        []*schema.Message{&copied, m2},
+			wantDropped: []*schema.Message{m1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, msg := range tt.pinned {
+				agent.SetMessagePinned(msg, true)
+			}
+			t.Cleanup(func() {
+				for _, msg := range tt.pinned {
+					agent.SetMessagePinned(msg, false)
+				}
+			})
+
+			messages := tt.history
+			if messages == nil {
+				messages = history
+			}
+			got, dropped := pruneMessages(messages, tt.window)
+			if !slices.Equal(got, tt.want) || !slices.Equal(dropped, tt.wantDropped) {
+				t.Errorf("pruneMessages(%d) kept %d and dropped %d messages, want %d and %d",
+					tt.window, len(got), len(dropped), len(tt.want), len(tt.wantDropped))
+			}
+		})
+	}
+}
+
+func TestUndoExchanges(t *testing.T) {
+	system := schema.SystemMessage("system")
+	user1 := schema.UserMessage("first")
+	answer1 := schema.AssistantMessage("answer", nil)
+	user2 := schema.UserMessage("second")
+	call := schema.AssistantMessage("", []schema.ToolCall{{ID: "1"}})
+	result := schema.ToolMessage("result", "1")
+	answer2 := schema.AssistantMessage("answer", nil)
+	history := []*schema.Message{system, user1, answer1, user2, call, result, answer2}
+
+	tests := []struct {
+		name        string
+		messages    []*schema.Message
+		n           int
+		want        []*schema.Message
+		wantRemoved int
+	}{
+		{
+			name:        "last exchange with its tool calls",
+			messages:    history,
+			n:           1,
+			want:        []*schema.Message{system, user1, answer1},
+			wantRemoved: 1,
+		},
+		{
+			name:        "two exchanges",
+			messages:    history,
+			n:           2,
+			want:        []*schema.Message{system},
+			wantRemoved: 2,
+		},
+		{
+			name:        "more than there are",
+			messages:    history,
+			n:           5,
+			want:        []*schema.Message{system},
+			wantRemoved: 2,
+		},
+		{
+			name:        "unanswered prompt",
+			messages:    []*schema.Message{user1, answer1, user2},
+			n:           1,
+			want:        []*schema.Message{user1, answer1},
+			wantRemoved: 1,
+		},
+		{
+			name:        "no exchanges",
+			messages:    []*schema.Message{system},
+			n:           1,
+			want:        []*schema.Message{system},
+			wantRemoved: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, removed := undoExchanges(tt.messages, tt.n)
+			if !slices.Equal(got, tt.want) || removed != tt.wantRemoved {
+				t.Errorf("undoExchanges(%d) = %d messages, %d removed, want %d messages, %d removed",
+					tt.n, len(got), removed, len(tt.want), tt.wantRemoved)
+			}
+		})
+	}
+}
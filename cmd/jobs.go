@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/citations"
+	"github.com/mark3labs/mcphost/internal/transcript"
+	"github.com/mark3labs/mcphost/internal/ui"
+)
+
+// backgroundJob is an agent turn started with /bg that runs while the user
+// keeps chatting
+type backgroundJob struct {
+	id      int
+	prompt  string
+	message *schema.Message
+	started time.Time
+	// events records the turn so /fg can display it
+	events *transcript.Writer
+
+	mu        sync.Mutex
+	done      bool
+	finished  time.Time
+	response  *schema.Message
+	err       error
+	announced bool
+	merged    bool
+}
+
+// backgroundJobs are the jobs started in an interactive session
+type backgroundJobs struct {
+	jobs []*backgroundJob
+}
+
+// start runs userMessage as a new turn on top of history in the background.
+// Tools that need confirmation are denied, since the prompt belongs to the
+// foreground conversation.
+func (b *backgroundJobs) start(ctx context.Context, mcpAgent *agent.Agent, modelName, prompt, userMessage string, history []*schema.Message) *backgroundJob {
+	job := &backgroundJob{
+		id:      len(b.jobs) + 1,
+		prompt:  prompt,
+		message: agent.NewUserMessage(userMessage),
+		started: time.Now(),
+		events:  transcript.NewWriter(nil, transcript.FormatText),
+	}
+	job.events.Record()
+	job.events.User(userMessage)
+	b.jobs = append(b.jobs, job)
+
+	// The turn gets its own copy of the history, which the foreground
+	// conversation goes on changing
+	messages := append(append([]*schema.Message(nil), history...), job.message)
+	messages, _ = pruneMessages(messages, messageWindow)
+	jobAgent := mcpAgent.WithToolApprovalHandler(nil)
+
+	go func() {
+		defer recoverCrash()
+		jobCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var sources citations.Tracker
+		response, err := jobAgent.GenerateWithLoop(jobCtx, messages,
+			func(toolName, toolArgs string) {
+				job.events.ToolCall(toolName, toolArgs)
+			},
+			nil,
+			func(toolName, toolArgs, result string, isError bool) {
+				job.events.ToolResult(toolName, toolArgs, result, isError)
+				if !isError {
+					sources.Add(toolArgs, result)
+				}
+			},
+			nil,
+			func(content string) {
+				job.events.Assistant(content, modelName)
+			},
+		)
+		if err != nil {
+			job.events.Error(err)
+			crashOnPanic(err)
+		} else {
+			job.events.Answer(response.Content, modelName, sources.Sources())
+		}
+
+		job.mu.Lock()
+		defer job.mu.Unlock()
+		job.done = true
+		job.finished = time.Now()
+		job.response = response
+		job.err = err
+	}()
+	return job
+}
+
+// get returns the job with the given id, or nil
+func (b *backgroundJobs) get(id int) *backgroundJob {
+	if id < 1 || id > len(b.jobs) {
+		return nil
+	}
+	return b.jobs[id-1]
+}
+
+// announceFinished tells the user about jobs that finished since the last call
+func (b *backgroundJobs) announceFinished(cli *ui.CLI) {
+	for _, job := range b.jobs {
+		job.mu.Lock()
+		if job.done && !job.announced {
+			job.announced = true
+			status := "finished"
+			if job.err != nil {
+				status = "failed"
+			}
+			cli.DisplayInfo(fmt.Sprintf("Background job %d %s after %s; /fg %d shows the result",
+				job.id, status, job.finished.Sub(job.started).Round(time.Second), job.id))
+		}
+		job.mu.Unlock()
+	}
+}
+
+// handleJobCommand handles /bg, /jobs and /fg, returning the history, which
+// /fg extends with the job's prompt and answer, and whether input was one of
+// these commands
+func handleJobCommand(ctx context.Context, input string, mcpAgent *agent.Agent, cli *ui.CLI, tw *transcript.Writer, jobs *backgroundJobs, modelName string, messages []*schema.Message) ([]*schema.Message, bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return messages, false
+	}
+
+	switch fields[0] {
+	case "/bg":
+		prompt := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "/bg"))
+		if prompt == "" {
+			cli.DisplayError(fmt.Errorf("usage: /bg <prompt>"))
+			return messages, true
+		}
+
+		userMessage := interpolateShellCommands(ctx, prompt, cli)
+		userMessage, err := expandUserPrompt(userMessage)
+		if err != nil {
+			cli.DisplayError(err)
+			return messages, true
+		}
+
+		job := jobs.start(ctx, mcpAgent, modelName, prompt, userMessage, messages)
+		cli.DisplayInfo(fmt.Sprintf("Started background job %d; /jobs lists jobs and /fg %d shows the result", job.id, job.id))
+		return messages, true
+	case "/jobs":
+		if len(jobs.jobs) == 0 {
+			cli.DisplayInfo("No background jobs. Start one with /bg <prompt>.")
+			return messages, true
+		}
+
+		var b strings.Builder
+		b.WriteString("## Background Jobs\n\n")
+		for _, job := range jobs.jobs {
+			job.mu.Lock()
+			status := fmt.Sprintf("running for %s", time.Since(job.started).Round(time.Second))
+			switch {
+			case job.done && job.err != nil:
+				status = "failed"
+			case job.done:
+				status = fmt.Sprintf("finished in %s", job.finished.Sub(job.started).Round(time.Second))
+			}
+			job.mu.Unlock()
+
+			preview := job.prompt
+			if len([]rune(preview)) > 60 {
+				preview = string([]rune(preview)[:60]) + "..."
+			}
+			fmt.Fprintf(&b, "%d. %s — %s\n", job.id, preview, status)
+		}
+		cli.DisplayInfo(b.String())
+		return messages, true
+	case "/fg":
+		if len(fields) != 2 {
+			cli.DisplayError(fmt.Errorf("usage: /fg <id>"))
+			return messages, true
+		}
+		id, err := strconv.Atoi(fields[1])
+		job := jobs.get(id)
+		if err != nil || job == nil {
+			cli.DisplayError(fmt.Errorf("no background job %s", fields[1]))
+			return messages, true
+		}
+
+		job.mu.Lock()
+		defer job.mu.Unlock()
+		if !job.done {
+			cli.DisplayInfo(fmt.Sprintf("Background job %d is still running (%s so far)",
+				job.id, time.Since(job.started).Round(time.Second)))
+			return messages, true
+		}
+		job.announced = true
+
+		events := job.events.Events()
+		replayEvents(cli, events)
+
+		// The first time a successful job is viewed, it joins the
+		// conversation so follow-up prompts can refer to it
+		if job.err == nil && !job.merged {
+			job.merged = true
+			tw.Append(events...)
+			messages = append(messages, job.message, job.response)
+		}
+		return messages, true
+	}
+	return messages, false
+}
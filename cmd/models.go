@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/mark3labs/mcphost/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	modelsMode     bool
+	modelsProvider string
+)
+
+var modelsCmd = &cobra.Command{
+	Use:   "models [provider]",
+	Short: "List the models providers offer",
+	Long: `List the models a provider offers, as model strings for --model, with
+their context size and whether they support tool calling. Without a provider,
+every provider that can list its models is asked; providers that fail, e.g.
+for lack of an API key, are reported on stderr and skipped.
+
+Context sizes come from the provider where it reports them, otherwise from
+mcphost's capability table, as does tool calling support. "?" marks what
+isn't known.
+
+Examples:
+  mcphost models
+  mcphost models ollama
+  mcphost models openai --openai-url http://localhost:8080/v1`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		modelsMode = true
+		if len(args) == 1 {
+			modelsProvider = args[0]
+		}
+
+		ctx, cancel := withSignalHandling(context.Background())
+		defer cancel()
+		return runMCPHost(ctx)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(modelsCmd)
+}
+
+// runModelsMode prints the models of the requested provider, or of all
+// providers that can list them
+func runModelsMode(ctx context.Context, modelConfig *models.ProviderConfig) error {
+	providers := []string{modelsProvider}
+	if modelsProvider == "" {
+		providers = nil
+		for _, p := range models.Providers() {
+			if p.List != nil {
+				providers = append(providers, p.Name)
+			}
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tCONTEXT\tTOOLS")
+	listed := 0
+	for _, provider := range providers {
+		list, err := models.ListModels(ctx, modelConfig, provider)
+		if err != nil {
+			if modelsProvider != "" {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", provider, err)
+			continue
+		}
+
+		for _, m := range list {
+			modelString := provider + ":" + m.Name
+			window, tools := "?", "?"
+			caps, known := models.LookupCapabilities(modelString)
+			switch {
+			case m.ContextWindow > 0:
+				window = strconv.Itoa(m.ContextWindow)
+			case known && caps.ContextWindow > 0:
+				window = strconv.Itoa(caps.ContextWindow)
+			}
+			if known {
+				tools = "no"
+				if caps.ToolCalling {
+					tools = "yes"
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", modelString, window, tools)
+			listed++
+		}
+	}
+	if listed == 0 && modelsProvider == "" {
+		return fmt.Errorf("no provider listed any models")
+	}
+	return w.Flush()
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/models"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	// Let the config file set the options of the built-in providers, e.g.
+	// ollama-host
+	for _, provider := range models.Providers() {
+		for _, opt := range provider.Options {
+			config.AllowKeys(opt.Name)
+		}
+	}
+}
+
+// registerProviders adds the external providers defined in the config file to
+// the model provider registry. Definitions that only set defaults must be
+// for a provider that is already registered.
+func registerProviders(definitions map[string]config.ProviderDefinition) error {
+	for name, def := range definitions {
+		var err error
+		switch {
+		case def.Plugin != "":
+			err = models.LoadPlugin(name, def.Plugin, def.Options)
+		case def.BaseURL != "":
+			err = models.Register(models.NewProxyProvider(name, models.ProxyConfig{
+				BaseURL:      def.BaseURL,
+				APIKeyEnv:    def.APIKeyEnv,
+				Organization: def.Organization,
+				Project:      def.Project,
+				Headers:      def.Headers,
+			}))
+		case def.Command != "":
+			err = models.Register(models.NewShimProvider(name, def.Command, def.Args, def.Env))
+		default:
+			if _, ok := models.Lookup(name); !ok {
+				err = fmt.Errorf("provider %s: unknown provider; set plugin, command or baseURL to define it", name)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// providerOptions collects the config file values of the options declared by
+// registered providers
+func providerOptions() map[string]string {
+	options := make(map[string]string)
+	for _, provider := range models.Providers() {
+		for _, opt := range provider.Options {
+			if v := viper.GetString(opt.Name); v != "" {
+				options[opt.Name] = v
+			}
+		}
+	}
+	return options
+}
+
+// providerDefaults collects the model settings of the providers section of
+// the config file, by provider
+func providerDefaults(definitions map[string]config.ProviderDefinition) map[string]models.ProviderDefaults {
+	defaults := make(map[string]models.ProviderDefaults, len(definitions))
+	for name, def := range definitions {
+		// The timeout was checked when the config was loaded
+		timeout, _ := time.ParseDuration(def.Timeout)
+		defaults[name] = models.ProviderDefaults{
+			MaxTokens:   def.MaxTokens,
+			Temperature: def.Temperature,
+			TopP:        def.TopP,
+			Timeout:     timeout,
+			Headers:     def.Headers,
+		}
+	}
+	return defaults
+}
+
+// modelMiddlewareOnce keeps the middleware from being added twice, since
+// models.Use adds to a chain kept for the whole process
+var modelMiddlewareOnce sync.Once
+
+// useModelMiddleware adds the model middleware the flags ask for: logging
+// of each model call in debug mode, and retries of failed calls with
+// --model-retries
+func useModelMiddleware() {
+	modelMiddlewareOnce.Do(func() {
+		if debugMode {
+			models.Use(models.LoggingMiddleware(log.Default()))
+		}
+		if modelRetries > 0 {
+			models.Use(models.RetryMiddleware(modelRetries, time.Second))
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/ui"
+)
+
+// reloadDelay is how long the config file must be left alone before it is
+// reloaded, since editors save in several writes
+const reloadDelay = 300 * time.Millisecond
+
+// flagChanged reports whether a flag of the root command was given. It is
+// set in init, since functions run by the root command can't refer to it.
+var flagChanged func(name string) bool
+
+// configWatch reloads the config file in interactive and bot sessions. It is
+// nil when the config didn't come from a file, e.g. in script mode.
+var configWatch *configWatcher
+
+// configWatcher applies changes to the config file while mcphost runs. New
+// MCP servers are started, and changed autoApprove lists and temperature
+// apply from the next model call. Other changes need a restart, which the
+// notices say.
+type configWatcher struct {
+	path  string
+	agent *agent.Agent
+
+	// onNotice is called with each notice; without it notices wait for
+	// announce
+	onNotice func(string)
+
+	// reloading serializes reloads; current is only used while it is held
+	reloading sync.Mutex
+	current   *config.Config
+
+	mu      sync.Mutex
+	notices []string
+}
+
+// newConfigWatcher returns a watcher for the config file at path, which was
+// loaded as mcpConfig, or nil if there is no file. mcpConfig is copied, since
+// flags are later applied to it, e.g. --debug.
+func newConfigWatcher(path string, mcpConfig *config.Config) *configWatcher {
+	if path == "" {
+		return nil
+	}
+	current := *mcpConfig
+	return &configWatcher{path: filepath.Clean(path), current: &current}
+}
+
+// start watches the config file until ctx is done, applying changes to
+// mcpAgent. Notices go to onNotice, or wait for announce if it is nil.
+func (w *configWatcher) start(ctx context.Context, mcpAgent *agent.Agent, onNotice func(string)) error {
+	if w == nil {
+		return nil
+	}
+	w.agent = mcpAgent
+	w.onNotice = onNotice
+
+	// Watch the directory, since editors often replace the file rather
+	// than write to it
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch config file: %v", err)
+	}
+	if err := watcher.Add(filepath.Dir(w.path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch config file: %v", err)
+	}
+
+	go func() {
+		defer recoverCrash()
+		defer watcher.Close()
+
+		var timer *time.Timer
+		for {
+			select {
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != w.path || !event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDelay, func() {
+					defer recoverCrash()
+					w.reload(ctx)
+				})
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// reload loads the config file again and applies what changed
+func (w *configWatcher) reload(ctx context.Context) {
+	w.reloading.Lock()
+	defer w.reloading.Unlock()
+
+	reloaded, err := config.LoadMCPConfig(w.path)
+	if err != nil {
+		w.notify(fmt.Sprintf("Config file not reloaded: %v", err))
+		return
+	}
+	current := w.current
+
+	change := config.Diff(current, reloaded)
+	if change.IsEmpty() {
+		return
+	}
+	restart := change.Restart
+
+	for _, name := range change.NewServers {
+		toolNames, err := w.agent.AddServer(ctx, reloaded, name)
+		if err != nil {
+			w.notify(fmt.Sprintf("Failed to start server %s: %v", name, err))
+			// Left out, so the next save tries again
+			delete(reloaded.MCPServers, name)
+			continue
+		}
+		w.notify(fmt.Sprintf("Started server %s with %d tools", name, len(toolNames)))
+	}
+	if change.AutoApprove || len(change.NewServers) > 0 {
+		w.agent.Permissions().Reload(current, reloaded)
+		if change.AutoApprove {
+			w.notify("Updated the autoApprove lists")
+		}
+	}
+
+	if change.Temperature {
+		switch {
+		case flagChanged("temperature") || os.Getenv("MCPHOST_TEMPERATURE") != "":
+			w.notify("The temperature in the config file is overridden by --temperature or MCPHOST_TEMPERATURE")
+		case reloaded.Temperature == nil:
+			// The provider was created with the old temperature
+			restart = append(restart, "temperature")
+		default:
+			w.agent.SetTemperature(*reloaded.Temperature)
+			w.notify(fmt.Sprintf("Temperature set to %g", *reloaded.Temperature))
+		}
+	}
+
+	if len(restart) > 0 {
+		w.notify(fmt.Sprintf("Restart mcphost to apply the changes to %s", strings.Join(restart, ", ")))
+	}
+	w.current = reloaded
+}
+
+// notify passes a notice on, or keeps it for announce
+func (w *configWatcher) notify(notice string) {
+	if w.onNotice != nil {
+		w.onNotice(notice)
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.notices = append(w.notices, notice)
+}
+
+// announce shows the notices of reloads since the last call
+func (w *configWatcher) announce(cli *ui.CLI) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	notices := w.notices
+	w.notices = nil
+	w.mu.Unlock()
+
+	for _, notice := range notices {
+		cli.DisplayInfo(notice)
+	}
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcphost/internal/citations"
+	"github.com/mark3labs/mcphost/internal/share"
+	"github.com/mark3labs/mcphost/internal/transcript"
+	"github.com/mark3labs/mcphost/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	renderFormat string
+	renderOutput string
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render <transcript>",
+	Short: "Replay a saved transcript without running a model",
+	Long: `Replay a transcript saved with --transcript file.jsonl or printed by
+--quiet=events, to review a session or automation run after the
+fact. No model or MCP server is started.
+
+Formats:
+  terminal  render the messages and tool calls like a live session (default)
+  markdown  write a Markdown document
+  html      write a standalone HTML page, like /share
+
+Examples:
+  mcphost render run.jsonl
+  mcphost render run.jsonl --compact
+  mcphost render run.jsonl --format html -o run.html`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open transcript: %v", err)
+		}
+		events, err := transcript.Read(file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return fmt.Errorf("transcript %s has no events", args[0])
+		}
+
+		session := share.Session{
+			Title:  strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0])),
+			Time:   events[0].Time,
+			Events: events,
+		}
+		for _, event := range events {
+			if event.Model != "" {
+				session.Model = event.Model
+				break
+			}
+		}
+
+		var page []byte
+		switch renderFormat {
+		case "terminal":
+			if renderOutput != "" {
+				return fmt.Errorf("--output can only be used with --format markdown or html")
+			}
+			return renderTranscript(events)
+		case "markdown":
+			page = share.RenderMarkdown(session)
+		case "html":
+			page, err = share.RenderHTML(session)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("invalid format %q (use terminal, markdown or html)", renderFormat)
+		}
+
+		if renderOutput == "" || renderOutput == "-" {
+			_, err = os.Stdout.Write(page)
+			return err
+		}
+		if err := os.WriteFile(renderOutput, page, 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %v", renderOutput, err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	renderCmd.Flags().StringVarP(&renderFormat, "format", "f", "terminal", "output format: terminal, markdown or html")
+	renderCmd.Flags().StringVarP(&renderOutput, "output", "o", "", "file to write markdown or html to (default stdout)")
+	rootCmd.AddCommand(renderCmd)
+}
+
+// renderTranscript replays transcript events through the terminal UI, using
+// the display flags of a live session and the times the events happened
+func renderTranscript(events []transcript.Event) error {
+	mode, err := ui.ParseRenderMode(renderMode)
+	if err != nil {
+		return err
+	}
+	var timeLayout string
+	if !noTimestamps {
+		timeLayout, err = ui.ParseTimeFormat(timeFormat)
+		if err != nil {
+			return err
+		}
+	}
+
+	cli, err := ui.NewCLI(ui.DisplayOptions{
+		SpinnerStyle: ui.SpinnerNone,
+		ShowToolArgs: showToolArgs,
+		Compact:      compactMode,
+		TimeFormat:   timeLayout,
+		RenderMode:   mode,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create CLI: %v", err)
+	}
+
+	replayEvents(cli, events)
+	return nil
+}
+
+// replayEvents displays transcript events with the times they happened
+func replayEvents(cli *ui.CLI, events []transcript.Event) {
+	var current time.Time
+	cli.SetClock(func() time.Time { return current })
+	defer cli.SetClock(nil)
+
+	for _, event := range events {
+		current = event.Time
+		switch event.Type {
+		case "user":
+			cli.DisplayUserMessage(event.Content)
+		case "assistant", "final":
+			cli.DisplayAssistantMessageWithModel(citations.WithFooter(event.Content, event.Sources), event.Model)
+		case "tool_call":
+			cli.DisplayToolCallMessage(event.Tool, event.Args)
+		case "tool_result":
+			cli.DisplayToolMessage(event.Tool, event.Args, event.Content, event.IsError)
+		case "error":
+			cli.DisplayError(errors.New(event.Content))
+		}
+	}
+}
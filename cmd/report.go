@@ -0,0 +1,342 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/citations"
+	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/transcript"
+	"github.com/mark3labs/mcphost/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportTemplateFile string
+	reportDataFile     string
+	reportOutput       string
+
+	// report and reportTemplate are loaded before the MCP servers start, so
+	// mistakes in them fail fast
+	report         *config.Report
+	reportTemplate *template.Template
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Generate a report from the answers to a set of queries",
+	Long: `Run the queries of a report definition, each in its own conversation,
+and render their answers into a Go template, for repeatable reports such as
+weekly status updates or changelog summaries.
+
+The report definition is a YAML or JSON file:
+
+  vars:
+    repo: mark3labs/mcphost
+  queries:
+    - name: merged
+      prompt: List the pull requests merged into {{.Vars.repo}} in the last 7 days.
+      schema:
+        type: array
+        items:
+          type: object
+          properties:
+            title: {type: string}
+            url: {type: string}
+    - name: summary
+      prompt: "Summarize these changes in one paragraph: {{json .Results.merged}}"
+
+A query with format json, or a schema, is answered as JSON and parsed; the
+others are answered as text. Prompts and the template get .Vars, .Results
+and .Sources by query name, .Model, .Date and .Time, and the functions json
+and join.
+
+Examples:
+  mcphost report --template weekly.md.tmpl --data weekly.yaml
+  mcphost report -t weekly.md.tmpl -d weekly.yaml -o weekly.md -m openai:gpt-4o`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if reportTemplateFile == "" || reportDataFile == "" {
+			return fmt.Errorf("report needs a --template and a --data file")
+		}
+		if promptFlag != "" || interactiveFlag {
+			return fmt.Errorf("report runs the queries of its data file and can't be combined with --prompt or --interactive")
+		}
+
+		var err error
+		if report, err = config.LoadReport(reportDataFile); err != nil {
+			return err
+		}
+		text, err := os.ReadFile(reportTemplateFile)
+		if err != nil {
+			return fmt.Errorf("error reading report template: %v", err)
+		}
+		reportTemplate, err = template.New(reportTemplateFile).Funcs(reportFuncs).Option("missingkey=zero").Parse(string(text))
+		if err != nil {
+			return fmt.Errorf("error parsing report template: %v", err)
+		}
+
+		ctx, cancel := withSignalHandling(context.Background())
+		defer cancel()
+		return runMCPHost(ctx)
+	},
+}
+
+func init() {
+	reportCmd.Flags().StringVarP(&reportTemplateFile, "template", "t", "", "Go template the report is rendered with")
+	reportCmd.Flags().StringVarP(&reportDataFile, "data", "d", "", "report definition with the queries to run, as YAML or JSON")
+	reportCmd.Flags().StringVarP(&reportOutput, "output", "o", "", "file to write the report to (default stdout)")
+	rootCmd.AddCommand(reportCmd)
+}
+
+// reportData is what report prompts and templates are rendered with
+type reportData struct {
+	Vars    map[string]any
+	Results map[string]any
+	Sources map[string][]citations.Source
+	Model   string
+	Date    string
+	Time    string
+}
+
+var reportFuncs = template.FuncMap{
+	// json encodes a value as indented JSON
+	"json": func(v any) (string, error) {
+		data, err := json.MarshalIndent(v, "", "  ")
+		return string(data), err
+	},
+	// join joins the items of a list with a separator
+	"join": func(items any, sep string) string {
+		switch v := items.(type) {
+		case []string:
+			return strings.Join(v, sep)
+		case []any:
+			parts := make([]string, len(v))
+			for i, item := range v {
+				parts[i] = fmt.Sprint(item)
+			}
+			return strings.Join(parts, sep)
+		default:
+			return fmt.Sprint(items)
+		}
+	},
+}
+
+// runReportMode runs the report's queries in order and writes the rendered
+// report
+func runReportMode(ctx context.Context, mcpAgent *agent.Agent, cli *ui.CLI, tw *transcript.Writer, modelName string, messages []*schema.Message) error {
+	now := time.Now()
+	data := reportData{
+		Vars:    report.Vars,
+		Results: make(map[string]any),
+		Sources: make(map[string][]citations.Source),
+		Model:   modelFlag,
+		Date:    now.Format("2006-01-02"),
+		Time:    now.Format("15:04"),
+	}
+
+	for _, query := range report.Queries {
+		prompt, err := renderReportPrompt(query, data)
+		if err != nil {
+			return err
+		}
+		if cli != nil {
+			cli.DisplayInfo(fmt.Sprintf("Running query %s", query.Name))
+		}
+		result, sources, err := runReportQuery(ctx, mcpAgent, cli, tw, modelName, messages, query, prompt)
+		if err != nil {
+			return fmt.Errorf("query %s: %v", query.Name, err)
+		}
+		data.Results[query.Name] = result
+		data.Sources[query.Name] = sources
+	}
+
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, data); err != nil {
+		return fmt.Errorf("error rendering report: %v", err)
+	}
+	if reportOutput == "" || reportOutput == "-" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(reportOutput, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", reportOutput, err)
+	}
+	if cli != nil {
+		cli.DisplayInfo(fmt.Sprintf("Report written to %s", reportOutput))
+	}
+	return nil
+}
+
+// renderReportPrompt renders a query's prompt with the vars and the earlier
+// results, asking for JSON if the query's answer is parsed
+func renderReportPrompt(query config.ReportQuery, data reportData) (string, error) {
+	tmpl, err := template.New(query.Name).Funcs(reportFuncs).Option("missingkey=zero").Parse(query.Prompt)
+	if err != nil {
+		return "", fmt.Errorf("query %s: error parsing prompt: %v", query.Name, err)
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("query %s: error rendering prompt: %v", query.Name, err)
+	}
+	prompt := strings.TrimSpace(sb.String())
+
+	if query.Format == config.ReportFormatJSON {
+		prompt += "\n\nAnswer with only a JSON value, without code fences or any other text."
+		if query.Schema != nil {
+			schemaJSON, err := json.MarshalIndent(query.Schema, "", "  ")
+			if err != nil {
+				return "", fmt.Errorf("query %s: invalid schema: %v", query.Name, err)
+			}
+			prompt += " It must match this JSON Schema:\n\n" + string(schemaJSON)
+		}
+	}
+	return prompt, nil
+}
+
+// runReportQuery runs a query in a conversation of its own and returns its
+// answer, parsed if it is JSON, and the sources its tools returned. An
+// answer that isn't the JSON asked for is sent back once to be corrected.
+func runReportQuery(ctx context.Context, mcpAgent *agent.Agent, cli *ui.CLI, tw *transcript.Writer, modelName string, messages []*schema.Message, query config.ReportQuery, prompt string) (any, []citations.Source, error) {
+	userMessage, err := expandUserPrompt(prompt)
+	if err != nil {
+		return nil, nil, err
+	}
+	messages = append(append([]*schema.Message(nil), messages...), schema.UserMessage(userMessage))
+	tw.User(userMessage)
+
+	var sources citations.Tracker
+	for attempt := 1; ; attempt++ {
+		response, err := runReportTurn(ctx, mcpAgent, cli, tw, modelName, messages, &sources)
+		if err != nil {
+			return nil, nil, err
+		}
+		tw.Answer(response.Content, modelName, sources.Sources())
+
+		if query.Format != config.ReportFormatJSON {
+			return strings.TrimSpace(response.Content), sources.Sources(), nil
+		}
+		value, err := parseJSONAnswer(response.Content, query.Schema)
+		if err == nil {
+			return value, sources.Sources(), nil
+		}
+		if attempt == 2 {
+			return nil, nil, fmt.Errorf("the answer isn't the JSON asked for: %v", err)
+		}
+
+		correction := fmt.Sprintf("That answer isn't valid: %v. Answer again with only the JSON value.", err)
+		messages = append(messages, response, schema.UserMessage(correction))
+		tw.User(correction)
+	}
+}
+
+// runReportTurn gets the agent's answer to messages, showing its tool calls
+func runReportTurn(ctx context.Context, mcpAgent *agent.Agent, cli *ui.CLI, tw *transcript.Writer, modelName string, messages []*schema.Message, sources *citations.Tracker) (*schema.Message, error) {
+	var spinner *ui.Spinner
+	startSpinner := func(message string) {
+		if cli != nil {
+			spinner = cli.NewSpinner(message)
+			spinner.Start()
+		}
+	}
+	stopSpinner := func() {
+		if spinner != nil {
+			spinner.Stop()
+			spinner = nil
+		}
+	}
+	defer stopSpinner()
+
+	startSpinner("Thinking...")
+	response, err := mcpAgent.GenerateWithLoop(ctx, messages,
+		func(toolName, toolArgs string) {
+			tw.ToolCall(toolName, toolArgs)
+			stopSpinner()
+			if cli != nil {
+				cli.DisplayToolCallMessage(toolName, toolArgs)
+			}
+			startSpinner(fmt.Sprintf("Executing %s...", toolName))
+		},
+		nil,
+		func(toolName, toolArgs, result string, isError bool) {
+			tw.ToolResult(toolName, toolArgs, result, isError)
+			if !isError {
+				sources.Add(toolArgs, result)
+			}
+			stopSpinner()
+			if cli != nil {
+				cli.DisplayToolMessage(toolName, toolArgs, result, isError)
+			}
+			startSpinner("Thinking...")
+		},
+		nil,
+		func(content string) {
+			tw.Assistant(content, modelName)
+		},
+	)
+	if err != nil {
+		tw.Error(err)
+		crashOnPanic(err)
+		return nil, err
+	}
+	return response, nil
+}
+
+// parseJSONAnswer parses an answer as JSON, leaving out code fences around
+// it, and checks it has the type the schema asks for. Numbers are kept as
+// written.
+func parseJSONAnswer(answer string, jsonSchema map[string]any) (any, error) {
+	text := strings.TrimSpace(answer)
+	if strings.HasPrefix(text, "```") {
+		if i := strings.Index(text, "\n"); i >= 0 {
+			text = text[i+1:]
+		}
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("invalid JSON: more than one value")
+	}
+
+	if want, ok := jsonSchema["type"].(string); ok && !hasJSONType(value, want) {
+		return nil, fmt.Errorf("expected a JSON %s", want)
+	}
+	return value, nil
+}
+
+// hasJSONType reports whether a decoded JSON value has a JSON Schema type
+func hasJSONType(value any, want string) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		return want == "object"
+	case []any:
+		return want == "array"
+	case string:
+		return want == "string"
+	case bool:
+		return want == "boolean"
+	case json.Number:
+		if want == "integer" {
+			_, err := v.Int64()
+			return err == nil
+		}
+		return want == "number"
+	case nil:
+		return want == "null"
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/paths"
+	"github.com/mark3labs/mcphost/internal/transcript"
+	"github.com/mark3labs/mcphost/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// resumeID is the checkpoint of the paused run given to mcphost resume
+var resumeID string
+
+// exitCodePaused is the exit code of a run that paused, so pipelines can
+// tell it from a failure
+const exitCodePaused = 3
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume <checkpoint>",
+	Short: "Continue a run paused by --pause-before-tools",
+	Long: `Continue a non-interactive run that paused before running tools.
+
+With --pause-before-tools, mcphost -p stops each time the model asks for
+tools, prints the tool calls and saves the run under a checkpoint ID in
+--checkpoint-dir, then exits with code 3. Once the calls have been reviewed,
+resume runs them and continues the run, possibly in another process or on
+another machine with the same configuration and checkpoint directory.
+
+The run's model, MCP servers and output flags are taken from the command
+line and config file as usual. Pass --pause-before-tools again to pause
+before the next tool calls too.
+
+Examples:
+  mcphost -p "Clean up the build directory" --pause-before-tools
+  mcphost resume run-20250101-120000-4242
+  mcphost resume run-20250101-120000-4242 --pause-before-tools --quiet`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if promptFlag != "" || interactiveFlag {
+			return fmt.Errorf("resume continues a saved run and can't be combined with --prompt or --interactive")
+		}
+		resumeID = args[0]
+
+		ctx, cancel := withSignalHandling(context.Background())
+		defer cancel()
+		return runMCPHost(ctx)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+}
+
+// defaultCheckpointDir returns the directory paused runs are saved in
+// without --checkpoint-dir
+func defaultCheckpointDir() (string, error) {
+	return paths.Data("checkpoints")
+}
+
+// newCheckpointID returns the ID a new paused run is saved under
+func newCheckpointID() string {
+	return fmt.Sprintf("run-%s-%d", time.Now().Format("20060102-150405"), os.Getpid())
+}
+
+// runResumeMode continues the paused run saved under resumeID
+func runResumeMode(ctx context.Context, mcpAgent *agent.Agent, cli *ui.CLI, tw *transcript.Writer, modelName string) error {
+	ok, err := mcpAgent.HasCheckPoint(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no paused run with checkpoint %s", resumeID)
+	}
+
+	if quietMode == "" && cli != nil {
+		cli.DisplayInfo(fmt.Sprintf("Resuming run %s", resumeID))
+	}
+	_, err = runAgentTurn(ctx, mcpAgent, cli, tw, newTurnEvents(quietMode), modelName, nil, quietMode)
+	return err
+}
+
+// displayPause shows the tool calls a paused run will make and how to
+// resume it
+func displayPause(cli *ui.CLI, paused *agent.InterruptedError) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Paused before %d tool calls:\n", len(paused.ToolCalls))
+	for _, call := range paused.ToolCalls {
+		fmt.Fprintf(&b, "  - %s %s\n", call.Function.Name, call.Function.Arguments)
+	}
+	fmt.Fprintf(&b, "Resume with: mcphost resume %s", paused.CheckPointID)
+
+	if cli != nil && quietMode == "" {
+		cli.DisplayInfo(b.String())
+	} else {
+		fmt.Fprintln(os.Stderr, b.String())
+	}
+}
+
+// isPaused reports whether err is a run that paused rather than failed
+func isPaused(err error) (*agent.InterruptedError, bool) {
+	var paused *agent.InterruptedError
+	if errors.As(err, &paused) {
+		return paused, true
+	}
+	return nil, false
+}
@@ -1,18 +1,30 @@
 package cmd
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcphost/internal/actions"
 	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/changes"
+	"github.com/mark3labs/mcphost/internal/citations"
 	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/language"
 	"github.com/mark3labs/mcphost/internal/models"
+	"github.com/mark3labs/mcphost/internal/paths"
+	"github.com/mark3labs/mcphost/internal/prompt"
+	"github.com/mark3labs/mcphost/internal/tools"
+	"github.com/mark3labs/mcphost/internal/transcript"
 	"github.com/mark3labs/mcphost/internal/ui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -21,22 +33,99 @@ import (
 
 var (
 	configFile       string
+	mcpCommands      []string
+	mcpURLs          []string
 	systemPromptFile string
 	messageWindow    int
 	modelFlag        string
+	utilityModel     string
 	openaiBaseURL    string
 	anthropicBaseURL string
 	openaiAPIKey     string
 	anthropicAPIKey  string
 	googleAPIKey     string
+	azureEndpoint    string
+	azureAPIKey      string
+	azureAPIVersion  string
 	debugMode        bool
 	promptFlag       string
-	quietFlag        bool
+	quietMode        string
+	outputFormat     string
+	printMode        string
 	scriptFlag       bool
+	interactiveFlag  bool
+	acpMode          bool
+	agentName        string
 	maxSteps         int
+	stepTimeout      time.Duration
+	maxDuration      time.Duration
+	modelRetries     int
+	spinnerStyle     string
+	showToolArgs     bool
+	compactMode      bool
+	timeFormat       string
+	noTimestamps     bool
+	noTitle          bool
+	renderMode       string
+	showSuggestions  bool
+	transcriptFile   string
+	outputFile       string
+	confirmTools     bool
+	readOnly         bool
+	toolChoice       string
+	noParallelTools  bool
+	noJSONRepair     bool
+	forceTool        string
+	forceToolArgs    string
+	returnDirect     []string
+	messageModifiers []string
+	pauseBeforeTools bool
+	stepMode         bool
+	checkpointDir    string
+	dataDir          string
+	workdir          string
+	sandboxMode      bool
+	sandboxApply     bool
+	snapshotsMode    bool
+	preflight        bool
+	ignoreCaps       bool
+	voiceMode        bool
+	speakMode        bool
+	nativeSearch     bool
+	contextFiles     []string
+	contextPaths     []string
+	contextBudget    int
+	promptSources    []config.PromptSource
+	noContextFiles   bool
+	noEnvironment    bool
+	serverInstr      bool
+	temperature      float32
+	responseLanguage string
+	shareEndpoint    string
+	dumpTrafficDir   string
 	scriptMCPConfig  *config.Config // Used to override config in script mode
 )
 
+// maxSuggestions is the number of follow-up prompts suggested with --suggestions
+const maxSuggestions = 3
+
+// Output modes for --quiet
+const (
+	quietFinal  = "final"
+	quietStream = "stream"
+	quietEvents = "events"
+)
+
+// outputGitHub is the --output format for GitHub Actions
+const outputGitHub = "github"
+
+// What --print writes to stdout in non-interactive mode
+const (
+	printContent     = "content"
+	printToolResults = "tool-results"
+	printEvents      = "events"
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "mcphost",
 	Short: "Chat with AI models through a unified interface",
@@ -59,25 +148,52 @@ Examples:
   # Non-interactive mode
   mcphost -p "What is the weather like today?"
   mcphost -p "Calculate 15 * 23" --quiet
+  mcphost -p "Write a haiku" --quiet=stream
+  mcphost -p "List the files here" --quiet=events
   
   # Script mode
   mcphost --script myscript.sh
-  ./myscript.sh  # if script has shebang #!/path/to/mcphost --script`,
+  ./myscript.sh  # if script has shebang #!/path/to/mcphost --script
+
+  # Agent definitions from ~/.config/mcphost/agents
+  mcphost agent reviewer
+  mcphost --agent reviewer -p "Review the last commit"
+
+  # As an external agent of an editor such as Zed
+  mcphost --acp`,
+	// Script files are passed as positional arguments
+	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runMCPHost(context.Background())
+		ctx, cancel := withSignalHandling(context.Background())
+		defer cancel()
+		return runMCPHost(ctx)
 	},
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	if sig := interruption(); sig != nil {
+		runShutdownHooks()
+		os.Exit(exitCodeForSignal(sig))
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if _, ok := isPaused(err); ok {
+			os.Exit(exitCodePaused)
+		}
 		os.Exit(1)
 	}
 }
 
 func init() {
+	flagChanged = rootCmd.PersistentFlags().Changed
+
 	rootCmd.PersistentFlags().
-		StringVar(&configFile, "config", "", "config file (default is $HOME/.mcp.json)")
+		StringVar(&configFile, "config", "", "config file (default is ~/.config/mcphost/config.yml)")
+	rootCmd.PersistentFlags().
+		StringArrayVar(&mcpCommands, "mcp", nil, "add a stdio MCP server for this run as \"name=command arg1 arg2\"; repeatable")
+	rootCmd.PersistentFlags().
+		StringArrayVar(&mcpURLs, "mcp-url", nil, "add an SSE MCP server for this run as \"name=url\"; repeatable")
 	rootCmd.PersistentFlags().
 		StringVar(&systemPromptFile, "system-prompt", "", "system prompt json file")
 	rootCmd.PersistentFlags().
@@ -85,16 +201,117 @@ func init() {
 	rootCmd.PersistentFlags().
 		StringVarP(&modelFlag, "model", "m", "anthropic:claude-sonnet-4-20250514",
 			"model to use (format: provider:model)")
+	rootCmd.PersistentFlags().
+		StringVar(&utilityModel, "utility-model", "", "model for internal tasks such as follow-up suggestions (format: provider:model, default: --model)")
+	rootCmd.PersistentFlags().
+		Float32Var(&temperature, "temperature", -1, "sampling temperature (-1 for the provider default)")
 	rootCmd.PersistentFlags().
 		BoolVar(&debugMode, "debug", false, "enable debug logging")
 	rootCmd.PersistentFlags().
 		StringVarP(&promptFlag, "prompt", "p", "", "run in non-interactive mode with the given prompt")
 	rootCmd.PersistentFlags().
-		BoolVar(&quietFlag, "quiet", false, "suppress all output (only works with --prompt)")
+		StringVar(&quietMode, "quiet", "", "suppress all UI output (only works with --prompt): final, stream or events; true and false also work")
+	rootCmd.PersistentFlags().Lookup("quiet").NoOptDefVal = quietFinal
+	rootCmd.PersistentFlags().
+		StringVar(&printMode, "print", printContent, "what non-interactive mode writes to stdout, with everything else on stderr: content, tool-results or events")
+	rootCmd.PersistentFlags().
+		StringVar(&outputFormat, "output", "", "CI output format: github writes errors as workflow annotations and the answer to the job summary, and masks secrets")
 	rootCmd.PersistentFlags().
 		BoolVar(&scriptFlag, "script", false, "run in script mode (parse YAML frontmatter and prompt from file)")
+	rootCmd.PersistentFlags().
+		StringVar(&agentName, "agent", "", "run a named agent definition from ~/.config/mcphost/agents")
+	rootCmd.PersistentFlags().
+		BoolVar(&interactiveFlag, "interactive", false, "stay in interactive mode after running the prompt or loading the script")
+	rootCmd.PersistentFlags().
+		BoolVar(&acpMode, "acp", false, "serve the agent client protocol (JSON-RPC) on stdin and stdout, for editors such as Zed")
 	rootCmd.PersistentFlags().
 		IntVar(&maxSteps, "max-steps", 0, "maximum number of agent steps (0 for unlimited)")
+	rootCmd.PersistentFlags().
+		DurationVar(&stepTimeout, "step-timeout", 0, "limit on each model call and tool call, e.g. 90s (0 for none)")
+	rootCmd.PersistentFlags().
+		DurationVar(&maxDuration, "max-duration", 0, "limit on each run of the agent, tool calls included, after which the model sums up what it has, e.g. 5m (0 for none)")
+	rootCmd.PersistentFlags().
+		IntVar(&modelRetries, "model-retries", 0, "times to retry a failed model call, waiting 1s, 2s, 4s... between tries")
+	rootCmd.PersistentFlags().
+		StringVar(&spinnerStyle, "spinner", "dots", "spinner style (dots, line, none)")
+	rootCmd.PersistentFlags().
+		BoolVar(&showToolArgs, "show-tool-args", true, "show tool arguments while tools are executing")
+	rootCmd.PersistentFlags().
+		BoolVar(&compactMode, "compact", false, "collapse each tool call and its result into a single line")
+	rootCmd.PersistentFlags().
+		StringVar(&timeFormat, "time-format", ui.DefaultTimeFormat, "message timestamp format (12h, 24h, iso, us, eu, time or a Go time layout)")
+	rootCmd.PersistentFlags().
+		BoolVar(&noTimestamps, "no-timestamps", false, "hide message timestamps")
+	rootCmd.PersistentFlags().
+		BoolVar(&noTitle, "no-title", false, "don't show the session, model and status in the terminal title")
+	rootCmd.PersistentFlags().
+		BoolVar(&showSuggestions, "suggestions", false, "suggest follow-up prompts after each response in interactive mode")
+	rootCmd.PersistentFlags().
+		StringVar(&renderMode, "render", string(ui.RenderAuto), "terminal rendering (auto, fancy, simple); simple avoids cursor movement for tmux/screen")
+	rootCmd.PersistentFlags().
+		BoolVar(&confirmTools, "confirm-tools", false, "ask before running tools that are not in a server's autoApprove list")
+	rootCmd.PersistentFlags().
+		BoolVar(&readOnly, "read-only", false, "block tools that look like they write, delete or execute something (see read-only-tools)")
+	rootCmd.PersistentFlags().
+		StringVar(&toolChoice, "tool-choice", "", "tool use on the first model call of each turn: auto, any, none or a tool name")
+	rootCmd.PersistentFlags().
+		BoolVar(&noParallelTools, "no-parallel-tools", false, "limit the model to one tool call per response")
+	rootCmd.PersistentFlags().
+		BoolVar(&noJSONRepair, "no-json-repair", false, "don't repair malformed JSON in tool call arguments")
+	rootCmd.PersistentFlags().
+		StringVar(&forceTool, "force-tool", "", "call this tool before the model's first step and give it the result")
+	rootCmd.PersistentFlags().
+		StringVar(&forceToolArgs, "force-args", "", "JSON arguments for --force-tool (default {})")
+	rootCmd.PersistentFlags().
+		StringSliceVar(&returnDirect, "return-direct", nil, "tools whose result is the final answer, ending the turn without another model call")
+	rootCmd.PersistentFlags().
+		StringSliceVar(&messageModifiers, "message-modifiers", nil, "modifiers applied to the messages before each model call, in order: datetime, strip-base64, project-context, tool-images")
+	rootCmd.PersistentFlags().
+		BoolVar(&pauseBeforeTools, "pause-before-tools", false, "with --prompt/-p, pause before running the tools the model asks for and save the run to resume with mcphost resume")
+	rootCmd.PersistentFlags().
+		BoolVar(&stepMode, "step", false, "pause after each model response and tool result until enter is pressed, taking typed text as a message for the model")
+	rootCmd.PersistentFlags().
+		StringVar(&checkpointDir, "checkpoint-dir", "", "directory paused runs are saved in (default checkpoints in --data-dir)")
+	rootCmd.PersistentFlags().
+		StringVar(&dataDir, "data-dir", "", "directory for checkpoints, saved runs, snapshots and crash reports (default $XDG_DATA_HOME/mcphost or ~/.local/share/mcphost)")
+	rootCmd.PersistentFlags().
+		StringVar(&workdir, "workdir", "", "directory to work in, where MCP servers are started and file references are resolved")
+	rootCmd.PersistentFlags().
+		BoolVar(&sandboxMode, "sandbox", false, "work in a temporary copy of the working directory and summarize the changes at the end")
+	rootCmd.PersistentFlags().
+		BoolVar(&sandboxApply, "sandbox-apply", false, "with --sandbox, copy the changes back to the working directory at the end")
+	rootCmd.PersistentFlags().
+		BoolVar(&snapshotsMode, "snapshots", false, "record the working directory in a shadow git repository before each tool call, for mcphost snapshots")
+	rootCmd.PersistentFlags().
+		BoolVar(&preflight, "preflight", false, "check that the model exists and the API key is valid before starting")
+	rootCmd.PersistentFlags().
+		BoolVar(&ignoreCaps, "ignore-capabilities", false, "don't adapt the session to the model's known capabilities, e.g. by disabling tools")
+	rootCmd.PersistentFlags().
+		BoolVar(&voiceMode, "voice", false, "speak prompts instead of typing them in interactive mode")
+	rootCmd.PersistentFlags().
+		BoolVar(&speakMode, "speak", false, "read answers aloud in interactive mode")
+	rootCmd.PersistentFlags().
+		BoolVar(&nativeSearch, "native-search", false, "let the model search the web itself (Gemini grounding, OpenAI search models); can't be combined with MCP tools")
+	rootCmd.PersistentFlags().
+		StringSliceVar(&contextFiles, "context-files", config.DefaultContextFiles, "project instruction files to include in the system prompt")
+	rootCmd.PersistentFlags().
+		BoolVar(&noContextFiles, "no-context-files", false, "don't include project instruction files in the system prompt")
+	rootCmd.PersistentFlags().
+		StringArrayVar(&contextPaths, "context", nil, "file, directory or glob (** for any depth) to load as context at the start of the session; repeatable")
+	rootCmd.PersistentFlags().
+		IntVar(&contextBudget, "context-budget", prompt.DefaultContextBudget, "maximum bytes of file content loaded by --context")
+	rootCmd.PersistentFlags().
+		BoolVar(&noEnvironment, "no-environment", false, "don't include the date, OS, shell and working directory in the system prompt")
+	rootCmd.PersistentFlags().
+		BoolVar(&serverInstr, "server-instructions", false, "include the instructions MCP servers give for their tools in the system prompt")
+	rootCmd.PersistentFlags().
+		StringVar(&responseLanguage, "language", "", "language to respond in as an ISO 639-1 code, e.g. de; answers in another language are retried")
+	rootCmd.PersistentFlags().
+		StringVar(&dumpTrafficDir, "dump-llm-traffic", "", "write each raw provider request/response to this directory as numbered JSON files, with API keys redacted")
+	rootCmd.PersistentFlags().
+		StringVar(&transcriptFile, "transcript", "", "append a transcript of the session to this file (.jsonl for JSON lines, otherwise plain text)")
+	rootCmd.PersistentFlags().
+		StringVar(&outputFile, "output-file", "", "write the final answer to this file as Markdown, with its sources")
 
 	flags := rootCmd.PersistentFlags()
 	flags.StringVar(&openaiBaseURL, "openai-url", "", "base URL for OpenAI API")
@@ -102,34 +319,109 @@ func init() {
 	flags.StringVar(&openaiAPIKey, "openai-api-key", "", "OpenAI API key")
 	flags.StringVar(&anthropicAPIKey, "anthropic-api-key", "", "Anthropic API key")
 	flags.StringVar(&googleAPIKey, "google-api-key", "", "Google (Gemini) API key")
+	flags.StringVar(&azureEndpoint, "azure-endpoint", "", "Azure OpenAI resource endpoint, e.g. https://myresource.openai.azure.com")
+	flags.StringVar(&azureAPIKey, "azure-api-key", "", "Azure OpenAI API key")
+	flags.StringVar(&azureAPIVersion, "azure-api-version", "", "Azure OpenAI API version (default 2024-10-21)")
 
 	// Bind flags to viper for config file support
 	viper.BindPFlag("system-prompt", rootCmd.PersistentFlags().Lookup("system-prompt"))
 	viper.BindPFlag("message-window", rootCmd.PersistentFlags().Lookup("message-window"))
 	viper.BindPFlag("model", rootCmd.PersistentFlags().Lookup("model"))
+	viper.BindPFlag("utility-model", rootCmd.PersistentFlags().Lookup("utility-model"))
 	viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
+	viper.BindPFlag("temperature", rootCmd.PersistentFlags().Lookup("temperature"))
 	viper.BindPFlag("max-steps", rootCmd.PersistentFlags().Lookup("max-steps"))
+	viper.BindPFlag("model-retries", rootCmd.PersistentFlags().Lookup("model-retries"))
+	viper.BindPFlag("step-timeout", rootCmd.PersistentFlags().Lookup("step-timeout"))
+	viper.BindPFlag("max-duration", rootCmd.PersistentFlags().Lookup("max-duration"))
+	viper.BindPFlag("spinner", rootCmd.PersistentFlags().Lookup("spinner"))
+	viper.BindPFlag("show-tool-args", rootCmd.PersistentFlags().Lookup("show-tool-args"))
+	viper.BindPFlag("compact", rootCmd.PersistentFlags().Lookup("compact"))
+	viper.BindPFlag("time-format", rootCmd.PersistentFlags().Lookup("time-format"))
+	viper.BindPFlag("no-timestamps", rootCmd.PersistentFlags().Lookup("no-timestamps"))
+	viper.BindPFlag("no-title", rootCmd.PersistentFlags().Lookup("no-title"))
+	viper.BindPFlag("render", rootCmd.PersistentFlags().Lookup("render"))
+	viper.BindPFlag("suggestions", rootCmd.PersistentFlags().Lookup("suggestions"))
+	viper.BindPFlag("transcript", rootCmd.PersistentFlags().Lookup("transcript"))
+	viper.BindPFlag("output-file", rootCmd.PersistentFlags().Lookup("output-file"))
+	viper.BindPFlag("dump-llm-traffic", rootCmd.PersistentFlags().Lookup("dump-llm-traffic"))
+	viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
+	viper.BindPFlag("print", rootCmd.PersistentFlags().Lookup("print"))
+	viper.BindPFlag("confirm-tools", rootCmd.PersistentFlags().Lookup("confirm-tools"))
+	viper.BindPFlag("read-only", rootCmd.PersistentFlags().Lookup("read-only"))
+	viper.BindPFlag("tool-choice", rootCmd.PersistentFlags().Lookup("tool-choice"))
+	viper.BindPFlag("no-parallel-tools", rootCmd.PersistentFlags().Lookup("no-parallel-tools"))
+	viper.BindPFlag("no-json-repair", rootCmd.PersistentFlags().Lookup("no-json-repair"))
+	viper.BindPFlag("force-tool", rootCmd.PersistentFlags().Lookup("force-tool"))
+	viper.BindPFlag("force-args", rootCmd.PersistentFlags().Lookup("force-args"))
+	viper.BindPFlag("returnDirectTools", rootCmd.PersistentFlags().Lookup("return-direct"))
+	viper.BindPFlag("message-modifiers", rootCmd.PersistentFlags().Lookup("message-modifiers"))
+	viper.BindPFlag("pause-before-tools", rootCmd.PersistentFlags().Lookup("pause-before-tools"))
+	viper.BindPFlag("step", rootCmd.PersistentFlags().Lookup("step"))
+	viper.BindPFlag("checkpoint-dir", rootCmd.PersistentFlags().Lookup("checkpoint-dir"))
+	viper.BindPFlag("data-dir", rootCmd.PersistentFlags().Lookup("data-dir"))
+	viper.BindPFlag("workdir", rootCmd.PersistentFlags().Lookup("workdir"))
+	viper.BindPFlag("sandbox", rootCmd.PersistentFlags().Lookup("sandbox"))
+	viper.BindPFlag("sandbox-apply", rootCmd.PersistentFlags().Lookup("sandbox-apply"))
+	viper.BindPFlag("snapshots", rootCmd.PersistentFlags().Lookup("snapshots"))
+	viper.BindPFlag("preflight", rootCmd.PersistentFlags().Lookup("preflight"))
+	viper.BindPFlag("ignore-capabilities", rootCmd.PersistentFlags().Lookup("ignore-capabilities"))
+	viper.BindPFlag("voice", rootCmd.PersistentFlags().Lookup("voice"))
+	viper.BindPFlag("speak", rootCmd.PersistentFlags().Lookup("speak"))
+	viper.BindPFlag("native-search", rootCmd.PersistentFlags().Lookup("native-search"))
+	viper.BindPFlag("context-files", rootCmd.PersistentFlags().Lookup("context-files"))
+	viper.BindPFlag("context", rootCmd.PersistentFlags().Lookup("context"))
+	viper.BindPFlag("context-budget", rootCmd.PersistentFlags().Lookup("context-budget"))
+	viper.BindPFlag("no-environment", rootCmd.PersistentFlags().Lookup("no-environment"))
+	viper.BindPFlag("server-instructions", rootCmd.PersistentFlags().Lookup("server-instructions"))
+	viper.BindPFlag("language", rootCmd.PersistentFlags().Lookup("language"))
 	viper.BindPFlag("openai-url", rootCmd.PersistentFlags().Lookup("openai-url"))
 	viper.BindPFlag("anthropic-url", rootCmd.PersistentFlags().Lookup("anthropic-url"))
 	viper.BindPFlag("openai-api-key", rootCmd.PersistentFlags().Lookup("openai-api-key"))
 	viper.BindPFlag("anthropic-api-key", rootCmd.PersistentFlags().Lookup("anthropic-api-key"))
 	viper.BindPFlag("google-api-key", rootCmd.PersistentFlags().Lookup("google-api-key"))
+	// The Azure settings are options of the azure provider, which reads them
+	// from viper
+	viper.BindPFlag("azure-endpoint", rootCmd.PersistentFlags().Lookup("azure-endpoint"))
+	viper.BindPFlag("azure-api-key", rootCmd.PersistentFlags().Lookup("azure-api-key"))
+	viper.BindPFlag("azure-api-version", rootCmd.PersistentFlags().Lookup("azure-api-version"))
+	viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config"))
+
+	// Every setting can also be given as MCPHOST_<KEY>, e.g. MCPHOST_MAX_STEPS.
+	// Flags take precedence over them, and they over the config file.
+	viper.SetEnvPrefix("mcphost")
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	viper.AutomaticEnv()
+
+	// With --config or MCPHOST_CONFIG, the config file of earlier versions
+	// may be the one given, so it isn't copied to the config directory
+	cobra.OnInitialize(func() {
+		if viper.GetString("config") != "" {
+			paths.SkipConfigMigration()
+		}
+	})
 }
 
 func runMCPHost(ctx context.Context) error {
+	// --config, or else MCPHOST_CONFIG
+	configFile = viper.GetString("config")
+
 	// Handle script mode
 	if scriptFlag {
 		return runScriptMode(ctx)
 	}
 
+	// Handle named agent definitions
+	if agentName != "" {
+		return runAgentMode(ctx, agentName)
+	}
+
 	return runNormalMode(ctx)
 }
 
-func runNormalMode(ctx context.Context) error {
-	// Validate flag combinations
-	if quietFlag && promptFlag == "" {
-		return fmt.Errorf("--quiet flag can only be used with --prompt/-p")
-	}
+func runNormalMode(ctx context.Context) (err error) {
+	// Save the session and write a crash report if mcphost panics
+	defer recoverCrash()
 
 	// Set up logging
 	if debugMode {
@@ -138,7 +430,6 @@ func runNormalMode(ctx context.Context) error {
 
 	// Load configuration
 	var mcpConfig *config.Config
-	var err error
 
 	if scriptMCPConfig != nil {
 		// Use script-provided config
@@ -152,33 +443,27 @@ func runNormalMode(ctx context.Context) error {
 	}
 
 	// Set up viper to read from the same config file for flag values
-	if configFile == "" {
+	viperConfigFile := configFile
+	if viperConfigFile == "" {
 		// Use default config file locations
-		homeDir, err := os.UserHomeDir()
-		if err == nil {
-			viper.SetConfigName(".mcphost")
-			viper.AddConfigPath(homeDir)
-			viper.SetConfigType("yaml")
-			if err := viper.ReadInConfig(); err != nil {
-				// Try .mcphost.json
-				viper.SetConfigType("json")
-				if err := viper.ReadInConfig(); err != nil {
-					// Try legacy .mcp files
-					viper.SetConfigName(".mcp")
-					viper.SetConfigType("yaml")
-					if err := viper.ReadInConfig(); err != nil {
-						viper.SetConfigType("json")
-						viper.ReadInConfig() // Ignore error if no config found
-					}
-				}
-			}
-		}
-	} else {
-		// Use specified config file
-		viper.SetConfigFile(configFile)
+		viperConfigFile, _ = config.DefaultConfigFile()
+	}
+	if viperConfigFile != "" {
+		viper.SetConfigFile(viperConfigFile)
 		viper.ReadInConfig() // Ignore error if file doesn't exist
 	}
 
+	// Interactive and bot sessions apply changes to the config file
+	if scriptMCPConfig == nil {
+		configWatch = newConfigWatcher(viper.ConfigFileUsed(), mcpConfig)
+	}
+
+	// Servers given with --mcp and --mcp-url are only for this run, so the
+	// config watcher doesn't see them
+	if err := mcpConfig.AddFlagServers(mcpCommands, mcpURLs); err != nil {
+		return err
+	}
+
 	// Override flag values with config file values (using viper's bound values)
 	if viper.GetString("system-prompt") != "" {
 		systemPromptFile = viper.GetString("system-prompt")
@@ -189,12 +474,165 @@ func runNormalMode(ctx context.Context) error {
 	if viper.GetString("model") != "" {
 		modelFlag = viper.GetString("model")
 	}
+	if viper.GetString("utility-model") != "" {
+		utilityModel = viper.GetString("utility-model")
+	}
 	if viper.GetBool("debug") {
 		debugMode = viper.GetBool("debug")
 	}
+	if viper.IsSet("temperature") {
+		temperature = float32(viper.GetFloat64("temperature"))
+	}
 	if viper.GetInt("max-steps") != 0 {
 		maxSteps = viper.GetInt("max-steps")
 	}
+	if viper.GetDuration("step-timeout") != 0 {
+		stepTimeout = viper.GetDuration("step-timeout")
+	}
+	if viper.GetDuration("max-duration") != 0 {
+		maxDuration = viper.GetDuration("max-duration")
+	}
+	if viper.GetInt("model-retries") != 0 {
+		modelRetries = viper.GetInt("model-retries")
+	}
+	if viper.GetString("spinner") != "" {
+		spinnerStyle = viper.GetString("spinner")
+	}
+	if viper.IsSet("show-tool-args") {
+		showToolArgs = viper.GetBool("show-tool-args")
+	}
+	if viper.GetBool("compact") {
+		compactMode = viper.GetBool("compact")
+	}
+	if viper.GetString("time-format") != "" {
+		timeFormat = viper.GetString("time-format")
+	}
+	if viper.GetBool("no-timestamps") {
+		noTimestamps = viper.GetBool("no-timestamps")
+	}
+	if viper.GetBool("no-title") {
+		noTitle = viper.GetBool("no-title")
+	}
+	if viper.GetString("render") != "" {
+		renderMode = viper.GetString("render")
+	}
+	if viper.GetBool("suggestions") {
+		showSuggestions = viper.GetBool("suggestions")
+	}
+	if viper.GetBool("no-environment") {
+		noEnvironment = viper.GetBool("no-environment")
+	}
+	if viper.GetBool("server-instructions") {
+		serverInstr = viper.GetBool("server-instructions")
+	}
+	if viper.GetString("language") != "" {
+		responseLanguage = viper.GetString("language")
+	}
+	if viper.GetString("share-endpoint") != "" {
+		shareEndpoint = viper.GetString("share-endpoint")
+	}
+	if viper.GetString("transcript") != "" {
+		transcriptFile = viper.GetString("transcript")
+	}
+	if viper.GetString("output-file") != "" {
+		outputFile = viper.GetString("output-file")
+	}
+	if viper.GetString("dump-llm-traffic") != "" {
+		dumpTrafficDir = viper.GetString("dump-llm-traffic")
+	}
+	if viper.GetString("output") != "" {
+		outputFormat = viper.GetString("output")
+	}
+	if viper.GetString("print") != "" {
+		printMode = viper.GetString("print")
+	}
+	if viper.GetBool("confirm-tools") {
+		confirmTools = viper.GetBool("confirm-tools")
+	}
+	if viper.GetBool("read-only") {
+		readOnly = viper.GetBool("read-only")
+	}
+	if viper.GetString("tool-choice") != "" {
+		toolChoice = viper.GetString("tool-choice")
+	}
+	if viper.GetBool("no-parallel-tools") {
+		noParallelTools = viper.GetBool("no-parallel-tools")
+	}
+	if viper.GetBool("no-json-repair") {
+		noJSONRepair = viper.GetBool("no-json-repair")
+	}
+	if viper.GetString("force-tool") != "" {
+		forceTool = viper.GetString("force-tool")
+	}
+	if viper.GetString("force-args") != "" {
+		forceToolArgs = viper.GetString("force-args")
+	}
+	if len(viper.GetStringSlice("returnDirectTools")) > 0 {
+		returnDirect = viper.GetStringSlice("returnDirectTools")
+	}
+	if len(viper.GetStringSlice("message-modifiers")) > 0 {
+		messageModifiers = viper.GetStringSlice("message-modifiers")
+	}
+	if viper.GetBool("pause-before-tools") {
+		pauseBeforeTools = viper.GetBool("pause-before-tools")
+	}
+	if viper.GetBool("step") {
+		stepMode = viper.GetBool("step")
+	}
+	if viper.GetString("checkpoint-dir") != "" {
+		checkpointDir = viper.GetString("checkpoint-dir")
+	}
+	if viper.GetString("data-dir") != "" {
+		dataDir = viper.GetString("data-dir")
+	}
+	paths.SetDataDir(dataDir)
+	if viper.GetString("workdir") != "" {
+		workdir = viper.GetString("workdir")
+	}
+	if viper.GetBool("sandbox") {
+		sandboxMode = viper.GetBool("sandbox")
+	}
+	if viper.GetBool("sandbox-apply") {
+		sandboxApply = viper.GetBool("sandbox-apply")
+	}
+	if viper.GetBool("snapshots") {
+		snapshotsMode = viper.GetBool("snapshots")
+	}
+	if viper.GetBool("preflight") {
+		preflight = viper.GetBool("preflight")
+	}
+	if viper.GetBool("ignore-capabilities") {
+		ignoreCaps = viper.GetBool("ignore-capabilities")
+	}
+	if viper.GetBool("voice") {
+		voiceMode = viper.GetBool("voice")
+	}
+	if viper.GetBool("speak") {
+		speakMode = viper.GetBool("speak")
+	}
+	if viper.GetBool("native-search") {
+		nativeSearch = viper.GetBool("native-search")
+	}
+	if mcpConfig.SpeechToText != nil {
+		speechToText = *mcpConfig.SpeechToText
+	}
+	if mcpConfig.TextToSpeech != nil {
+		textToSpeech = *mcpConfig.TextToSpeech
+	}
+	if viper.IsSet("context-files") {
+		contextFiles = viper.GetStringSlice("context-files")
+	}
+	if len(viper.GetStringSlice("context")) > 0 {
+		contextPaths = viper.GetStringSlice("context")
+	}
+	if viper.GetInt("context-budget") != 0 {
+		contextBudget = viper.GetInt("context-budget")
+	}
+	if viper.IsSet("system-prompts") {
+		if err := viper.UnmarshalKey("system-prompts", &promptSources); err != nil {
+			return fmt.Errorf("invalid system-prompts: %v", err)
+		}
+	}
 	if viper.GetString("openai-url") != "" {
 		openaiBaseURL = viper.GetString("openai-url")
 	}
@@ -210,21 +648,173 @@ func runNormalMode(ctx context.Context) error {
 	if viper.GetString("google-api-key") != "" {
 		googleAPIKey = viper.GetString("google-api-key")
 	}
+	if viper.GetString("azure-api-key") != "" {
+		azureAPIKey = viper.GetString("azure-api-key")
+	}
+
+	// Script frontmatter takes precedence over flags and the config file
+	if scriptMCPConfig != nil {
+		applyScriptConfig(scriptMCPConfig)
+	}
+
+	// A script may define a multi-turn conversation instead of a single prompt
+	var conversation []config.ConversationTurn
+	if scriptMCPConfig != nil {
+		conversation = scriptMCPConfig.Conversation
+	}
+
+	// --quiet used to be a bool, so --quiet=true and quiet: false still work
+	if quiet, err := strconv.ParseBool(quietMode); err == nil {
+		quietMode = ""
+		if quiet {
+			quietMode = quietFinal
+		}
+	}
+
+	// Validate flag combinations
+	if quietMode != "" && (interactiveFlag || promptFlag == "" && len(conversation) == 0 && resumeID == "" && resumeRunID == "") {
+		return fmt.Errorf("--quiet flag can only be used with --prompt/-p")
+	}
+	if interactiveFlag && len(conversation) > 0 {
+		return fmt.Errorf("--interactive can't be combined with a scripted conversation")
+	}
+	if interactiveFlag && resumeID != "" {
+		return fmt.Errorf("--interactive can't be combined with mcphost resume")
+	}
+	if acpMode && (interactiveFlag || promptFlag != "" || len(conversation) > 0 || resumeID != "" || resumeRunID != "" || quietMode != "" || voiceMode || speakMode) {
+		return fmt.Errorf("--acp can't be combined with --prompt, --interactive, --quiet, --voice, --speak, a scripted conversation, mcphost resume or mcphost resume-run")
+	}
+	if stepMode && (quietMode != "" || acpMode) {
+		return fmt.Errorf("--step needs the terminal and can't be combined with --quiet or --acp")
+	}
+	if pauseBeforeTools && resumeID == "" && (interactiveFlag || promptFlag == "" || len(conversation) > 0) {
+		return fmt.Errorf("--pause-before-tools can only be used with --prompt/-p")
+	}
+	if voiceMode {
+		if !interactiveFlag && (promptFlag != "" || len(conversation) > 0 || resumeID != "" || resumeRunID != "") {
+			return fmt.Errorf("--voice can only be used in interactive mode")
+		}
+		// Fail before starting MCP servers if recording can't work
+		if _, err := newListener(); err != nil {
+			return err
+		}
+	}
+	if speakMode {
+		if !interactiveFlag && (promptFlag != "" || len(conversation) > 0 || resumeID != "" || resumeRunID != "") {
+			return fmt.Errorf("--speak can only be used in interactive mode")
+		}
+		if err := startSpeaker(nil); err != nil {
+			return err
+		}
+	}
+	switch quietMode {
+	case "", quietFinal, quietStream, quietEvents:
+	default:
+		return fmt.Errorf("invalid --quiet mode %q (expected final, stream, events, true or false)", quietMode)
+	}
+	switch printMode {
+	case printContent, printToolResults, printEvents:
+	default:
+		return fmt.Errorf("invalid --print value %q (expected content, tool-results or events)", printMode)
+	}
+	switch outputFormat {
+	case "":
+	case outputGitHub:
+		// Hide credentials in the job log and turn errors into annotations
+		maskSecrets(mcpConfig)
+		defer func() {
+			if err != nil {
+				actions.Annotate(os.Stderr, actions.LevelError, "mcphost", err.Error())
+			}
+		}()
+	default:
+		return fmt.Errorf("invalid --output format %q (expected github)", outputFormat)
+	}
+
+	// Servers' stderr is only shown inline in debug mode
+	mcpConfig.Debug = debugMode
+
+	// Tools and file references work in --workdir, or a sandbox copy of it
+	finishWorkdir, err := enterWorkdir()
+	if err != nil {
+		return err
+	}
+	defer finishWorkdir()
 
-	systemPrompt, err := config.LoadSystemPrompt(systemPromptFile)
+	cwd, err := os.Getwd()
 	if err != nil {
-		return fmt.Errorf("failed to load system prompt: %v", err)
+		return fmt.Errorf("failed to get working directory: %v", err)
+	}
+
+	// The system prompt is assembled from the configured sources, by default
+	// the --system-prompt file, the environment and the project instruction
+	// files, once the agent knows which tools are available
+	promptOptions := config.PromptOptions{
+		SystemPromptFile: systemPromptFile,
+		Dir:              cwd,
+		Now:              time.Now(),
+		NoEnvironment:    noEnvironment,
+		NoContextFiles:   noContextFiles,
+		ContextFiles:     contextFiles,
+		Guardrails:       mcpConfig.Guardrails,
+		Model:            modelFlag,
+		Servers:          serverNames(mcpConfig),
+	}
+	buildSystemPrompt := func(toolInfos []*schema.ToolInfo, origins map[string]tools.ToolOrigin, servers map[string]tools.ServerInfo) (string, error) {
+		promptOptions.Tools = promptTools(toolInfos, origins)
+		if serverInstr {
+			promptOptions.ServerInstructions = make(map[string]string, len(servers))
+			for name, server := range servers {
+				promptOptions.ServerInstructions[name] = server.Instructions
+			}
+		}
+		systemPrompt, err := config.BuildSystemPrompt(promptSources, promptOptions)
+		if err != nil || responseLanguage == "" {
+			return systemPrompt, err
+		}
+		return strings.TrimSpace(systemPrompt + "\n\n" + language.Directive(responseLanguage)), nil
 	}
 
+	if err := registerProviders(mcpConfig.Providers); err != nil {
+		return err
+	}
+	useModelMiddleware()
+
 	// Create model configuration
 	modelConfig := &models.ProviderConfig{
 		ModelString:      modelFlag,
-		SystemPrompt:     systemPrompt,
+		Temperature:      temperatureSetting(temperature),
 		AnthropicAPIKey:  anthropicAPIKey,
 		AnthropicBaseURL: anthropicBaseURL,
 		OpenAIAPIKey:     openaiAPIKey,
 		OpenAIBaseURL:    openaiBaseURL,
 		GoogleAPIKey:     googleAPIKey,
+		TrafficDir:       dumpTrafficDir,
+		Defaults:         providerDefaults(mcpConfig.Providers),
+		Options:          providerOptions(),
+
+		ToolChoice:             toolChoice,
+		DisableParallelToolUse: noParallelTools,
+		ToolSeparator:          mcpConfig.Separator(),
+		NativeSearch:           nativeSearch,
+		RepairToolArgs:         !noJSONRepair,
+	}
+
+	// The models command only needs the provider configuration
+	if modelsMode {
+		return runModelsMode(ctx, modelConfig)
+	}
+
+	// Fail on a bad model name or API key before starting MCP servers,
+	// rather than on the first prompt
+	var preflightLatency time.Duration
+	if preflight {
+		preflightCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		preflightLatency, err = models.Preflight(preflightCtx, modelConfig)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("preflight check of %s failed: %v", modelFlag, err)
+		}
 	}
 
 	// Create agent configuration
@@ -233,12 +823,83 @@ func runNormalMode(ctx context.Context) error {
 		agentMaxSteps = 1000 // Set a high limit for "unlimited"
 	}
 
+	// Adapt to what the model is known to support, rather than failing
+	// cryptically at generation time: a model that can't call tools runs
+	// without MCP servers, and one that can't see images isn't sent base64
+	// data it can't read
+	modifierNames := messageModifiers
+	disableTools := false
+	var capabilityNotes []string
+	vision := true
+	if caps, known := models.LookupCapabilities(modelFlag); known && !ignoreCaps {
+		if !caps.ToolCalling && len(mcpConfig.MCPServers) > 0 {
+			if forceTool != "" || len(returnDirect) > 0 {
+				return fmt.Errorf("%s doesn't support tool calling, which --force-tool and --return-direct need (use --ignore-capabilities to try anyway)", modelFlag)
+			}
+			disableTools = true
+			capabilityNotes = append(capabilityNotes, fmt.Sprintf("%s doesn't support tool calling; running without MCP tools (use --ignore-capabilities to load them anyway)", modelFlag))
+		}
+		if !caps.Vision && !slices.Contains(modifierNames, "strip-base64") {
+			modifierNames = append(append([]string{}, modifierNames...), "strip-base64")
+		}
+		vision = caps.Vision
+	}
+
+	// Gemini and OpenAI's search models can't search natively and call
+	// function tools in the same request
+	if nativeSearch && len(mcpConfig.MCPServers) > 0 && !disableTools {
+		return fmt.Errorf("--native-search can't be combined with MCP servers; use the web_search builtin tool instead")
+	}
+
+	// Screenshots are only useful if the model is shown them as images
+	for _, server := range mcpConfig.MCPServers {
+		if server.Builtin == "screenshot" && vision && !slices.Contains(modifierNames, "tool-images") {
+			modifierNames = append([]string{"tool-images"}, modifierNames...)
+		}
+	}
+
+	messageModifier, err := agent.ChainMessageModifiers(modifierNames)
+	if err != nil {
+		return err
+	}
+
 	agentConfig := &agent.AgentConfig{
-		ModelConfig:   modelConfig,
-		MCPConfig:     mcpConfig,
-		SystemPrompt:  systemPrompt,
-		MaxSteps:      agentMaxSteps,
-		MessageWindow: messageWindow,
+		ModelConfig:      modelConfig,
+		UtilityModel:     utilityModel,
+		MCPConfig:        mcpConfig,
+		SystemPromptFunc: buildSystemPrompt,
+		MaxSteps:         agentMaxSteps,
+		StepTimeout:      stepTimeout,
+		MaxDuration:      maxDuration,
+		RepairToolArgs:   !noJSONRepair,
+		MessageWindow:    messageWindow,
+		ConfirmTools:     confirmTools,
+		Language:         responseLanguage,
+		ForceTool:        forceTool,
+		ForceToolArgs:    forceToolArgs,
+		MessageModifier:  messageModifier,
+		DisableTools:     disableTools,
+	}
+	if readOnly {
+		agentConfig.ReadOnlyTools = mcpConfig.ReadOnlyPatterns()
+	}
+	if len(returnDirect) > 0 {
+		agentConfig.ToolReturnDirectly = make(map[string]struct{}, len(returnDirect))
+		for _, name := range returnDirect {
+			agentConfig.ToolReturnDirectly[name] = struct{}{}
+		}
+	}
+
+	// Paused runs are saved as files, to be continued by mcphost resume
+	if pauseBeforeTools || resumeID != "" {
+		dir := checkpointDir
+		if dir == "" {
+			if dir, err = defaultCheckpointDir(); err != nil {
+				return err
+			}
+		}
+		agentConfig.CheckPointStore = agent.NewFileCheckPointStore(dir)
+		agentConfig.PauseBeforeTools = pauseBeforeTools
 	}
 
 	// Create the agent
@@ -247,6 +908,47 @@ func runNormalMode(ctx context.Context) error {
 		return fmt.Errorf("failed to create agent: %v", err)
 	}
 	defer mcpAgent.Close()
+	onShutdown(func() { mcpAgent.Close() })
+
+	// Record the working directory before each tool call, for mcphost snapshots
+	if snapshotsMode {
+		if err := enableSnapshots(mcpAgent); err != nil {
+			return err
+		}
+	}
+
+	// The CLI shows capability notes once the tools are loaded; without it
+	// they go to stderr, like other notices
+	if botPlatform != "" || acpMode || graphMode || quietMode != "" {
+		for _, note := range capabilityNotes {
+			fmt.Fprintln(os.Stderr, note)
+		}
+	}
+
+	// In bot mode the agent answers chat messages instead of the terminal
+	if botPlatform != "" {
+		return runBotMode(ctx, mcpAgent)
+	}
+
+	// In ACP mode an editor sends the prompts, on stdin
+	if acpMode {
+		return runACPMode(ctx, mcpAgent)
+	}
+
+	// The graph command only needs the compiled agent
+	if graphMode {
+		return runGraphMode(mcpAgent)
+	}
+
+	// A new run that may pause is saved under a new checkpoint ID, a resumed
+	// run under its own
+	if agentConfig.CheckPointStore != nil {
+		id := resumeID
+		if id == "" {
+			id = newCheckpointID()
+		}
+		mcpAgent = mcpAgent.WithCheckPoint(id)
+	}
 
 	// Get model name for display
 	parts := strings.SplitN(modelFlag, ":", 2)
@@ -256,12 +958,49 @@ func runNormalMode(ctx context.Context) error {
 	}
 
 	// Get tools
-	tools := mcpAgent.GetTools()
+	loadedTools := mcpAgent.GetTools()
 
 	// Create CLI interface (skip if quiet mode)
 	var cli *ui.CLI
-	if !quietFlag {
-		cli, err = ui.NewCLI()
+	if quietMode == "" {
+		style, err := ui.ParseSpinnerStyle(spinnerStyle)
+		if err != nil {
+			return err
+		}
+
+		mode, err := ui.ParseRenderMode(renderMode)
+		if err != nil {
+			return err
+		}
+
+		var timeLayout string
+		if !noTimestamps {
+			timeLayout, err = ui.ParseTimeFormat(timeFormat)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Without a terminal session, stdout is reserved for the --print
+		// output and everything else goes to stderr
+		var output io.Writer
+		if !interactiveFlag && (promptFlag != "" || len(conversation) > 0 || resumeID != "" || report != nil) {
+			output = os.Stderr
+		}
+
+		cli, err = ui.NewCLI(ui.DisplayOptions{
+			SpinnerStyle: style,
+			ShowToolArgs: showToolArgs,
+			Compact:      compactMode,
+			TimeFormat:   timeLayout,
+			RenderMode:   mode,
+			Output:       output,
+			Blocked: func(toolName string) bool {
+				_, blocked := mcpAgent.Permissions().Blocked(toolName)
+				return blocked
+			},
+			Complete: completeUserPrompt,
+		})
 		if err != nil {
 			return fmt.Errorf("failed to create CLI: %v", err)
 		}
@@ -270,7 +1009,24 @@ func runNormalMode(ctx context.Context) error {
 		if len(parts) == 2 {
 			cli.DisplayInfo(fmt.Sprintf("Model loaded: %s (%s)", parts[0], parts[1]))
 		}
-		cli.DisplayInfo(fmt.Sprintf("Loaded %d tools from MCP servers", len(tools)))
+		if preflight {
+			cli.DisplayInfo(fmt.Sprintf("Preflight check passed in %s", preflightLatency.Round(time.Millisecond)))
+		}
+		cli.DisplayInfo(fmt.Sprintf("Loaded %d tools from MCP servers", len(loadedTools)))
+		if readOnly {
+			blocked := 0
+			for _, tool := range loadedTools {
+				if info, err := tool.Info(ctx); err == nil {
+					if _, ok := mcpAgent.Permissions().Blocked(info.Name); ok {
+						blocked++
+					}
+				}
+			}
+			cli.DisplayInfo(fmt.Sprintf("Read-only mode: %d of %d tools are blocked", blocked, len(loadedTools)))
+		}
+		for _, note := range capabilityNotes {
+			cli.DisplayInfo(note)
+		}
 	}
 
 	// Prepare data for slash commands
@@ -280,51 +1036,288 @@ func runNormalMode(ctx context.Context) error {
 	}
 
 	var toolNames []string
-	for _, tool := range tools {
+	for _, tool := range loadedTools {
 		if info, err := tool.Info(ctx); err == nil {
 			toolNames = append(toolNames, info.Name)
 		}
 	}
 
-	// Main interaction logic
-	var messages []*schema.Message
-
-	// Check if running in non-interactive mode
-	if promptFlag != "" {
-		return runNonInteractiveMode(ctx, mcpAgent, cli, promptFlag, modelName, messages, quietFlag)
+	// Ask the user before running tools the permission policy doesn't
+	// auto-approve; without a UI such calls are denied
+	if cli != nil {
+		mcpAgent.SetToolApprovalHandler(func(toolName, toolArgs string) bool {
+			approval, err := cli.ConfirmToolCall(toolName, toolArgs)
+			if err != nil {
+				cli.DisplayError(fmt.Errorf("confirmation error: %v", err))
+				return false
+			}
+			if approval == ui.ToolApprovedAlways {
+				mcpAgent.Permissions().Approve(toolName)
+			}
+			return approval != ui.ToolDenied
+		})
 	}
 
-	// Quiet mode is not allowed in interactive mode
-	if quietFlag {
-		return fmt.Errorf("--quiet flag can only be used with --prompt/-p")
+	// Open the transcript, if requested
+	var tw *transcript.Writer
+	if transcriptFile != "" {
+		tw, err = transcript.Open(transcriptFile)
+		if err != nil {
+			return err
+		}
+		defer tw.Close()
+		onShutdown(func() { tw.Close() })
 	}
 
-	return runInteractiveMode(ctx, mcpAgent, cli, serverNames, toolNames, modelName, messages)
-}
+	// Keep the session in memory so /share can export it
+	if tw == nil {
+		tw = transcript.NewWriter(nil, transcript.FormatText)
+	}
+	tw.Record()
+	watchForCrashes(tw, mcpConfig)
+
+	// Main interaction logic
+	var messages []*schema.Message
+
+	// Files given with --context start the conversation and are never pruned
+	if len(contextPaths) > 0 {
+		contextMessage, err := loadContextMessage(cli)
+		if err != nil {
+			return err
+		}
+		if contextMessage != nil {
+			agent.SetMessagePinned(contextMessage, true)
+			messages = append(messages, contextMessage)
+		}
+	}
+
+	// Continue a paused run
+	if resumeID != "" {
+		return runResumeMode(ctx, mcpAgent, cli, tw, modelName)
+	}
+
+	// Render a report from the answers to its queries
+	if report != nil {
+		return runReportMode(ctx, mcpAgent, cli, tw, modelName, messages)
+	}
+
+	// Non-interactive runs are saved as they go, so that a run that is
+	// killed or crashes can be continued with mcphost resume-run
+	if !interactiveFlag && !pauseBeforeTools && (promptFlag != "" || len(conversation) > 0 || resumeRunID != "") {
+		if resumeRunID != "" {
+			activeRun, err = loadRunJournal(resumeRunID)
+		} else {
+			activeRun, err = newRunJournal(modelFlag)
+		}
+		if err != nil {
+			return err
+		}
+		mcpAgent = mcpAgent.WithProgressHandler(activeRun.save)
+		onShutdown(activeRun.interrupted)
+	}
+
+	// Continue a run that was killed or crashed
+	if resumeRunID != "" {
+		err := runSavedRun(ctx, mcpAgent, cli, tw, activeRun, modelName)
+		activeRun.end(err)
+		return err
+	}
+
+	// Run a scripted conversation turn by turn
+	if len(conversation) > 0 {
+		err := runConversation(ctx, mcpAgent, cli, tw, conversation, modelName, messages, quietMode, false)
+		activeRun.end(err)
+		return err
+	}
+
+	// Interactive sessions show what they are doing in the terminal title
+	if interactiveFlag || promptFlag == "" && quietMode == "" {
+		terminalTitle.start(modelName)
+		defer terminalTitle.stop()
+	}
+
+	// In interactive mode the prompt, if any, is the first turn of the session
+	if interactiveFlag {
+		if promptFlag != "" {
+			// Errors are displayed by the turn itself, the session goes on
+			if history, err := runPromptTurn(ctx, mcpAgent, cli, tw, promptFlag, modelName, messages, ""); err == nil {
+				messages = history
+			}
+		}
+		return runInteractiveMode(ctx, mcpAgent, cli, tw, serverNames, toolNames, modelName, messages)
+	}
+
+	// Check if running in non-interactive mode
+	if promptFlag != "" {
+		err := runNonInteractiveMode(ctx, mcpAgent, cli, tw, promptFlag, modelName, messages, quietMode)
+		activeRun.end(err)
+		return err
+	}
+
+	// Quiet mode is not allowed in interactive mode
+	if quietMode != "" {
+		return fmt.Errorf("--quiet flag can only be used with --prompt/-p")
+	}
+
+	return runInteractiveMode(ctx, mcpAgent, cli, tw, serverNames, toolNames, modelName, messages)
+}
+
+// loadContextMessage loads the --context files into a user message, or nil
+// if they hold no text files
+func loadContextMessage(cli *ui.CLI) (*schema.Message, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("error getting working directory: %v", err)
+	}
+
+	result, err := prompt.LoadContext(contextPaths, cwd, contextBudget)
+	if err != nil {
+		return nil, err
+	}
+	if cli != nil && quietMode == "" {
+		msg := fmt.Sprintf("Loaded %d context files (%d KB)", len(result.Included), (result.Size+1023)/1024)
+		if len(result.Omitted) > 0 {
+			msg += fmt.Sprintf(", %d left out by --context-budget", len(result.Omitted))
+		}
+		cli.DisplayInfo(msg)
+	}
+	if result.Content == "" {
+		return nil, nil
+	}
+	return schema.UserMessage(result.Content), nil
+}
 
 // runNonInteractiveMode handles the non-interactive mode execution
-func runNonInteractiveMode(ctx context.Context, mcpAgent *agent.Agent, cli *ui.CLI, prompt, modelName string, messages []*schema.Message, quiet bool) error {
+func runNonInteractiveMode(ctx context.Context, mcpAgent *agent.Agent, cli *ui.CLI, tw *transcript.Writer, prompt, modelName string, messages []*schema.Message, quietMode string) error {
+	_, err := runPromptTurn(ctx, mcpAgent, cli, tw, prompt, modelName, messages, quietMode)
+	return err
+}
+
+// runConversation runs the turns of a scripted conversation in one session
+// and checks each response against the turn's expected substrings. With
+// resumed, messages already end with the first turn's prompt, of a saved
+// run that stopped during the turn.
+func runConversation(ctx context.Context, mcpAgent *agent.Agent, cli *ui.CLI, tw *transcript.Writer, turns []config.ConversationTurn, modelName string, messages []*schema.Message, quietMode string, resumed bool) error {
+	failures := 0
+	for i, turn := range turns {
+		// Prune messages if needed
+		messages, _ = pruneMessages(messages, messageWindow)
+
+		activeRun.setTurns(turns[i:])
+		var err error
+		if i == 0 && resumed {
+			messages, err = runAgentTurn(ctx, mcpAgent, cli, tw, newTurnEvents(quietMode), modelName, messages, quietMode)
+		} else {
+			messages, err = runPromptTurn(ctx, mcpAgent, cli, tw, turn.Prompt, modelName, messages, quietMode)
+		}
+		if err != nil {
+			return fmt.Errorf("turn %d: %v", i+1, err)
+		}
+		activeRun.setTurns(turns[i+1:])
+		activeRun.save(messages)
+		if quietMode == quietFinal || quietMode == quietStream {
+			fmt.Println()
+		}
+
+		response := messages[len(messages)-1].Content
+		for _, expected := range turn.Expect {
+			if strings.Contains(response, expected) {
+				continue
+			}
+			failures++
+			failure := fmt.Errorf("turn %d: expected response to contain %q", i+1, expected)
+			tw.Error(failure)
+			if outputFormat == outputGitHub {
+				actions.Annotate(os.Stderr, actions.LevelError, "Expectation failed", failure.Error())
+			}
+			if cli != nil {
+				cli.DisplayError(failure)
+			} else {
+				fmt.Fprintf(os.Stderr, "FAIL %v\n", failure)
+			}
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d expectation(s) failed", failures)
+	}
+	return nil
+}
+
+// runPromptTurn sends a single prompt to the agent, displaying progress
+// unless quiet, and returns the history with the prompt and response appended
+func runPromptTurn(ctx context.Context, mcpAgent *agent.Agent, cli *ui.CLI, tw *transcript.Writer, prompt, modelName string, messages []*schema.Message, quietMode string) ([]*schema.Message, error) {
+	quiet := quietMode != ""
+	events := newTurnEvents(quietMode)
+
 	// Display user message (skip if quiet)
 	if !quiet && cli != nil {
 		cli.DisplayUserMessage(prompt)
 	}
 
+	// Expand @file references before sending the prompt to the model
+	userMessage, err := expandUserPrompt(prompt)
+	if err != nil {
+		if !quiet && cli != nil {
+			cli.DisplayError(err)
+		}
+		return messages, err
+	}
+
 	// Add user message to history
-	messages = append(messages, schema.UserMessage(prompt))
+	messages = append(messages, agent.NewUserMessage(userMessage))
+	tw.User(userMessage)
+	events.User(userMessage)
+
+	return runAgentTurn(ctx, mcpAgent, cli, tw, events, modelName, messages, quietMode)
+}
+
+// newTurnEvents returns the writer printing every step of a turn to stdout
+// as a JSON line in events mode, or nil
+func newTurnEvents(quietMode string) *transcript.Writer {
+	if quietMode == quietEvents || quietMode == "" && printMode == printEvents {
+		return transcript.NewWriter(os.Stdout, transcript.FormatJSONL)
+	}
+	return nil
+}
+
+// runAgentTurn runs the agent on messages, displaying progress unless quiet,
+// and returns the history with the response appended. A paused run returns
+// an *agent.InterruptedError after showing how to resume it.
+func runAgentTurn(ctx context.Context, mcpAgent *agent.Agent, cli *ui.CLI, tw *transcript.Writer, events *transcript.Writer, modelName string, messages []*schema.Message, quietMode string) ([]*schema.Message, error) {
+	quiet := quietMode != ""
+
+	// In stream mode response content is printed to stdout as it arrives
+	var onChunk agent.StreamingResponseHandler
+	if quietMode == quietStream {
+		onChunk = func(chunk string) {
+			fmt.Print(chunk)
+		}
+	}
 
 	// Get agent response with controlled spinner that stops for tool call display
-	var response *schema.Message
 	var currentSpinner *ui.Spinner
 
+	// The pages and resources the tools return are listed under the answer
+	var sources citations.Tracker
+
+	// In step-by-step mode the run waits for the user after each step
+	if stepMode && !quiet && cli != nil {
+		mcpAgent = withStepPauses(mcpAgent, cli, tw, events, &currentSpinner)
+	}
+
 	// Start initial spinner (skip if quiet)
+	terminalTitle.setStatus("thinking")
 	if !quiet && cli != nil {
-		currentSpinner = ui.NewSpinner("Thinking...")
+		currentSpinner = cli.NewSpinner("Thinking...")
 		currentSpinner.Start()
 	}
 
-	response, err := mcpAgent.GenerateWithLoop(ctx, messages,
+	response, err := mcpAgent.GenerateWithLoopStreaming(ctx, messages,
 		// Tool call handler - called when a tool is about to be executed
 		func(toolName, toolArgs string) {
+			tw.ToolCall(toolName, toolArgs)
+			events.ToolCall(toolName, toolArgs)
 			if !quiet && cli != nil {
 				// Stop spinner before displaying tool call
 				if currentSpinner != nil {
@@ -336,10 +1329,15 @@ func runNonInteractiveMode(ctx context.Context, mcpAgent *agent.Agent, cli *ui.C
 		},
 		// Tool execution handler - called when tool execution starts/ends
 		func(toolName string, isStarting bool) {
+			if isStarting {
+				terminalTitle.setStatus("executing " + toolName)
+			} else {
+				terminalTitle.setStatus("thinking")
+			}
 			if !quiet && cli != nil {
 				if isStarting {
 					// Start spinner for tool execution
-					currentSpinner = ui.NewSpinner(fmt.Sprintf("Executing %s...", toolName))
+					currentSpinner = cli.NewSpinner(fmt.Sprintf("Executing %s...", toolName))
 					currentSpinner.Start()
 				} else {
 					// Stop spinner when tool execution completes
@@ -352,10 +1350,21 @@ func runNonInteractiveMode(ctx context.Context, mcpAgent *agent.Agent, cli *ui.C
 		},
 		// Tool result handler - called when a tool execution completes
 		func(toolName, toolArgs, result string, isError bool) {
+			tw.ToolResult(toolName, toolArgs, result, isError)
+			events.ToolResult(toolName, toolArgs, result, isError)
+			if !isError {
+				sources.Add(toolArgs, result)
+			}
+			if isError && outputFormat == outputGitHub {
+				actions.Annotate(os.Stderr, actions.LevelWarning, "Tool "+toolName+" failed", result)
+			}
+			if !quiet && printMode == printToolResults {
+				fmt.Println(result)
+			}
 			if !quiet && cli != nil {
 				cli.DisplayToolMessage(toolName, toolArgs, result, isError)
 				// Start spinner again for next LLM call
-				currentSpinner = ui.NewSpinner("Thinking...")
+				currentSpinner = cli.NewSpinner("Thinking...")
 				currentSpinner.Start()
 			}
 		},
@@ -372,6 +1381,8 @@ func runNonInteractiveMode(ctx context.Context, mcpAgent *agent.Agent, cli *ui.C
 
 		// Tool call content handler - called when content accompanies tool calls
 		func(content string) {
+			tw.Assistant(content, modelName)
+			events.Assistant(content, modelName)
 			if !quiet && cli != nil {
 				// Stop spinner before displaying content
 				if currentSpinner != nil {
@@ -380,157 +1391,626 @@ func runNonInteractiveMode(ctx context.Context, mcpAgent *agent.Agent, cli *ui.C
 				}
 				cli.DisplayAssistantMessageWithModel(content, modelName)
 				// Start spinner again for tool calls
-				currentSpinner = ui.NewSpinner("Thinking...")
+				currentSpinner = cli.NewSpinner("Thinking...")
 				currentSpinner.Start()
 			}
 		},
+		onChunk,
 	)
 
 	// Make sure spinner is stopped if still running
 	if !quiet && cli != nil && currentSpinner != nil {
 		currentSpinner.Stop()
 	}
+	if paused, ok := isPaused(err); ok {
+		tw.Error(err)
+		events.Error(err)
+		displayPause(cli, paused)
+		return messages, err
+	}
 	if err != nil {
+		tw.Error(err)
+		events.Error(err)
+		crashOnPanic(err)
 		if !quiet && cli != nil {
 			cli.DisplayError(fmt.Errorf("agent error: %v", err))
 		}
-		return err
+		return messages, err
+	}
+
+	// An answer given once the time limit was reached sums up the work so far
+	if limit := agent.MessageTimeLimit(response); limit > 0 {
+		notice := timeLimitNotice(limit)
+		if !quiet && cli != nil {
+			cli.DisplayInfo(notice)
+		} else {
+			fmt.Fprintln(os.Stderr, notice)
+		}
+	}
+
+	cited := sources.Sources()
+	tw.Answer(response.Content, modelName, cited)
+
+	if outputFormat == outputGitHub {
+		if err := actions.AppendSummary(citations.WithFooter(response.Content, cited)); err != nil {
+			actions.Annotate(os.Stderr, actions.LevelWarning, "", err.Error())
+		}
 	}
 
 	// Display assistant response with model name (skip if quiet)
 	if !quiet && cli != nil {
-		if err := cli.DisplayAssistantMessageWithModel(response.Content, modelName); err != nil {
-			cli.DisplayError(fmt.Errorf("display error: %v", err))
-			return err
+		switch printMode {
+		case printContent:
+			cli.DisplayAnswer(response.Content, modelName)
+		case printEvents:
+			events.Final(response.Content, modelName, cited)
+			fallthrough
+		default:
+			if err := cli.DisplayAssistantMessageWithModel(citations.WithFooter(response.Content, cited), modelName); err != nil {
+				cli.DisplayError(fmt.Errorf("display error: %v", err))
+				return messages, err
+			}
+		}
+	} else {
+		switch quietMode {
+		case quietFinal:
+			// In quiet mode, only output the final response content to stdout
+			fmt.Print(response.Content)
+		case quietEvents:
+			events.Final(response.Content, modelName, cited)
 		}
-	} else if quiet {
-		// In quiet mode, only output the final response content to stdout
-		fmt.Print(response.Content)
 	}
 
-	// Exit after displaying the final response
-	return nil
+	// Add assistant response to history
+	messages = append(messages, response)
+	if err := recordAnswer(response, cited); err != nil {
+		return messages, err
+	}
+	return messages, nil
+}
+
+// timeLimitNotice says that an answer was given once the --max-duration
+// was reached
+func timeLimitNotice(limit time.Duration) string {
+	return fmt.Sprintf("Stopped after the --max-duration of %s; the answer sums up the results so far", limit)
+}
+
+// withStepPauses returns a copy of the agent that waits for the user after
+// each model response and tool result, for --step. Text typed while paused
+// is shown and recorded as a user message. spinner is the turn's spinner,
+// stopped while paused and started again after. Aborting the pause lets the
+// rest of the turn run without pausing.
+func withStepPauses(mcpAgent *agent.Agent, cli *ui.CLI, tw, events *transcript.Writer, spinner **ui.Spinner) *agent.Agent {
+	stepping := true
+	return mcpAgent.WithStepHandler(func(done string) string {
+		if !stepping {
+			return ""
+		}
+		if *spinner != nil {
+			(*spinner).Stop()
+			*spinner = nil
+		}
+		terminalTitle.setStatus("paused")
+		text, err := cli.WaitForStep(done)
+		if err != nil {
+			stepping = false
+			if err != io.EOF {
+				cli.DisplayError(fmt.Errorf("step error: %v", err))
+			}
+			cli.DisplayInfo("Continuing without pausing until the end of the turn")
+		}
+		if text != "" {
+			cli.DisplayUserMessage(text)
+			tw.User(text)
+			events.User(text)
+		}
+		terminalTitle.setStatus("thinking")
+		*spinner = cli.NewSpinner("Thinking...")
+		(*spinner).Start()
+		return text
+	})
 }
 
 // runInteractiveMode handles the interactive mode execution
-func runInteractiveMode(ctx context.Context, mcpAgent *agent.Agent, cli *ui.CLI, serverNames, toolNames []string, modelName string, messages []*schema.Message) error {
+func runInteractiveMode(ctx context.Context, mcpAgent *agent.Agent, cli *ui.CLI, tw *transcript.Writer, serverNames, toolNames []string, modelName string, messages []*schema.Message) error {
+	sessions := newChatSessions(messages)
+	jobs := &backgroundJobs{}
+	if speaker != nil {
+		speaker.OnError = cli.DisplayError
+		defer speaker.Stop()
+	}
+
+	// Save the files tool calls name before they run, for /changes
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %v", err)
+	}
+	tracker := changes.NewTracker(cwd)
+	mcpAgent.AddBeforeToolRun(func(toolName, toolArgs string) {
+		tracker.Snapshot(toolArgs)
+	})
+
+	if err := configWatch.start(ctx, mcpAgent, nil); err != nil {
+		cli.DisplayError(err)
+	}
 
 	// Main interaction loop
 	for {
+		jobs.announceFinished(cli)
+		configWatch.announce(cli)
+
+		// With --voice the prompt is spoken; if nothing is said, it's typed
+		var prompt string
+		if voiceMode {
+			prompt = listenForPrompt(ctx, cli)
+		}
+
 		// Get user input
-		prompt, err := cli.GetPrompt()
-		if err == io.EOF {
-			fmt.Println("\nGoodbye!")
-			return nil
+		if prompt == "" {
+			terminalTitle.setStatus("idle")
+			prompt, err = cli.GetPrompt()
+			if err == io.EOF {
+				fmt.Println("\nGoodbye!")
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get prompt: %v", err)
+			}
 		}
-		if err != nil {
-			return fmt.Errorf("failed to get prompt: %v", err)
+
+		// /voice speaks a single prompt
+		if strings.TrimSpace(prompt) == "/voice" {
+			prompt = listenForPrompt(ctx, cli)
 		}
 
 		if prompt == "" {
 			continue
 		}
 
+		// /set variables are substituted as {{name}} in everything else
+		// typed, including commands such as /bg
+		if handleVariableCommand(prompt, cli, tw, sessions.variables()) {
+			continue
+		}
+		prompt = substituteVariables(prompt, sessions.variables())
+
+		// @model:<model> and /ask <model> send a single prompt to another
+		// model; the session keeps its own
+		turnAgent, turnModelName := mcpAgent, modelName
+		modelString, routedPrompt, routed, err := parseModelOverride(prompt)
+		if err != nil {
+			cli.DisplayError(err)
+			continue
+		}
+		if routed {
+			turnAgent, err = mcpAgent.WithModel(ctx, modelString)
+			if err != nil {
+				cli.DisplayError(fmt.Errorf("failed to create model %s: %v", modelString, err))
+				continue
+			}
+			_, turnModelName, _ = strings.Cut(modelString, ":")
+			prompt = routedPrompt
+		}
+
 		// Handle slash commands
-		if cli.IsSlashCommand(prompt) {
-			if cli.HandleSlashCommand(prompt, serverNames, toolNames, messages) {
+		if !routed && cli.IsSlashCommand(prompt) {
+			// History commands rewrite the conversation and may run a new turn
+			if updated, handled := handleHistoryCommand(ctx, prompt, mcpAgent, cli, tw, modelName, messages); handled {
+				messages = updated
+				continue
+			}
+			if updated, handled := handleSessionCommand(prompt, cli, sessions, messages); handled {
+				messages = updated
+				terminalTitle.setSession(sessions.current)
+				continue
+			}
+			if updated, handled := handleJobCommand(ctx, prompt, mcpAgent, cli, tw, jobs, modelName, messages); handled {
+				messages = updated
+				continue
+			}
+			if handleAnswerCommand(prompt, cli, messages, modelName) {
+				continue
+			}
+			if fields := strings.Fields(prompt); len(fields) > 0 && fields[0] == "/share" {
+				handleShareCommand(ctx, fields[1:], cli, tw, modelName)
+				continue
+			}
+			if strings.TrimSpace(prompt) == "/tokens" {
+				handleTokensCommand(ctx, mcpAgent, cli, messages)
+				continue
+			}
+			if strings.TrimSpace(prompt) == "/trace" {
+				handleTraceCommand(mcpAgent, cli)
+				continue
+			}
+			if strings.TrimSpace(prompt) == "/servers" {
+				cli.DisplayServers(serverDetails(serverNames, mcpAgent.Servers()))
+				continue
+			}
+			if handleChangesCommand(prompt, cli, tracker) {
+				continue
+			}
+			if handleSpeakCommand(prompt, cli) {
+				continue
+			}
+			if handleAgentSlashCommand(prompt, mcpAgent, cli) {
+				continue
+			}
+			if cli.HandleSlashCommand(prompt, toolNames, messages) {
 				continue
 			}
 			cli.DisplayError(fmt.Errorf("unknown command: %s", prompt))
 			continue
 		}
 
+		// A number picks one of the suggested follow-up prompts
+		prompt, _ = cli.SelectSuggestion(prompt)
+
 		// Display user message
 		cli.DisplayUserMessage(prompt)
 
+		// Run !`command` and $(command) escapes, then expand @file references
+		userMessage := interpolateShellCommands(ctx, prompt, cli)
+		userMessage, err = expandUserPrompt(userMessage)
+		if err != nil {
+			cli.DisplayError(err)
+			continue
+		}
+
 		// Add user message to history
-		messages = append(messages, schema.UserMessage(prompt))
+		messages = append(messages, agent.NewUserMessage(userMessage))
+		tw.User(userMessage)
 
-		// Prune messages if needed
-		if len(messages) > messageWindow {
-			messages = messages[len(messages)-messageWindow:]
-		}
+		terminalTitle.setModel(turnModelName)
+		messages = runInteractiveTurn(ctx, turnAgent, cli, tw, turnModelName, messages)
+		terminalTitle.setModel(modelName)
+	}
+}
 
-		// Get agent response with controlled spinner that stops for tool call display
-		var response *schema.Message
-		var currentSpinner *ui.Spinner
+// runInteractiveTurn gets and displays the agent's response to the history,
+// which ends with the user's prompt, and returns the history with the
+// response added. On error the history is returned without a response, so
+// the prompt can be retried with /retry.
+func runInteractiveTurn(ctx context.Context, mcpAgent *agent.Agent, cli *ui.CLI, tw *transcript.Writer, modelName string, messages []*schema.Message) []*schema.Message {
+	// Prune messages if needed, keeping pinned messages
+	messages = pruneHistory(cli, messages)
 
-		// Start initial spinner
-		currentSpinner = ui.NewSpinner("Thinking...")
-		currentSpinner.Start()
+	// Get agent response with controlled spinner that stops for tool call display
+	var response *schema.Message
+	var currentSpinner *ui.Spinner
+	var err error
+	var sources citations.Tracker
 
-		response, err = mcpAgent.GenerateWithLoop(ctx, messages,
-			// Tool call handler - called when a tool is about to be executed
-			func(toolName, toolArgs string) {
-				// Stop spinner before displaying tool call
-				if currentSpinner != nil {
-					currentSpinner.Stop()
-					currentSpinner = nil
-				}
-				cli.DisplayToolCallMessage(toolName, toolArgs)
-			},
-			// Tool execution handler - called when tool execution starts/ends
-			func(toolName string, isStarting bool) {
-				if isStarting {
-					// Start spinner for tool execution
-					currentSpinner = ui.NewSpinner(fmt.Sprintf("Executing %s...", toolName))
-					currentSpinner.Start()
-				} else {
-					// Stop spinner when tool execution completes
-					if currentSpinner != nil {
-						currentSpinner.Stop()
-						currentSpinner = nil
-					}
-				}
-			},
-			// Tool result handler - called when a tool execution completes
-			func(toolName, toolArgs, result string, isError bool) {
-				cli.DisplayToolMessage(toolName, toolArgs, result, isError)
-				// Start spinner again for next LLM call
-				currentSpinner = ui.NewSpinner("Thinking...")
+	// The answer is shown as it streams in
+	answer := cli.NewAnswerStream(modelName)
+
+	// In step-by-step mode the run waits for the user after each step
+	if stepMode {
+		mcpAgent = withStepPauses(mcpAgent, cli, tw, nil, &currentSpinner)
+	}
+
+	// Start initial spinner
+	terminalTitle.setStatus("thinking")
+	currentSpinner = cli.NewSpinner("Thinking...")
+	currentSpinner.Start()
+
+	response, err = mcpAgent.GenerateWithLoopStreaming(ctx, messages,
+		// Tool call handler - called when a tool is about to be executed
+		func(toolName, toolArgs string) {
+			tw.ToolCall(toolName, toolArgs)
+			// Stop spinner before displaying tool call
+			if currentSpinner != nil {
+				currentSpinner.Stop()
+				currentSpinner = nil
+			}
+			answer.Discard()
+			cli.DisplayToolCallMessage(toolName, toolArgs)
+		},
+		// Tool execution handler - called when tool execution starts/ends
+		func(toolName string, isStarting bool) {
+			if isStarting {
+				terminalTitle.setStatus("executing " + toolName)
+				// Start spinner for tool execution
+				currentSpinner = cli.NewSpinner(fmt.Sprintf("Executing %s...", toolName))
 				currentSpinner.Start()
-			},
-			// Response handler - called when the LLM generates a response
-			func(content string) {
-				// Stop spinner when we get the final response
-				if currentSpinner != nil {
-					currentSpinner.Stop()
-					currentSpinner = nil
-				}
-			},
-			// Tool call content handler - called when content accompanies tool calls
-			func(content string) {
-				// Stop spinner before displaying content
+			} else {
+				terminalTitle.setStatus("thinking")
+				// Stop spinner when tool execution completes
 				if currentSpinner != nil {
 					currentSpinner.Stop()
 					currentSpinner = nil
 				}
-				cli.DisplayAssistantMessageWithModel(content, modelName)
-				// Start spinner again for tool calls
-				currentSpinner = ui.NewSpinner("Thinking...")
-				currentSpinner.Start()
-			},
-		)
+			}
+		},
+		// Tool result handler - called when a tool execution completes
+		func(toolName, toolArgs, result string, isError bool) {
+			tw.ToolResult(toolName, toolArgs, result, isError)
+			if !isError {
+				sources.Add(toolArgs, result)
+			}
+			cli.DisplayToolMessage(toolName, toolArgs, result, isError)
+			// Start spinner again for next LLM call
+			currentSpinner = cli.NewSpinner("Thinking...")
+			currentSpinner.Start()
+		},
+		// Response handler - called when the LLM generates a response
+		func(content string) {
+			// Stop spinner when we get the final response
+			if currentSpinner != nil {
+				currentSpinner.Stop()
+				currentSpinner = nil
+			}
+		},
+		// Tool call content handler - called when content accompanies tool calls
+		func(content string) {
+			tw.Assistant(content, modelName)
+			// Stop spinner before displaying content
+			if currentSpinner != nil {
+				currentSpinner.Stop()
+				currentSpinner = nil
+			}
+			answer.Finish(content)
+			// Start spinner again for tool calls
+			currentSpinner = cli.NewSpinner("Thinking...")
+			currentSpinner.Start()
+		},
+		// Streaming handler - called with each chunk of the model's output
+		func(chunk string) {
+			// Stop spinner once the answer starts
+			if currentSpinner != nil {
+				currentSpinner.Stop()
+				currentSpinner = nil
+			}
+			answer.Write(chunk)
+		},
+	)
 
-		// Make sure spinner is stopped if still running
-		if currentSpinner != nil {
-			currentSpinner.Stop()
-		}
+	// Make sure spinner is stopped if still running
+	if currentSpinner != nil {
+		currentSpinner.Stop()
+	}
+	if err != nil {
+		answer.Discard()
+		tw.Error(err)
+		crashOnPanic(err)
+		cli.DisplayError(fmt.Errorf("agent error: %v", err))
+		return messages
+	}
+	cited := sources.Sources()
+	tw.Answer(response.Content, modelName, cited)
+	if err := recordAnswer(response, cited); err != nil {
+		cli.DisplayError(err)
+	}
+
+	// Display assistant response with model name and its sources, in place
+	// of the streamed answer
+	answer.Finish(citations.WithFooter(response.Content, cited))
+	speakAnswer(response.Content)
+	if limit := agent.MessageTimeLimit(response); limit > 0 {
+		cli.DisplayInfo(timeLimitNotice(limit))
+	}
+
+	// Add assistant response to history
+	messages = append(messages, response)
+
+	if showSuggestions {
+		spinner := cli.NewSpinner("Suggesting follow-ups...")
+		spinner.Start()
+		suggestions, err := mcpAgent.SuggestFollowUps(ctx, messages, maxSuggestions)
+		spinner.Stop()
 		if err != nil {
-			cli.DisplayError(fmt.Errorf("agent error: %v", err))
-			continue
+			cli.DisplayError(err)
+		} else {
+			cli.DisplaySuggestions(suggestions)
 		}
+	}
 
-		// Display assistant response with model name
-		if err := cli.DisplayAssistantMessageWithModel(response.Content, modelName); err != nil {
-			cli.DisplayError(fmt.Errorf("display error: %v", err))
+	return messages
+}
+
+// completeUserPrompt completes the @file reference being typed at the end of
+// a prompt, relative to the working directory
+func completeUserPrompt(input string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return input
+	}
+	return prompt.CompleteFileReference(input, cwd)
+}
+
+// expandUserPrompt expands @file references in a user prompt relative to the
+// working directory
+func expandUserPrompt(input string) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %v", err)
+	}
+
+	expanded, _, err := prompt.ExpandFileReferences(input, cwd)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand file references: %v", err)
+	}
+	return expanded, nil
+}
+
+// interpolateShellCommands replaces !`command` and $(command) escapes in an
+// interactive prompt with the command output, asking before each command
+func interpolateShellCommands(ctx context.Context, input string, cli *ui.CLI) string {
+	return prompt.InterpolateShellCommands(ctx, input, cli.ConfirmShellCommand)
+}
+
+// temperatureSetting converts the --temperature value to a provider setting,
+// where nil keeps the provider default
+func temperatureSetting(value float32) *float32 {
+	if value < 0 {
+		return nil
+	}
+	return &value
+}
+
+// maskSecrets tells GitHub Actions to hide API keys and MCP server headers,
+// which may hold credentials, in the job log
+func maskSecrets(mcpConfig *config.Config) {
+	actions.MaskEnvironment(os.Stderr)
+	for _, key := range []string{anthropicAPIKey, openaiAPIKey, googleAPIKey, azureAPIKey} {
+		actions.Mask(os.Stderr, key)
+	}
+	for _, server := range mcpConfig.MCPServers {
+		for _, header := range server.Headers {
+			if _, value, ok := strings.Cut(header, ":"); ok {
+				actions.Mask(os.Stderr, value)
+			}
+		}
+	}
+}
+
+// serverNames returns the names of the configured MCP servers, sorted
+func serverNames(mcpConfig *config.Config) []string {
+	names := make([]string, 0, len(mcpConfig.MCPServers))
+	for name := range mcpConfig.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// serverDetails describes the configured servers, and those added to the
+// config file since, for /servers
+func serverDetails(configured []string, running map[string]tools.ServerInfo) []ui.ServerDetails {
+	names := slices.Clone(configured)
+	for name := range running {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	details := make([]ui.ServerDetails, 0, len(names))
+	for _, name := range names {
+		server, ok := running[name]
+		details = append(details, ui.ServerDetails{
+			Name:           name,
+			Running:        ok,
+			Implementation: strings.TrimSpace(server.Name + " " + server.Version),
+			Capabilities:   server.Capabilities,
+			Instructions:   server.Instructions,
+		})
+	}
+	return details
+}
+
+// promptTools describes the agent's tools for system prompt templates
+func promptTools(toolInfos []*schema.ToolInfo, origins map[string]tools.ToolOrigin) []config.PromptTool {
+	described := make([]config.PromptTool, 0, len(toolInfos))
+	for _, info := range toolInfos {
+		described = append(described, config.PromptTool{
+			Name:        info.Name,
+			Server:      origins[info.Name].Server,
+			Description: info.Desc,
+		})
+	}
+	return described
+}
+
+// handleAgentSlashCommand handles slash commands that need access to the agent
+// and returns true if handled
+func handleAgentSlashCommand(input string, mcpAgent *agent.Agent, cli *ui.CLI) bool {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch fields[0] {
+	case "/logs":
+		if len(fields) < 2 {
+			cli.DisplayError(fmt.Errorf("usage: /logs <server>"))
+			return true
 		}
+		lines, ok := mcpAgent.GetServerLogs(fields[1])
+		if !ok {
+			cli.DisplayError(fmt.Errorf("no logs for server %s (only stdio servers have logs)", fields[1]))
+			return true
+		}
+		cli.DisplayServerLogs(fields[1], lines)
+		return true
+	case "/permissions":
+		handlePermissionsCommand(fields[1:], mcpAgent.Permissions(), cli)
+		return true
+	case "/system":
+		handleSystemCommand(fields[1:], mcpAgent, cli)
+		return true
+	default:
+		return false
+	}
+}
 
-		// Add assistant response to history
-		messages = append(messages, response)
+// handleSystemCommand views or edits the system prompt of the session
+func handleSystemCommand(args []string, mcpAgent *agent.Agent, cli *ui.CLI) {
+	switch {
+	case len(args) == 0:
+		systemPrompt := mcpAgent.SystemPrompt()
+		if systemPrompt == "" {
+			cli.DisplayInfo("No system prompt is set. Use `/system edit` to write one.")
+			return
+		}
+		cli.DisplayInfo("## System Prompt\n\n" + systemPrompt)
+	case len(args) == 1 && args[0] == "edit":
+		edited, err := editText(mcpAgent.SystemPrompt(), "mcphost-system-*.md")
+		if err != nil {
+			cli.DisplayError(err)
+			return
+		}
+		edited = strings.TrimSpace(edited)
+		if edited == strings.TrimSpace(mcpAgent.SystemPrompt()) {
+			cli.DisplayInfo("System prompt unchanged")
+			return
+		}
+		mcpAgent.SetSystemPrompt(edited)
+		cli.DisplayInfo("System prompt updated for the rest of the session")
+	default:
+		cli.DisplayError(fmt.Errorf("usage: /system [edit]"))
 	}
 }
 
+// handlePermissionsCommand views or modifies the live tool permission policy
+func handlePermissionsCommand(args []string, policy *tools.PermissionPolicy, cli *ui.CLI) {
+	usage := fmt.Errorf("usage: /permissions [allow <tool> | revoke <tool> | confirm on|off]")
+
+	if len(args) == 0 {
+		cli.DisplayPermissions(policy.Confirm(), policy.ReadOnly(), policy.Rules())
+		return
+	}
+	if len(args) != 2 {
+		cli.DisplayError(usage)
+		return
+	}
+
+	switch args[0] {
+	case "allow":
+		policy.Approve(args[1])
+	case "revoke":
+		if !policy.Revoke(args[1]) {
+			cli.DisplayError(fmt.Errorf("%s is not auto-approved", args[1]))
+			return
+		}
+	case "confirm":
+		switch args[1] {
+		case "on":
+			policy.SetConfirm(true)
+		case "off":
+			policy.SetConfirm(false)
+		default:
+			cli.DisplayError(usage)
+			return
+		}
+	default:
+		cli.DisplayError(usage)
+		return
+	}
+
+	cli.DisplayPermissions(policy.Confirm(), policy.ReadOnly(), policy.Rules())
+}
+
 // runScriptMode handles script mode execution
 func runScriptMode(ctx context.Context) error {
 	var scriptFile string
@@ -542,10 +2022,19 @@ func runScriptMode(ctx context.Context) error {
 
 	// Filter out flags to find the script file
 	for _, arg := range args {
+		if arg == "--" {
+			// Everything after -- belongs to the script
+			break
+		}
 		if arg == "--script" {
 			// Skip the --script flag itself
 			continue
 		}
+		if arg == "-" {
+			// Read the script from stdin, e.g. a here-doc
+			scriptFile = arg
+			break
+		}
 		if strings.HasPrefix(arg, "-") {
 			// Skip other flags
 			continue
@@ -565,11 +2054,24 @@ func runScriptMode(ctx context.Context) error {
 		return fmt.Errorf("failed to parse script file: %v", err)
 	}
 
+	// Expose the arguments after -- to the script's prompts
+	substituteScriptArgs(scriptConfig, scriptArgs(args))
+
+	return runWithScriptConfig(ctx, scriptConfig)
+}
+
+// runWithScriptConfig runs the normal execution path with the settings of a
+// script or agent definition taking precedence over flags and the config file
+func runWithScriptConfig(ctx context.Context, scriptConfig *config.Config) error {
 	// Override the global configFile and promptFlag with script values
 	originalConfigFile := configFile
 	originalPromptFlag := promptFlag
 	originalModelFlag := modelFlag
+	originalUtilityModel := utilityModel
 	originalMaxSteps := maxSteps
+	originalStepTimeout := stepTimeout
+	originalMaxDuration := maxDuration
+	originalModelRetries := modelRetries
 	originalMessageWindow := messageWindow
 	originalDebugMode := debugMode
 	originalSystemPromptFile := systemPromptFile
@@ -578,9 +2080,56 @@ func runScriptMode(ctx context.Context) error {
 	originalGoogleAPIKey := googleAPIKey
 	originalOpenAIURL := openaiBaseURL
 	originalAnthropicURL := anthropicBaseURL
+	originalSpinnerStyle := spinnerStyle
+	originalShowToolArgs := showToolArgs
+	originalCompactMode := compactMode
+	originalContextFiles := contextFiles
+	originalContextPaths := contextPaths
+	originalContextBudget := contextBudget
+	originalPromptSources := promptSources
+	originalTemperature := temperature
+	originalQuietMode := quietMode
+	originalOutputFormat := outputFormat
+	originalPrintMode := printMode
+	originalTranscriptFile := transcriptFile
+	originalOutputFile := outputFile
+	originalConfirmTools := confirmTools
+	originalReadOnly := readOnly
+	originalToolChoice := toolChoice
+	originalNoParallelTools := noParallelTools
+	originalNoJSONRepair := noJSONRepair
+	originalForceTool := forceTool
+	originalForceToolArgs := forceToolArgs
+	originalReturnDirect := returnDirect
+	originalMessageModifiers := messageModifiers
+	originalPauseBeforeTools := pauseBeforeTools
+	originalCheckpointDir := checkpointDir
+	originalDataDir := dataDir
+	originalWorkdir := workdir
+	originalSandboxMode := sandboxMode
+	originalSandboxApply := sandboxApply
+	originalSnapshotsMode := snapshotsMode
+	originalPreflight := preflight
+	originalIgnoreCaps := ignoreCaps
+	originalStepMode := stepMode
+	originalVoiceMode := voiceMode
+	originalSpeakMode := speakMode
+	originalNativeSearch := nativeSearch
+	originalInteractiveFlag := interactiveFlag
+	originalNoEnvironment := noEnvironment
+	originalServerInstr := serverInstr
+	originalResponseLanguage := responseLanguage
+	originalShareEndpoint := shareEndpoint
+	originalDumpTrafficDir := dumpTrafficDir
+	originalTimeFormat := timeFormat
+	originalNoTimestamps := noTimestamps
+	originalNoTitle := noTitle
+	originalRenderMode := renderMode
+	originalShowSuggestions := showSuggestions
 
 	// Create config from script or load normal config
 	var mcpConfig *config.Config
+	var err error
 	if len(scriptConfig.MCPServers) > 0 {
 		// Use servers from script
 		mcpConfig = scriptConfig
@@ -594,9 +2143,21 @@ func runScriptMode(ctx context.Context) error {
 		if scriptConfig.Model != "" {
 			mcpConfig.Model = scriptConfig.Model
 		}
+		if scriptConfig.UtilityModel != "" {
+			mcpConfig.UtilityModel = scriptConfig.UtilityModel
+		}
 		if scriptConfig.MaxSteps != 0 {
 			mcpConfig.MaxSteps = scriptConfig.MaxSteps
 		}
+		if scriptConfig.StepTimeout != "" {
+			mcpConfig.StepTimeout = scriptConfig.StepTimeout
+		}
+		if scriptConfig.MaxDuration != "" {
+			mcpConfig.MaxDuration = scriptConfig.MaxDuration
+		}
+		if scriptConfig.ModelRetries != 0 {
+			mcpConfig.ModelRetries = scriptConfig.ModelRetries
+		}
 		if scriptConfig.MessageWindow != 0 {
 			mcpConfig.MessageWindow = scriptConfig.MessageWindow
 		}
@@ -624,52 +2185,180 @@ func runScriptMode(ctx context.Context) error {
 		if scriptConfig.Prompt != "" {
 			mcpConfig.Prompt = scriptConfig.Prompt
 		}
+		if scriptConfig.Spinner != "" {
+			mcpConfig.Spinner = scriptConfig.Spinner
+		}
+		if scriptConfig.ShowToolArgs != nil {
+			mcpConfig.ShowToolArgs = scriptConfig.ShowToolArgs
+		}
+		if scriptConfig.Compact {
+			mcpConfig.Compact = scriptConfig.Compact
+		}
+		if len(scriptConfig.ContextFiles) > 0 {
+			mcpConfig.ContextFiles = scriptConfig.ContextFiles
+		}
+		if len(scriptConfig.Context) > 0 {
+			mcpConfig.Context = scriptConfig.Context
+		}
+		if scriptConfig.ContextBudget != 0 {
+			mcpConfig.ContextBudget = scriptConfig.ContextBudget
+		}
+		if len(scriptConfig.SystemPrompts) > 0 {
+			mcpConfig.SystemPrompts = scriptConfig.SystemPrompts
+		}
+		if len(scriptConfig.Guardrails) > 0 {
+			mcpConfig.Guardrails = scriptConfig.Guardrails
+		}
+		if scriptConfig.ToolSeparator != "" {
+			mcpConfig.ToolSeparator = scriptConfig.ToolSeparator
+		}
+		if len(scriptConfig.Conversation) > 0 {
+			mcpConfig.Conversation = scriptConfig.Conversation
+		}
+		if scriptConfig.Temperature != nil {
+			mcpConfig.Temperature = scriptConfig.Temperature
+		}
+		if scriptConfig.Quiet != "" {
+			mcpConfig.Quiet = scriptConfig.Quiet
+		}
+		if scriptConfig.Output != "" {
+			mcpConfig.Output = scriptConfig.Output
+		}
+		if scriptConfig.Print != "" {
+			mcpConfig.Print = scriptConfig.Print
+		}
+		if scriptConfig.Transcript != "" {
+			mcpConfig.Transcript = scriptConfig.Transcript
+		}
+		if scriptConfig.OutputFile != "" {
+			mcpConfig.OutputFile = scriptConfig.OutputFile
+		}
+		if scriptConfig.ConfirmTools {
+			mcpConfig.ConfirmTools = scriptConfig.ConfirmTools
+		}
+		if scriptConfig.ReadOnly {
+			mcpConfig.ReadOnly = scriptConfig.ReadOnly
+		}
+		if len(scriptConfig.ReadOnlyTools) > 0 {
+			mcpConfig.ReadOnlyTools = scriptConfig.ReadOnlyTools
+		}
+		if scriptConfig.ToolChoice != "" {
+			mcpConfig.ToolChoice = scriptConfig.ToolChoice
+		}
+		if scriptConfig.NoParallelTools {
+			mcpConfig.NoParallelTools = scriptConfig.NoParallelTools
+		}
+		if scriptConfig.NoJSONRepair {
+			mcpConfig.NoJSONRepair = scriptConfig.NoJSONRepair
+		}
+		if scriptConfig.ForceTool != "" {
+			mcpConfig.ForceTool = scriptConfig.ForceTool
+		}
+		if scriptConfig.ForceArgs != "" {
+			mcpConfig.ForceArgs = scriptConfig.ForceArgs
+		}
+		if len(scriptConfig.ReturnDirectTools) > 0 {
+			mcpConfig.ReturnDirectTools = scriptConfig.ReturnDirectTools
+		}
+		if len(scriptConfig.MessageModifiers) > 0 {
+			mcpConfig.MessageModifiers = scriptConfig.MessageModifiers
+		}
+		if scriptConfig.PauseBeforeTools {
+			mcpConfig.PauseBeforeTools = scriptConfig.PauseBeforeTools
+		}
+		if scriptConfig.CheckpointDir != "" {
+			mcpConfig.CheckpointDir = scriptConfig.CheckpointDir
+		}
+		if scriptConfig.DataDir != "" {
+			mcpConfig.DataDir = scriptConfig.DataDir
+		}
+		if scriptConfig.Workdir != "" {
+			mcpConfig.Workdir = scriptConfig.Workdir
+		}
+		if scriptConfig.Sandbox {
+			mcpConfig.Sandbox = scriptConfig.Sandbox
+		}
+		if scriptConfig.SandboxApply {
+			mcpConfig.SandboxApply = scriptConfig.SandboxApply
+		}
+		if scriptConfig.Snapshots {
+			mcpConfig.Snapshots = scriptConfig.Snapshots
+		}
+		if scriptConfig.Preflight {
+			mcpConfig.Preflight = scriptConfig.Preflight
+		}
+		if scriptConfig.IgnoreCaps {
+			mcpConfig.IgnoreCaps = scriptConfig.IgnoreCaps
+		}
+		if scriptConfig.Step {
+			mcpConfig.Step = scriptConfig.Step
+		}
+		if scriptConfig.Voice {
+			mcpConfig.Voice = scriptConfig.Voice
+		}
+		if scriptConfig.SpeechToText != nil {
+			mcpConfig.SpeechToText = scriptConfig.SpeechToText
+		}
+		if scriptConfig.Speak {
+			mcpConfig.Speak = scriptConfig.Speak
+		}
+		if scriptConfig.TextToSpeech != nil {
+			mcpConfig.TextToSpeech = scriptConfig.TextToSpeech
+		}
+		if scriptConfig.NativeSearch {
+			mcpConfig.NativeSearch = scriptConfig.NativeSearch
+		}
+		if scriptConfig.Interactive {
+			mcpConfig.Interactive = scriptConfig.Interactive
+		}
+		if scriptConfig.NoEnvironment {
+			mcpConfig.NoEnvironment = scriptConfig.NoEnvironment
+		}
+		if scriptConfig.ServerInstructions {
+			mcpConfig.ServerInstructions = scriptConfig.ServerInstructions
+		}
+		if scriptConfig.Language != "" {
+			mcpConfig.Language = scriptConfig.Language
+		}
+		if scriptConfig.ShareEndpoint != "" {
+			mcpConfig.ShareEndpoint = scriptConfig.ShareEndpoint
+		}
+		if scriptConfig.DumpLLMTraffic != "" {
+			mcpConfig.DumpLLMTraffic = scriptConfig.DumpLLMTraffic
+		}
+		if len(scriptConfig.Providers) > 0 {
+			mcpConfig.Providers = scriptConfig.Providers
+		}
+		if scriptConfig.TimeFormat != "" {
+			mcpConfig.TimeFormat = scriptConfig.TimeFormat
+		}
+		if scriptConfig.NoTimestamps {
+			mcpConfig.NoTimestamps = scriptConfig.NoTimestamps
+		}
+		if scriptConfig.NoTitle {
+			mcpConfig.NoTitle = scriptConfig.NoTitle
+		}
+		if scriptConfig.Render != "" {
+			mcpConfig.Render = scriptConfig.Render
+		}
+		if scriptConfig.Suggestions {
+			mcpConfig.Suggestions = scriptConfig.Suggestions
+		}
 	}
 
 	// Override the global config for normal mode
 	scriptMCPConfig = mcpConfig
 
-	// Apply script configuration to global flags
-	if mcpConfig.Prompt != "" {
-		promptFlag = mcpConfig.Prompt
-	}
-	if mcpConfig.Model != "" {
-		modelFlag = mcpConfig.Model
-	}
-	if mcpConfig.MaxSteps != 0 {
-		maxSteps = mcpConfig.MaxSteps
-	}
-	if mcpConfig.MessageWindow != 0 {
-		messageWindow = mcpConfig.MessageWindow
-	}
-	if mcpConfig.Debug {
-		debugMode = mcpConfig.Debug
-	}
-	if mcpConfig.SystemPrompt != "" {
-		systemPromptFile = mcpConfig.SystemPrompt
-	}
-	if mcpConfig.OpenAIAPIKey != "" {
-		openaiAPIKey = mcpConfig.OpenAIAPIKey
-	}
-	if mcpConfig.AnthropicAPIKey != "" {
-		anthropicAPIKey = mcpConfig.AnthropicAPIKey
-	}
-	if mcpConfig.GoogleAPIKey != "" {
-		googleAPIKey = mcpConfig.GoogleAPIKey
-	}
-	if mcpConfig.OpenAIURL != "" {
-		openaiBaseURL = mcpConfig.OpenAIURL
-	}
-	if mcpConfig.AnthropicURL != "" {
-		anthropicBaseURL = mcpConfig.AnthropicURL
-	}
-
 	// Restore original values after execution
 	defer func() {
 		configFile = originalConfigFile
 		promptFlag = originalPromptFlag
 		modelFlag = originalModelFlag
+		utilityModel = originalUtilityModel
 		maxSteps = originalMaxSteps
+		stepTimeout = originalStepTimeout
+		maxDuration = originalMaxDuration
+		modelRetries = originalModelRetries
 		messageWindow = originalMessageWindow
 		debugMode = originalDebugMode
 		systemPromptFile = originalSystemPromptFile
@@ -678,6 +2367,52 @@ func runScriptMode(ctx context.Context) error {
 		googleAPIKey = originalGoogleAPIKey
 		openaiBaseURL = originalOpenAIURL
 		anthropicBaseURL = originalAnthropicURL
+		spinnerStyle = originalSpinnerStyle
+		showToolArgs = originalShowToolArgs
+		compactMode = originalCompactMode
+		contextFiles = originalContextFiles
+		contextPaths = originalContextPaths
+		contextBudget = originalContextBudget
+		promptSources = originalPromptSources
+		temperature = originalTemperature
+		quietMode = originalQuietMode
+		outputFormat = originalOutputFormat
+		printMode = originalPrintMode
+		transcriptFile = originalTranscriptFile
+		outputFile = originalOutputFile
+		confirmTools = originalConfirmTools
+		readOnly = originalReadOnly
+		toolChoice = originalToolChoice
+		noParallelTools = originalNoParallelTools
+		noJSONRepair = originalNoJSONRepair
+		forceTool = originalForceTool
+		forceToolArgs = originalForceToolArgs
+		returnDirect = originalReturnDirect
+		messageModifiers = originalMessageModifiers
+		pauseBeforeTools = originalPauseBeforeTools
+		checkpointDir = originalCheckpointDir
+		dataDir = originalDataDir
+		workdir = originalWorkdir
+		sandboxMode = originalSandboxMode
+		sandboxApply = originalSandboxApply
+		snapshotsMode = originalSnapshotsMode
+		preflight = originalPreflight
+		ignoreCaps = originalIgnoreCaps
+		stepMode = originalStepMode
+		voiceMode = originalVoiceMode
+		speakMode = originalSpeakMode
+		nativeSearch = originalNativeSearch
+		interactiveFlag = originalInteractiveFlag
+		noEnvironment = originalNoEnvironment
+		serverInstr = originalServerInstr
+		responseLanguage = originalResponseLanguage
+		shareEndpoint = originalShareEndpoint
+		dumpTrafficDir = originalDumpTrafficDir
+		timeFormat = originalTimeFormat
+		noTimestamps = originalNoTimestamps
+		noTitle = originalNoTitle
+		renderMode = originalRenderMode
+		showSuggestions = originalShowSuggestions
 		scriptMCPConfig = nil
 	}()
 
@@ -685,56 +2420,331 @@ func runScriptMode(ctx context.Context) error {
 	return runNormalMode(ctx)
 }
 
-// parseScriptFile parses a script file with YAML frontmatter and returns config
+// applyScriptConfig overrides the global flag values with the values set in
+// a script's frontmatter, so scripts are self-contained
+func applyScriptConfig(cfg *config.Config) {
+	if cfg.Prompt != "" {
+		promptFlag = cfg.Prompt
+	}
+	if cfg.Model != "" {
+		modelFlag = cfg.Model
+	}
+	if cfg.UtilityModel != "" {
+		utilityModel = cfg.UtilityModel
+	}
+	if cfg.MaxSteps != 0 {
+		maxSteps = cfg.MaxSteps
+	}
+	if cfg.StepTimeout != "" {
+		// Validated when the config was loaded
+		stepTimeout, _ = time.ParseDuration(cfg.StepTimeout)
+	}
+	if cfg.MaxDuration != "" {
+		// Validated when the config was loaded
+		maxDuration, _ = time.ParseDuration(cfg.MaxDuration)
+	}
+	if cfg.ModelRetries != 0 {
+		modelRetries = cfg.ModelRetries
+	}
+	if cfg.MessageWindow != 0 {
+		messageWindow = cfg.MessageWindow
+	}
+	if cfg.Debug {
+		debugMode = cfg.Debug
+	}
+	if cfg.SystemPrompt != "" {
+		systemPromptFile = cfg.SystemPrompt
+	}
+	if cfg.OpenAIAPIKey != "" {
+		openaiAPIKey = cfg.OpenAIAPIKey
+	}
+	if cfg.AnthropicAPIKey != "" {
+		anthropicAPIKey = cfg.AnthropicAPIKey
+	}
+	if cfg.GoogleAPIKey != "" {
+		googleAPIKey = cfg.GoogleAPIKey
+	}
+	if cfg.OpenAIURL != "" {
+		openaiBaseURL = cfg.OpenAIURL
+	}
+	if cfg.AnthropicURL != "" {
+		anthropicBaseURL = cfg.AnthropicURL
+	}
+	if cfg.Spinner != "" {
+		spinnerStyle = cfg.Spinner
+	}
+	if cfg.ShowToolArgs != nil {
+		showToolArgs = *cfg.ShowToolArgs
+	}
+	if cfg.Compact {
+		compactMode = cfg.Compact
+	}
+	if len(cfg.ContextFiles) > 0 {
+		contextFiles = cfg.ContextFiles
+	}
+	if len(cfg.Context) > 0 {
+		contextPaths = cfg.Context
+	}
+	if cfg.ContextBudget != 0 {
+		contextBudget = cfg.ContextBudget
+	}
+	if len(cfg.SystemPrompts) > 0 {
+		promptSources = cfg.SystemPrompts
+	}
+	if cfg.Temperature != nil {
+		temperature = *cfg.Temperature
+	}
+	if cfg.Quiet != "" {
+		quietMode = cfg.Quiet
+	}
+	if cfg.Output != "" {
+		outputFormat = cfg.Output
+	}
+	if cfg.Print != "" {
+		printMode = cfg.Print
+	}
+	if cfg.Transcript != "" {
+		transcriptFile = cfg.Transcript
+	}
+	if cfg.OutputFile != "" {
+		outputFile = cfg.OutputFile
+	}
+	if cfg.ConfirmTools {
+		confirmTools = cfg.ConfirmTools
+	}
+	if cfg.ReadOnly {
+		readOnly = cfg.ReadOnly
+	}
+	if cfg.ToolChoice != "" {
+		toolChoice = cfg.ToolChoice
+	}
+	if cfg.NoParallelTools {
+		noParallelTools = cfg.NoParallelTools
+	}
+	if cfg.NoJSONRepair {
+		noJSONRepair = cfg.NoJSONRepair
+	}
+	if cfg.ForceTool != "" {
+		forceTool = cfg.ForceTool
+	}
+	if cfg.ForceArgs != "" {
+		forceToolArgs = cfg.ForceArgs
+	}
+	if len(cfg.ReturnDirectTools) > 0 {
+		returnDirect = cfg.ReturnDirectTools
+	}
+	if len(cfg.MessageModifiers) > 0 {
+		messageModifiers = cfg.MessageModifiers
+	}
+	if cfg.PauseBeforeTools {
+		pauseBeforeTools = cfg.PauseBeforeTools
+	}
+	if cfg.CheckpointDir != "" {
+		checkpointDir = cfg.CheckpointDir
+	}
+	if cfg.DataDir != "" {
+		dataDir = cfg.DataDir
+	}
+	if cfg.Workdir != "" {
+		workdir = cfg.Workdir
+	}
+	if cfg.Sandbox {
+		sandboxMode = cfg.Sandbox
+	}
+	if cfg.SandboxApply {
+		sandboxApply = cfg.SandboxApply
+	}
+	if cfg.Snapshots {
+		snapshotsMode = cfg.Snapshots
+	}
+	if cfg.Preflight {
+		preflight = cfg.Preflight
+	}
+	if cfg.IgnoreCaps {
+		ignoreCaps = cfg.IgnoreCaps
+	}
+	if cfg.Step {
+		stepMode = cfg.Step
+	}
+	if cfg.Voice {
+		voiceMode = cfg.Voice
+	}
+	if cfg.Speak {
+		speakMode = cfg.Speak
+	}
+	if cfg.NativeSearch {
+		nativeSearch = cfg.NativeSearch
+	}
+	if cfg.Interactive {
+		interactiveFlag = cfg.Interactive
+	}
+	if cfg.NoEnvironment {
+		noEnvironment = cfg.NoEnvironment
+	}
+	if cfg.ServerInstructions {
+		serverInstr = cfg.ServerInstructions
+	}
+	if cfg.Language != "" {
+		responseLanguage = cfg.Language
+	}
+	if cfg.ShareEndpoint != "" {
+		shareEndpoint = cfg.ShareEndpoint
+	}
+	if cfg.DumpLLMTraffic != "" {
+		dumpTrafficDir = cfg.DumpLLMTraffic
+	}
+	if cfg.TimeFormat != "" {
+		timeFormat = cfg.TimeFormat
+	}
+	if cfg.NoTimestamps {
+		noTimestamps = cfg.NoTimestamps
+	}
+	if cfg.NoTitle {
+		noTitle = cfg.NoTitle
+	}
+	if cfg.Render != "" {
+		renderMode = cfg.Render
+	}
+	if cfg.Suggestions {
+		showSuggestions = cfg.Suggestions
+	}
+}
+
+// parseScriptFile parses a script file with YAML frontmatter and returns config.
+// A filename of "-" reads the script from stdin.
 func parseScriptFile(filename string) (*config.Config, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
+	var file io.Reader = os.Stdin
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		file = f
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %v", err)
+	}
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
 
 	// Skip shebang line if present
-	if scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "#!") {
-			// If it's not a shebang, we need to process this line
-			return parseScriptContent(line + "\n" + readRemainingLines(scanner))
+	if strings.HasPrefix(content, "#!") {
+		if i := strings.Index(content, "\n"); i >= 0 {
+			content = content[i+1:]
+		} else {
+			content = ""
 		}
 	}
 
-	// Read the rest of the file
-	content := readRemainingLines(scanner)
 	return parseScriptContent(content)
 }
 
-// readRemainingLines reads all remaining lines from a scanner
-func readRemainingLines(scanner *bufio.Scanner) string {
-	var lines []string
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+// scriptArgs returns the arguments after the first "--", which are passed
+// to the script rather than to mcphost
+func scriptArgs(args []string) []string {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[i+1:]
+		}
 	}
-	return strings.Join(lines, "\n")
+	return nil
 }
 
-// parseScriptContent parses the content to extract YAML frontmatter
-func parseScriptContent(content string) (*config.Config, error) {
-	lines := strings.Split(content, "\n")
+// scriptArgPattern matches ${args} and ${argN} placeholders
+var scriptArgPattern = regexp.MustCompile(`\$\{(args|arg[0-9]+)\}`)
+
+// substituteScriptArgs replaces ${args} with all script arguments joined by
+// spaces and ${argN} with the Nth argument (starting at 1) in the script's
+// prompts. Placeholders for missing arguments become empty.
+func substituteScriptArgs(scriptConfig *config.Config, args []string) {
+	replace := func(text string) string {
+		return scriptArgPattern.ReplaceAllStringFunc(text, func(match string) string {
+			name := match[2 : len(match)-1]
+			if name == "args" {
+				return strings.Join(args, " ")
+			}
+			n, err := strconv.Atoi(strings.TrimPrefix(name, "arg"))
+			if err != nil || n < 1 || n > len(args) {
+				return ""
+			}
+			return args[n-1]
+		})
+	}
 
-	// Find YAML frontmatter
-	var yamlLines []string
+	scriptConfig.Prompt = replace(scriptConfig.Prompt)
+	for i := range scriptConfig.Conversation {
+		scriptConfig.Conversation[i].Prompt = replace(scriptConfig.Conversation[i].Prompt)
+	}
+}
 
-	for _, line := range lines {
-		yamlLines = append(yamlLines, line)
+// frontmatterDelimiter opens and closes the YAML frontmatter of a script
+const frontmatterDelimiter = "---"
+
+// parseScriptContent parses the content to extract YAML frontmatter.
+//
+// When the content starts with a "---" line, everything up to the next "---"
+// line is the frontmatter and the rest of the file is the prompt, so the
+// prompt can contain any text. Otherwise the whole content is parsed as YAML,
+// which is the original script format.
+func parseScriptContent(content string) (*config.Config, error) {
+	frontmatter, body, delimited := splitFrontmatter(content)
+	if !delimited {
+		frontmatter = content
 	}
 
-	// Parse YAML
-	yamlContent := strings.Join(yamlLines, "\n")
 	var scriptConfig config.Config
-	if err := yaml.Unmarshal([]byte(yamlContent), &scriptConfig); err != nil {
+	if err := yaml.Unmarshal([]byte(frontmatter), &scriptConfig); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML: %v", err)
 	}
 
+	if delimited {
+		body = strings.TrimSpace(body)
+		if body != "" {
+			if scriptConfig.Prompt != "" || len(scriptConfig.Conversation) > 0 {
+				return nil, fmt.Errorf("script defines a prompt in both the frontmatter and the body")
+			}
+			scriptConfig.Prompt = body
+		}
+	}
+
+	if err := scriptConfig.Validate(); err != nil {
+		return nil, err
+	}
 	return &scriptConfig, nil
 }
+
+// splitFrontmatter splits content into the YAML between the leading pair of
+// "---" lines and the body after them. Blank lines and comments before the
+// opening delimiter are ignored. ok is false if the content has no
+// delimited frontmatter.
+func splitFrontmatter(content string) (frontmatter, body string, ok bool) {
+	lines := strings.Split(content, "\n")
+
+	open := -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.TrimRight(line, " \t") == frontmatterDelimiter {
+			open = i
+		}
+		break
+	}
+	if open < 0 {
+		return "", "", false
+	}
+
+	for i := open + 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], " \t") == frontmatterDelimiter {
+			frontmatter = strings.Join(lines[open+1:i], "\n")
+			body = strings.Join(lines[i+1:], "\n")
+			return frontmatter, body, true
+		}
+	}
+
+	// An unterminated "---" is a YAML document marker, not frontmatter
+	return "", "", false
+}
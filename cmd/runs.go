@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/paths"
+	"github.com/mark3labs/mcphost/internal/transcript"
+	"github.com/mark3labs/mcphost/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// resumeRunID is the saved run given to mcphost resume-run
+var resumeRunID string
+
+var resumeRunCmd = &cobra.Command{
+	Use:   "resume-run [id]",
+	Short: "Continue a non-interactive run that was killed or crashed",
+	Long: `Continue a non-interactive run that stopped before it finished.
+
+Runs of mcphost -p and of scripts are saved in runs in the data directory as
+they go: the conversation after every model response and tool result, and
+the turns of a scripted conversation still to come. A run that finishes is
+deleted; one that fails, is interrupted, crashes or is killed stays, and
+resume-run continues it where it stopped. Tool calls the model asked for
+that have no result yet are made first.
+
+Without an ID, resume-run lists the saved runs.
+
+The run's model, MCP servers and output flags are taken from the command
+line and config file as usual, so pass the flags of the original run.
+
+Examples:
+  mcphost resume-run
+  mcphost resume-run run-20250101-120000-4242
+  mcphost resume-run run-20250101-120000-4242 --quiet`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return listSavedRuns()
+		}
+		if promptFlag != "" || interactiveFlag {
+			return fmt.Errorf("resume-run continues a saved run and can't be combined with --prompt or --interactive")
+		}
+		resumeRunID = args[0]
+
+		ctx, cancel := withSignalHandling(context.Background())
+		defer cancel()
+		return runMCPHost(ctx)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resumeRunCmd)
+}
+
+// savedRun is the state of a non-interactive run, saved as it goes
+type savedRun struct {
+	ID      string    `json:"id"`
+	Model   string    `json:"model"`
+	Started time.Time `json:"started"`
+	Updated time.Time `json:"updated"`
+	// Messages is the conversation so far, which may end with tool calls
+	// that have no result yet
+	Messages []*schema.Message `json:"messages"`
+	// Turns are the turns of a scripted conversation from the one being
+	// run on
+	Turns []config.ConversationTurn `json:"turns,omitempty"`
+}
+
+// runJournal saves a run in the runs directory each time it progresses. Its
+// methods do nothing on a nil *runJournal.
+type runJournal struct {
+	mu    sync.Mutex
+	path  string
+	run   savedRun
+	ended bool
+	// failed is set once saving failed, which is reported only once
+	failed bool
+}
+
+// activeRun is the journal of the non-interactive run in progress, if any
+var activeRun *runJournal
+
+// runsDir returns the directory runs are saved in
+func runsDir() (string, error) {
+	return paths.Data("runs")
+}
+
+// savedRunPath returns the file of a saved run. IDs are file names, so they
+// can't contain path separators.
+func savedRunPath(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid run ID %q", id)
+	}
+	dir, err := runsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id+".json"), nil
+}
+
+// newRunJournal returns a journal saving a new run of the model under a
+// new ID
+func newRunJournal(model string) (*runJournal, error) {
+	id := newCheckpointID()
+	path, err := savedRunPath(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &runJournal{path: path, run: savedRun{ID: id, Model: model, Started: now, Updated: now}}, nil
+}
+
+// loadRunJournal returns the journal of the saved run id, which goes on
+// saving the run under the same ID
+func loadRunJournal(id string) (*runJournal, error) {
+	path, err := savedRunPath(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no saved run %s; mcphost resume-run lists the saved runs", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read saved run: %v", err)
+	}
+
+	journal := &runJournal{path: path}
+	if err := json.Unmarshal(data, &journal.run); err != nil {
+		return nil, fmt.Errorf("invalid saved run %s: %v", id, err)
+	}
+	return journal, nil
+}
+
+// save saves the run's conversation; it is the agent's progress handler
+func (j *runJournal) save(messages []*schema.Message) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.run.Messages = messages
+	j.write()
+}
+
+// setTurns saves the turns of a scripted conversation from the one about to
+// run on
+func (j *runJournal) setTurns(turns []config.ConversationTurn) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.run.Turns = turns
+}
+
+// write replaces the saved run atomically, so that a run killed while
+// saving keeps its previous state; j.mu must be held
+func (j *runJournal) write() {
+	if j.ended {
+		return
+	}
+	j.run.Updated = time.Now()
+	data, err := json.Marshal(j.run)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(j.path), 0700)
+	}
+	if err == nil {
+		tmp := j.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0600); err == nil {
+			if err = os.Rename(tmp, j.path); err != nil {
+				os.Remove(tmp)
+			}
+		}
+	}
+	if err != nil && !j.failed {
+		j.failed = true
+		fmt.Fprintf(os.Stderr, "Failed to save the run, so it can't be resumed: %v\n", err)
+	}
+}
+
+// end deletes the saved run if it finished without error, and otherwise
+// says how to continue it
+func (j *runJournal) end(err error) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.ended {
+		return
+	}
+	j.ended = true
+
+	if err == nil {
+		os.Remove(j.path)
+		return
+	}
+	if _, paused := isPaused(err); !paused {
+		j.hint()
+	}
+}
+
+// interrupted says how to continue the run when mcphost is interrupted or
+// crashes; it is a shutdown hook
+func (j *runJournal) interrupted() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.ended {
+		return
+	}
+	j.ended = true
+	j.hint()
+}
+
+// hint says how to continue the run, if it was saved; j.mu must be held
+func (j *runJournal) hint() {
+	if _, err := os.Stat(j.path); err == nil {
+		fmt.Fprintf(os.Stderr, "Continue the run with: mcphost resume-run %s\n", j.run.ID)
+	}
+}
+
+// runSavedRun continues the saved run of the journal: the turn it stopped
+// in and then any turns of its scripted conversation still to come
+func runSavedRun(ctx context.Context, mcpAgent *agent.Agent, cli *ui.CLI, tw *transcript.Writer, journal *runJournal, modelName string) error {
+	run := journal.run
+	// The run's system prompt is replaced by the current one
+	messages := run.Messages
+	for len(messages) > 0 && messages[0].Role == schema.System {
+		messages = messages[1:]
+	}
+	if len(messages) == 0 {
+		return fmt.Errorf("saved run %s has no messages", run.ID)
+	}
+
+	if quietMode == "" && cli != nil {
+		cli.DisplayInfo(fmt.Sprintf("Resuming run %s from %s", run.ID, run.Updated.Format("2006-01-02 15:04:05")))
+	}
+
+	// A run saved between the turns of a scripted conversation ends with
+	// the answer of the last turn it finished
+	last := messages[len(messages)-1]
+	answered := last.Role == schema.Assistant && len(last.ToolCalls) == 0
+	if len(run.Turns) > 0 {
+		return runConversation(ctx, mcpAgent, cli, tw, run.Turns, modelName, messages, quietMode, !answered)
+	}
+	if answered {
+		return nil
+	}
+	_, err := runAgentTurn(ctx, mcpAgent, cli, tw, newTurnEvents(quietMode), modelName, messages, quietMode)
+	return err
+}
+
+// listSavedRuns prints the saved runs, oldest first
+func listSavedRuns() error {
+	dir, err := runsDir()
+	if err != nil {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	var runs []savedRun
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var run savedRun
+		if json.Unmarshal(data, &run) == nil {
+			runs = append(runs, run)
+		}
+	}
+	if len(runs) == 0 {
+		fmt.Println("No saved runs")
+		return nil
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Started.Before(runs[j].Started) })
+
+	for _, run := range runs {
+		fmt.Printf("%s  %s  %s  %s\n", run.ID, run.Updated.Format("2006-01-02 15:04"), run.Model, savedRunPrompt(run))
+	}
+	return nil
+}
+
+// savedRunPrompt returns the start of the last prompt of a saved run
+func savedRunPrompt(run savedRun) string {
+	for i := len(run.Messages) - 1; i >= 0; i-- {
+		if msg := run.Messages[i]; msg.Role == schema.User {
+			prompt := strings.Join(strings.Fields(msg.Content), " ")
+			if runes := []rune(prompt); len(runes) > 60 {
+				prompt = string(runes[:59]) + "…"
+			}
+			return prompt
+		}
+	}
+	return ""
+}
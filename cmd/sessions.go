@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/ui"
+)
+
+// chatSessions are the named conversations of an interactive session. They
+// share the agent and its MCP connections but each has its own history.
+type chatSessions struct {
+	names   []string
+	history map[string][]*schema.Message
+	// dropped holds the messages the message window dropped from each
+	// conversation but the current one, which are in droppedMessages
+	dropped map[string][]*schema.Message
+	current string
+	// initial is the history new conversations start with: the messages
+	// pinned at startup, such as preloaded context
+	initial []*schema.Message
+	// vars holds the /set variables of each conversation
+	vars map[string]map[string]string
+}
+
+// newChatSessions returns a set holding a single conversation named main
+// with the history messages
+func newChatSessions(messages []*schema.Message) *chatSessions {
+	sessions := &chatSessions{
+		names:   []string{"main"},
+		history: map[string][]*schema.Message{"main": messages},
+		dropped: make(map[string][]*schema.Message),
+		vars:    make(map[string]map[string]string),
+		current: "main",
+	}
+	for _, msg := range messages {
+		if agent.MessagePinned(msg) {
+			sessions.initial = append(sessions.initial, msg)
+		}
+	}
+	return sessions
+}
+
+// variables returns the /set variables of the current conversation
+func (s *chatSessions) variables() map[string]string {
+	vars, ok := s.vars[s.current]
+	if !ok {
+		vars = make(map[string]string)
+		s.vars[s.current] = vars
+	}
+	return vars
+}
+
+// handleSessionCommand handles /new, /switch and /list. It saves messages as
+// the current conversation's history and returns the history of the
+// conversation that is current afterwards, and whether input was one of
+// these commands.
+func handleSessionCommand(input string, cli *ui.CLI, sessions *chatSessions, messages []*schema.Message) ([]*schema.Message, bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return messages, false
+	}
+
+	switch fields[0] {
+	case "/new":
+		if len(fields) > 2 {
+			cli.DisplayError(fmt.Errorf("usage: /new [name]"))
+			return messages, true
+		}
+		var name string
+		if len(fields) == 2 {
+			name = fields[1]
+		} else {
+			for n := len(sessions.names) + 1; ; n++ {
+				name = fmt.Sprintf("chat%d", n)
+				if _, exists := sessions.history[name]; !exists {
+					break
+				}
+			}
+		}
+		if _, exists := sessions.history[name]; exists {
+			cli.DisplayError(fmt.Errorf("session %s already exists; use /switch %s", name, name))
+			return messages, true
+		}
+
+		sessions.history[sessions.current] = messages
+		sessions.dropped[sessions.current] = droppedMessages
+		sessions.names = append(sessions.names, name)
+		sessions.history[name] = append([]*schema.Message(nil), sessions.initial...)
+		sessions.current = name
+		droppedMessages = nil
+		cli.DisplayInfo(fmt.Sprintf("Started session %s", name))
+		return sessions.history[name], true
+	case "/switch":
+		if len(fields) != 2 {
+			cli.DisplayError(fmt.Errorf("usage: /switch <name>"))
+			return messages, true
+		}
+		name := fields[1]
+		history, exists := sessions.history[name]
+		if !exists {
+			cli.DisplayError(fmt.Errorf("no session named %s (sessions: %s)", name, strings.Join(sessions.names, ", ")))
+			return messages, true
+		}
+
+		sessions.history[sessions.current] = messages
+		sessions.dropped[sessions.current] = droppedMessages
+		sessions.current = name
+		droppedMessages = sessions.dropped[name]
+		cli.DisplayInfo(fmt.Sprintf("Switched to session %s (%d messages); /history shows its conversation",
+			name, countHistoryMessages(history)))
+		return history, true
+	case "/list":
+		sessions.history[sessions.current] = messages
+
+		var b strings.Builder
+		b.WriteString("## Sessions\n\n")
+		for _, name := range sessions.names {
+			marker := ""
+			if name == sessions.current {
+				marker = " (current)"
+			}
+			fmt.Fprintf(&b, "- **%s**%s: %d messages\n", name, marker, countHistoryMessages(sessions.history[name]))
+		}
+		cli.DisplayInfo(b.String())
+		return messages, true
+	}
+	return messages, false
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mark3labs/mcphost/internal/share"
+	"github.com/mark3labs/mcphost/internal/transcript"
+	"github.com/mark3labs/mcphost/internal/ui"
+)
+
+// handleShareCommand exports the session as a standalone HTML page, saving
+// it to a file or uploading it to a gist or the configured share endpoint
+func handleShareCommand(ctx context.Context, args []string, cli *ui.CLI, tw *transcript.Writer, modelName string) {
+	target := "file"
+	if len(args) > 0 {
+		target = args[0]
+	}
+	if len(args) > 1 || target != "file" && target != "gist" && target != "endpoint" {
+		cli.DisplayError(fmt.Errorf("usage: /share [file|gist|endpoint]"))
+		return
+	}
+
+	events := tw.Events()
+	if len(events) == 0 {
+		cli.DisplayError(fmt.Errorf("there is nothing to share yet"))
+		return
+	}
+
+	now := time.Now()
+	page, err := share.RenderHTML(share.Session{
+		Title:  "mcphost session",
+		Model:  modelName,
+		Time:   now,
+		Events: events,
+	})
+	if err != nil {
+		cli.DisplayError(err)
+		return
+	}
+	filename := fmt.Sprintf("mcphost-session-%s.html", now.Format("20060102-150405"))
+
+	switch target {
+	case "file":
+		if err := os.WriteFile(filename, page, 0600); err != nil {
+			cli.DisplayError(fmt.Errorf("failed to write %s: %v", filename, err))
+			return
+		}
+		cli.DisplayInfo(fmt.Sprintf("Session saved to %s", filename))
+	case "gist":
+		token := os.Getenv("GITHUB_TOKEN")
+		if token == "" {
+			token = os.Getenv("GH_TOKEN")
+		}
+		if token == "" {
+			cli.DisplayError(fmt.Errorf("set GITHUB_TOKEN to a token with the gist scope to share to a gist"))
+			return
+		}
+		url, err := share.UploadGist(ctx, token, filename, "mcphost session", page)
+		if err != nil {
+			cli.DisplayError(err)
+			return
+		}
+		cli.DisplayInfo(fmt.Sprintf("Session shared as a secret gist: %s", url))
+	case "endpoint":
+		if shareEndpoint == "" {
+			cli.DisplayError(fmt.Errorf("no share-endpoint is configured"))
+			return
+		}
+		url, err := share.Upload(ctx, shareEndpoint, page)
+		if err != nil {
+			cli.DisplayError(err)
+			return
+		}
+		cli.DisplayInfo(fmt.Sprintf("Session shared: %s", url))
+	}
+}
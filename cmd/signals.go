@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var (
+	shutdownMu    sync.Mutex
+	shutdownHooks []func()
+	// interruptedBy is the signal that cancelled the command, if any
+	interruptedBy os.Signal
+)
+
+// onShutdown registers a function to run when mcphost is interrupted, e.g. to
+// close MCP clients or flush session data. Hooks run in reverse order of
+// registration, like deferred calls, once the command has returned.
+func onShutdown(hook func()) {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+	shutdownHooks = append(shutdownHooks, hook)
+}
+
+// runShutdownHooks runs and clears all registered shutdown hooks
+func runShutdownHooks() {
+	shutdownMu.Lock()
+	hooks := shutdownHooks
+	shutdownHooks = nil
+	shutdownMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// withSignalHandling returns a context that is cancelled on SIGINT/SIGTERM.
+// On the first signal the context is cancelled, so the command returns
+// through its deferred cleanup, and Execute then runs the shutdown hooks and
+// exits with the signal's code. A second signal exits immediately, for a
+// command stuck where it doesn't check the context.
+func withSignalHandling(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+
+	sigChan := make(chan os.Signal, 2)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		var sig os.Signal
+		select {
+		case sig = <-sigChan:
+		case <-ctx.Done():
+			signal.Stop(sigChan)
+			return
+		}
+
+		shutdownMu.Lock()
+		interruptedBy = sig
+		shutdownMu.Unlock()
+		fmt.Fprintln(os.Stderr, "\nInterrupted, shutting down. Press Ctrl+C again to exit at once.")
+		cancel()
+
+		<-sigChan
+		os.Exit(exitCodeForSignal(sig))
+	}()
+
+	return ctx, cancel
+}
+
+// interruption returns the signal that cancelled the command, or nil
+func interruption() os.Signal {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+	return interruptedBy
+}
+
+// exitCodeForSignal follows the shell convention of 128 + signal number
+func exitCodeForSignal(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
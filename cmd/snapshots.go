@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/paths"
+	"github.com/mark3labs/mcphost/internal/snapshot"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var snapshotsCmd = &cobra.Command{
+	Use:   "snapshots",
+	Short: "List or restore snapshots taken before tool calls",
+	Long: `With --snapshots, mcphost records the working directory in a shadow git
+repository in the data directory (--data-dir) before each tool call that
+runs, if anything changed since the last snapshot. The project's own
+repository is not touched, and files ignored by its .gitignore are left out.
+
+These commands work on the snapshots of the current directory, or of
+--workdir.
+
+Examples:
+  mcphost --snapshots -p "Rename the config package"
+  mcphost snapshots list
+  mcphost snapshots restore 3f2a9c1b7e`,
+}
+
+var snapshotsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the snapshots of the working directory, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openSnapshots()
+		if err != nil {
+			return err
+		}
+		snapshots, err := store.List()
+		if err != nil {
+			return err
+		}
+		if len(snapshots) == 0 {
+			fmt.Println("No snapshots.")
+			return nil
+		}
+		for _, s := range snapshots {
+			fmt.Printf("%s  %s  %s\n", s.ID, s.Time.Format("2006-01-02 15:04:05"), s.Message)
+		}
+		return nil
+	},
+}
+
+var snapshotsRestoreCmd = &cobra.Command{
+	Use:   "restore <id>",
+	Short: "Restore the working directory to a snapshot",
+	Long: `Restore the working directory to a snapshot: changed files are restored
+and files created since are deleted. The current state is recorded as a
+snapshot first, so the restore can be undone by restoring that one.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openSnapshots()
+		if err != nil {
+			return err
+		}
+		current, err := store.Restore(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Restored %s. The previous state is snapshot %s.\n", args[0], current)
+		return nil
+	},
+}
+
+func init() {
+	snapshotsCmd.AddCommand(snapshotsListCmd, snapshotsRestoreCmd)
+	rootCmd.AddCommand(snapshotsCmd)
+}
+
+// openSnapshots opens the snapshots of --workdir or the current directory
+func openSnapshots() (*snapshot.Store, error) {
+	dir := workdir
+	if dir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %v", err)
+		}
+		dir = cwd
+	}
+	if err := useDataDir(); err != nil {
+		return nil, err
+	}
+	return snapshot.Open(dir)
+}
+
+// useDataDir applies --data-dir, MCPHOST_DATA_DIR or data-dir in the config
+// file, for commands that don't run a session
+func useDataDir() error {
+	dir := viper.GetString("data-dir")
+	if dir == "" {
+		mcpConfig, err := config.LoadMCPConfig(viper.GetString("config"))
+		if err != nil {
+			return fmt.Errorf("failed to load MCP config: %v", err)
+		}
+		dir = mcpConfig.DataDir
+	}
+	paths.SetDataDir(dir)
+	return nil
+}
+
+// enableSnapshots makes the agent record the working directory, which
+// --workdir has been changed into, before each tool call runs
+func enableSnapshots(mcpAgent *agent.Agent) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %v", err)
+	}
+	store, err := snapshot.Open(cwd)
+	if err != nil {
+		return err
+	}
+	mcpAgent.AddBeforeToolRun(func(toolName, toolArgs string) {
+		message := "before " + toolName
+		if args := strings.TrimSpace(toolArgs); args != "" && args != "{}" {
+			if len([]rune(args)) > 100 {
+				args = string([]rune(args)[:100]) + "..."
+			}
+			message += " " + args
+		}
+		if _, _, err := store.Take(message); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to take snapshot: %v\n", err)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// terminalTitle shows the interactive session in the terminal title
+var terminalTitle = &titleBar{}
+
+// titleBar keeps the terminal title showing the current session, model and
+// what mcphost is doing, so that it can be told apart from other tabs. It
+// does nothing until started, and with --no-title or without a terminal.
+type titleBar struct {
+	mu      sync.Mutex
+	enabled bool
+	session string
+	model   string
+	status  string
+}
+
+// start shows the title for a session with the model, saving the previous
+// title so that stop can restore it
+func (t *titleBar) start(model string) {
+	if noTitle || os.Getenv("TERM") == "dumb" || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.enabled {
+		return
+	}
+	t.enabled = true
+	t.session = "main"
+	t.model = model
+	t.status = "idle"
+	// Save the title on the terminal's title stack
+	fmt.Fprint(os.Stdout, "\x1b[22;0t")
+	t.write()
+	onShutdown(t.stop)
+}
+
+// stop restores the title from before start
+func (t *titleBar) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.enabled {
+		return
+	}
+	t.enabled = false
+	fmt.Fprint(os.Stdout, "\x1b[23;0t")
+}
+
+// setSession shows the name of the current conversation
+func (t *titleBar) setSession(name string) {
+	t.update(func() { t.session = name })
+}
+
+// setModel shows the model answering the prompts
+func (t *titleBar) setModel(model string) {
+	t.update(func() { t.model = model })
+}
+
+// setStatus shows what mcphost is doing, e.g. idle or thinking
+func (t *titleBar) setStatus(status string) {
+	t.update(func() { t.status = status })
+}
+
+func (t *titleBar) update(change func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.enabled {
+		return
+	}
+	change()
+	t.write()
+}
+
+// write sets the title; t.mu must be held
+func (t *titleBar) write() {
+	title := strings.Join([]string{"mcphost", t.session, t.model, t.status}, " · ")
+	// Control characters would end the escape sequence early
+	title = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, title)
+	fmt.Fprintf(os.Stdout, "\x1b]0;%s\x07", title)
+}
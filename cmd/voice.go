@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/speech"
+	"github.com/mark3labs/mcphost/internal/ui"
+)
+
+var (
+	// speechToText and textToSpeech hold the speech settings of the config
+	// file
+	speechToText config.SpeechToTextConfig
+	textToSpeech config.TextToSpeechConfig
+
+	// speaker reads answers aloud while speakMode is on. It is created when
+	// speaking is first turned on.
+	speaker *speech.Speaker
+)
+
+// openAIKey returns the OpenAI API key of the model settings, which the
+// openai speech backends use
+func openAIKey() string {
+	if openaiAPIKey != "" {
+		return openaiAPIKey
+	}
+	return os.Getenv("OPENAI_API_KEY")
+}
+
+// newListener returns a listener for spoken prompts. The openai backend
+// uses the OpenAI credentials of the model settings.
+func newListener() (*speech.Listener, error) {
+	return speech.NewListener(speechToText, openAIKey(), openaiBaseURL)
+}
+
+// listenForPrompt records a spoken prompt and returns its transcript. It
+// returns "" if nothing was said or the recording failed, after showing why.
+func listenForPrompt(ctx context.Context, cli *ui.CLI) string {
+	listener, err := newListener()
+	if err != nil {
+		cli.DisplayError(err)
+		return ""
+	}
+	// Don't record the answer being read aloud
+	if speaker != nil {
+		speaker.Wait()
+	}
+
+	spinner := cli.NewSpinner("Listening... (pause to finish)")
+	spinner.Start()
+	file, err := listener.Record(ctx)
+	spinner.Stop()
+	if errors.Is(err, speech.ErrNothingHeard) {
+		cli.DisplayInfo("Nothing was heard. Type your prompt instead, or /voice to try again.")
+		return ""
+	}
+	if err != nil {
+		cli.DisplayError(err)
+		return ""
+	}
+	defer os.Remove(file)
+
+	spinner = cli.NewSpinner("Transcribing...")
+	spinner.Start()
+	text, err := listener.Transcribe(ctx, file)
+	spinner.Stop()
+	if err != nil {
+		cli.DisplayError(err)
+		return ""
+	}
+	if text == "" {
+		cli.DisplayInfo("Nothing was heard. Type your prompt instead, or /voice to try again.")
+	}
+	return text
+}
+
+// startSpeaker creates the speaker if needed and turns speaking on. Before
+// the CLI exists, cli is nil and runInteractiveMode sets how errors are shown.
+func startSpeaker(cli *ui.CLI) error {
+	if speaker == nil {
+		s, err := speech.NewSpeaker(textToSpeech, openAIKey(), openaiBaseURL)
+		if err != nil {
+			return err
+		}
+		if cli != nil {
+			s.OnError = cli.DisplayError
+		}
+		speaker = s
+	}
+	speakMode = true
+	return nil
+}
+
+// speakAnswer reads an answer aloud if speaking is on
+func speakAnswer(answer string) {
+	if speakMode && speaker != nil {
+		speaker.Speak(answer)
+	}
+}
+
+// handleSpeakCommand handles /speak [on|off] and reports whether input was
+// that command
+func handleSpeakCommand(input string, cli *ui.CLI) bool {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || fields[0] != "/speak" {
+		return false
+	}
+
+	switch {
+	case len(fields) == 1:
+		state := "off"
+		if speakMode {
+			state = "on"
+		}
+		cli.DisplayInfo(fmt.Sprintf("Reading answers aloud is %s. Use /speak on or /speak off to change it.", state))
+	case len(fields) == 2 && fields[1] == "on":
+		if err := startSpeaker(cli); err != nil {
+			cli.DisplayError(err)
+			return true
+		}
+		cli.DisplayInfo("Answers will be read aloud")
+	case len(fields) == 2 && fields[1] == "off":
+		speakMode = false
+		if speaker != nil {
+			speaker.Stop()
+		}
+		cli.DisplayInfo("Answers will no longer be read aloud")
+	default:
+		cli.DisplayError(fmt.Errorf("usage: /speak [on|off]"))
+	}
+	return true
+}
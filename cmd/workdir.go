@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/mark3labs/mcphost/internal/sandbox"
+)
+
+// enterWorkdir changes into --workdir, if given, and with --sandbox into a
+// scratch copy of it, so that MCP servers started from mcphost and file
+// references work there. The returned function reports what changed in the
+// sandbox when the session ends and, with --sandbox-apply, makes the
+// changes in the real directory.
+func enterWorkdir() (func(), error) {
+	if workdir != "" {
+		if err := os.Chdir(workdir); err != nil {
+			return nil, fmt.Errorf("failed to change to workdir: %v", err)
+		}
+	}
+	// Listing models runs no tools, so it needs no sandbox
+	if !sandboxMode || modelsMode {
+		if sandboxApply && !sandboxMode {
+			return nil, fmt.Errorf("--sandbox-apply can only be used with --sandbox")
+		}
+		return func() {}, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %v", err)
+	}
+	box, err := sandbox.New(cwd)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chdir(box.Dir); err != nil {
+		box.Remove()
+		return nil, fmt.Errorf("failed to change to sandbox: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Working in sandbox %s (a copy of %s)\n", box.Dir, box.Source)
+
+	var once sync.Once
+	finish := func() {
+		once.Do(func() { finishSandbox(box) })
+	}
+	onShutdown(finish)
+	return finish, nil
+}
+
+// finishSandbox prints the changes made in the sandbox and applies them
+// with --sandbox-apply. A sandbox with changes that weren't applied is kept
+// for inspection.
+func finishSandbox(box *sandbox.Sandbox) {
+	// Leave the sandbox so it can be removed
+	os.Chdir(box.Source)
+
+	changes, err := box.Changes()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to compare sandbox: %v\nThe sandbox is kept in %s\n", err, box.Dir)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\nSandbox changes:\n%s\n", sandbox.Summary(changes))
+
+	switch {
+	case len(changes) == 0:
+	case sandboxApply:
+		if err := box.Apply(changes); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to apply sandbox changes: %v\nThe sandbox is kept in %s\n", err, box.Dir)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Applied to %s\n", box.Source)
+	default:
+		fmt.Fprintf(os.Stderr, "Not applied; the sandbox is kept in %s\n", box.Dir)
+		return
+	}
+	box.Remove()
+}
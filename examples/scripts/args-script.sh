@@ -0,0 +1,6 @@
+#!/usr/local/bin/mcphost --script
+---
+# Usage: ./args-script.sh -- <topic> [style]
+max-steps: 1
+---
+Write a haiku about ${arg1} in the style of ${arg2}.
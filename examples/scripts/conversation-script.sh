@@ -0,0 +1,12 @@
+#!/usr/local/bin/mcphost --script
+# Multi-turn smoke test: each turn runs in the same session and the
+# response must contain every "expect" substring
+mcpServers:
+  filesystem:
+    command: npx
+    args: ["-y", "@modelcontextprotocol/server-filesystem", "/tmp"]
+conversation:
+  - prompt: Create a file /tmp/mcphost-smoke.txt containing the word "pineapple".
+  - prompt: Read /tmp/mcphost-smoke.txt and tell me exactly what it contains.
+    expect:
+      - pineapple
@@ -0,0 +1,9 @@
+#!/usr/local/bin/mcphost --script
+---
+# Settings go between the --- lines; the rest of the file is the prompt
+max-steps: 5
+---
+List the tools you have available.
+For each one, answer in this format:
+Name: the tool name
+Purpose: one sentence
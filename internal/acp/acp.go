@@ -0,0 +1,371 @@
+// Package acp serves the agent client protocol, JSON-RPC over stdio, so that
+// editors such as Zed can run mcphost as an external agent. Prompts are
+// answered as in interactive mode, with the model's output, tool calls and
+// permission requests sent to the editor as they happen.
+package acp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcphost/internal/agent"
+)
+
+// Config configures a Server
+type Config struct {
+	Agent *agent.Agent
+	// MessageWindow is the number of messages kept per session
+	MessageWindow int
+}
+
+// Server answers the prompts of an editor with the agent
+type Server struct {
+	config Config
+	conn   *conn
+
+	mu          sync.Mutex
+	sessions    map[string]*session
+	nextSession int
+}
+
+// session is a conversation started by the editor. Its prompts are
+// answered one at a time.
+type session struct {
+	sync.Mutex
+
+	id       string
+	messages []*schema.Message
+	calls    toolCalls
+
+	// cancel stops the prompt being answered, if any
+	cancelMu sync.Mutex
+	cancel   context.CancelFunc
+}
+
+// New creates a server
+func New(config Config) *Server {
+	return &Server{config: config, sessions: make(map[string]*session)}
+}
+
+// Run reads requests from in and writes responses and notifications to out
+// until in ends or ctx is cancelled
+func (s *Server) Run(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.conn = newConn(out)
+	return s.conn.serve(ctx, in, s.handle)
+}
+
+// handle answers a request or notification of the editor
+func (s *Server) handle(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case methodInitialize:
+		return s.initialize(params)
+	case methodAuthenticate:
+		// No authentication methods are offered
+		return nil, nil
+	case methodNewSession:
+		return s.newSession(params)
+	case methodPrompt:
+		return s.prompt(ctx, params)
+	case methodCancel:
+		return nil, s.cancel(params)
+	}
+	return nil, &Error{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", method)}
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, error) {
+	var req initializeRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, invalidParams("invalid initialize params: %v", err)
+	}
+	return initializeResponse{
+		ProtocolVersion: ProtocolVersion,
+		AgentCapabilities: agentCapabilities{
+			PromptCapabilities: promptCapabilities{EmbeddedContext: true},
+		},
+		AuthMethods: []any{},
+		AgentInfo:   implementation{Name: "mcphost", Title: "MCPHost"},
+	}, nil
+}
+
+// newSession starts a conversation. The MCP servers of the editor aren't
+// started; the agent has the servers of its config.
+func (s *Server) newSession(params json.RawMessage) (any, error) {
+	var req newSessionRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, invalidParams("invalid session/new params: %v", err)
+	}
+	if len(req.MCPServers) > 0 {
+		log.Printf("acp: ignoring %d MCP servers of the editor, configure them in mcphost", len(req.MCPServers))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextSession++
+	id := fmt.Sprintf("session-%d", s.nextSession)
+	s.sessions[id] = &session{id: id}
+	return newSessionResponse{SessionID: id}, nil
+}
+
+// session returns the session with the given ID
+func (s *Server) session(id string) (*session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil, invalidParams("unknown session %q", id)
+	}
+	return sess, nil
+}
+
+// prompt answers a prompt, sending the model's output and the tool calls as
+// session updates while it runs
+func (s *Server) prompt(ctx context.Context, params json.RawMessage) (any, error) {
+	var req promptRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, invalidParams("invalid session/prompt params: %v", err)
+	}
+	sess, err := s.session(req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	text := promptText(req.Prompt)
+	if text == "" {
+		return nil, invalidParams("empty prompt")
+	}
+
+	sess.Lock()
+	defer sess.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sess.setCancel(cancel)
+	defer sess.setCancel(nil)
+
+	messages := append(append([]*schema.Message(nil), sess.messages...), agent.NewUserMessage(text))
+
+	turnAgent := s.config.Agent.WithToolApprovalHandler(func(toolName, toolArgs string) bool {
+		return s.requestPermission(ctx, sess, toolName, toolArgs)
+	})
+	response, err := turnAgent.GenerateWithLoopStreaming(ctx, messages,
+		func(toolName, toolArgs string) {
+			s.update(sess, toolCall{
+				SessionUpdate: "tool_call",
+				ToolCallID:    sess.calls.start(toolName, toolArgs),
+				Title:         toolName,
+				Status:        toolPending,
+				RawInput:      rawInput(toolArgs),
+			})
+		},
+		func(toolName string, isStarting bool) {
+			if isStarting {
+				s.update(sess, toolCall{
+					SessionUpdate: "tool_call_update",
+					ToolCallID:    sess.calls.running(toolName),
+					Status:        toolInProgress,
+				})
+			}
+		},
+		func(toolName, toolArgs, result string, isError bool) {
+			status := toolCompleted
+			if isError {
+				status = toolFailed
+			}
+			s.update(sess, toolCall{
+				SessionUpdate: "tool_call_update",
+				ToolCallID:    sess.calls.finish(toolName, toolArgs),
+				Status:        status,
+				Content:       []toolCallContent{{Type: "content", Content: textBlock(result)}},
+			})
+		},
+		nil,
+		// Content that accompanies tool calls has already been streamed
+		nil,
+		func(chunk string) {
+			s.update(sess, messageChunk{SessionUpdate: "agent_message_chunk", Content: textBlock(chunk)})
+		},
+	)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return promptResponse{StopReason: stopCancelled}, nil
+		}
+		return nil, err
+	}
+
+	messages = append(messages, response)
+	if s.config.MessageWindow > 0 && len(messages) > s.config.MessageWindow {
+		messages = messages[len(messages)-s.config.MessageWindow:]
+	}
+	sess.messages = messages
+	return promptResponse{StopReason: stopEndTurn}, nil
+}
+
+// cancel stops the prompt a session is answering
+func (s *Server) cancel(params json.RawMessage) error {
+	var req cancelNotification
+	if err := json.Unmarshal(params, &req); err != nil {
+		return invalidParams("invalid session/cancel params: %v", err)
+	}
+	sess, err := s.session(req.SessionID)
+	if err != nil {
+		return err
+	}
+	sess.cancelMu.Lock()
+	defer sess.cancelMu.Unlock()
+	if sess.cancel != nil {
+		sess.cancel()
+	}
+	return nil
+}
+
+// requestPermission asks the editor whether a tool call may run. A tool
+// allowed always is approved for the rest of the run, as in interactive
+// mode.
+func (s *Server) requestPermission(ctx context.Context, sess *session, toolName, toolArgs string) bool {
+	req := requestPermissionRequest{
+		SessionID: sess.id,
+		ToolCall: toolCall{
+			ToolCallID: sess.calls.find(toolName, toolArgs),
+			Title:      toolName,
+			RawInput:   rawInput(toolArgs),
+		},
+		Options: []permissionOption{
+			{OptionID: optionAllowOnce, Name: "Allow", Kind: optionAllowOnce},
+			{OptionID: optionAllowAlways, Name: "Always allow " + toolName, Kind: optionAllowAlways},
+			{OptionID: optionRejectOnce, Name: "Deny", Kind: optionRejectOnce},
+		},
+	}
+	var resp requestPermissionResponse
+	if err := s.conn.call(ctx, methodRequestPermission, req, &resp); err != nil {
+		if ctx.Err() == nil {
+			log.Printf("acp: permission request for %s failed: %v", toolName, err)
+		}
+		return false
+	}
+	if resp.Outcome.Outcome != "selected" {
+		return false
+	}
+	switch resp.Outcome.OptionID {
+	case optionAllowAlways:
+		s.config.Agent.Permissions().Approve(toolName)
+		return true
+	case optionAllowOnce:
+		return true
+	}
+	return false
+}
+
+// update sends a session update to the editor
+func (s *Server) update(sess *session, update any) {
+	if err := s.conn.notify(methodUpdate, sessionNotification{SessionID: sess.id, Update: update}); err != nil {
+		log.Printf("acp: %v", err)
+	}
+}
+
+// setCancel sets the function that stops the session's prompt
+func (sess *session) setCancel(cancel context.CancelFunc) {
+	sess.cancelMu.Lock()
+	defer sess.cancelMu.Unlock()
+	sess.cancel = cancel
+}
+
+// promptText joins the content blocks of a prompt into the text of a user
+// message. Embedded resources, such as files the user mentioned, are
+// included with their contents.
+func promptText(blocks []contentBlock) string {
+	var parts []string
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			parts = append(parts, block.Text)
+		case "resource_link":
+			parts = append(parts, block.URI)
+		case "resource":
+			if block.Resource == nil {
+				continue
+			}
+			if block.Resource.Text == "" {
+				parts = append(parts, block.Resource.URI)
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("Contents of %s:\n```\n%s\n```", block.Resource.URI, block.Resource.Text))
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n\n"))
+}
+
+// rawInput returns the arguments of a tool call as JSON, or nil if the model
+// sent invalid JSON
+func rawInput(toolArgs string) json.RawMessage {
+	if !json.Valid([]byte(toolArgs)) {
+		return nil
+	}
+	return json.RawMessage(toolArgs)
+}
+
+// toolCalls gives the tool calls of a session the IDs the editor knows them
+// by. The agent reports calls by name and arguments, and calls that run at
+// the same time are told apart by them.
+type toolCalls struct {
+	mu      sync.Mutex
+	next    int
+	pending []pendingCall
+}
+
+type pendingCall struct {
+	id, name, args string
+}
+
+// start gives a new tool call its ID
+func (c *toolCalls) start(name, args string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.next++
+	id := fmt.Sprintf("call-%d", c.next)
+	c.pending = append(c.pending, pendingCall{id: id, name: name, args: args})
+	return id
+}
+
+// find returns the ID of a started tool call
+func (c *toolCalls) find(name, args string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, call := range c.pending {
+		if call.name == name && call.args == args {
+			return call.id
+		}
+	}
+	return ""
+}
+
+// running returns the ID of a started call of the named tool, for updates
+// that don't have the arguments
+func (c *toolCalls) running(name string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, call := range c.pending {
+		if call.name == name {
+			return call.id
+		}
+	}
+	return ""
+}
+
+// finish returns the ID of a started tool call and forgets it
+func (c *toolCalls) finish(name, args string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, call := range c.pending {
+		if call.name == name && call.args == args {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			return call.id
+		}
+	}
+	return ""
+}
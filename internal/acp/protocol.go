@@ -0,0 +1,150 @@
+package acp
+
+import "encoding/json"
+
+// ProtocolVersion is the version of the agent client protocol implemented
+const ProtocolVersion = 1
+
+// Methods of the agent client protocol
+const (
+	methodInitialize        = "initialize"
+	methodAuthenticate      = "authenticate"
+	methodNewSession        = "session/new"
+	methodPrompt            = "session/prompt"
+	methodCancel            = "session/cancel"
+	methodUpdate            = "session/update"
+	methodRequestPermission = "session/request_permission"
+)
+
+// Reasons a prompt turn ends
+const (
+	stopEndTurn   = "end_turn"
+	stopCancelled = "cancelled"
+)
+
+// Statuses of a tool call
+const (
+	toolPending    = "pending"
+	toolInProgress = "in_progress"
+	toolCompleted  = "completed"
+	toolFailed     = "failed"
+)
+
+// Permission options offered for a tool call
+const (
+	optionAllowOnce   = "allow_once"
+	optionAllowAlways = "allow_always"
+	optionRejectOnce  = "reject_once"
+)
+
+type initializeRequest struct {
+	ProtocolVersion int `json:"protocolVersion"`
+}
+
+type initializeResponse struct {
+	ProtocolVersion   int               `json:"protocolVersion"`
+	AgentCapabilities agentCapabilities `json:"agentCapabilities"`
+	AuthMethods       []any             `json:"authMethods"`
+	AgentInfo         implementation    `json:"agentInfo"`
+}
+
+type agentCapabilities struct {
+	LoadSession        bool               `json:"loadSession"`
+	PromptCapabilities promptCapabilities `json:"promptCapabilities"`
+}
+
+type promptCapabilities struct {
+	Image           bool `json:"image"`
+	Audio           bool `json:"audio"`
+	EmbeddedContext bool `json:"embeddedContext"`
+}
+
+type implementation struct {
+	Name  string `json:"name"`
+	Title string `json:"title,omitempty"`
+}
+
+type newSessionRequest struct {
+	Cwd        string            `json:"cwd"`
+	MCPServers []json.RawMessage `json:"mcpServers"`
+}
+
+type newSessionResponse struct {
+	SessionID string `json:"sessionId"`
+}
+
+type promptRequest struct {
+	SessionID string         `json:"sessionId"`
+	Prompt    []contentBlock `json:"prompt"`
+}
+
+type promptResponse struct {
+	StopReason string `json:"stopReason"`
+}
+
+type cancelNotification struct {
+	SessionID string `json:"sessionId"`
+}
+
+// contentBlock is text, a link to a resource or an embedded resource
+type contentBlock struct {
+	Type     string            `json:"type"`
+	Text     string            `json:"text,omitempty"`
+	URI      string            `json:"uri,omitempty"`
+	Name     string            `json:"name,omitempty"`
+	Resource *embeddedResource `json:"resource,omitempty"`
+}
+
+type embeddedResource struct {
+	URI  string `json:"uri"`
+	Text string `json:"text,omitempty"`
+}
+
+func textBlock(text string) contentBlock {
+	return contentBlock{Type: "text", Text: text}
+}
+
+type sessionNotification struct {
+	SessionID string `json:"sessionId"`
+	Update    any    `json:"update"`
+}
+
+type messageChunk struct {
+	SessionUpdate string       `json:"sessionUpdate"`
+	Content       contentBlock `json:"content"`
+}
+
+// toolCall reports a tool call, first as "tool_call" and then with updates
+// as "tool_call_update", which only carry what changed
+type toolCall struct {
+	SessionUpdate string            `json:"sessionUpdate,omitempty"`
+	ToolCallID    string            `json:"toolCallId"`
+	Title         string            `json:"title,omitempty"`
+	Status        string            `json:"status,omitempty"`
+	RawInput      json.RawMessage   `json:"rawInput,omitempty"`
+	Content       []toolCallContent `json:"content,omitempty"`
+}
+
+type toolCallContent struct {
+	Type    string       `json:"type"`
+	Content contentBlock `json:"content"`
+}
+
+type requestPermissionRequest struct {
+	SessionID string             `json:"sessionId"`
+	ToolCall  toolCall           `json:"toolCall"`
+	Options   []permissionOption `json:"options"`
+}
+
+type permissionOption struct {
+	OptionID string `json:"optionId"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+}
+
+type requestPermissionResponse struct {
+	Outcome struct {
+		Outcome  string `json:"outcome"`
+		OptionID string `json:"optionId"`
+	} `json:"outcome"`
+}
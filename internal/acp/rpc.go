@@ -0,0 +1,246 @@
+package acp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// Error is a JSON-RPC error, sent to the client as the response to a request
+// or received from it
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// invalidParams returns the error for a request with unusable params
+func invalidParams(format string, args ...any) *Error {
+	return &Error{Code: codeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// message is a JSON-RPC request, notification or response as read from the
+// client. Requests and notifications have a method, notifications no ID.
+type message struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+}
+
+// outgoing is a request or notification sent to the client
+type outgoing struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      *int64 `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// response answers a request of the client
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// handler answers a request or notification of the client. The result of a
+// notification is dropped.
+type handler func(ctx context.Context, method string, params json.RawMessage) (any, error)
+
+// conn is a JSON-RPC 2.0 connection of newline-delimited messages. Requests
+// go both ways: the client sends prompts, the agent asks for permissions.
+type conn struct {
+	out     io.Writer
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan *message
+}
+
+func newConn(out io.Writer) *conn {
+	return &conn{out: out, pending: make(map[int64]chan *message)}
+}
+
+// serve reads messages from in until it ends or ctx is done. Each request
+// and notification is handled in its own goroutine, so that a cancellation
+// can arrive while a prompt is answered. Requests still being handled when
+// in ends are cancelled.
+func (c *conn) serve(ctx context.Context, in io.Reader, handle handler) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReader(in)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	for {
+		var line []byte
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read from client: %v", err)
+		case line = <-lines:
+		}
+
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			if len(bytes.TrimSpace(line)) > 0 {
+				c.respond(nil, nil, &Error{Code: codeParseError, Message: err.Error()})
+			}
+			continue
+		}
+
+		switch {
+		case msg.Method != "":
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				result, err := handle(ctx, msg.Method, msg.Params)
+				if msg.ID != nil {
+					c.respond(msg.ID, result, err)
+				}
+			}()
+		case msg.ID != nil:
+			c.deliver(&msg)
+		default:
+			c.respond(nil, nil, &Error{Code: codeInvalidRequest, Message: "invalid request"})
+		}
+	}
+}
+
+// respond sends the response to the request with the given ID. Errors other
+// than *Error are reported as internal errors.
+func (c *conn) respond(id json.RawMessage, result any, err error) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	resp := response{JSONRPC: "2.0", ID: id}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: codeInternalError, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+	} else {
+		if result == nil {
+			result = struct{}{}
+		}
+		resp.Result = result
+	}
+	c.write(resp)
+}
+
+// notify sends a notification to the client
+func (c *conn) notify(method string, params any) error {
+	return c.write(outgoing{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// call sends a request to the client and decodes its result into result. It
+// gives up when ctx is done.
+func (c *conn) call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	reply := make(chan *message, 1)
+	c.pending[id] = reply
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(outgoing{JSONRPC: "2.0", ID: &id, Method: method, Params: params}); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case msg := <-reply:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			return fmt.Errorf("invalid result of %s: %v", method, err)
+		}
+		return nil
+	}
+}
+
+// deliver passes a response of the client to the call waiting for it
+func (c *conn) deliver(msg *message) {
+	id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	reply, ok := c.pending[id]
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	// Only the first response counts
+	select {
+	case reply <- msg:
+	default:
+	}
+}
+
+// write sends a message as a single line
+func (c *conn) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %v", err)
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := c.out.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write to client: %v", err)
+	}
+	return nil
+}
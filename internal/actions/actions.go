@@ -0,0 +1,89 @@
+// Package actions writes GitHub Actions workflow commands, so mcphost's
+// errors show up as annotations and its answers in the job summary
+package actions
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Annotation levels
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNotice  = "notice"
+)
+
+// secretSuffixes mark environment variables whose values are masked
+var secretSuffixes = []string{"_KEY", "_TOKEN", "_SECRET", "_PASSWORD"}
+
+// Annotate writes an annotation workflow command, e.g. ::error::message
+func Annotate(w io.Writer, level, title, message string) {
+	if title != "" {
+		fmt.Fprintf(w, "::%s title=%s::%s\n", level, escapeProperty(title), escapeData(message))
+		return
+	}
+	fmt.Fprintf(w, "::%s::%s\n", level, escapeData(message))
+}
+
+// Mask tells the runner to hide a value in the job's logs
+func Mask(w io.Writer, secret string) {
+	for _, line := range strings.Split(secret, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			fmt.Fprintf(w, "::add-mask::%s\n", escapeData(line))
+		}
+	}
+}
+
+// MaskEnvironment masks the values of environment variables that look like
+// credentials, such as ANTHROPIC_API_KEY or GITHUB_TOKEN
+func MaskEnvironment(w io.Writer) {
+	for _, entry := range os.Environ() {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || value == "" {
+			continue
+		}
+		for _, suffix := range secretSuffixes {
+			if strings.HasSuffix(strings.ToUpper(name), suffix) {
+				Mask(w, value)
+				break
+			}
+		}
+	}
+}
+
+// AppendSummary appends Markdown to the job summary. It does nothing outside
+// GitHub Actions.
+func AppendSummary(markdown string) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open job summary: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := io.WriteString(file, strings.TrimRight(markdown, "\n")+"\n\n"); err != nil {
+		return fmt.Errorf("failed to write job summary: %v", err)
+	}
+	return nil
+}
+
+// escapeData escapes a workflow command's message
+func escapeData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeProperty escapes a workflow command's property value
+func escapeProperty(s string) string {
+	s = escapeData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
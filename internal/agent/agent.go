@@ -2,9 +2,14 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/tool"
@@ -12,6 +17,8 @@ import (
 	"github.com/cloudwego/eino/flow/agent"
 	"github.com/cloudwego/eino/schema"
 	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/jsonrepair"
+	"github.com/mark3labs/mcphost/internal/language"
 	"github.com/mark3labs/mcphost/internal/models"
 	"github.com/mark3labs/mcphost/internal/tools"
 )
@@ -37,14 +44,72 @@ type AgentConfig struct {
 	MaxSteps      int
 	MessageWindow int
 
+	// SystemPromptFunc, if set, builds the system prompt once the available
+	// tools are known, replacing SystemPrompt. origins gives the servers of
+	// the tools, and servers what the servers told about themselves.
+	SystemPromptFunc func(toolInfos []*schema.ToolInfo, origins map[string]tools.ToolOrigin, servers map[string]tools.ServerInfo) (string, error)
+
+	// ConfirmTools requires approval before running tools that aren't auto-approved.
+	ConfirmTools bool
+	// ReadOnlyTools, if set, turns on read-only mode, blocking the tools
+	// matching these patterns
+	ReadOnlyTools []string
+
+	// UtilityModel is the model, as provider:model, for the agent's own
+	// tasks such as suggesting follow-up prompts, e.g. a small local model.
+	// It is created like the main model. Empty means the main model.
+	UtilityModel string
+
+	// Language is the ISO 639-1 code of the language responses must be
+	// written in. Responses detected to be in another language are retried.
+	Language string
+
+	// ForceTool names a tool to call, with the JSON arguments in
+	// ForceToolArgs, before the model's first step. Its result is given to
+	// the model as if the model had called it.
+	ForceTool     string
+	ForceToolArgs string
+
 	// MessageModifier.
 	// modify the input messages before the model is called, it's useful when you want to add some system prompt or other messages.
 	MessageModifier MessageModifier
 
-	// Tools that will make agent return directly when the tool is called.
+	// Tools that will make agent return directly when the tool is called, by
+	// full "server__tool" name or bare name. The tool's result is the answer.
 	// When multiple tools are called and more than one tool is in the return directly list, only the first one will be returned.
 	ToolReturnDirectly map[string]struct{}
 
+	// CheckPointStore saves runs that pause, so that they can be resumed,
+	// possibly by another process. Runs use it when the agent is given a
+	// checkpoint ID with WithCheckPoint.
+	CheckPointStore CheckPointStore
+
+	// DisableTools runs the agent without MCP tools, e.g. for models that
+	// can't call them. MCP servers aren't started.
+	DisableTools bool
+
+	// PauseBeforeTools pauses runs before each run of the tools the model
+	// asked for, e.g. for a human to review the calls. It needs a
+	// CheckPointStore.
+	PauseBeforeTools bool
+
+	// StepTimeout bounds each model call, including reading its stream, and
+	// each tool call. A model call that times out fails the run with a
+	// *StepTimeoutError; a tool call that times out gives the model the
+	// error as its result. Zero means no limit.
+	StepTimeout time.Duration
+
+	// MaxDuration bounds each run as a whole, including its tool calls.
+	// Once it is reached, the model is asked for a final answer from the
+	// results so far, without tools; MessageTimeLimit reports such answers.
+	// Zero means no limit.
+	MaxDuration time.Duration
+
+	// RepairToolArgs repairs malformed JSON in the arguments of tool calls,
+	// e.g. trailing commas or single quotes, before the tools run. Repairs
+	// are logged.
+	RepairToolArgs bool
+
 	// StreamOutputHandler is a function to determine whether the model's streaming output contains tool calls.
 	StreamToolCallChecker func(ctx context.Context, modelOutput *schema.StreamReader[*schema.Message]) (bool, error)
 }
@@ -64,6 +129,12 @@ type ResponseHandler func(content string)
 // ToolCallContentHandler is a function type for handling content that accompanies tool calls
 type ToolCallContentHandler func(content string)
 
+// ToolApprovalHandler is a function type for asking whether a tool call may run
+type ToolApprovalHandler func(toolName, toolArgs string) bool
+
+// StreamingResponseHandler is a function type for handling response content as it streams in
+type StreamingResponseHandler func(chunk string)
+
 func firstChunkStreamToolCallChecker(_ context.Context, sr *schema.StreamReader[*schema.Message]) (bool, error) {
 	defer sr.Close()
 
@@ -94,15 +165,52 @@ const (
 	ToolsNodeName = "Tools"
 )
 
-// Agent is the agent with real-time tool call display.
+// Agent is the agent with real-time tool call display. It is safe for
+// concurrent use: each run takes a snapshot of the system prompt and tool
+// handlers when it starts and keeps its other state in its context, so one
+// agent can serve several conversations at once.
 type Agent struct {
 	runnable         compose.Runnable[[]*schema.Message, *schema.Message]
 	graph            *compose.Graph[[]*schema.Message, *schema.Message]
 	graphAddNodeOpts []compose.GraphAddNodeOpt
 	toolManager      *tools.MCPToolManager
 	model            model.ToolCallingChatModel
+	modelName        string
+	providers        *providerCache
+	utilityModel     model.ToolCallingChatModel
+	toolInfos        []*schema.ToolInfo
 	maxSteps         int
 	systemPrompt     string
+	permissions      *tools.PermissionPolicy
+	approveTool      ToolApprovalHandler
+	beforeToolRun    []ToolCallHandler
+	temperature      *float32
+	language         string
+	forcedTool       *forcedToolCall
+	traces           *traceStore
+	graphInfo        *graphInfoRecorder
+	checkPoints      CheckPointStore
+	checkPointID     string
+	pauseBeforeTools bool
+	stepTimeout      time.Duration
+	maxDuration      time.Duration
+	repairToolArgs   bool
+	onProgress       ProgressHandler
+	onStep           StepHandler
+
+	// addedTools are the tools of servers added with AddServer
+	addedTools *addedTools
+
+	// mu guards systemPrompt, approveTool, beforeToolRun and temperature,
+	// which may be changed while runs are going. Copies share it.
+	mu *sync.RWMutex
+}
+
+// forcedToolCall is a tool call made once, at the start of the first turn.
+// It is a pointer in Agent so that copies share whether it has run.
+type forcedToolCall struct {
+	call schema.ToolCall
+	once sync.Once
 }
 
 var registerStateOnce sync.Once
@@ -117,12 +225,15 @@ func NewAgent(ctx context.Context, config *AgentConfig) (*Agent, error) {
 
 	// Create and load MCP tools
 	toolManager := tools.NewMCPToolManager()
-	if err := toolManager.LoadTools(ctx, config.MCPConfig); err != nil {
-		return nil, fmt.Errorf("failed to load MCP tools: %v", err)
+	if !config.DisableTools {
+		if err := toolManager.LoadTools(ctx, config.MCPConfig); err != nil {
+			// Don't leave already-started servers running
+			toolManager.Close()
+			return nil, fmt.Errorf("failed to load MCP tools: %v", err)
+		}
 	}
 
 	var (
-		toolsNode       *compose.ToolsNode
 		toolInfos       []*schema.ToolInfo
 		toolCallChecker = config.StreamToolCallChecker
 		messageModifier = config.MessageModifier
@@ -139,58 +250,110 @@ func NewAgent(ctx context.Context, config *AgentConfig) (*Agent, error) {
 		toolCallChecker = firstChunkStreamToolCallChecker
 	}
 
-	// Create tools config
-	toolsConfig := compose.ToolsNodeConfig{
-		Tools: toolManager.GetTools(),
-	}
-
 	// Only set up tools if we have any
-	hasTools := len(toolsConfig.Tools) > 0
+	availableTools := toolManager.GetTools()
+	hasTools := len(availableTools) > 0
 
 	if hasTools {
-		if toolInfos, err = genToolInfos(ctx, toolsConfig); err != nil {
+		if toolInfos, err = genToolInfos(ctx, compose.ToolsNodeConfig{Tools: availableTools}); err != nil {
 			return nil, err
 		}
+	}
 
-		if toolsNode, err = compose.NewToolNode(ctx, &toolsConfig); err != nil {
-			return nil, err
+	systemPrompt := config.SystemPrompt
+	if config.SystemPromptFunc != nil {
+		if systemPrompt, err = config.SystemPromptFunc(toolInfos, toolManager.Origins(), toolManager.Servers()); err != nil {
+			toolManager.Close()
+			return nil, fmt.Errorf("failed to build system prompt: %v", err)
 		}
 	}
 
-	chatModel, err := agent.ChatModelWithTools(nil, model, toolInfos)
-	if err != nil {
-		// If binding tools fails and we have no tools, just use the model directly
-		if !hasTools {
-			chatModel = model
-		} else {
+	var forcedTool *forcedToolCall
+	if config.ForceTool != "" {
+		if forcedTool, err = newForcedToolCall(config.ForceTool, config.ForceToolArgs, toolInfos, toolManager.Origins()); err != nil {
+			toolManager.Close()
 			return nil, err
 		}
 	}
 
+	// Tools that return directly may be given by their bare names too
+	returnDirectly := make(map[string]struct{}, len(config.ToolReturnDirectly))
+	for name := range config.ToolReturnDirectly {
+		match := resolveToolName(name, toolInfos, toolManager.Origins())
+		if match == "" {
+			toolManager.Close()
+			return nil, fmt.Errorf("return-direct tool not found: %s", name)
+		}
+		returnDirectly[match] = struct{}{}
+	}
+
+	if config.PauseBeforeTools && config.CheckPointStore == nil {
+		toolManager.Close()
+		return nil, fmt.Errorf("pausing before tools needs a checkpoint store")
+	}
+
 	maxSteps := config.MaxSteps
 	if maxSteps == 0 {
 		maxSteps = 20
 	}
 
+	// The graph's nodes run with the state of the agent the run is for,
+	// taken from the run's context
+	a := &Agent{
+		toolManager:    toolManager,
+		model:          model,
+		modelName:      config.ModelConfig.ModelString,
+		providers:      newProviderCache(config.ModelConfig, model),
+		toolInfos:      toolInfos,
+		maxSteps:       maxSteps,
+		systemPrompt:   systemPrompt,
+		permissions:    tools.NewPermissionPolicy(config.MCPConfig, config.ConfirmTools, toolManager.Origin),
+		language:       config.Language,
+		forcedTool:     forcedTool,
+		traces:         &traceStore{},
+		graphInfo:      &graphInfoRecorder{},
+		checkPoints:    config.CheckPointStore,
+		stepTimeout:    config.StepTimeout,
+		maxDuration:    config.MaxDuration,
+		repairToolArgs: config.RepairToolArgs,
+		addedTools:     &addedTools{},
+		mu:             &sync.RWMutex{},
+	}
+	if config.ReadOnlyTools != nil {
+		a.permissions.SetReadOnly(config.ReadOnlyTools)
+	}
+	a.utilityModel = model
+	if config.UtilityModel != "" {
+		if a.utilityModel, err = a.providers.get(ctx, config.UtilityModel); err != nil {
+			toolManager.Close()
+			return nil, fmt.Errorf("failed to create utility model: %v", err)
+		}
+	}
+
 	graph := compose.NewGraph[[]*schema.Message, *schema.Message](compose.WithGenLocalState(func(ctx context.Context) *state {
 		return &state{Messages: make([]*schema.Message, 0, maxSteps+1)}
 	}))
 
 	modelPreHandle := func(ctx context.Context, input []*schema.Message, state *state) ([]*schema.Message, error) {
 		state.Messages = append(state.Messages, input...)
+		run := a.runState(ctx)
 
 		// Add system prompt if provided and not already present
-		if config.SystemPrompt != "" {
+		if prompt := run.systemPrompt; prompt != "" {
 			hasSystemMessage := false
 			if len(state.Messages) > 0 && state.Messages[0].Role == schema.System {
 				hasSystemMessage = true
 			}
 
 			if !hasSystemMessage {
-				systemMsg := schema.SystemMessage(config.SystemPrompt)
+				systemMsg := schema.SystemMessage(prompt)
 				state.Messages = append([]*schema.Message{systemMsg}, state.Messages...)
 			}
 		}
+		// Messages typed while the run was paused in step-by-step mode
+		state.Messages = append(state.Messages, run.takeAdjustments()...)
+		run.messages = state.Messages
+		run.reportProgress(state.Messages)
 
 		if messageModifier == nil {
 			return state.Messages, nil
@@ -201,7 +364,7 @@ func NewAgent(ctx context.Context, config *AgentConfig) (*Agent, error) {
 		return messageModifier(ctx, modifiedInput), nil
 	}
 
-	if err = graph.AddChatModelNode(nodeKeyModel, chatModel, compose.WithStatePreHandler(modelPreHandle), compose.WithNodeName(ModelNodeName)); err != nil {
+	if err = graph.AddChatModelNode(nodeKeyModel, &graphModel{agent: a}, compose.WithStatePreHandler(modelPreHandle), compose.WithNodeName(ModelNodeName)); err != nil {
 		return nil, err
 	}
 
@@ -211,19 +374,55 @@ func NewAgent(ctx context.Context, config *AgentConfig) (*Agent, error) {
 
 	// Only add tools node and related logic if we have tools
 	if hasTools {
+		// Tools run one at a time so that approvals can be asked in order
+		toolsConfig := compose.ToolsNodeConfig{
+			ExecuteSequentially: true,
+			UnknownToolsHandler: func(ctx context.Context, name, input string) (string, error) {
+				run := a.runState(ctx)
+				result := run.agent.callTool(ctx, run, name, input, a.addedTools.lookup(name))
+				run.reportToolResult(ctx, result)
+				run.pauseStep("tool " + name)
+				return result, nil
+			},
+		}
+		for i, t := range availableTools {
+			invokable, ok := t.(tool.InvokableTool)
+			if !ok {
+				return nil, fmt.Errorf("tool %s is not invokable", toolInfos[i].Name)
+			}
+			toolsConfig.Tools = append(toolsConfig.Tools, &graphTool{InvokableTool: invokable, agent: a, name: toolInfos[i].Name})
+		}
+
+		toolsNode, err := compose.NewToolNode(ctx, &toolsConfig)
+		if err != nil {
+			return nil, err
+		}
+
 		toolsNodePreHandle := func(ctx context.Context, input *schema.Message, state *state) (*schema.Message, error) {
 			if input == nil {
 				return state.Messages[len(state.Messages)-1], nil // used for rerun interrupt resume
 			}
+			if a.repairToolArgs {
+				repairToolArgs(input)
+			}
 			state.Messages = append(state.Messages, input)
-			state.ReturnDirectlyToolCallID = getReturnDirectlyToolCallID(input, config.ToolReturnDirectly)
+			state.ReturnDirectlyToolCallID = getReturnDirectlyToolCallID(input, returnDirectly)
+
+			run := a.runState(ctx)
+			run.reportProgress(state.Messages)
+
+			// Display any content that accompanies the tool calls
+			if input.Content != "" && run.onToolCallContent != nil {
+				run.onToolCallContent(input.Content)
+			}
+			run.pauseStep("the model's response")
 			return input, nil
 		}
 		if err = graph.AddToolsNode(nodeKeyTools, toolsNode, compose.WithStatePreHandler(toolsNodePreHandle), compose.WithNodeName(ToolsNodeName)); err != nil {
 			return nil, err
 		}
 
-		modelPostBranchCondition := func(_ context.Context, sr *schema.StreamReader[*schema.Message]) (endNode string, err error) {
+		modelPostBranchCondition := func(ctx context.Context, sr *schema.StreamReader[*schema.Message]) (endNode string, err error) {
 			if isToolCall, err := toolCallChecker(ctx, sr); err != nil {
 				return "", err
 			} else if isToolCall {
@@ -236,7 +435,7 @@ func NewAgent(ctx context.Context, config *AgentConfig) (*Agent, error) {
 			return nil, err
 		}
 
-		if len(config.ToolReturnDirectly) > 0 {
+		if len(returnDirectly) > 0 {
 			if err = buildReturnDirectly(graph); err != nil {
 				return nil, err
 			}
@@ -250,21 +449,69 @@ func NewAgent(ctx context.Context, config *AgentConfig) (*Agent, error) {
 		}
 	}
 
-	compileOpts := []compose.GraphCompileOption{compose.WithMaxRunSteps(maxSteps), compose.WithNodeTriggerMode(compose.AnyPredecessor), compose.WithGraphName(GraphName)}
-	runnable, err := graph.Compile(ctx, compileOpts...)
+	// Every step is a model call and a run of the tools it asked for; the
+	// last tools may be followed by the direct return node
+	compileOpts := []compose.GraphCompileOption{compose.WithMaxRunSteps(2*maxSteps + 1), compose.WithNodeTriggerMode(compose.AnyPredecessor), compose.WithGraphName(GraphName)}
+	// Only the agent's own runs record the graph's structure and pause; an
+	// exported graph is checkpointed with the graph it is added to
+	runOpts := append([]compose.GraphCompileOption{compose.WithGraphCompileCallbacks(a.graphInfo)}, compileOpts...)
+	if a.checkPoints != nil {
+		runOpts = append(runOpts, compose.WithCheckPointStore(a.checkPoints))
+	}
+	if config.PauseBeforeTools && hasTools {
+		runOpts = append(runOpts, compose.WithInterruptBeforeNodes([]string{nodeKeyTools}))
+		a.pauseBeforeTools = true
+	}
+	runnable, err := graph.Compile(ctx, runOpts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Agent{
-		runnable:         runnable,
-		graph:            graph,
-		graphAddNodeOpts: []compose.GraphAddNodeOpt{compose.WithGraphCompileOptions(compileOpts...)},
-		toolManager:      toolManager,
-		model:            model,
-		maxSteps:         maxSteps,
-		systemPrompt:     config.SystemPrompt,
-	}, nil
+	a.runnable = runnable
+	a.graph = graph
+	a.graphAddNodeOpts = []compose.GraphAddNodeOpt{compose.WithGraphCompileOptions(compileOpts...)}
+	return a, nil
+}
+
+// newForcedToolCall resolves a tool given by its full "server__tool" name or
+// its bare name and checks its arguments
+func newForcedToolCall(name, args string, toolInfos []*schema.ToolInfo, origins map[string]tools.ToolOrigin) (*forcedToolCall, error) {
+	if args == "" {
+		args = "{}"
+	}
+	if !json.Valid([]byte(args)) {
+		return nil, fmt.Errorf("invalid arguments for forced tool %s: not valid JSON", name)
+	}
+
+	match := resolveToolName(name, toolInfos, origins)
+	if match == "" {
+		return nil, fmt.Errorf("forced tool not found: %s", name)
+	}
+
+	return &forcedToolCall{call: schema.ToolCall{
+		// Gemini matches tool results to calls by name, so the ID is the name
+		ID:       match,
+		Type:     "function",
+		Function: schema.FunctionCall{Name: match, Arguments: args},
+	}}, nil
+}
+
+// resolveToolName returns the full name of a tool given by its full
+// "server__tool" name or its bare name, or "" if there is no such tool.
+// origins gives the bare names of the full ones. Full names may have been
+// changed by config.SanitizeToolName.
+func resolveToolName(name string, toolInfos []*schema.ToolInfo, origins map[string]tools.ToolOrigin) string {
+	var match string
+	sanitized := config.SanitizeToolName(name)
+	for _, info := range toolInfos {
+		if info.Name == name || info.Name == sanitized {
+			return info.Name
+		}
+		if origin, ok := origins[info.Name]; match == "" && ok && origin.Tool == name {
+			match = info.Name
+		}
+	}
+	return match
 }
 
 func buildReturnDirectly(graph *compose.Graph[[]*schema.Message, *schema.Message]) (err error) {
@@ -347,6 +594,19 @@ func getReturnDirectlyToolCallID(input *schema.Message, toolReturnDirectly map[s
 	return ""
 }
 
+// repairToolArgs repairs malformed JSON in the arguments of the tool calls
+// of a model response, logging each repair. Arguments that can't be
+// repaired are left for callTool to reject.
+func repairToolArgs(msg *schema.Message) {
+	for i, call := range msg.ToolCalls {
+		repaired, err := jsonrepair.Repair(call.Function.Arguments)
+		if err == nil && repaired != call.Function.Arguments {
+			log.Printf("repaired the JSON arguments of %s: %s", call.Function.Name, repaired)
+			msg.ToolCalls[i].Function.Arguments = repaired
+		}
+	}
+}
+
 // Generate generates a response from the agent.
 func (a *Agent) Generate(ctx context.Context, input []*schema.Message, opts ...compose.Option) (*schema.Message, error) {
 	// Convert compose options to agent options
@@ -354,6 +614,7 @@ func (a *Agent) Generate(ctx context.Context, input []*schema.Message, opts ...c
 	if len(opts) > 0 {
 		agentOpts = append(agentOpts, agent.WithComposeOptions(opts...))
 	}
+	ctx = withRunState(ctx, a.newRunState())
 	return a.runnable.Invoke(ctx, input, agent.GetComposeOptions(agentOpts...)...)
 }
 
@@ -364,125 +625,302 @@ func (a *Agent) Stream(ctx context.Context, input []*schema.Message, opts ...com
 	if len(opts) > 0 {
 		agentOpts = append(agentOpts, agent.WithComposeOptions(opts...))
 	}
+	ctx = withRunState(ctx, a.newRunState())
 	return a.runnable.Stream(ctx, input, agent.GetComposeOptions(agentOpts...)...)
 }
 
-// GenerateWithLoop processes messages with a custom loop that displays tool calls in real-time
+// GenerateWithLoop runs the agent graph on messages, reporting tool calls
+// and responses to the handlers as they happen
 func (a *Agent) GenerateWithLoop(ctx context.Context, messages []*schema.Message,
 	onToolCall ToolCallHandler, onToolExecution ToolExecutionHandler, onToolResult ToolResultHandler, onResponse ResponseHandler, onToolCallContent ToolCallContentHandler) (*schema.Message, error) {
+	return a.GenerateWithLoopStreaming(ctx, messages, onToolCall, onToolExecution, onToolResult, onResponse, onToolCallContent, nil)
+}
+
+// GenerateWithLoopStreaming is like GenerateWithLoop but streams model output,
+// passing content to onChunk as it arrives. A nil onChunk disables streaming.
+func (a *Agent) GenerateWithLoopStreaming(ctx context.Context, messages []*schema.Message,
+	onToolCall ToolCallHandler, onToolExecution ToolExecutionHandler, onToolResult ToolResultHandler, onResponse ResponseHandler, onToolCallContent ToolCallContentHandler, onChunk StreamingResponseHandler) (_ *schema.Message, err error) {
+	if a.pauseBeforeTools && a.checkPointID == "" {
+		return nil, fmt.Errorf("pausing before tools needs a checkpoint ID")
+	}
+
+	// A paused run continues from its checkpoint, which holds the
+	// conversation, so messages are ignored
+	resuming, err := a.HasCheckPoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	run := a.newRunState()
+	run.onToolExecution = onToolExecution
+	run.onToolCallContent = onToolCallContent
+	run.onChunk = onChunk
+
+	// Record the run so /trace can show it, and its tool calls with the
+	// answer
+	defer run.trace.finish(a.traces)
+	defer run.endStep()
+	defer run.recoverPanic(&err)
+	var calls toolCallRecorder
+	run.onToolCall, run.onToolResult = run.trace.wrapToolHandlers(calls.wrap(onToolCall, onToolResult))
+	ctx = withRunState(ctx, run)
+
+	// The time limit covers the whole run, tool calls included
+	runCtx, cancel := a.withTimeLimit(ctx)
+	defer cancel()
 
 	// Create a copy of messages to avoid modifying the original
-	workingMessages := make([]*schema.Message, len(messages))
-	copy(workingMessages, messages)
+	input := make([]*schema.Message, len(messages))
+	copy(input, messages)
+
+	// A conversation ending with tool calls that have no results, such as
+	// that of a run killed while its tools ran, continues with those calls
+	if !resuming {
+		for _, call := range pendingToolCalls(input) {
+			input = append(input, a.runToolCall(runCtx, run, call))
+			run.reportProgress(input)
+		}
+	}
+
+	// Make the forced tool call, once, as if the model had asked for it
+	if a.forcedTool != nil && !resuming {
+		a.forcedTool.once.Do(func() {
+			call := a.forcedTool.call
+			input = append(input,
+				schema.AssistantMessage("", []schema.ToolCall{call}),
+				a.runToolCall(runCtx, run, call))
+		})
+	}
+
+	response, err := a.runGraph(runCtx, run, input)
+
+	// Ask once more if the response is in the wrong language
+	if err == nil && a.language != "" && response.Role == schema.Assistant && !language.Matches(response.Content, a.language) {
+		retry := append(append([]*schema.Message(nil), run.messages...), response, schema.UserMessage(language.Retry(a.language)))
+		response, err = a.runGraph(runCtx, run, retry)
+	}
 
-	// Add system prompt if provided
-	if a.systemPrompt != "" {
-		hasSystemMessage := false
-		if len(workingMessages) > 0 && workingMessages[0].Role == schema.System {
-			hasSystemMessage = true
+	// Once out of time, the model answers from what it has so far
+	if err != nil && timeLimitReached(runCtx) && ctx.Err() == nil {
+		response, err = a.finishAfterTimeLimit(ctx, run, input)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	calls.stamp(response, a.modelName)
+	if onResponse != nil && response.Content != "" {
+		onResponse(response.Content)
+	}
+	return response, nil
+}
+
+// runGraph runs the compiled graph once, streaming if the run has a chunk
+// handler, and returns the final response
+func (a *Agent) runGraph(ctx context.Context, run *runState, input []*schema.Message) (*schema.Message, error) {
+	var opts []compose.Option
+	if a.checkPointID != "" {
+		opts = append(opts, compose.WithCheckPointID(a.checkPointID))
+	}
+
+	var response *schema.Message
+	var err error
+	if run.onChunk == nil {
+		response, err = a.runnable.Invoke(ctx, input, opts...)
+	} else {
+		response, err = a.streamGraph(ctx, input, opts...)
+	}
+
+	// A panic the graph recovered is reported as such
+	if run.panicked != nil {
+		return nil, run.panicked
+	}
+
+	if _, paused := compose.ExtractInterruptInfo(err); paused {
+		return nil, &InterruptedError{CheckPointID: a.checkPointID, ToolCalls: run.pendingToolCalls()}
+	}
+
+	if err != nil && !errors.Is(err, compose.ErrExceedMaxSteps) {
+		// The model call timed out, possibly while its stream was read
+		if timeout := run.stepTimeout(); timeout != nil {
+			return nil, timeout
 		}
+		// Report the model's own error rather than the graph's wrapping
+		if run.modelErr != nil {
+			err = run.modelErr
+		}
+		return nil, fmt.Errorf("failed to generate response: %v", err)
+	}
 
-		if !hasSystemMessage {
-			systemMsg := schema.SystemMessage(a.systemPrompt)
-			workingMessages = append([]*schema.Message{systemMsg}, workingMessages...)
+	// The run is over, so a later run with the same ID starts afresh. A run
+	// that failed keeps its checkpoint and can be resumed again.
+	if a.checkPointID != "" && a.checkPoints != nil {
+		if err := a.checkPoints.Delete(ctx, a.checkPointID); err != nil {
+			return nil, err
 		}
 	}
+	if err != nil {
+		return schema.AssistantMessage("Maximum number of steps reached.", nil), nil
+	}
 
-	// Get available tools
-	availableTools := a.toolManager.GetTools()
-	var toolInfos []*schema.ToolInfo
-	toolMap := make(map[string]tool.BaseTool)
+	// A tool that returns directly ends the run with its result, which is
+	// the answer
+	if response.Role == schema.Tool {
+		if run.onChunk != nil {
+			run.onChunk(response.Content)
+		}
+		response = schema.AssistantMessage(response.Content, nil)
+	}
+	return response, nil
+}
+
+// streamGraph streams a run of the graph and returns the concatenated
+// response. Content has already been passed to the chunk handler by the
+// model node.
+func (a *Agent) streamGraph(ctx context.Context, input []*schema.Message, opts ...compose.Option) (*schema.Message, error) {
+	stream, err := a.runnable.Stream(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
 
-	for _, t := range availableTools {
-		info, err := t.Info(ctx)
+	var chunks []*schema.Message
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			continue
+			return nil, err
+		}
+		if chunk != nil {
+			chunks = append(chunks, chunk)
 		}
-		toolInfos = append(toolInfos, info)
-		toolMap[info.Name] = t
 	}
 
-	// Main loop
-	for step := 0; step < a.maxSteps; step++ {
-		// Call the LLM
-		response, err := a.model.Generate(ctx, workingMessages, model.WithTools(toolInfos))
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate response: %v", err)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("empty response stream")
+	}
+	return schema.ConcatMessages(chunks)
+}
+
+// runToolCall runs a tool call outside the graph and returns the tool
+// message with its result or error
+func (a *Agent) runToolCall(ctx context.Context, run *runState, toolCall schema.ToolCall) *schema.Message {
+	var selected tool.InvokableTool
+	for _, t := range a.toolManager.GetTools() {
+		if info, err := t.Info(ctx); err == nil && info.Name == toolCall.Function.Name {
+			selected, _ = t.(tool.InvokableTool)
+			break
 		}
+	}
+	result := a.callTool(ctx, run, toolCall.Function.Name, toolCall.Function.Arguments, selected)
+	run.pauseStep("tool " + toolCall.Function.Name)
+	return schema.ToolMessage(result, toolCall.ID)
+}
 
-		// Add response to working messages
-		workingMessages = append(workingMessages, response)
+// modelOptions returns the options for a model call offering the agent's tools
+func (a *Agent) modelOptions() []model.Option {
+	opts := []model.Option{model.WithTools(append(slices.Clip(a.toolInfos), a.addedTools.infos()...))}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.temperature != nil {
+		opts = append(opts, model.WithTemperature(*a.temperature))
+	}
+	return opts
+}
 
-		// Check if this is a tool call or final response
-		if len(response.ToolCalls) > 0 {
-			// Display any content that accompanies the tool calls
-			if response.Content != "" && onToolCallContent != nil {
-				onToolCallContent(response.Content)
-			}
+// WithTemperature returns a copy of the agent that samples at the given
+// temperature, e.g. to regenerate an answer with more variety. The copy
+// shares the tools and permissions of the original.
+func (a *Agent) WithTemperature(temperature float32) *Agent {
+	copied := a.copy()
+	copied.temperature = &temperature
+	return copied
+}
 
-			// Handle tool calls
-			for _, toolCall := range response.ToolCalls {
-				// Notify about tool call
-				if onToolCall != nil {
-					onToolCall(toolCall.Function.Name, toolCall.Function.Arguments)
-				}
+// SystemPrompt returns the system prompt sent with every model call
+func (a *Agent) SystemPrompt() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.systemPrompt
+}
 
-				// Execute the tool
-				if selectedTool, exists := toolMap[toolCall.Function.Name]; exists {
-					// Notify tool execution start
-					if onToolExecution != nil {
-						onToolExecution(toolCall.Function.Name, true)
-					}
+// SetSystemPrompt replaces the system prompt for subsequent runs
+func (a *Agent) SetSystemPrompt(prompt string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.systemPrompt = prompt
+}
 
-					output, err := selectedTool.(tool.InvokableTool).InvokableRun(ctx, toolCall.Function.Arguments)
+// GetTools returns the list of available tools
+func (a *Agent) GetTools() []tool.BaseTool {
+	return a.toolManager.GetTools()
+}
 
-					// Notify tool execution end
-					if onToolExecution != nil {
-						onToolExecution(toolCall.Function.Name, false)
-					}
+// SetToolApprovalHandler sets the function asked to approve tool calls that
+// the permission policy doesn't auto-approve. Without a handler such calls
+// are denied. Runs already going keep the handler they started with.
+func (a *Agent) SetToolApprovalHandler(handler ToolApprovalHandler) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.approveTool = handler
+}
 
-					if err != nil {
-						errorMsg := fmt.Sprintf("Tool execution error: %v", err)
-						toolMessage := schema.ToolMessage(errorMsg, toolCall.ID)
-						workingMessages = append(workingMessages, toolMessage)
-
-						if onToolResult != nil {
-							onToolResult(toolCall.Function.Name, toolCall.Function.Arguments, errorMsg, true)
-						}
-					} else {
-						toolMessage := schema.ToolMessage(output, toolCall.ID)
-						workingMessages = append(workingMessages, toolMessage)
-
-						if onToolResult != nil {
-							onToolResult(toolCall.Function.Name, toolCall.Function.Arguments, output, false)
-						}
-					}
-				} else {
-					errorMsg := fmt.Sprintf("Tool not found: %s", toolCall.Function.Name)
-					toolMessage := schema.ToolMessage(errorMsg, toolCall.ID)
-					workingMessages = append(workingMessages, toolMessage)
+// AddBeforeToolRun adds a function called with each approved tool call just
+// before it runs, e.g. to save the files it may change. Functions are called
+// in the order they were added.
+func (a *Agent) AddBeforeToolRun(handler ToolCallHandler) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// Always reallocate, since runs and copies hold the old slice
+	a.beforeToolRun = append(slices.Clip(a.beforeToolRun), handler)
+}
 
-					if onToolResult != nil {
-						onToolResult(toolCall.Function.Name, toolCall.Function.Arguments, errorMsg, true)
-					}
-				}
-			}
-		} else {
-			// This is a final response
-			if onResponse != nil && response.Content != "" {
-				onResponse(response.Content)
-			}
-			return response, nil
-		}
+// WithToolApprovalHandler returns a copy of the agent that asks handler to
+// approve tool calls, e.g. to decide per user. The copy shares the tools and
+// permissions of the original.
+func (a *Agent) WithToolApprovalHandler(handler ToolApprovalHandler) *Agent {
+	copied := a.copy()
+	copied.approveTool = handler
+	return copied
+}
+
+// copy returns a shallow copy of the agent, taken while its settings can't
+// change
+func (a *Agent) copy() *Agent {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	copied := *a
+	return &copied
+}
+
+// Permissions returns the live tool permission policy
+func (a *Agent) Permissions() *tools.PermissionPolicy {
+	return a.permissions
+}
+
+// isToolApproved checks the permission policy and, if needed, asks the
+// run's approval handler
+func (a *Agent) isToolApproved(run *runState, toolName, toolArgs string) bool {
+	if !a.permissions.NeedsApproval(toolName) {
+		return true
+	}
+	if run.approveTool == nil {
+		return false
 	}
+	return run.approveTool(toolName, toolArgs)
+}
 
-	// If we reach here, we've exceeded max steps
-	return schema.AssistantMessage("Maximum number of steps reached.", nil), nil
+// GetServerLogs returns the most recent stderr output of a stdio MCP server
+func (a *Agent) GetServerLogs(serverName string) ([]string, bool) {
+	return a.toolManager.GetServerLogs(serverName)
 }
 
-// GetTools returns the list of available tools
-func (a *Agent) GetTools() []tool.BaseTool {
-	return a.toolManager.GetTools()
+// Servers returns what the started MCP servers told about themselves,
+// keyed by server name
+func (a *Agent) Servers() map[string]tools.ServerInfo {
+	return a.toolManager.Servers()
 }
 
 // Close closes the agent and cleans up resources
@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// CheckPointStore saves the state of paused runs so they can be resumed
+type CheckPointStore interface {
+	compose.CheckPointStore
+	// Delete removes a checkpoint once its run has finished. Deleting a
+	// checkpoint that doesn't exist is not an error.
+	Delete(ctx context.Context, checkPointID string) error
+}
+
+// InterruptedError is returned when a run pauses before running the tools
+// the model asked for. The run is saved under CheckPointID and continues
+// where it stopped when run again with the same checkpoint.
+type InterruptedError struct {
+	CheckPointID string
+	// ToolCalls are the tool calls that run when the run is resumed
+	ToolCalls []schema.ToolCall
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("run paused before %d tool calls (checkpoint %s)", len(e.ToolCalls), e.CheckPointID)
+}
+
+// FileCheckPointStore keeps checkpoints as files in a directory, so that a
+// paused run can be resumed by another process, or on another machine with
+// the same configuration
+type FileCheckPointStore struct {
+	dir string
+}
+
+// NewFileCheckPointStore returns a store keeping checkpoints in dir, which
+// is created when the first checkpoint is saved
+func NewFileCheckPointStore(dir string) *FileCheckPointStore {
+	return &FileCheckPointStore{dir: dir}
+}
+
+// path returns the file of a checkpoint. IDs are file names, so they can't
+// point outside the directory.
+func (s *FileCheckPointStore) path(checkPointID string) (string, error) {
+	if checkPointID == "" || strings.ContainsAny(checkPointID, `/\`) || strings.HasPrefix(checkPointID, ".") {
+		return "", fmt.Errorf("invalid checkpoint ID %q", checkPointID)
+	}
+	return filepath.Join(s.dir, checkPointID+".ckpt"), nil
+}
+
+// Get implements compose.CheckPointStore
+func (s *FileCheckPointStore) Get(_ context.Context, checkPointID string) ([]byte, bool, error) {
+	path, err := s.path(checkPointID)
+	if err != nil {
+		return nil, false, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	return data, true, nil
+}
+
+// Set implements compose.CheckPointStore. The file is replaced atomically,
+// so an interrupted write leaves the previous checkpoint intact.
+func (s *FileCheckPointStore) Set(_ context.Context, checkPointID string, checkPoint []byte) error {
+	path, err := s.path(checkPointID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, checkPoint, 0600); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	return nil
+}
+
+// Delete implements CheckPointStore
+func (s *FileCheckPointStore) Delete(_ context.Context, checkPointID string) error {
+	path, err := s.path(checkPointID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete checkpoint: %v", err)
+	}
+	return nil
+}
+
+// WithCheckPoint returns a copy of the agent whose runs are saved under id
+// when they pause. If a paused run is saved under id, the copy's next run
+// continues it instead of starting a new one. The copy shares the tools and
+// permissions of the original.
+func (a *Agent) WithCheckPoint(id string) *Agent {
+	copied := a.copy()
+	copied.checkPointID = id
+	return copied
+}
+
+// HasCheckPoint reports whether a paused run is saved under the agent's
+// checkpoint ID
+func (a *Agent) HasCheckPoint(ctx context.Context) (bool, error) {
+	if a.checkPoints == nil || a.checkPointID == "" {
+		return false, nil
+	}
+	_, ok, err := a.checkPoints.Get(ctx, a.checkPointID)
+	return ok, err
+}
+
+// pendingToolCalls returns the tool calls of the run's last model response,
+// which are the calls a run paused before the Tools node will make
+func (run *runState) pendingToolCalls() []schema.ToolCall {
+	if len(run.response) == 0 {
+		return nil
+	}
+	response, err := schema.ConcatMessages(run.response)
+	if err != nil {
+		return nil
+	}
+	return response.ToolCalls
+}
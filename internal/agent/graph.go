@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/eino/compose"
+)
+
+// graphInfoRecorder keeps the structure of the agent graph when it is
+// compiled
+type graphInfoRecorder struct {
+	info *compose.GraphInfo
+}
+
+// OnFinish implements compose.GraphCompileCallback
+func (r *graphInfoRecorder) OnFinish(_ context.Context, info *compose.GraphInfo) {
+	r.info = info
+}
+
+// graphEdge is an edge of the agent graph; branch edges are taken depending
+// on a node's output
+type graphEdge struct {
+	from, to string
+	branch   bool
+}
+
+// GraphDiagram renders the compiled agent graph, with its nodes, edges,
+// branches and the tools of the Tools node, as a Mermaid flowchart or a
+// Graphviz DOT graph
+func (a *Agent) GraphDiagram(format string) (string, error) {
+	if a.graphInfo == nil || a.graphInfo.info == nil {
+		return "", fmt.Errorf("the agent graph has not been compiled")
+	}
+	info := a.graphInfo.info
+
+	keys := []string{compose.START}
+	for key := range info.Nodes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys[1:])
+	keys = append(keys, compose.END)
+
+	var edges []graphEdge
+	for from, tos := range info.Edges {
+		for _, to := range tos {
+			edges = append(edges, graphEdge{from: from, to: to})
+		}
+	}
+	for from, branches := range info.Branches {
+		for _, branch := range branches {
+			for to := range branch.GetEndNode() {
+				edges = append(edges, graphEdge{from: from, to: to, branch: true})
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].from != edges[j].from {
+			return edges[i].from < edges[j].from
+		}
+		return edges[i].to < edges[j].to
+	})
+
+	// labels are the lines each node is labelled with
+	labels := func(key string) []string {
+		node, ok := info.Nodes[key]
+		if !ok || node.Name == "" {
+			return []string{key}
+		}
+		lines := []string{node.Name}
+		if key == nodeKeyTools {
+			for _, t := range a.toolInfos {
+				lines = append(lines, t.Name)
+			}
+		}
+		return lines
+	}
+
+	var b strings.Builder
+	switch format {
+	case "mermaid":
+		// Node IDs are prefixed because "end" is a Mermaid keyword
+		id := func(key string) string { return "n_" + key }
+		b.WriteString("flowchart TD\n")
+		for _, key := range keys {
+			label := strings.ReplaceAll(strings.Join(labels(key), "<br/>"), `"`, "#quot;")
+			if key == compose.START || key == compose.END {
+				fmt.Fprintf(&b, "    %s([\"%s\"])\n", id(key), label)
+			} else {
+				fmt.Fprintf(&b, "    %s[\"%s\"]\n", id(key), label)
+			}
+		}
+		for _, e := range edges {
+			arrow := "-->"
+			if e.branch {
+				arrow = "-.->"
+			}
+			fmt.Fprintf(&b, "    %s %s %s\n", id(e.from), arrow, id(e.to))
+		}
+	case "dot":
+		fmt.Fprintf(&b, "digraph %q {\n    rankdir=TB;\n", GraphName)
+		for _, key := range keys {
+			shape := "box"
+			if key == compose.START || key == compose.END {
+				shape = "oval"
+			}
+			fmt.Fprintf(&b, "    %q [label=%q, shape=%s];\n", key, strings.Join(labels(key), "\n"), shape)
+		}
+		for _, e := range edges {
+			style := ""
+			if e.branch {
+				style = " [style=dashed]"
+			}
+			fmt.Fprintf(&b, "    %q -> %q%s;\n", e.from, e.to, style)
+		}
+		b.WriteString("}\n")
+	default:
+		return "", fmt.Errorf("invalid graph format %q (use mermaid or dot)", format)
+	}
+	return b.String(), nil
+}
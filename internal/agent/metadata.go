@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"encoding/json"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// Keys of the metadata kept in the Extra field of messages. The values are
+// strings, so they survive checkpoints and JSON unchanged. Providers don't
+// send Extra to the model.
+const (
+	extraTime      = "mcphost_time"
+	extraModel     = "mcphost_model"
+	extraToolCalls = "mcphost_tool_calls"
+	extraTimeLimit = "mcphost_time_limit"
+	extraPinned    = "mcphost_pinned"
+)
+
+// ToolCallRecord is a tool call made while an answer was generated
+type ToolCallRecord struct {
+	Name    string    `json:"name"`
+	Args    string    `json:"args"`
+	Result  string    `json:"result"`
+	IsError bool      `json:"is_error,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// NewUserMessage returns a user message stamped with the current time
+func NewUserMessage(content string) *schema.Message {
+	return StampMessage(schema.UserMessage(content), time.Now())
+}
+
+// StampMessage records that msg was sent or received at t, and returns it
+func StampMessage(msg *schema.Message, t time.Time) *schema.Message {
+	setExtra(msg, extraTime, t.Format(time.RFC3339Nano))
+	return msg
+}
+
+// MessageTime returns when msg was sent or received, or the zero time if it
+// wasn't recorded
+func MessageTime(msg *schema.Message) time.Time {
+	value, _ := msg.Extra[extraTime].(string)
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// MessageModel returns the model that generated an answer, as
+// provider:model, or "" if it wasn't recorded
+func MessageModel(msg *schema.Message) string {
+	model, _ := msg.Extra[extraModel].(string)
+	return model
+}
+
+// MessageToolCalls returns the tool calls made while an answer was
+// generated
+func MessageToolCalls(msg *schema.Message) []ToolCallRecord {
+	value, _ := msg.Extra[extraToolCalls].(string)
+	if value == "" {
+		return nil
+	}
+	var calls []ToolCallRecord
+	if err := json.Unmarshal([]byte(value), &calls); err != nil {
+		return nil
+	}
+	return calls
+}
+
+// SetMessageToolCalls records the tool calls made while an answer was
+// generated, e.g. by a run whose answer was put together elsewhere
+func SetMessageToolCalls(msg *schema.Message, calls []ToolCallRecord) {
+	if len(calls) == 0 {
+		return
+	}
+	data, err := json.Marshal(calls)
+	if err != nil {
+		return
+	}
+	setExtra(msg, extraToolCalls, string(data))
+}
+
+// MessagePinned reports whether msg is pinned, i.e. kept in the context
+// regardless of the message window
+func MessagePinned(msg *schema.Message) bool {
+	value, _ := msg.Extra[extraPinned].(string)
+	return value == "true"
+}
+
+// SetMessagePinned pins or unpins msg. The pin is part of the message, so
+// copies of the message keep it.
+func SetMessagePinned(msg *schema.Message, pinned bool) {
+	if pinned {
+		setExtra(msg, extraPinned, "true")
+		return
+	}
+	if _, ok := msg.Extra[extraPinned]; ok {
+		extra := maps.Clone(msg.Extra)
+		delete(extra, extraPinned)
+		msg.Extra = extra
+	}
+}
+
+// setExtra sets a metadata value of msg. The map is copied, since messages
+// made from the same chunks may share it.
+func setExtra(msg *schema.Message, key, value string) {
+	extra := maps.Clone(msg.Extra)
+	if extra == nil {
+		extra = make(map[string]any)
+	}
+	extra[key] = value
+	msg.Extra = extra
+}
+
+// toolCallRecorder collects the tool calls of a run, with their results
+type toolCallRecorder struct {
+	mu       sync.Mutex
+	calls    []ToolCallRecord
+	answered []bool
+}
+
+// wrap returns tool handlers that record tool calls and then call the given
+// handlers. A result belongs to the first call of the same tool with the
+// same arguments that has none yet.
+func (r *toolCallRecorder) wrap(onToolCall ToolCallHandler, onToolResult ToolResultHandler) (ToolCallHandler, ToolResultHandler) {
+	return func(toolName, toolArgs string) {
+			r.mu.Lock()
+			r.calls = append(r.calls, ToolCallRecord{Name: toolName, Args: toolArgs, Time: time.Now()})
+			r.answered = append(r.answered, false)
+			r.mu.Unlock()
+			if onToolCall != nil {
+				onToolCall(toolName, toolArgs)
+			}
+		}, func(toolName, toolArgs, result string, isError bool) {
+			r.mu.Lock()
+			for i, call := range r.calls {
+				if !r.answered[i] && call.Name == toolName && call.Args == toolArgs {
+					r.calls[i].Result = result
+					r.calls[i].IsError = isError
+					r.answered[i] = true
+					break
+				}
+			}
+			r.mu.Unlock()
+			if onToolResult != nil {
+				onToolResult(toolName, toolArgs, result, isError)
+			}
+		}
+}
+
+// stamp records the time, model and tool calls of the run's answer
+func (r *toolCallRecorder) stamp(response *schema.Message, model string) {
+	StampMessage(response, time.Now())
+	if model != "" {
+		setExtra(response, extraModel, model)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	SetMessageToolCalls(response, r.calls)
+}
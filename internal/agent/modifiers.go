@@ -0,0 +1,253 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+var (
+	modifiersMu sync.RWMutex
+	modifiers   = map[string]MessageModifier{
+		"datetime":        datetimeModifier,
+		"strip-base64":    stripBase64Modifier,
+		"project-context": projectContextModifier,
+		"tool-images":     toolImagesModifier,
+	}
+)
+
+// RegisterMessageModifier makes a message modifier available by name, e.g.
+// for the message-modifiers setting, replacing any modifier of the same name
+func RegisterMessageModifier(name string, modifier MessageModifier) error {
+	if name == "" {
+		return fmt.Errorf("message modifier has no name")
+	}
+	if modifier == nil {
+		return fmt.Errorf("message modifier %s is nil", name)
+	}
+
+	modifiersMu.Lock()
+	defer modifiersMu.Unlock()
+	modifiers[name] = modifier
+	return nil
+}
+
+// MessageModifiers returns the names of the registered message modifiers,
+// sorted
+func MessageModifiers() []string {
+	modifiersMu.RLock()
+	defer modifiersMu.RUnlock()
+
+	names := make([]string, 0, len(modifiers))
+	for name := range modifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ChainMessageModifiers returns a modifier that applies the named registered
+// modifiers, then extra, in order. It returns nil if there is nothing to
+// apply.
+func ChainMessageModifiers(names []string, extra ...MessageModifier) (MessageModifier, error) {
+	var chain []MessageModifier
+	modifiersMu.RLock()
+	for _, name := range names {
+		modifier, ok := modifiers[name]
+		if !ok {
+			modifiersMu.RUnlock()
+			return nil, fmt.Errorf("unknown message modifier: %s (available: %s)", name, strings.Join(MessageModifiers(), ", "))
+		}
+		chain = append(chain, modifier)
+	}
+	modifiersMu.RUnlock()
+
+	for _, modifier := range extra {
+		if modifier != nil {
+			chain = append(chain, modifier)
+		}
+	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+
+	return func(ctx context.Context, input []*schema.Message) []*schema.Message {
+		for _, modifier := range chain {
+			input = modifier(ctx, input)
+		}
+		return input
+	}, nil
+}
+
+// withSystemNote adds note to the system message at the start of input, or
+// adds a system message if there is none. Messages are copied, not changed.
+func withSystemNote(input []*schema.Message, note string) []*schema.Message {
+	if len(input) > 0 && input[0].Role == schema.System {
+		system := *input[0]
+		system.Content = strings.TrimRight(system.Content, "\n") + "\n\n" + note
+		return append([]*schema.Message{&system}, input[1:]...)
+	}
+	return append([]*schema.Message{schema.SystemMessage(note)}, input...)
+}
+
+// datetimeModifier tells the model the current date and time, which the
+// system prompt only knows as of the start of the session
+func datetimeModifier(_ context.Context, input []*schema.Message) []*schema.Message {
+	now := time.Now()
+	zone, _ := now.Zone()
+	return withSystemNote(input, fmt.Sprintf("Current date and time: %s (%s, UTC%s)",
+		now.Format("Monday, 2 January 2006 15:04"), zone, now.Format("-07:00")))
+}
+
+// base64Blob matches data URIs and long runs of base64, as found in tool
+// results with images or files
+var base64Blob = regexp.MustCompile(`data:[\w/+.-]+;base64,[A-Za-z0-9+/=]+|[A-Za-z0-9+/]{256,}={0,2}`)
+
+// stripBase64Modifier replaces base64 data in messages with a placeholder,
+// since the model can't read it and it uses many tokens
+func stripBase64Modifier(_ context.Context, input []*schema.Message) []*schema.Message {
+	output := make([]*schema.Message, len(input))
+	for i, msg := range input {
+		output[i] = msg
+		if !base64Blob.MatchString(msg.Content) {
+			continue
+		}
+		stripped := *msg
+		stripped.Content = base64Blob.ReplaceAllStringFunc(msg.Content, func(blob string) string {
+			return fmt.Sprintf("[base64 data, %d bytes omitted]", len(blob))
+		})
+		output[i] = &stripped
+	}
+	return output
+}
+
+// toolImagesModifier shows the model the images in MCP tool results, which
+// providers only accept in user messages: the images of the tool results
+// since the last user message follow them in a user message, and are
+// replaced with a placeholder in the results. Images of earlier turns are
+// left out to save tokens.
+func toolImagesModifier(_ context.Context, input []*schema.Message) []*schema.Message {
+	lastUser := -1
+	for i, msg := range input {
+		if msg.Role == schema.User {
+			lastUser = i
+		}
+	}
+
+	output := make([]*schema.Message, 0, len(input))
+	var images []schema.ChatMessagePart
+	for i, msg := range input {
+		if msg.Role == schema.Tool {
+			content, found := extractToolImages(msg.Content, len(images), i > lastUser)
+			if content != msg.Content {
+				stripped := *msg
+				stripped.Content = content
+				msg = &stripped
+			}
+			images = append(images, found...)
+		}
+		output = append(output, msg)
+
+		// Images follow the last of consecutive tool results
+		if len(images) > 0 && (i == len(input)-1 || input[i+1].Role != schema.Tool) {
+			parts := append([]schema.ChatMessagePart{{
+				Type: schema.ChatMessagePartTypeText,
+				Text: "Images returned by the tool calls above:",
+			}}, images...)
+			output = append(output, &schema.Message{Role: schema.User, MultiContent: parts})
+			images = nil
+		}
+	}
+	return output
+}
+
+// extractToolImages replaces the images in a serialized MCP tool result with
+// placeholders, numbered after the n images already taken, and returns the
+// result and, if keep is set, the images as message parts
+func extractToolImages(content string, n int, keep bool) (string, []schema.ChatMessagePart) {
+	var result map[string]any
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return content, nil
+	}
+	items, ok := result["content"].([]any)
+	if !ok {
+		return content, nil
+	}
+
+	var images []schema.ChatMessagePart
+	replaced := false
+	for i, item := range items {
+		part, ok := item.(map[string]any)
+		if !ok || part["type"] != "image" {
+			continue
+		}
+		data, _ := part["data"].(string)
+		mimeType, _ := part["mimeType"].(string)
+		if data == "" || mimeType == "" {
+			continue
+		}
+
+		placeholder := "[image from an earlier turn, no longer shown]"
+		if keep {
+			images = append(images, schema.ChatMessagePart{
+				Type:     schema.ChatMessagePartTypeImageURL,
+				ImageURL: &schema.ChatMessageImageURL{URL: "data:" + mimeType + ";base64," + data},
+			})
+			placeholder = fmt.Sprintf("[image %d, shown below]", n+len(images))
+		}
+		items[i] = map[string]any{"type": "text", "text": placeholder}
+		replaced = true
+	}
+	if !replaced {
+		return content, nil
+	}
+
+	stripped, err := json.Marshal(result)
+	if err != nil {
+		return content, nil
+	}
+	return string(stripped), images
+}
+
+// projectContextModifier tells the model the working directory and, in a git
+// repository, the current branch and uncommitted changes, which tools may
+// have changed since the session started
+func projectContextModifier(ctx context.Context, input []*schema.Message) []*schema.Message {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return input
+	}
+	lines := []string{"Project context:", fmt.Sprintf("- Working directory: %s", cwd)}
+
+	if branch, err := exec.CommandContext(ctx, "git", "rev-parse", "--abbrev-ref", "HEAD").Output(); err == nil {
+		lines = append(lines, fmt.Sprintf("- Git branch: %s", strings.TrimSpace(string(branch))))
+
+		if status, err := exec.CommandContext(ctx, "git", "status", "--short").Output(); err == nil {
+			changes := strings.Split(strings.TrimRight(string(status), "\n"), "\n")
+			switch {
+			case len(status) == 0:
+				lines = append(lines, "- No uncommitted changes")
+			case len(changes) > 20:
+				lines = append(lines, fmt.Sprintf("- Uncommitted changes (%d files, first 20):", len(changes)))
+				changes = changes[:20]
+			default:
+				lines = append(lines, "- Uncommitted changes:")
+			}
+			if len(status) > 0 {
+				for _, change := range changes {
+					lines = append(lines, "  "+change)
+				}
+			}
+		}
+	}
+	return withSystemNote(input, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"slices"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ProgressHandler receives the conversation of a run each time it grows:
+// before each model call, when the model asks for tools and after each tool
+// result. The conversation starts with the system prompt and may end with
+// tool calls that have no result yet. Saving it lets a run that was killed
+// continue with GenerateWithLoop, which makes the missing tool calls first.
+type ProgressHandler func(messages []*schema.Message)
+
+// WithProgressHandler returns a copy of the agent whose runs report their
+// progress to handler, e.g. to save them as they go. The copy shares the
+// tools and permissions of the original.
+func (a *Agent) WithProgressHandler(handler ProgressHandler) *Agent {
+	copied := a.copy()
+	copied.onProgress = handler
+	return copied
+}
+
+// reportProgress records the conversation so far and passes it to the
+// progress handler
+func (run *runState) reportProgress(messages []*schema.Message) {
+	run.progress = slices.Clone(messages)
+	if run.agent.onProgress != nil {
+		run.agent.onProgress(slices.Clone(run.progress))
+	}
+}
+
+// reportToolResult adds the result of the tool call ctx belongs to to the
+// conversation so far
+func (run *runState) reportToolResult(ctx context.Context, result string) {
+	run.progress = append(run.progress, schema.ToolMessage(result, compose.GetToolCallID(ctx)))
+	if run.agent.onProgress != nil {
+		run.agent.onProgress(slices.Clone(run.progress))
+	}
+}
+
+// pendingToolCalls returns the tool calls of the last assistant message of
+// messages that have no result after it
+func pendingToolCalls(messages []*schema.Message) []schema.ToolCall {
+	answered := make(map[string]bool)
+	i := len(messages) - 1
+	for ; i >= 0 && messages[i].Role == schema.Tool; i-- {
+		answered[messages[i].ToolCallID] = true
+	}
+	if i < 0 || messages[i].Role != schema.Assistant {
+		return nil
+	}
+
+	var pending []schema.ToolCall
+	for _, call := range messages[i].ToolCalls {
+		if !answered[call.ID] {
+			pending = append(pending, call)
+		}
+	}
+	return pending
+}
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/mark3labs/mcphost/internal/models"
+)
+
+// providerCache holds the models the agent has created, by model string, so
+// that a model asked for again is reused. It is a pointer in Agent so that
+// copies share it.
+type providerCache struct {
+	// config is the provider configuration of the agent's own model, which
+	// other models are created with too
+	config *models.ProviderConfig
+
+	mu     sync.Mutex
+	models map[string]model.ToolCallingChatModel
+}
+
+// newProviderCache returns a cache holding the agent's own model, created
+// with config
+func newProviderCache(config *models.ProviderConfig, chatModel model.ToolCallingChatModel) *providerCache {
+	return &providerCache{
+		config: config,
+		models: map[string]model.ToolCallingChatModel{config.ModelString: chatModel},
+	}
+}
+
+// get returns the model for modelString, creating it the first time
+func (c *providerCache) get(ctx context.Context, modelString string) (model.ToolCallingChatModel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if chatModel, ok := c.models[modelString]; ok {
+		return chatModel, nil
+	}
+
+	config := *c.config
+	config.ModelString = modelString
+	chatModel, err := models.CreateProvider(ctx, &config)
+	if err != nil {
+		return nil, err
+	}
+	c.models[modelString] = chatModel
+	return chatModel, nil
+}
+
+// WithModel returns a copy of the agent that answers with the model given
+// as provider:model, e.g. for a single turn. The model is created with the
+// agent's provider configuration the first time it is asked for. The copy
+// shares the tools and permissions of the original.
+func (a *Agent) WithModel(ctx context.Context, modelString string) (*Agent, error) {
+	chatModel, err := a.providers.get(ctx, modelString)
+	if err != nil {
+		return nil, err
+	}
+	copied := a.copy()
+	copied.model = chatModel
+	copied.modelName = modelString
+	return copied, nil
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+// addedTools are the tools of MCP servers added while the agent runs. They
+// aren't in the graph's Tools node, which can't change once compiled, so
+// calls to them go through its handler for unknown tools. It is a pointer
+// in Agent so that copies share it.
+type addedTools struct {
+	mu    sync.RWMutex
+	tools map[string]tool.InvokableTool
+	list  []*schema.ToolInfo
+}
+
+// lookup returns the added tool of the given name, or nil
+func (t *addedTools) lookup(name string) tool.InvokableTool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tools[name]
+}
+
+// infos returns the infos of the added tools
+func (t *addedTools) infos() []*schema.ToolInfo {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return slices.Clip(t.list)
+}
+
+// add adds tools whose infos have been read
+func (t *addedTools) add(tools []tool.InvokableTool, infos []*schema.ToolInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tools == nil {
+		t.tools = make(map[string]tool.InvokableTool)
+	}
+	for i, info := range infos {
+		t.tools[info.Name] = tools[i]
+	}
+	t.list = append(slices.Clip(t.list), infos...)
+}
+
+// AddServer starts an MCP server that was added to the config while the
+// agent runs and offers its tools to the model from the next model call
+// on. It returns the names of the tools. The agent must have started with
+// tools, since otherwise its graph has no Tools node to run them.
+func (a *Agent) AddServer(ctx context.Context, mcpConfig *config.Config, serverName string) ([]string, error) {
+	if len(a.toolInfos) == 0 {
+		return nil, fmt.Errorf("the agent started without tools; restart mcphost to use server %s", serverName)
+	}
+
+	serverTools, err := a.toolManager.AddServer(ctx, mcpConfig, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	invokables := make([]tool.InvokableTool, 0, len(serverTools))
+	infos := make([]*schema.ToolInfo, 0, len(serverTools))
+	names := make([]string, 0, len(serverTools))
+	for _, t := range serverTools {
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			continue
+		}
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tool info from server %s: %v", serverName, err)
+		}
+		invokables = append(invokables, invokable)
+		infos = append(infos, info)
+		names = append(names, info.Name)
+	}
+	a.addedTools.add(invokables, infos)
+	return names, nil
+}
+
+// SetTemperature changes the temperature of the model calls of later runs.
+// Copies made before keep theirs.
+func (a *Agent) SetTemperature(temperature float32) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.temperature = &temperature
+}
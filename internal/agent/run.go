@@ -0,0 +1,312 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcphost/internal/jsonrepair"
+)
+
+// runState is what a run of the compiled graph needs besides its input: the
+// agent it runs for, which may be a copy with another temperature or
+// approval handler, the agent's settings as they were when the run started,
+// and the handlers reporting progress. The graph is compiled once and may be
+// running for several conversations, so each run carries its state in its
+// context.
+type runState struct {
+	agent             *Agent
+	systemPrompt      string
+	approveTool       ToolApprovalHandler
+	beforeToolRun     []ToolCallHandler
+	onToolCall        ToolCallHandler
+	onToolExecution   ToolExecutionHandler
+	onToolResult      ToolResultHandler
+	onToolCallContent ToolCallContentHandler
+	onChunk           StreamingResponseHandler
+	trace             *traceRecorder
+
+	// messages is the conversation as last sent to the model
+	messages []*schema.Message
+	// progress is the conversation so far, as last reported to the
+	// agent's progress handler
+	progress []*schema.Message
+	// adjustments are the messages typed while paused in step-by-step
+	// mode, given to the model at its next call
+	adjustments []string
+	// modelErr is the error of the failed model call, if any
+	modelErr error
+	// response is the last model response, as the chunks read so far when
+	// streaming
+	response []*schema.Message
+
+	// step is the context of the model or tool call being made, which ends
+	// after the agent's step timeout
+	step       context.Context
+	stepCancel context.CancelFunc
+
+	// panicked is the panic recovered in the run, if any
+	panicked *PanicError
+}
+
+// PanicError is a panic recovered during a run, in a tool, the model or one
+// of the run's handlers. The run fails with it, so that the caller can save
+// its work before exiting, rather than the process crashing.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// recoverPanic is deferred by the run's graph nodes and handlers. It turns a
+// panic into a *PanicError in *err and records it on the run.
+func (run *runState) recoverPanic(err *error) {
+	if value := recover(); value != nil {
+		if run.panicked == nil {
+			run.panicked = &PanicError{Value: value, Stack: debug.Stack()}
+		}
+		*err = run.panicked
+	}
+}
+
+// StepTimeoutError is returned, or given to the model as a tool's result,
+// when a model call or tool call takes longer than the step timeout
+type StepTimeoutError struct {
+	// Tool is the tool that was called, or empty for a model call
+	Tool    string
+	Timeout time.Duration
+}
+
+func (e *StepTimeoutError) Error() string {
+	if e.Tool == "" {
+		return fmt.Sprintf("model call timed out after %s", e.Timeout)
+	}
+	return fmt.Sprintf("tool %s timed out after %s", e.Tool, e.Timeout)
+}
+
+// startStep ends the run's previous step and returns the context of a model
+// call, or of a call of the named tool
+func (run *runState) startStep(ctx context.Context, toolName string) context.Context {
+	run.endStep()
+	run.step = ctx
+	if timeout := run.agent.stepTimeout; timeout > 0 {
+		run.step, run.stepCancel = context.WithTimeoutCause(ctx, timeout, &StepTimeoutError{Tool: toolName, Timeout: timeout})
+	}
+	return run.step
+}
+
+// endStep releases the context of the run's last step
+func (run *runState) endStep() {
+	if run.stepCancel != nil {
+		run.stepCancel()
+		run.stepCancel = nil
+	}
+}
+
+// stepTimeout returns the error of the run's current step if it timed out
+func (run *runState) stepTimeout() *StepTimeoutError {
+	if run.step == nil {
+		return nil
+	}
+	var timeout *StepTimeoutError
+	if errors.As(context.Cause(run.step), &timeout) {
+		return timeout
+	}
+	return nil
+}
+
+// stepError returns err, or the step's timeout if that is what caused it
+func (run *runState) stepError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if timeout := run.stepTimeout(); timeout != nil {
+		return timeout
+	}
+	return err
+}
+
+type runStateKey struct{}
+
+// withRunState returns a context carrying the state of a run
+func withRunState(ctx context.Context, run *runState) context.Context {
+	return context.WithValue(ctx, runStateKey{}, run)
+}
+
+// newRunState returns the state of a new run of the agent, without
+// progress handlers
+func (a *Agent) newRunState() *runState {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return &runState{
+		agent:         a,
+		systemPrompt:  a.systemPrompt,
+		approveTool:   a.approveTool,
+		beforeToolRun: a.beforeToolRun,
+		trace:         newTraceRecorder(),
+	}
+}
+
+// runState returns the state of the run ctx belongs to. Runs of an exported
+// graph have none and get a state without handlers.
+func (a *Agent) runState(ctx context.Context) *runState {
+	if run, ok := ctx.Value(runStateKey{}).(*runState); ok {
+		return run
+	}
+	return a.newRunState()
+}
+
+// graphModel is the graph's ChatModel node. It calls the model with the
+// tools and options of the run's agent and records each call.
+type graphModel struct {
+	agent *Agent
+}
+
+// Generate implements model.BaseChatModel
+func (m *graphModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (_ *schema.Message, err error) {
+	run := m.agent.runState(ctx)
+	defer run.recoverPanic(&err)
+	start := time.Now()
+	response, err := run.agent.model.Generate(run.startStep(ctx, ""), input, append(run.agent.modelOptions(), opts...)...)
+	err = run.stepError(err)
+	run.trace.modelCall(start, response, err)
+	if err != nil {
+		run.modelErr = err
+	}
+	run.response = []*schema.Message{response}
+	return response, err
+}
+
+// Stream implements model.BaseChatModel, passing content to the run's
+// chunk handler as the graph reads it. The step timeout covers reading the
+// whole stream.
+func (m *graphModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (_ *schema.StreamReader[*schema.Message], err error) {
+	run := m.agent.runState(ctx)
+	defer run.recoverPanic(&err)
+	start := time.Now()
+	stream, err := run.agent.model.Stream(run.startStep(ctx, ""), input, append(run.agent.modelOptions(), opts...)...)
+	err = run.stepError(err)
+	node := run.trace.modelCall(start, nil, err)
+	if err != nil {
+		run.modelErr = err
+		return nil, err
+	}
+
+	run.response = nil
+	return schema.StreamReaderWithConvert(stream, func(chunk *schema.Message) (_ *schema.Message, err error) {
+		defer run.recoverPanic(&err)
+		if chunk == nil {
+			return nil, schema.ErrNoValue
+		}
+		run.trace.modelChunk(node, chunk)
+		run.response = append(run.response, chunk)
+		if chunk.Content != "" && run.onChunk != nil {
+			run.onChunk(chunk.Content)
+		}
+		return chunk, nil
+	}), nil
+}
+
+// graphTool runs a tool in the graph's Tools node through callTool, so that
+// calls are approved and reported and errors become results
+type graphTool struct {
+	tool.InvokableTool
+	agent *Agent
+	name  string
+}
+
+// InvokableRun implements tool.InvokableTool
+func (t *graphTool) InvokableRun(ctx context.Context, args string, _ ...tool.Option) (_ string, err error) {
+	run := t.agent.runState(ctx)
+	defer run.recoverPanic(&err)
+	result := run.agent.callTool(ctx, run, t.name, args, t.InvokableTool)
+	run.reportToolResult(ctx, result)
+	run.pauseStep("tool " + t.name)
+	return result, nil
+}
+
+// callTool asks for approval if needed and runs a tool call, reporting it to
+// the run's handlers, and returns the result or error message. A nil tool
+// means the model asked for a tool that doesn't exist.
+func (a *Agent) callTool(ctx context.Context, run *runState, name, args string, selected tool.InvokableTool) string {
+	// Notify about tool call
+	if run.onToolCall != nil {
+		run.onToolCall(name, args)
+	}
+
+	// Arguments that couldn't be repaired, such as ones cut off at the token
+	// limit, go back to the model instead of running the tool with them
+	if a.repairToolArgs {
+		if _, err := jsonrepair.Repair(args); err != nil {
+			errorMsg := fmt.Sprintf("Tool call not run: %v. Call %s again with complete arguments.", err, name)
+			if run.onToolResult != nil {
+				run.onToolResult(name, args, errorMsg, true)
+			}
+			return errorMsg
+		}
+	}
+
+	// Read-only mode blocks tools that may change something, even approved ones
+	if pattern, blocked := a.permissions.Blocked(name); blocked {
+		errorMsg := fmt.Sprintf("Tool call blocked by read-only mode: %s matches %q. Only tools that don't change anything can be used.", name, pattern)
+		if run.onToolResult != nil {
+			run.onToolResult(name, args, errorMsg, true)
+		}
+		return errorMsg
+	}
+
+	// Ask for approval if the permission policy requires it
+	if !a.isToolApproved(run, name, args) {
+		errorMsg := fmt.Sprintf("Tool call denied by user: %s", name)
+		if run.onToolResult != nil {
+			run.onToolResult(name, args, errorMsg, true)
+		}
+		return errorMsg
+	}
+
+	if selected == nil {
+		errorMsg := fmt.Sprintf("Tool not found: %s", name)
+		if run.onToolResult != nil {
+			run.onToolResult(name, args, errorMsg, true)
+		}
+		return errorMsg
+	}
+
+	for _, handler := range run.beforeToolRun {
+		handler(name, args)
+	}
+
+	// Notify tool execution start
+	if run.onToolExecution != nil {
+		run.onToolExecution(name, true)
+	}
+
+	output, err := selected.InvokableRun(run.startStep(ctx, name), args)
+	err = run.stepError(err)
+
+	// Notify tool execution end
+	if run.onToolExecution != nil {
+		run.onToolExecution(name, false)
+	}
+
+	if err != nil {
+		errorMsg := fmt.Sprintf("Tool execution error: %v", err)
+		if run.onToolResult != nil {
+			run.onToolResult(name, args, errorMsg, true)
+		}
+		return errorMsg
+	}
+
+	if run.onToolResult != nil {
+		run.onToolResult(name, args, output, false)
+	}
+	return output
+}
@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"github.com/cloudwego/eino/schema"
+)
+
+// StepHandler is called in step-by-step mode after each model response that
+// asks for tools and after each tool result, and the run waits until it
+// returns. done describes the step, e.g. "tool fs__read_file". It returns
+// text to give the model as a user message before its next call, or "" to
+// go on unchanged.
+type StepHandler func(done string) string
+
+// WithStepHandler returns a copy of the agent whose runs pause after each
+// step until handler returns. The copy shares the tools and permissions of
+// the original.
+func (a *Agent) WithStepHandler(handler StepHandler) *Agent {
+	copied := a.copy()
+	copied.onStep = handler
+	return copied
+}
+
+// pauseStep waits for the step handler after a step, keeping the text it
+// returns for the next model call
+func (run *runState) pauseStep(done string) {
+	if run.agent.onStep == nil {
+		return
+	}
+	if text := run.agent.onStep(done); text != "" {
+		run.adjustments = append(run.adjustments, text)
+	}
+}
+
+// takeAdjustments returns the user messages given while the run was paused
+// since the last model call. They follow the tool results, since a model
+// response asking for tools must be followed by their results.
+func (run *runState) takeAdjustments() []*schema.Message {
+	var messages []*schema.Message
+	for _, text := range run.adjustments {
+		messages = append(messages, schema.UserMessage(text))
+	}
+	run.adjustments = nil
+	return messages
+}
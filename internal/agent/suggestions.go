@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// suggestionPrompt asks the model for follow-up prompts in a format that is
+// easy to parse
+const suggestionPrompt = `Suggest %d short follow-up prompts the user might send next in this conversation. ` +
+	`Write them from the user's point of view, one per line, without numbering, quotes or any other text.`
+
+// SuggestFollowUps asks the utility model for up to n short prompts the user
+// might send next. Tools are not offered, so no tool runs as a side effect.
+func (a *Agent) SuggestFollowUps(ctx context.Context, messages []*schema.Message, n int) ([]string, error) {
+	input := make([]*schema.Message, 0, len(messages)+2)
+	if prompt := a.SystemPrompt(); prompt != "" {
+		input = append(input, schema.SystemMessage(prompt))
+	}
+	input = append(input, messages...)
+	input = append(input, schema.UserMessage(fmt.Sprintf(suggestionPrompt, n)))
+
+	if a.stepTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.stepTimeout)
+		defer cancel()
+	}
+	response, err := a.utilityModel.Generate(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate suggestions: %v", err)
+	}
+
+	return parseSuggestions(response.Content, n), nil
+}
+
+// listMarkerPattern matches a leading bullet or "1." style list marker
+var listMarkerPattern = regexp.MustCompile(`^(?:[-*•]|\d+[.)])\s+`)
+
+// parseSuggestions extracts up to n prompts from the model's reply, dropping
+// any list markers or quotes the model added anyway
+func parseSuggestions(content string, n int) []string {
+	var suggestions []string
+	for _, line := range strings.Split(content, "\n") {
+		line = listMarkerPattern.ReplaceAllString(strings.TrimSpace(line), "")
+		line = strings.Trim(line, `"'`)
+		if line == "" {
+			continue
+		}
+		suggestions = append(suggestions, line)
+		if len(suggestions) == n {
+			break
+		}
+	}
+	return suggestions
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSuggestions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		n       int
+		want    []string
+	}{
+		{
+			name:    "one per line",
+			content: "Explain the error\nShow the tests",
+			n:       3,
+			want:    []string{"Explain the error", "Show the tests"},
+		},
+		{
+			name:    "list markers",
+			content: "1. First\n2) Second\n- Third\n* Fourth\n• Fifth",
+			n:       5,
+			want:    []string{"First", "Second", "Third", "Fourth", "Fifth"},
+		},
+		{
+			name:    "quotes and blank lines",
+			content: "\n  \"Run it again\"  \n\n'Try another way'\n",
+			n:       3,
+			want:    []string{"Run it again", "Try another way"},
+		},
+		{
+			name:    "at most n",
+			content: "a\nb\nc\nd",
+			n:       2,
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "number without marker kept",
+			content: "2024 results",
+			n:       1,
+			want:    []string{"2024 results"},
+		},
+		{
+			name:    "empty",
+			content: "",
+			n:       3,
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseSuggestions(tt.content, tt.n)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseSuggestions(%q, %d) = %q, want %q", tt.content, tt.n, got, tt.want)
+			}
+		})
+	}
+}
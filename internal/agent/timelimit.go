@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// TimeLimitError is the cause of the context of a run that took longer than
+// the agent's MaxDuration
+type TimeLimitError struct {
+	Limit time.Duration
+}
+
+func (e *TimeLimitError) Error() string {
+	return fmt.Sprintf("run took longer than its time limit of %s", e.Limit)
+}
+
+// timeLimitPrompt asks the model for an answer once the time limit is
+// reached, with the limit as its argument
+const timeLimitPrompt = "The time limit of %s for this task has been reached, so no more tools can be used. " +
+	"Give your best final answer from the results so far, and say briefly what is left undone."
+
+// withTimeLimit returns the context of a run, which ends after the agent's
+// MaxDuration, if any
+func (a *Agent) withTimeLimit(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.maxDuration <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, a.maxDuration, &TimeLimitError{Limit: a.maxDuration})
+}
+
+// timeLimitReached reports whether runCtx, a context returned by
+// withTimeLimit, ended because of the time limit
+func timeLimitReached(runCtx context.Context) bool {
+	var limit *TimeLimitError
+	return errors.As(context.Cause(runCtx), &limit)
+}
+
+// finishAfterTimeLimit asks the model for a final answer from the
+// conversation as it was when the time limit was reached. input is the
+// run's input, used if the model was never called. Tool calls that have no
+// result yet are answered as not run, and the answer's own tool calls are
+// dropped.
+func (a *Agent) finishAfterTimeLimit(ctx context.Context, run *runState, input []*schema.Message) (*schema.Message, error) {
+	messages := slices.Clone(run.progress)
+	if len(messages) == 0 {
+		messages = slices.Clone(input)
+		if run.systemPrompt != "" && (len(messages) == 0 || messages[0].Role != schema.System) {
+			messages = append([]*schema.Message{schema.SystemMessage(run.systemPrompt)}, messages...)
+		}
+	}
+	for _, call := range pendingToolCalls(messages) {
+		messages = append(messages, schema.ToolMessage("Not run: the time limit was reached", call.ID))
+	}
+	messages = append(messages, schema.UserMessage(fmt.Sprintf(timeLimitPrompt, a.maxDuration)))
+
+	start := time.Now()
+	response, err := a.model.Generate(run.startStep(ctx, ""), messages, a.modelOptions()...)
+	err = run.stepError(err)
+	run.trace.modelCall(start, response, err)
+	if err != nil {
+		return nil, fmt.Errorf("%v, and the final answer failed: %v", &TimeLimitError{Limit: a.maxDuration}, err)
+	}
+	if response.Content == "" {
+		return nil, &TimeLimitError{Limit: a.maxDuration}
+	}
+
+	if run.onChunk != nil {
+		run.onChunk(response.Content)
+	}
+	answer := schema.AssistantMessage(response.Content, nil)
+	answer.ResponseMeta = response.ResponseMeta
+	setExtra(answer, extraTimeLimit, a.maxDuration.String())
+	return answer, nil
+}
+
+// MessageTimeLimit returns the time limit an answer was given after, as a
+// best effort from the work done until then, or 0 if the run finished in time
+func MessageTimeLimit(msg *schema.Message) time.Duration {
+	value, _ := msg.Extra[extraTimeLimit].(string)
+	limit, _ := time.ParseDuration(value)
+	return limit
+}
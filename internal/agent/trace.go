@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// TraceNode is one step of an agent run: a call of the graph's ChatModel
+// node, with the tool calls it asked for as children, or a call of a tool
+// in the Tools node
+type TraceNode struct {
+	// Node is ModelNodeName or ToolsNodeName
+	Node     string
+	Tool     string
+	Args     string
+	Start    time.Time
+	Duration time.Duration
+	// Usage is the model's token usage, nil if the provider didn't report it
+	Usage    *schema.TokenUsage
+	IsError  bool
+	Error    string
+	Children []*TraceNode
+}
+
+// Trace is the execution of one GenerateWithLoop call
+type Trace struct {
+	Start    time.Time
+	Duration time.Duration
+	Steps    []*TraceNode
+}
+
+// Usage returns the token usage summed over all model calls
+func (t *Trace) Usage() schema.TokenUsage {
+	var usage schema.TokenUsage
+	for _, node := range t.Steps {
+		if node.Usage != nil {
+			usage.PromptTokens += node.Usage.PromptTokens
+			usage.CompletionTokens += node.Usage.CompletionTokens
+			usage.TotalTokens += node.Usage.TotalTokens
+		}
+	}
+	return usage
+}
+
+// traceStore keeps the trace of the last run. It is a pointer in Agent so
+// that copies share it.
+type traceStore struct {
+	mu   sync.Mutex
+	last *Trace
+}
+
+// LastTrace returns the trace of the last completed run, or nil
+func (a *Agent) LastTrace() *Trace {
+	if a.traces == nil {
+		return nil
+	}
+	a.traces.mu.Lock()
+	defer a.traces.mu.Unlock()
+	return a.traces.last
+}
+
+// traceRecorder builds the trace of a run as it happens
+type traceRecorder struct {
+	trace *Trace
+	// model is the model call whose tool calls are running, nil before the
+	// first model call
+	model *TraceNode
+	tool  *TraceNode
+}
+
+func newTraceRecorder() *traceRecorder {
+	return &traceRecorder{trace: &Trace{Start: time.Now()}}
+}
+
+// modelCall records a model call that started at start and returns its
+// node, which a streamed call keeps updating as chunks arrive
+func (r *traceRecorder) modelCall(start time.Time, response *schema.Message, err error) *TraceNode {
+	node := &TraceNode{Node: ModelNodeName, Start: start, Duration: time.Since(start)}
+	if err != nil {
+		node.IsError = true
+		node.Error = err.Error()
+	}
+	if response != nil && response.ResponseMeta != nil {
+		node.Usage = response.ResponseMeta.Usage
+	}
+	r.trace.Steps = append(r.trace.Steps, node)
+	r.model = node
+	return node
+}
+
+// modelChunk updates the node of a streamed model call with a chunk
+func (r *traceRecorder) modelChunk(node *TraceNode, chunk *schema.Message) {
+	node.Duration = time.Since(node.Start)
+	if chunk.ResponseMeta != nil && chunk.ResponseMeta.Usage != nil {
+		node.Usage = chunk.ResponseMeta.Usage
+	}
+}
+
+// wrapToolHandlers returns tool handlers that record tool calls and then
+// call the given handlers
+func (r *traceRecorder) wrapToolHandlers(onToolCall ToolCallHandler, onToolResult ToolResultHandler) (ToolCallHandler, ToolResultHandler) {
+	return func(toolName, toolArgs string) {
+			r.tool = &TraceNode{Node: ToolsNodeName, Tool: toolName, Args: toolArgs, Start: time.Now()}
+			if r.model != nil {
+				r.model.Children = append(r.model.Children, r.tool)
+			} else {
+				r.trace.Steps = append(r.trace.Steps, r.tool)
+			}
+			if onToolCall != nil {
+				onToolCall(toolName, toolArgs)
+			}
+		}, func(toolName, toolArgs, result string, isError bool) {
+			if r.tool != nil {
+				r.tool.Duration = time.Since(r.tool.Start)
+				r.tool.IsError = isError
+				if isError {
+					r.tool.Error = result
+				}
+				r.tool = nil
+			}
+			if onToolResult != nil {
+				onToolResult(toolName, toolArgs, result, isError)
+			}
+		}
+}
+
+// finish stores the trace as the agent's last
+func (r *traceRecorder) finish(store *traceStore) {
+	r.trace.Duration = time.Since(r.trace.Start)
+	if store == nil {
+		return
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.last = r.trace
+}
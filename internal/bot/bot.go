@@ -0,0 +1,212 @@
+// Package bot runs mcphost as a chat bot, with a conversation per channel
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/citations"
+)
+
+// Message is a message received from a chat platform
+type Message struct {
+	ChannelID string
+	UserID    string
+	UserName  string
+	Text      string
+}
+
+// Platform connects the bot to a chat service
+type Platform interface {
+	// Name returns the platform's name for logs
+	Name() string
+	// Run receives messages until ctx is cancelled, calling handle for each
+	Run(ctx context.Context, handle func(Message)) error
+	// Send posts a reply to a channel
+	Send(ctx context.Context, channelID, text string) error
+	// Typing shows that the bot is working on a reply, where supported
+	Typing(ctx context.Context, channelID string)
+}
+
+// Config configures a Bot
+type Config struct {
+	Agent *agent.Agent
+	// MessageWindow is the number of messages kept per channel
+	MessageWindow int
+	// SessionTimeout is how long an idle channel keeps its conversation
+	SessionTimeout time.Duration
+	// RateLimit is the number of messages a user may send per minute, 0 for
+	// no limit
+	RateLimit int
+	// Admins are the user IDs allowed to use tools that need approval;
+	// everyone else is limited to auto-approved tools
+	Admins []string
+}
+
+// Bot answers chat messages with the agent
+type Bot struct {
+	config   Config
+	sessions *SessionManager
+	limiter  *RateLimiter
+
+	mu sync.Mutex
+	// last is closed when the last message queued for a channel has been
+	// handled
+	last map[string]chan struct{}
+}
+
+// resetCommands clear the conversation of the channel they're sent in
+var resetCommands = []string{"/reset", "!reset"}
+
+// New creates a bot
+func New(config Config) *Bot {
+	return &Bot{
+		config:   config,
+		sessions: NewSessionManager(config.MessageWindow, config.SessionTimeout),
+		limiter:  NewRateLimiter(config.RateLimit, time.Minute),
+		last:     make(map[string]chan struct{}),
+	}
+}
+
+// Run connects to the platform and answers messages until ctx is cancelled
+func (b *Bot) Run(ctx context.Context, platform Platform) error {
+	log.Printf("%s: bot started", platform.Name())
+	return platform.Run(ctx, func(msg Message) {
+		b.queue(ctx, msg.ChannelID, func() { b.handle(ctx, platform, msg) })
+	})
+}
+
+// queue runs fn in a new goroutine once the functions queued before for the
+// same channel have returned, so that a channel's messages are answered one
+// at a time and in the order they arrived. Channels don't wait for each
+// other.
+func (b *Bot) queue(ctx context.Context, channelID string, fn func()) {
+	b.mu.Lock()
+	prev := b.last[channelID]
+	done := make(chan struct{})
+	b.last[channelID] = done
+	b.mu.Unlock()
+
+	go func() {
+		defer func() {
+			close(done)
+			b.mu.Lock()
+			if b.last[channelID] == done {
+				delete(b.last, channelID)
+			}
+			b.mu.Unlock()
+		}()
+		if prev != nil {
+			select {
+			case <-prev:
+			case <-ctx.Done():
+				return
+			}
+		}
+		fn()
+	}()
+}
+
+// handle answers a single message
+func (b *Bot) handle(ctx context.Context, platform Platform, msg Message) {
+	text := strings.TrimSpace(msg.Text)
+	if text == "" {
+		return
+	}
+
+	if !b.limiter.Allow(msg.UserID) {
+		b.send(ctx, platform, msg.ChannelID, "You're sending messages too quickly, please try again in a minute.")
+		return
+	}
+
+	if slices.Contains(resetCommands, strings.ToLower(text)) {
+		b.sessions.Reset(msg.ChannelID)
+		b.send(ctx, platform, msg.ChannelID, "Conversation reset.")
+		return
+	}
+
+	session := b.sessions.Get(msg.ChannelID)
+	session.Lock()
+	defer session.Unlock()
+
+	platform.Typing(ctx, msg.ChannelID)
+
+	// Several people may talk in one channel, so say who is speaking
+	prompt := text
+	if msg.UserName != "" {
+		prompt = fmt.Sprintf("%s: %s", msg.UserName, text)
+	}
+	messages := append(session.Messages(), agent.NewUserMessage(prompt))
+
+	isAdmin := slices.Contains(b.config.Admins, msg.UserID)
+	turnAgent := b.config.Agent.WithToolApprovalHandler(func(toolName, toolArgs string) bool {
+		if !isAdmin {
+			log.Printf("%s: denied %s to user %s", platform.Name(), toolName, msg.UserID)
+		}
+		return isAdmin
+	})
+
+	var sources citations.Tracker
+	response, err := turnAgent.GenerateWithLoop(ctx, messages, nil, nil,
+		func(toolName, toolArgs, result string, isError bool) {
+			if !isError {
+				sources.Add(toolArgs, result)
+			}
+		}, nil, nil)
+	if err != nil {
+		log.Printf("%s: channel %s: %v", platform.Name(), msg.ChannelID, err)
+		b.send(ctx, platform, msg.ChannelID, "Sorry, something went wrong while answering.")
+		return
+	}
+
+	session.SetMessages(append(messages, response))
+
+	reply := strings.TrimSpace(response.Content)
+	if reply == "" {
+		reply = "(no response)"
+	}
+	reply = citations.WithFooter(reply, sources.Sources())
+	b.send(ctx, platform, msg.ChannelID, reply)
+}
+
+// send posts a reply, logging failures
+func (b *Bot) send(ctx context.Context, platform Platform, channelID, text string) {
+	if err := platform.Send(ctx, channelID, text); err != nil {
+		log.Printf("%s: failed to reply in channel %s: %v", platform.Name(), channelID, err)
+	}
+}
+
+// splitMessage splits text into chunks of at most limit runes, preferring to
+// break at newlines
+func splitMessage(text string, limit int) []string {
+	var chunks []string
+	runes := []rune(text)
+	for len(runes) > limit {
+		cut := limit
+		if i := lastIndex(runes[:limit], '\n'); i > limit/2 {
+			cut = i + 1
+		}
+		chunks = append(chunks, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	if len(runes) > 0 {
+		chunks = append(chunks, string(runes))
+	}
+	return chunks
+}
+
+// lastIndex returns the index of the last r in runes, or -1
+func lastIndex(runes []rune, r rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
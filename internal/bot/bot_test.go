@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQueueOrder(t *testing.T) {
+	b := New(Config{})
+	ctx := context.Background()
+
+	var (
+		mu       sync.Mutex
+		answered []int
+		wg       sync.WaitGroup
+	)
+	for i := range 20 {
+		wg.Add(1)
+		b.queue(ctx, "channel", func() {
+			defer wg.Done()
+			// Earlier messages take longer, so they'd be overtaken if
+			// they weren't waited for
+			time.Sleep(time.Duration(20-i) * time.Millisecond)
+			mu.Lock()
+			answered = append(answered, i)
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	want := make([]int, 20)
+	for i := range want {
+		want[i] = i
+	}
+	if !slices.Equal(answered, want) {
+		t.Errorf("messages answered in order %v, want %v", answered, want)
+	}
+}
+
+func TestQueueChannels(t *testing.T) {
+	b := New(Config{})
+	ctx := context.Background()
+
+	// A slow reply in one channel doesn't hold up another
+	release := make(chan struct{})
+	b.queue(ctx, "slow", func() { <-release })
+	defer close(release)
+
+	done := make(chan struct{})
+	b.queue(ctx, "fast", func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("message in another channel waited for the slow one")
+	}
+}
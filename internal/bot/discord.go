@@ -0,0 +1,293 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	discordAPI     = "https://discord.com/api/v10"
+	discordGateway = "wss://gateway.discord.gg/?v=10&encoding=json"
+
+	// GUILD_MESSAGES, DIRECT_MESSAGES and MESSAGE_CONTENT
+	discordIntents = 1<<9 | 1<<12 | 1<<15
+
+	// discordMessageLimit is the maximum length of a Discord message
+	discordMessageLimit = 2000
+)
+
+// Discord gateway opcodes
+const (
+	discordOpDispatch       = 0
+	discordOpHeartbeat      = 1
+	discordOpIdentify       = 2
+	discordOpReconnect      = 7
+	discordOpInvalidSession = 9
+	discordOpHello          = 10
+)
+
+// reconnectDelay is how long to wait before reconnecting after an error
+const reconnectDelay = 5 * time.Second
+
+// discordPayload is a gateway message
+type discordPayload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d,omitempty"`
+	S  *int64          `json:"s,omitempty"`
+	T  string          `json:"t,omitempty"`
+}
+
+// discordMessage is the MESSAGE_CREATE event
+type discordMessage struct {
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id"`
+	Content   string `json:"content"`
+	Author    struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+		Bot      bool   `json:"bot"`
+	} `json:"author"`
+	Mentions []struct {
+		ID string `json:"id"`
+	} `json:"mentions"`
+}
+
+// Discord connects to Discord through the gateway. The bot answers direct
+// messages and messages in server channels that mention it.
+type Discord struct {
+	token  string
+	client *http.Client
+
+	mu    sync.Mutex
+	botID string
+}
+
+// NewDiscord creates a Discord platform for a bot token
+func NewDiscord(token string) *Discord {
+	return &Discord{token: token, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Name returns the platform's name
+func (d *Discord) Name() string {
+	return "discord"
+}
+
+// Run receives messages until ctx is cancelled, reconnecting on errors
+func (d *Discord) Run(ctx context.Context, handle func(Message)) error {
+	for {
+		err := d.connect(ctx, handle)
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("discord: connection lost: %v, reconnecting", err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// connect runs a single gateway session
+func (d *Discord) connect(ctx context.Context, handle func(Message)) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, discordGateway, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	var hello discordPayload
+	if err := conn.ReadJSON(&hello); err != nil {
+		return err
+	}
+	if hello.Op != discordOpHello {
+		return fmt.Errorf("expected hello, got opcode %d", hello.Op)
+	}
+	var helloData struct {
+		HeartbeatInterval int `json:"heartbeat_interval"`
+	}
+	if err := json.Unmarshal(hello.D, &helloData); err != nil {
+		return err
+	}
+
+	// Writes come from the read loop and the heartbeat goroutine
+	var writeMu sync.Mutex
+	var seqMu sync.Mutex
+	var seq *int64
+	send := func(op int, data any) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(map[string]any{"op": op, "d": data})
+	}
+	heartbeat := func() error {
+		seqMu.Lock()
+		last := seq
+		seqMu.Unlock()
+		return send(discordOpHeartbeat, last)
+	}
+
+	err = send(discordOpIdentify, map[string]any{
+		"token":   d.token,
+		"intents": discordIntents,
+		"properties": map[string]string{
+			"os":      runtime.GOOS,
+			"browser": "mcphost",
+			"device":  "mcphost",
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(helloData.HeartbeatInterval) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if heartbeat() != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		var payload discordPayload
+		if err := conn.ReadJSON(&payload); err != nil {
+			return err
+		}
+		if payload.S != nil {
+			seqMu.Lock()
+			seq = payload.S
+			seqMu.Unlock()
+		}
+
+		switch payload.Op {
+		case discordOpHeartbeat:
+			if err := heartbeat(); err != nil {
+				return err
+			}
+		case discordOpReconnect:
+			return fmt.Errorf("reconnect requested")
+		case discordOpInvalidSession:
+			return fmt.Errorf("invalid session")
+		case discordOpDispatch:
+			d.dispatch(payload, handle)
+		}
+	}
+}
+
+// dispatch handles a gateway event
+func (d *Discord) dispatch(payload discordPayload, handle func(Message)) {
+	switch payload.T {
+	case "READY":
+		var ready struct {
+			User struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		}
+		if err := json.Unmarshal(payload.D, &ready); err == nil {
+			d.mu.Lock()
+			d.botID = ready.User.ID
+			d.mu.Unlock()
+		}
+	case "MESSAGE_CREATE":
+		var msg discordMessage
+		if err := json.Unmarshal(payload.D, &msg); err != nil || msg.Author.Bot {
+			return
+		}
+
+		d.mu.Lock()
+		botID := d.botID
+		d.mu.Unlock()
+
+		// In servers, only answer messages addressed to the bot
+		if msg.GuildID != "" {
+			mentioned := false
+			for _, mention := range msg.Mentions {
+				mentioned = mentioned || mention.ID == botID
+			}
+			if !mentioned {
+				return
+			}
+		}
+
+		mention := regexp.MustCompile(`<@!?` + regexp.QuoteMeta(botID) + `>`)
+		handle(Message{
+			ChannelID: msg.ChannelID,
+			UserID:    msg.Author.ID,
+			UserName:  msg.Author.Username,
+			Text:      mention.ReplaceAllString(msg.Content, ""),
+		})
+	}
+}
+
+// Send posts a reply, split into several messages if it is too long
+func (d *Discord) Send(ctx context.Context, channelID, text string) error {
+	for _, chunk := range splitMessage(text, discordMessageLimit) {
+		if err := d.post(ctx, "/channels/"+channelID+"/messages", map[string]string{"content": chunk}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Typing shows the typing indicator in a channel
+func (d *Discord) Typing(ctx context.Context, channelID string) {
+	d.post(ctx, "/channels/"+channelID+"/typing", nil)
+}
+
+// post calls the Discord REST API
+func (d *Discord) post(ctx context.Context, path string, body any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discordAPI+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+d.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(respBody))
+	}
+	return nil
+}
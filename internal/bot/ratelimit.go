@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter allows each user a number of messages per period
+type RateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	period time.Duration
+	recent map[string][]time.Time
+}
+
+// NewRateLimiter creates a rate limiter. A limit of 0 allows everything.
+func NewRateLimiter(limit int, period time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:  limit,
+		period: period,
+		recent: make(map[string][]time.Time),
+	}
+}
+
+// Allow records a message from the user and reports whether it is within
+// the limit
+func (r *RateLimiter) Allow(userID string) bool {
+	if r.limit <= 0 {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var kept []time.Time
+	for _, t := range r.recent[userID] {
+		if now.Sub(t) < r.period {
+			kept = append(kept, t)
+		}
+	}
+
+	if len(kept) >= r.limit {
+		r.recent[userID] = kept
+		return false
+	}
+	r.recent[userID] = append(kept, now)
+	return true
+}
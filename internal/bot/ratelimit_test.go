@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  int
+		period time.Duration
+		// wait is the time between messages
+		wait  time.Duration
+		users []string
+		want  []bool
+	}{
+		{
+			name:   "no limit",
+			limit:  0,
+			period: time.Minute,
+			users:  []string{"a", "a", "a"},
+			want:   []bool{true, true, true},
+		},
+		{
+			name:   "over the limit",
+			limit:  2,
+			period: time.Minute,
+			users:  []string{"a", "a", "a", "a"},
+			want:   []bool{true, true, false, false},
+		},
+		{
+			name:   "per user",
+			limit:  1,
+			period: time.Minute,
+			users:  []string{"a", "b", "a", "b"},
+			want:   []bool{true, true, false, false},
+		},
+		{
+			name:   "old messages expire",
+			limit:  1,
+			period: 10 * time.Millisecond,
+			wait:   20 * time.Millisecond,
+			users:  []string{"a", "a", "a"},
+			want:   []bool{true, true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimiter(tt.limit, tt.period)
+			for i, user := range tt.users {
+				if i > 0 {
+					time.Sleep(tt.wait)
+				}
+				if got := limiter.Allow(user); got != tt.want[i] {
+					t.Errorf("message %d from %s: Allow() = %v, want %v", i, user, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// Session is the conversation of a single channel. Lock it while a reply is
+// generated, so that the history isn't changed meanwhile; the order in which
+// messages are answered is up to the caller.
+type Session struct {
+	sync.Mutex
+
+	window   int
+	messages []*schema.Message
+	lastUsed time.Time
+}
+
+// Messages returns a copy of the session's history
+func (s *Session) Messages() []*schema.Message {
+	return append([]*schema.Message(nil), s.messages...)
+}
+
+// SetMessages replaces the history, keeping the last window messages
+func (s *Session) SetMessages(messages []*schema.Message) {
+	if s.window > 0 && len(messages) > s.window {
+		messages = messages[len(messages)-s.window:]
+	}
+	s.messages = messages
+}
+
+// SessionManager keeps a session per channel and forgets idle ones
+type SessionManager struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+	window   int
+	timeout  time.Duration
+}
+
+// NewSessionManager creates a session manager whose sessions keep window
+// messages and expire after timeout without use (0 to keep them forever)
+func NewSessionManager(window int, timeout time.Duration) *SessionManager {
+	return &SessionManager{
+		sessions: make(map[string]*Session),
+		window:   window,
+		timeout:  timeout,
+	}
+}
+
+// Get returns the session of a channel, starting a new one if needed
+func (m *SessionManager) Get(channelID string) *Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.expire(now)
+
+	session, ok := m.sessions[channelID]
+	if !ok {
+		session = &Session{window: m.window}
+		m.sessions[channelID] = session
+	}
+	session.lastUsed = now
+	return session
+}
+
+// Reset forgets the conversation of a channel
+func (m *SessionManager) Reset(channelID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, channelID)
+}
+
+// expire removes sessions that have been idle longer than the timeout
+func (m *SessionManager) expire(now time.Time) {
+	if m.timeout <= 0 {
+		return
+	}
+	for channelID, session := range m.sessions {
+		if now.Sub(session.lastUsed) > m.timeout {
+			delete(m.sessions, channelID)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	telegramAPI = "https://api.telegram.org/bot"
+
+	// telegramPollTimeout is how long a getUpdates long poll waits, in seconds
+	telegramPollTimeout = 30
+
+	// telegramMessageLimit is the maximum length of a Telegram message
+	telegramMessageLimit = 4096
+)
+
+// telegramResponse is the envelope of every Bot API response
+type telegramResponse struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description"`
+	Result      json.RawMessage `json:"result"`
+}
+
+// telegramUpdate is an update received with getUpdates
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Text string `json:"text"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		From *struct {
+			ID       int64  `json:"id"`
+			Username string `json:"username"`
+			Name     string `json:"first_name"`
+			IsBot    bool   `json:"is_bot"`
+		} `json:"from"`
+	} `json:"message"`
+}
+
+// Telegram connects to Telegram through the Bot API using long polling
+type Telegram struct {
+	token    string
+	client   *http.Client
+	username string
+}
+
+// NewTelegram creates a Telegram platform for a bot token
+func NewTelegram(token string) *Telegram {
+	return &Telegram{
+		token:  token,
+		client: &http.Client{Timeout: (telegramPollTimeout + 10) * time.Second},
+	}
+}
+
+// Name returns the platform's name
+func (t *Telegram) Name() string {
+	return "telegram"
+}
+
+// Run receives messages until ctx is cancelled
+func (t *Telegram) Run(ctx context.Context, handle func(Message)) error {
+	var me struct {
+		Username string `json:"username"`
+	}
+	if err := t.call(ctx, "getMe", nil, &me); err != nil {
+		return fmt.Errorf("failed to connect to Telegram: %v", err)
+	}
+	t.username = me.Username
+
+	var offset int64
+	for {
+		var updates []telegramUpdate
+		err := t.call(ctx, "getUpdates", map[string]any{
+			"offset":          offset,
+			"timeout":         telegramPollTimeout,
+			"allowed_updates": []string{"message"},
+		}, &updates)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			log.Printf("telegram: failed to get updates: %v", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(reconnectDelay):
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			msg := update.Message
+			if msg == nil || msg.From == nil || msg.From.IsBot || msg.Text == "" {
+				continue
+			}
+
+			userName := msg.From.Username
+			if userName == "" {
+				userName = msg.From.Name
+			}
+			handle(Message{
+				ChannelID: strconv.FormatInt(msg.Chat.ID, 10),
+				UserID:    strconv.FormatInt(msg.From.ID, 10),
+				UserName:  userName,
+				Text:      t.stripMention(msg.Text),
+			})
+		}
+	}
+}
+
+// stripMention removes the bot's @username, used to address it in groups
+func (t *Telegram) stripMention(text string) string {
+	if t.username == "" {
+		return text
+	}
+	return strings.ReplaceAll(text, "@"+t.username, "")
+}
+
+// Send posts a reply, split into several messages if it is too long
+func (t *Telegram) Send(ctx context.Context, channelID, text string) error {
+	for _, chunk := range splitMessage(text, telegramMessageLimit) {
+		err := t.call(ctx, "sendMessage", map[string]any{"chat_id": channelID, "text": chunk}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Typing shows the typing indicator in a chat
+func (t *Telegram) Typing(ctx context.Context, channelID string) {
+	t.call(ctx, "sendChatAction", map[string]any{"chat_id": channelID, "action": "typing"}, nil)
+}
+
+// call calls a Bot API method and decodes its result into result, if not nil
+func (t *Telegram) call(ctx context.Context, method string, params map[string]any, result any) error {
+	if params == nil {
+		params = map[string]any{}
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, telegramAPI+t.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// Don't leak the token, which is part of the URL, into logs
+		return fmt.Errorf("%s request failed: %v", method, strings.ReplaceAll(err.Error(), t.token, "***"))
+	}
+	defer resp.Body.Close()
+
+	var envelope telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("%s: invalid response: %v", method, err)
+	}
+	if !envelope.OK {
+		return fmt.Errorf("%s: %s", method, envelope.Description)
+	}
+	if result != nil {
+		return json.Unmarshal(envelope.Result, result)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Package builtin provides tools that run inside mcphost as in-process MCP
+// servers. They are enabled like any other MCP server, with the builtin key
+// of an mcpServers entry naming them.
+package builtin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// builtinServer is a server mcphost can run in-process
+type builtinServer struct {
+	// sensitive servers' tools need confirmation before every call, even
+	// without --confirm-tools, unless autoApprove lists them
+	sensitive bool
+	// new creates the server from the options of its mcpServers entry
+	new func(options map[string]any) (*server.MCPServer, error)
+}
+
+var servers = map[string]builtinServer{
+	"screenshot": {sensitive: true, new: newScreenshotServer},
+	"fetch":      {new: newFetchServer},
+	"web_search": {new: newSearchServer},
+}
+
+// NewServer creates the named builtin server from the options of its
+// mcpServers entry
+func NewServer(name string, options map[string]any) (*server.MCPServer, error) {
+	s, ok := servers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown builtin server %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return s.new(options)
+}
+
+// Sensitive reports whether the named builtin server's tools need
+// confirmation before every call
+func Sensitive(name string) bool {
+	return servers[name].sensitive
+}
+
+// Names returns the names of the builtin servers, sorted
+func Names() []string {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// decodeOptions decodes the options of an mcpServers entry into target,
+// rejecting options the server doesn't have
+func decodeOptions(options map[string]any, target any) error {
+	if len(options) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return fmt.Errorf("invalid options: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		return fmt.Errorf("invalid options: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	defaultFetchMaxBytes  = 5 << 20
+	defaultFetchMaxLength = 20000
+	defaultFetchTimeout   = 30
+	defaultUserAgent      = "mcphost (+https://github.com/mark3labs/mcphost)"
+)
+
+// fetchOptions are the options of a fetch server
+type fetchOptions struct {
+	// Allow limits requests to these domains and their subdomains
+	Allow []string `json:"allow"`
+	// Deny refuses requests to these domains and their subdomains
+	Deny []string `json:"deny"`
+	// AllowPost offers POST requests, which can change things, besides GET
+	AllowPost bool `json:"allowPost"`
+	// AllowPrivate allows requests to loopback and private network
+	// addresses, such as services on the user's machine
+	AllowPrivate bool `json:"allowPrivate"`
+	// MaxBytes is the most of a response that is read
+	MaxBytes int64 `json:"maxBytes"`
+	// MaxLength is the most characters returned by one call
+	MaxLength int `json:"maxLength"`
+	// Timeout is the time limit of a request in seconds
+	Timeout   int    `json:"timeout"`
+	UserAgent string `json:"userAgent"`
+}
+
+// fetchTool fetches URLs
+type fetchTool struct {
+	options fetchOptions
+	client  *http.Client
+}
+
+func newFetchServer(options map[string]any) (*server.MCPServer, error) {
+	t := &fetchTool{}
+	if err := decodeOptions(options, &t.options); err != nil {
+		return nil, err
+	}
+	if t.options.MaxBytes == 0 {
+		t.options.MaxBytes = defaultFetchMaxBytes
+	}
+	if t.options.MaxLength == 0 {
+		t.options.MaxLength = defaultFetchMaxLength
+	}
+	if t.options.Timeout == 0 {
+		t.options.Timeout = defaultFetchTimeout
+	}
+	if t.options.UserAgent == "" {
+		t.options.UserAgent = defaultUserAgent
+	}
+	t.options.Allow = normalizeDomains(t.options.Allow)
+	t.options.Deny = normalizeDomains(t.options.Deny)
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !t.options.AllowPrivate {
+		dialer.Control = refusePrivate
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	if !t.options.AllowPrivate {
+		// Through a proxy, the addresses dialed aren't the ones fetched
+		transport.Proxy = nil
+	}
+	t.client = &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(t.options.Timeout) * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return t.permitted(req.URL)
+		},
+	}
+
+	desc := "Fetch a URL from the internet and return its content. Web pages are returned as Markdown with only their main content; other text, such as JSON, as is. Long content is cut off; fetch again with offset to read on."
+	if len(t.options.Allow) > 0 {
+		desc += fmt.Sprintf(" Only these domains can be fetched: %s.", strings.Join(t.options.Allow, ", "))
+	}
+	toolOptions := []mcp.ToolOption{
+		mcp.WithDescription(desc),
+		mcp.WithString("url", mcp.Required(), mcp.Description("The http or https URL to fetch")),
+		mcp.WithBoolean("raw", mcp.Description("Return web pages as HTML instead of extracting their content as Markdown")),
+		mcp.WithNumber("offset", mcp.Description("Character to start at, to read on where earlier content was cut off")),
+	}
+	if t.options.AllowPost {
+		toolOptions = append(toolOptions,
+			mcp.WithString("method", mcp.Enum("GET", "POST"), mcp.Description("HTTP method (default GET)")),
+			mcp.WithString("body", mcp.Description("Request body of a POST request")),
+			mcp.WithString("contentType", mcp.Description("Content type of the body (default application/json)")),
+		)
+	} else {
+		toolOptions = append(toolOptions, mcp.WithReadOnlyHintAnnotation(true))
+	}
+
+	s := server.NewMCPServer("fetch", "1.0.0", server.WithToolCapabilities(false))
+	s.AddTool(mcp.NewTool("fetch", toolOptions...), t.handle)
+	return s, nil
+}
+
+func (t *fetchTool) handle(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	rawURL, err := request.RequireString("url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid URL %q: only http and https URLs can be fetched", rawURL)), nil
+	}
+	if err := t.permitted(target); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	method := strings.ToUpper(request.GetString("method", "GET"))
+	var body io.Reader
+	switch {
+	case method == "GET":
+	case method == "POST" && t.options.AllowPost:
+		body = strings.NewReader(request.GetString("body", ""))
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("method %s is not allowed", method)), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid request: %v", err)), nil
+	}
+	req.Header.Set("User-Agent", t.options.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/json,text/*;q=0.9,*/*;q=0.5")
+	if body != nil {
+		req.Header.Set("Content-Type", request.GetString("contentType", "application/json"))
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to fetch %s: %v", target, err)), nil
+	}
+	defer resp.Body.Close()
+
+	content, truncated, err := t.read(resp)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read %s: %v", target, err)), nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	header := fmt.Sprintf("Contents of %s", resp.Request.URL)
+	if (mediaType == "text/html" || mediaType == "application/xhtml+xml") && !request.GetBool("raw", false) {
+		page := extractPage(content, resp.Request.URL)
+		if page.title != "" {
+			header = fmt.Sprintf("Contents of %s (%s)", resp.Request.URL, page.title)
+		}
+		content = page.markdown
+	}
+	if resp.StatusCode >= 400 {
+		return mcp.NewToolResultError(fmt.Sprintf("fetching %s failed: HTTP %d\n\n%s", target, resp.StatusCode, cut(content, 2000))), nil
+	}
+	if truncated {
+		content += fmt.Sprintf("\n\n[The response was larger than %d bytes; the rest was not read]", t.options.MaxBytes)
+	}
+
+	return mcp.NewToolResultText(header + ":\n\n" + t.page(content, request.GetInt("offset", 0))), nil
+}
+
+// read reads a text response, up to maxBytes, as UTF-8
+func (t *fetchTool) read(resp *http.Response) (string, bool, error) {
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && !isText(mediaType) {
+		return "", false, fmt.Errorf("unsupported content type %s; only text content can be fetched", mediaType)
+	}
+
+	reader, err := charset.NewReader(resp.Body, contentType)
+	if err != nil {
+		reader = resp.Body
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, t.options.MaxBytes+1))
+	if err != nil {
+		return "", false, err
+	}
+	truncated := int64(len(data)) > t.options.MaxBytes
+	if truncated {
+		data = data[:t.options.MaxBytes]
+	}
+	return string(data), truncated, nil
+}
+
+// page returns the maxLength characters of content starting at offset,
+// noting where to continue if there is more
+func (t *fetchTool) page(content string, offset int) string {
+	runes := []rune(content)
+	if offset < 0 || offset > len(runes) {
+		return fmt.Sprintf("[offset %d is past the end of the content, which has %d characters]", offset, len(runes))
+	}
+	end := min(offset+t.options.MaxLength, len(runes))
+	text := string(runes[offset:end])
+	if end < len(runes) {
+		text += fmt.Sprintf("\n\n[Content cut off at character %d of %d; fetch again with offset %d to read on]", end, len(runes), end)
+	}
+	return text
+}
+
+// permitted checks a URL's host against the allowed and denied domains
+func (t *fetchTool) permitted(u *url.URL) error {
+	// A trailing dot makes a name fully qualified without changing the host
+	host := strings.TrimRight(strings.ToLower(u.Hostname()), ".")
+	for _, domain := range t.options.Deny {
+		if inDomain(host, domain) {
+			return fmt.Errorf("fetching from %s is not allowed", host)
+		}
+	}
+	if len(t.options.Allow) == 0 {
+		return nil
+	}
+	for _, domain := range t.options.Allow {
+		if inDomain(host, domain) {
+			return nil
+		}
+	}
+	return fmt.Errorf("fetching from %s is not allowed; only these domains can be fetched: %s", host, strings.Join(t.options.Allow, ", "))
+}
+
+// inDomain reports whether host is domain or one of its subdomains
+func inDomain(host, domain string) bool {
+	if host == domain {
+		return true
+	}
+	// An IP address has no subdomains, so it only matches itself
+	return net.ParseIP(host) == nil && strings.HasSuffix(host, "."+domain)
+}
+
+// normalizeDomains lowercases domains and removes wildcards, since domains
+// always include their subdomains
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "*.")
+		if domain != "" {
+			normalized = append(normalized, strings.TrimSuffix(domain, "."))
+		}
+	}
+	return normalized
+}
+
+// refusePrivate refuses connections to loopback, private and link-local
+// addresses, after DNS resolution so names pointing at them are refused too
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return errors.New("connecting to local and private network addresses is not allowed (set allowPrivate in the fetch options to allow it)")
+	}
+	return nil
+}
+
+// isText reports whether a media type is text the model can read
+func isText(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "json") || strings.HasSuffix(mediaType, "xml") ||
+		mediaType == "application/javascript" || strings.HasSuffix(mediaType, "yaml")
+}
+
+// cut shortens text to at most n characters
+func cut(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "..."
+}
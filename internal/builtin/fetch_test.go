@@ -0,0 +1,112 @@
+package builtin
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestFetchPermitted(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		deny    []string
+		url     string
+		wantErr bool
+	}{
+		{
+			name: "no lists",
+			url:  "https://example.com/page",
+		},
+		{
+			name:  "allowed domain",
+			allow: []string{"example.com"},
+			url:   "https://example.com/page",
+		},
+		{
+			name:  "allowed subdomain",
+			allow: []string{"example.com"},
+			url:   "https://docs.example.com/page",
+		},
+		{
+			name:    "not allowed",
+			allow:   []string{"example.com"},
+			url:     "https://example.org/page",
+			wantErr: true,
+		},
+		{
+			name:    "suffix that isn't a subdomain",
+			allow:   []string{"example.com"},
+			url:     "https://badexample.com/page",
+			wantErr: true,
+		},
+		{
+			name:    "denied subdomain",
+			deny:    []string{"ads.example.com"},
+			url:     "https://x.ads.example.com/",
+			wantErr: true,
+		},
+		{
+			name:    "deny wins over allow",
+			allow:   []string{"example.com"},
+			deny:    []string{"private.example.com"},
+			url:     "https://private.example.com/",
+			wantErr: true,
+		},
+		{
+			name:    "denied with trailing dot",
+			deny:    []string{"example.com"},
+			url:     "https://example.com./page",
+			wantErr: true,
+		},
+		{
+			name:  "allowed with trailing dot",
+			allow: []string{"example.com"},
+			url:   "https://docs.example.com./page",
+		},
+		{
+			name:    "address not allowed",
+			allow:   []string{"example.com"},
+			url:     "http://93.184.215.14/page",
+			wantErr: true,
+		},
+		{
+			name:    "address doesn't match as a subdomain",
+			allow:   []string{"215.14"},
+			url:     "http://93.184.215.14/page",
+			wantErr: true,
+		},
+		{
+			name:  "listed address",
+			allow: []string{"93.184.215.14", "2001:db8::1"},
+			url:   "http://[2001:db8::1]:8080/page",
+		},
+		{
+			name:    "denied address",
+			deny:    []string{"93.184.215.14"},
+			url:     "http://93.184.215.14/",
+			wantErr: true,
+		},
+		{
+			name:  "case and port ignored",
+			allow: []string{"example.com"},
+			url:   "https://Docs.EXAMPLE.com:8443/page",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := &fetchTool{options: fetchOptions{
+				Allow: normalizeDomains(tt.allow),
+				Deny:  normalizeDomains(tt.deny),
+			}}
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = tool.permitted(u)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("permitted(%s) error = %v, want error %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,471 @@
+package builtin
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// webPage is the main content of a web page
+type webPage struct {
+	title    string
+	markdown string
+}
+
+// skippedElements are never part of a page's content
+var skippedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Nav: true, atom.Aside: true, atom.Footer: true, atom.Form: true,
+	atom.Button: true, atom.Input: true, atom.Select: true, atom.Textarea: true,
+	atom.Iframe: true, atom.Svg: true, atom.Canvas: true, atom.Object: true,
+	atom.Embed: true, atom.Dialog: true, atom.Head: true,
+}
+
+// blockElements start a new Markdown block
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Blockquote: true, atom.Center: true,
+	atom.Details: true, atom.Dd: true, atom.Div: true, atom.Dl: true, atom.Dt: true,
+	atom.Fieldset: true, atom.Figcaption: true, atom.Figure: true, atom.H1: true,
+	atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Header: true, atom.Hgroup: true, atom.Hr: true, atom.Li: true, atom.Main: true,
+	atom.Ol: true, atom.P: true, atom.Pre: true, atom.Section: true, atom.Summary: true,
+	atom.Table: true, atom.Ul: true, atom.Body: true,
+}
+
+// unlikelyContent matches the class or id of page furniture such as
+// comments, menus and cookie banners, unless likelyContent matches too
+var (
+	unlikelyContent = regexp.MustCompile(`(?i)\b(comments?|sidebar|footer|menu|navbar|nav|breadcrumbs?|cookies?|consent|banner|advert|ads?|sponsored|share|sharing|social|related|recommended|promo|popup|modal|newsletter|subscribe|signup|skip-link|masthead)\b`)
+	likelyContent   = regexp.MustCompile(`(?i)\b(article|body|column|content|main|post|entry)\b`)
+)
+
+var (
+	spaces     = regexp.MustCompile(`[ \t\r\n\f]+`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// extractPage finds the main content of an HTML page, leaving out menus,
+// sidebars, footers and the like, and converts it to Markdown
+func extractPage(document string, base *url.URL) webPage {
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return webPage{markdown: document}
+	}
+	c := &markdownConverter{base: base}
+	return webPage{
+		title:    strings.TrimSpace(spaces.ReplaceAllString(textOf(find(doc, atom.Title)), " ")),
+		markdown: strings.TrimSpace(blankLines.ReplaceAllString(c.blocks(mainContent(doc)), "\n\n")),
+	}
+}
+
+// mainContent returns the element holding a page's main content: its only
+// article, its main element, the element with the most paragraph text, or
+// else the body
+func mainContent(doc *html.Node) *html.Node {
+	var articles, mains []*html.Node
+	scores := map[*html.Node]int{}
+	walk(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		if leftOut(n) {
+			return false
+		}
+		switch {
+		case n.DataAtom == atom.Article:
+			articles = append(articles, n)
+		case n.DataAtom == atom.Main || attr(n, "role") == "main":
+			mains = append(mains, n)
+		case n.DataAtom == atom.P && n.Parent != nil:
+			// Paragraphs count for their parent, and half for its parent
+			if length := len(strings.TrimSpace(textOf(n))); length >= 25 {
+				scores[n.Parent] += length
+				if n.Parent.Parent != nil {
+					scores[n.Parent.Parent] += length / 2
+				}
+			}
+		}
+		return true
+	})
+
+	if len(articles) == 1 {
+		return articles[0]
+	}
+	if len(mains) > 0 {
+		return mains[0]
+	}
+	var best *html.Node
+	for n, score := range scores {
+		if score >= 200 && (best == nil || score > scores[best]) {
+			best = n
+		}
+	}
+	if best != nil {
+		return best
+	}
+	if body := find(doc, atom.Body); body != nil {
+		return body
+	}
+	return doc
+}
+
+// markdownConverter converts HTML to Markdown
+type markdownConverter struct {
+	base *url.URL
+}
+
+// blocks converts the children of n, which may mix text and blocks, to
+// Markdown blocks separated by blank lines
+func (c *markdownConverter) blocks(n *html.Node) string {
+	var out []string
+	var paragraph strings.Builder
+	flush := func() {
+		if text := cleanInline(paragraph.String()); text != "" {
+			out = append(out, text)
+		}
+		paragraph.Reset()
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if leftOut(child) {
+			continue
+		}
+		if child.Type == html.ElementNode && blockElements[child.DataAtom] {
+			flush()
+			if block := c.block(child); block != "" {
+				out = append(out, block)
+			}
+			continue
+		}
+		paragraph.WriteString(c.inline(child))
+	}
+	flush()
+	return strings.Join(out, "\n\n")
+}
+
+// block converts a block element to Markdown
+func (c *markdownConverter) block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := cleanInline(strings.ReplaceAll(c.inlineChildren(n), "\n", " "))
+		if text == "" {
+			return ""
+		}
+		return strings.Repeat("#", int(n.Data[1]-'0')) + " " + text
+	case atom.P, atom.Dt, atom.Summary, atom.Figcaption:
+		return cleanInline(c.inlineChildren(n))
+	case atom.Pre:
+		code := strings.Trim(textOf(n), "\n")
+		if code == "" {
+			return ""
+		}
+		return "```" + codeLanguage(n) + "\n" + code + "\n```"
+	case atom.Hr:
+		return "---"
+	case atom.Blockquote:
+		return prefixLines(c.blocks(n), "> ", "> ")
+	case atom.Ul, atom.Ol:
+		return c.list(n)
+	case atom.Table:
+		return c.table(n)
+	default:
+		return c.blocks(n)
+	}
+}
+
+// list converts a list to Markdown, with nested lists indented
+func (c *markdownConverter) list(n *html.Node) string {
+	var items []string
+	number := 1
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.DataAtom != atom.Li || leftOut(li) {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+		content := strings.ReplaceAll(c.blocks(li), "\n\n", "\n")
+		if content == "" {
+			continue
+		}
+		items = append(items, prefixLines(content, marker, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table converts a table to a Markdown table, with its first row as the
+// header
+func (c *markdownConverter) table(n *html.Node) string {
+	var rows [][]string
+	walk(n, func(node *html.Node) bool {
+		if node != n && node.DataAtom == atom.Table {
+			return false
+		}
+		if node.DataAtom != atom.Tr {
+			return true
+		}
+		var cells []string
+		for cell := node.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+				text := cleanInline(strings.ReplaceAll(c.inlineChildren(cell), "\n", " "))
+				cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+		return false
+	})
+	if len(rows) == 0 {
+		return ""
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	lines := make([]string, 0, len(rows)+1)
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inlineChildren converts the children of n to inline Markdown
+func (c *markdownConverter) inlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if !leftOut(child) {
+			b.WriteString(c.inline(child))
+		}
+	}
+	return b.String()
+}
+
+// inline converts a node to inline Markdown
+func (c *markdownConverter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return spaces.ReplaceAllString(n.Data, " ")
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "\n"
+	case atom.A:
+		text := strings.TrimSpace(c.inlineChildren(n))
+		href := c.resolve(attr(n, "href"))
+		if text == "" || href == "" {
+			return text
+		}
+		return "[" + text + "](" + href + ")"
+	case atom.Img:
+		alt := strings.TrimSpace(attr(n, "alt"))
+		src := c.resolve(attr(n, "src"))
+		if alt == "" || src == "" {
+			return ""
+		}
+		return "![" + alt + "](" + src + ")"
+	case atom.Strong, atom.B:
+		return wrap(c.inlineChildren(n), "**")
+	case atom.Em, atom.I:
+		return wrap(c.inlineChildren(n), "_")
+	case atom.Del, atom.S:
+		return wrap(c.inlineChildren(n), "~~")
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		return wrap(spaces.ReplaceAllString(textOf(n), " "), "`")
+	default:
+		return c.inlineChildren(n)
+	}
+}
+
+// leftOut reports whether a node is left out of a page's content
+func leftOut(n *html.Node) bool {
+	switch n.Type {
+	case html.TextNode:
+		return false
+	case html.ElementNode:
+	default:
+		return true
+	}
+	if skippedElements[n.DataAtom] || hidden(n) {
+		return true
+	}
+	switch n.DataAtom {
+	case atom.Article, atom.Main, atom.Body, atom.Html:
+		return false
+	}
+	names := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyContent.MatchString(names) && !likelyContent.MatchString(names) && !holdsContent(n)
+}
+
+// holdsContent reports whether an element contains what looks like a page's
+// main content, so a wrapper with a misleading class isn't left out
+func holdsContent(n *html.Node) bool {
+	found := false
+	walk(n, func(node *html.Node) bool {
+		if found || node.Type != html.ElementNode {
+			return !found
+		}
+		found = node != n && (node.DataAtom == atom.Article || node.DataAtom == atom.Main ||
+			attr(node, "role") == "main" || likelyContent.MatchString(attr(node, "id")))
+		return !found
+	})
+	return found
+}
+
+// resolve makes a link absolute, returning "" for links that go nowhere a
+// model could follow
+func (c *markdownConverter) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(ref, "javascript:") || strings.HasPrefix(ref, "data:") {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if c.base != nil {
+		u = c.base.ResolveReference(u)
+	}
+	return u.String()
+}
+
+// hidden reports whether an element is hidden from readers
+func hidden(n *html.Node) bool {
+	if _, ok := attrValue(n, "hidden"); ok || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(attr(n, "style")), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+// codeLanguage returns the language of a code block from a language-x or
+// lang-x class on it or its code element
+func codeLanguage(pre *html.Node) string {
+	classes := attr(pre, "class")
+	if code := find(pre, atom.Code); code != nil {
+		classes += " " + attr(code, "class")
+	}
+	for _, class := range strings.Fields(classes) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if lang, ok := strings.CutPrefix(class, prefix); ok {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// wrap surrounds text with a Markdown marker, keeping surrounding spaces
+// outside the marker
+func wrap(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	lead := text[:strings.Index(text, trimmed)]
+	trail := text[len(lead)+len(trimmed):]
+	return lead + marker + trimmed + marker + trail
+}
+
+// cleanInline trims the lines of inline Markdown and collapses spaces
+func cleanInline(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spaces.ReplaceAllString(line, " "))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// prefixLines prefixes the first line of text with first and the others
+// with rest
+func prefixLines(text, first, rest string) string {
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		lines[i] = strings.TrimRight(prefix+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// walk calls fn on n and its descendants in document order, skipping the
+// descendants of nodes for which fn returns false
+func walk(n *html.Node, fn func(*html.Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		walk(child, fn)
+	}
+}
+
+// find returns the first element of a kind in n, or nil
+func find(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walk(n, func(node *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if node.Type == html.ElementNode && node.DataAtom == a {
+			found = node
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// textOf returns the text of a node and its descendants
+func textOf(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	var b strings.Builder
+	walk(n, func(node *html.Node) bool {
+		if node.Type == html.ElementNode && (node.DataAtom == atom.Script || node.DataAtom == atom.Style) {
+			return false
+		}
+		if node.Type == html.TextNode {
+			b.WriteString(node.Data)
+		}
+		return true
+	})
+	return b.String()
+}
+
+// attr returns the value of an attribute, or ""
+func attr(n *html.Node, key string) string {
+	value, _ := attrValue(n, key)
+	return value
+}
+
+func attrValue(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,269 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// defaultMaxWidth is the width screenshots are scaled down to, which keeps
+// text legible while using a fraction of the tokens of a full-size image
+const defaultMaxWidth = 1280
+
+// macWindowBounds prints the position and size of the first window whose
+// title contains $MCPHOST_WINDOW
+const macWindowBounds = `set wanted to system attribute "MCPHOST_WINDOW"
+tell application "System Events"
+	repeat with p in (every process whose visible is true)
+		repeat with w in (every window of p)
+			if name of w contains wanted then
+				set {x, y} to position of w
+				set {width, height} to size of w
+				return (x as text) & "," & (y as text) & "," & (width as text) & "," & (height as text)
+			end if
+		end repeat
+	end repeat
+end tell
+error "no window titled " & wanted`
+
+// windowsCapture saves the virtual screen, or the first window whose title
+// contains $env:MCPHOST_WINDOW, to $env:MCPHOST_FILE
+const windowsCapture = `Add-Type -AssemblyName System.Windows.Forms, System.Drawing
+Add-Type 'using System; using System.Runtime.InteropServices; public struct RECT { public int L, T, R, B; } public class Win { [DllImport("user32.dll")] public static extern bool GetWindowRect(IntPtr h, out RECT r); }'
+if ($env:MCPHOST_WINDOW) {
+	$p = Get-Process | Where-Object { $_.MainWindowTitle.Contains($env:MCPHOST_WINDOW) } | Select-Object -First 1
+	if (-not $p) { throw "no window titled $env:MCPHOST_WINDOW" }
+	$r = New-Object RECT
+	[Win]::GetWindowRect($p.MainWindowHandle, [ref]$r) | Out-Null
+	$b = [Drawing.Rectangle]::FromLTRB($r.L, $r.T, $r.R, $r.B)
+} else {
+	$b = [Windows.Forms.SystemInformation]::VirtualScreen
+}
+$bmp = New-Object Drawing.Bitmap $b.Width, $b.Height
+[Drawing.Graphics]::FromImage($bmp).CopyFromScreen($b.Location, [Drawing.Point]::Empty, $b.Size)
+$bmp.Save($env:MCPHOST_FILE, [Drawing.Imaging.ImageFormat]::Png)`
+
+// screenshotOptions are the options of a screenshot server
+type screenshotOptions struct {
+	// Windows limits captures to windows whose title contains one of these;
+	// the whole screen can then not be captured
+	Windows []string `json:"windows"`
+	// MaxWidth is the width larger screenshots are scaled down to
+	MaxWidth int `json:"maxWidth"`
+	// Command replaces the platform's capture command. {file} stands for
+	// the PNG file to write and {window} for the requested window title.
+	Command []string `json:"command"`
+}
+
+// screenshotTool captures the user's screen
+type screenshotTool struct {
+	options screenshotOptions
+}
+
+func newScreenshotServer(options map[string]any) (*server.MCPServer, error) {
+	t := &screenshotTool{}
+	if err := decodeOptions(options, &t.options); err != nil {
+		return nil, err
+	}
+	if t.options.MaxWidth == 0 {
+		t.options.MaxWidth = defaultMaxWidth
+	}
+
+	windowDesc := "Title, or part of the title, of the window to capture. Leave empty to capture the whole screen."
+	if len(t.options.Windows) > 0 {
+		windowDesc = fmt.Sprintf("Title, or part of the title, of the window to capture. Only windows titled %s can be captured.", strings.Join(quoted(t.options.Windows), " or "))
+	}
+
+	s := server.NewMCPServer("screenshot", "1.0.0", server.WithToolCapabilities(false))
+	s.AddTool(mcp.NewTool("screenshot",
+		mcp.WithDescription("Take a screenshot of the user's screen, or of one window, to see what it shows, e.g. to debug a user interface. The user is asked before each screenshot is taken."),
+		mcp.WithString("window", mcp.Description(windowDesc)),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), t.handle)
+	return s, nil
+}
+
+func (t *screenshotTool) handle(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	window := strings.TrimSpace(request.GetString("window", ""))
+	if err := t.permitted(window); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	tmp, err := os.CreateTemp("", "mcphost-screenshot-*.png")
+	if err != nil {
+		return nil, err
+	}
+	file := tmp.Name()
+	tmp.Close()
+	defer os.Remove(file)
+
+	if err := t.capture(ctx, window, file); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	data, width, height, err := shrinkPNG(file, t.options.MaxWidth)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("screenshot failed: %v", err)), nil
+	}
+
+	what := "the screen"
+	if window != "" {
+		what = fmt.Sprintf("the window titled %q", window)
+	}
+	return mcp.NewToolResultImage(fmt.Sprintf("Screenshot of %s (%dx%d)", what, width, height),
+		base64.StdEncoding.EncodeToString(data), "image/png"), nil
+}
+
+// permitted checks a capture against the windows the options allow
+func (t *screenshotTool) permitted(window string) error {
+	if len(t.options.Windows) == 0 {
+		return nil
+	}
+	if window == "" {
+		return fmt.Errorf("only windows titled %s can be captured, not the whole screen", strings.Join(quoted(t.options.Windows), " or "))
+	}
+	for _, allowed := range t.options.Windows {
+		if strings.Contains(strings.ToLower(window), strings.ToLower(allowed)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("capturing the window %q is not allowed; only windows titled %s can be captured", window, strings.Join(quoted(t.options.Windows), " or "))
+}
+
+// capture writes a PNG screenshot of the screen, or of the window whose
+// title contains window, to file
+func (t *screenshotTool) capture(ctx context.Context, window, file string) error {
+	env := append(os.Environ(), "MCPHOST_WINDOW="+window, "MCPHOST_FILE="+file)
+
+	var args []string
+	switch {
+	case len(t.options.Command) > 0:
+		for _, arg := range t.options.Command {
+			args = append(args, strings.NewReplacer("{file}", file, "{window}", window).Replace(arg))
+		}
+	case runtime.GOOS == "darwin":
+		args = []string{"screencapture", "-x", file}
+		if window != "" {
+			cmd := exec.CommandContext(ctx, "osascript", "-e", macWindowBounds)
+			cmd.Env = env
+			bounds, err := output(cmd, "finding the window")
+			if err != nil {
+				return err
+			}
+			args = []string{"screencapture", "-x", "-R", bounds, file}
+		}
+	case runtime.GOOS == "windows":
+		args = []string{"powershell", "-NoProfile", "-Command", windowsCapture}
+	case os.Getenv("WAYLAND_DISPLAY") != "":
+		if window != "" {
+			return fmt.Errorf("capturing a single window isn't supported on Wayland; set a capture command in the screenshot options")
+		}
+		args = []string{"grim", file}
+	default:
+		target := "root"
+		if window != "" {
+			cmd := exec.CommandContext(ctx, "xdotool", "search", "--onlyvisible", "--name", window)
+			ids, err := output(cmd, "finding the window")
+			if err != nil || ids == "" {
+				return fmt.Errorf("no window titled %q (finding windows needs xdotool)", window)
+			}
+			target = strings.Fields(ids)[0]
+		}
+		args = []string{"import", "-window", target, file}
+	}
+
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return fmt.Errorf("screenshots need %s, or a capture command in the screenshot options: %v", args[0], err)
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = env
+	_, err := output(cmd, "screenshot")
+	return err
+}
+
+// output runs a command and returns its trimmed output, or its stderr
+// output as the error if it fails
+func output(cmd *exec.Cmd, name string) (string, error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s failed: %s", name, msg)
+		}
+		return "", fmt.Errorf("%s failed: %v", name, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// shrinkPNG reads a PNG file, scaling it down to maxWidth if it is wider,
+// and returns it with its final size
+func shrinkPNG(file string, maxWidth int) ([]byte, int, int, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("invalid PNG: %v", err)
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() <= maxWidth {
+		return data, bounds.Dx(), bounds.Dy(), nil
+	}
+
+	scaled := scaleDown(img, maxWidth)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaled); err != nil {
+		return nil, 0, 0, err
+	}
+	return buf.Bytes(), scaled.Bounds().Dx(), scaled.Bounds().Dy(), nil
+}
+
+// scaleDown scales an image down to width, keeping its aspect ratio, by
+// averaging the source pixels each target pixel covers
+func scaleDown(src image.Image, width int) *image.RGBA {
+	sb := src.Bounds()
+	height := max(1, sb.Dy()*width/sb.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := sb.Min.Y + y*sb.Dy()/height
+		y1 := max(y0+1, sb.Min.Y+(y+1)*sb.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := sb.Min.X + x*sb.Dx()/width
+			x1 := max(x0+1, sb.Min.X+(x+1)*sb.Dx()/width)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(b / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// quoted quotes each of a list of strings
+func quoted(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strconv.Quote(v)
+	}
+	return out
+}
@@ -0,0 +1,267 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	defaultSearchCount = 5
+	maxSearchCount     = 10
+)
+
+// searchOptions are the options of a web_search server
+type searchOptions struct {
+	// Engine is brave, searxng or google
+	Engine string `json:"engine"`
+	// APIKey is the Brave Search or Google API key, else taken from
+	// BRAVE_API_KEY or GOOGLE_CSE_API_KEY
+	APIKey string `json:"apiKey"`
+	// URL is the SearXNG instance, else taken from SEARXNG_URL
+	URL string `json:"url"`
+	// CX is the Google Programmable Search Engine ID, else taken from
+	// GOOGLE_CSE_ID
+	CX string `json:"cx"`
+	// Count is the number of results returned unless the model asks for
+	// another number
+	Count int `json:"count"`
+}
+
+// searchResult is a web search result as given to the model
+type searchResult struct {
+	Position  int    `json:"position"`
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	Snippet   string `json:"snippet,omitempty"`
+	Published string `json:"published,omitempty"`
+}
+
+// searchEngine searches the web
+type searchEngine func(ctx context.Context, query string, count int) ([]searchResult, error)
+
+// searchTool searches the web with the configured engine
+type searchTool struct {
+	options searchOptions
+	search  searchEngine
+}
+
+var searchClient = &http.Client{Timeout: 30 * time.Second}
+
+func newSearchServer(options map[string]any) (*server.MCPServer, error) {
+	t := &searchTool{}
+	if err := decodeOptions(options, &t.options); err != nil {
+		return nil, err
+	}
+	if t.options.Count == 0 {
+		t.options.Count = defaultSearchCount
+	}
+
+	var err error
+	switch t.options.Engine {
+	case "brave":
+		t.search, err = braveEngine(t.options)
+	case "searxng":
+		t.search, err = searxngEngine(t.options)
+	case "google":
+		t.search, err = googleEngine(t.options)
+	case "":
+		return nil, fmt.Errorf("web_search needs an engine option: brave, searxng or google")
+	default:
+		return nil, fmt.Errorf("unknown search engine %q (expected brave, searxng or google)", t.options.Engine)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := server.NewMCPServer("web_search", "1.0.0", server.WithToolCapabilities(false))
+	s.AddTool(mcp.NewTool("web_search",
+		mcp.WithDescription("Search the web and return the title, URL and a snippet of each result, as JSON. Use it for current events and facts you may not know. Cite the URLs of the results you use in your answer."),
+		mcp.WithString("query", mcp.Required(), mcp.Description("The search query")),
+		mcp.WithNumber("count", mcp.Description(fmt.Sprintf("Number of results, at most %d (default %d)", maxSearchCount, t.options.Count))),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
+	), t.handle)
+	return s, nil
+}
+
+func (t *searchTool) handle(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := request.RequireString("query")
+	if err != nil || strings.TrimSpace(query) == "" {
+		return mcp.NewToolResultError("a query is required"), nil
+	}
+	count := min(max(request.GetInt("count", t.options.Count), 1), maxSearchCount)
+
+	results, err := t.search(ctx, query, count)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("search failed: %v", err)), nil
+	}
+	if len(results) > count {
+		results = results[:count]
+	}
+	for i := range results {
+		results[i].Position = i + 1
+		results[i].Snippet = plainSnippet(results[i].Snippet)
+	}
+
+	var out strings.Builder
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(map[string]any{"query": query, "results": results}); err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(strings.TrimSpace(out.String())), nil
+}
+
+// braveEngine searches with the Brave Search API
+func braveEngine(options searchOptions) (searchEngine, error) {
+	key := optionOrEnv(options.APIKey, "BRAVE_API_KEY")
+	if key == "" {
+		return nil, fmt.Errorf("the brave search engine needs an API key in apiKey or BRAVE_API_KEY")
+	}
+	return func(ctx context.Context, query string, count int) ([]searchResult, error) {
+		params := url.Values{"q": {query}, "count": {fmt.Sprint(count)}}
+		var response struct {
+			Web struct {
+				Results []struct {
+					Title       string `json:"title"`
+					URL         string `json:"url"`
+					Description string `json:"description"`
+					Age         string `json:"age"`
+				} `json:"results"`
+			} `json:"web"`
+		}
+		header := http.Header{"X-Subscription-Token": {key}}
+		if err := getJSON(ctx, "https://api.search.brave.com/res/v1/web/search?"+params.Encode(), header, &response); err != nil {
+			return nil, err
+		}
+		var results []searchResult
+		for _, r := range response.Web.Results {
+			results = append(results, searchResult{Title: r.Title, URL: r.URL, Snippet: r.Description, Published: r.Age})
+		}
+		return results, nil
+	}, nil
+}
+
+// searxngEngine searches with a SearXNG instance, which must have the JSON
+// output format enabled
+func searxngEngine(options searchOptions) (searchEngine, error) {
+	base := optionOrEnv(options.URL, "SEARXNG_URL")
+	if base == "" {
+		return nil, fmt.Errorf("the searxng search engine needs the URL of an instance in url or SEARXNG_URL")
+	}
+	return func(ctx context.Context, query string, count int) ([]searchResult, error) {
+		params := url.Values{"q": {query}, "format": {"json"}}
+		var response struct {
+			Results []struct {
+				Title         string `json:"title"`
+				URL           string `json:"url"`
+				Content       string `json:"content"`
+				PublishedDate string `json:"publishedDate"`
+			} `json:"results"`
+		}
+		err := getJSON(ctx, strings.TrimSuffix(base, "/")+"/search?"+params.Encode(), nil, &response)
+		if err != nil {
+			if strings.Contains(err.Error(), "HTTP 403") {
+				return nil, fmt.Errorf("%v (enable the json format in the search.formats setting of the SearXNG instance)", err)
+			}
+			return nil, err
+		}
+		var results []searchResult
+		for _, r := range response.Results {
+			results = append(results, searchResult{Title: r.Title, URL: r.URL, Snippet: r.Content, Published: r.PublishedDate})
+		}
+		return results, nil
+	}, nil
+}
+
+// googleEngine searches with the Google Custom Search JSON API
+func googleEngine(options searchOptions) (searchEngine, error) {
+	key := optionOrEnv(options.APIKey, "GOOGLE_CSE_API_KEY")
+	cx := optionOrEnv(options.CX, "GOOGLE_CSE_ID")
+	if key == "" || cx == "" {
+		return nil, fmt.Errorf("the google search engine needs an API key in apiKey or GOOGLE_CSE_API_KEY, and a search engine ID in cx or GOOGLE_CSE_ID")
+	}
+	return func(ctx context.Context, query string, count int) ([]searchResult, error) {
+		params := url.Values{"key": {key}, "cx": {cx}, "q": {query}, "num": {fmt.Sprint(count)}}
+		var response struct {
+			Items []struct {
+				Title   string `json:"title"`
+				Link    string `json:"link"`
+				Snippet string `json:"snippet"`
+			} `json:"items"`
+		}
+		if err := getJSON(ctx, "https://www.googleapis.com/customsearch/v1?"+params.Encode(), nil, &response); err != nil {
+			return nil, err
+		}
+		var results []searchResult
+		for _, r := range response.Items {
+			results = append(results, searchResult{Title: r.Title, URL: r.Link, Snippet: r.Snippet})
+		}
+		return results, nil
+	}, nil
+}
+
+// getJSON sends a GET request and decodes the JSON response into out
+func getJSON(ctx context.Context, rawURL string, header http.Header, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", defaultUserAgent)
+
+	resp, err := searchClient.Do(req)
+	if err != nil {
+		// Don't show the URL, which may hold an API key
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if len(bytes.TrimSpace(detail)) == 0 {
+			return fmt.Errorf("HTTP %d", resp.StatusCode)
+		}
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response: %v", err)
+	}
+	return nil
+}
+
+// optionOrEnv returns an option's value, or else the environment variable's
+func optionOrEnv(value, env string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// plainSnippet removes the highlighting markup some engines put in snippets
+func plainSnippet(snippet string) string {
+	return strings.TrimSpace(spaces.ReplaceAllString(html.UnescapeString(htmlTag.ReplaceAllString(snippet, "")), " "))
+}
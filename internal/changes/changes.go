@@ -0,0 +1,211 @@
+// Package changes tracks the files tool calls change, so that the changes
+// can be reviewed and reverted file by file. Changes are described like the
+// sandbox's, with sandbox.Change.
+package changes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcphost/internal/sandbox"
+)
+
+// maxFileSize is the size up to which files are saved before tool calls
+const maxFileSize = 1 << 20
+
+// original is a file as it was before the first tool call naming it
+type original struct {
+	content []byte
+	mode    fs.FileMode
+	existed bool
+}
+
+// Tracker saves the files named in tool call arguments before the calls
+// run, and compares them with their current content. Files are found by
+// argument names, as used by filesystem MCP servers: arguments whose name
+// contains "path" or "file", and "source" and "destination".
+type Tracker struct {
+	mu sync.Mutex
+	// dir resolves relative paths
+	dir   string
+	files map[string]*original
+}
+
+// NewTracker returns a tracker resolving relative paths against dir
+func NewTracker(dir string) *Tracker {
+	return &Tracker{dir: dir, files: make(map[string]*original)}
+}
+
+// Snapshot saves the files named in a tool call's JSON arguments that
+// aren't tracked yet. Directories and files larger than 1 MB are skipped.
+func (t *Tracker) Snapshot(args string) {
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(args), &parsed); err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, path := range pathArgs(parsed) {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(t.dir, path)
+		}
+		path = filepath.Clean(path)
+		if _, ok := t.files[path]; ok {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		switch {
+		case os.IsNotExist(err):
+			t.files[path] = &original{}
+		case err != nil || !info.Mode().IsRegular() || info.Size() > maxFileSize:
+			continue
+		default:
+			content, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			t.files[path] = &original{content: content, mode: info.Mode().Perm(), existed: true}
+		}
+	}
+}
+
+// Changes returns the tracked files that differ from their saved version,
+// sorted by path
+func (t *Tracker) Changes() []sandbox.Change {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var changes []sandbox.Change
+	for path, orig := range t.files {
+		current, exists := readFile(path)
+		var status sandbox.Status
+		switch {
+		case !orig.existed && !exists:
+			continue
+		case !orig.existed:
+			status = sandbox.Added
+		case !exists:
+			status = sandbox.Deleted
+		case bytes.Equal(orig.content, current):
+			continue
+		default:
+			status = sandbox.Modified
+		}
+		changes = append(changes, sandbox.Compare(t.display(path), status, orig.content, current))
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// Diff returns the unified diff of a tracked file since it was saved
+func (t *Tracker) Diff(name string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	path, orig, err := t.lookup(name)
+	if err != nil {
+		return "", err
+	}
+	current, _ := readFile(path)
+	if sandbox.IsBinary(orig.content) || sandbox.IsBinary(current) {
+		return "", fmt.Errorf("%s is a binary file", name)
+	}
+
+	oldName, newName := "a/"+t.display(path), "b/"+t.display(path)
+	if !orig.existed {
+		oldName = "/dev/null"
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		newName = "/dev/null"
+	}
+	return UnifiedDiff(oldName, newName, string(orig.content), string(current)), nil
+}
+
+// Revert restores a tracked file to its saved version, deleting it if it
+// didn't exist
+func (t *Tracker) Revert(name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	path, orig, err := t.lookup(name)
+	if err != nil {
+		return err
+	}
+	if !orig.existed {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete %s: %v", name, err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to restore %s: %v", name, err)
+	}
+	if err := os.WriteFile(path, orig.content, orig.mode); err != nil {
+		return fmt.Errorf("failed to restore %s: %v", name, err)
+	}
+	return nil
+}
+
+// lookup finds a tracked file by the path shown by Changes, or any path
+// resolving to it
+func (t *Tracker) lookup(name string) (string, *original, error) {
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(t.dir, path)
+	}
+	path = filepath.Clean(path)
+	orig, ok := t.files[path]
+	if !ok {
+		return "", nil, fmt.Errorf("no changes tracked for %s", name)
+	}
+	return path, orig, nil
+}
+
+// display returns path relative to the tracker's directory if it is inside
+// it, otherwise the absolute path
+func (t *Tracker) display(path string) string {
+	if rel, err := filepath.Rel(t.dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// pathArgs returns the string values of the arguments naming files
+func pathArgs(args map[string]any) []string {
+	var paths []string
+	for key, value := range args {
+		name := strings.ToLower(key)
+		if !strings.Contains(name, "path") && !strings.Contains(name, "file") && name != "source" && name != "destination" {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			paths = append(paths, v)
+		case []any:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					paths = append(paths, s)
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// readFile returns a file's content and whether it exists
+func readFile(path string) ([]byte, bool) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return content, true
+}
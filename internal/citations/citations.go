@@ -0,0 +1,234 @@
+// Package citations collects the web pages and resources tools return during
+// a turn, so answers can list the sources they are based on
+package citations
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxTitleLength is the most characters of a title that are kept
+const maxTitleLength = 120
+
+// Source is a web page or resource a tool returned
+type Source struct {
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url"`
+}
+
+// Tracker collects the sources of a turn's tool results, in the order they
+// were returned and without duplicates. The zero value is ready to use.
+type Tracker struct {
+	mu      sync.Mutex
+	sources []Source
+}
+
+// Add records the sources of a successful tool call
+func (t *Tracker) Add(args, result string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, source := range Extract(args, result) {
+		if i := t.index(source.URL); i >= 0 {
+			if t.sources[i].Title == "" {
+				t.sources[i].Title = source.Title
+			}
+			continue
+		}
+		t.sources = append(t.sources, source)
+	}
+}
+
+// Sources returns the sources recorded so far
+func (t *Tracker) Sources() []Source {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Source(nil), t.sources...)
+}
+
+func (t *Tracker) index(u string) int {
+	for i, source := range t.sources {
+		if source.URL == u {
+			return i
+		}
+	}
+	return -1
+}
+
+var (
+	// fetchHeader is the first line of a fetch builtin result
+	fetchHeader = regexp.MustCompile(`^Contents of (\S+)(?: \((.*)\))?:\n`)
+	webURL      = regexp.MustCompile(`https?://[^\s<>"'()\[\]{}]+`)
+)
+
+// Extract returns the sources of a tool call: the URL it was called with, or
+// the pages, search results and resources in its result. Results from MCP
+// tools are serialized CallToolResults; other results are read as text.
+// URLs mentioned in the text of a result are only taken if the call wasn't
+// for a URL, since the content fetched from a page links to many others.
+func Extract(args, result string) []Source {
+	target := argumentURL(args)
+
+	var sources []Source
+	var texts []string
+	var callResult struct {
+		Content []map[string]any `json:"content"`
+	}
+	if err := json.Unmarshal([]byte(result), &callResult); err != nil || callResult.Content == nil {
+		texts = append(texts, result)
+	}
+	for _, item := range callResult.Content {
+		switch item["type"] {
+		case "text":
+			text, _ := item["text"].(string)
+			texts = append(texts, text)
+		case "resource":
+			resource, _ := item["resource"].(map[string]any)
+			sources = appendSource(sources, stringField(resource, "uri"), "")
+		case "resource_link":
+			sources = appendSource(sources, stringField(item, "uri"), stringField(item, "title", "name"))
+		}
+	}
+
+	for _, text := range texts {
+		if m := fetchHeader.FindStringSubmatch(text); m != nil {
+			sources = appendSource(sources, m[1], m[2])
+			target = ""
+			continue
+		}
+		var data any
+		if err := json.Unmarshal([]byte(text), &data); err == nil {
+			sources = walk(sources, data)
+			continue
+		}
+		if target == "" {
+			for _, u := range webURL.FindAllString(text, -1) {
+				sources = appendSource(sources, strings.TrimRight(u, ".,;:!?"), "")
+			}
+		}
+	}
+
+	if target != "" {
+		sources = append([]Source{{URL: target}}, sources...)
+	}
+	return sources
+}
+
+// argumentURL returns the web URL a tool was called with, if any
+func argumentURL(args string) string {
+	var arguments map[string]any
+	if err := json.Unmarshal([]byte(args), &arguments); err != nil {
+		return ""
+	}
+	u := stringField(arguments, "url", "uri", "link")
+	if !isWeb(u) {
+		return ""
+	}
+	return u
+}
+
+// walk collects the sources in JSON data: objects with a web URL in a url,
+// link or uri field, titled by their title or name field
+func walk(sources []Source, data any) []Source {
+	switch v := data.(type) {
+	case map[string]any:
+		if u := stringField(v, "url", "link", "uri"); isWeb(u) {
+			sources = appendSource(sources, u, stringField(v, "title", "name"))
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sources = walk(sources, v[key])
+		}
+	case []any:
+		for _, value := range v {
+			sources = walk(sources, value)
+		}
+	}
+	return sources
+}
+
+// appendSource appends a source unless it has no URL or is already listed
+func appendSource(sources []Source, u, title string) []Source {
+	u = strings.TrimSpace(u)
+	if u == "" {
+		return sources
+	}
+	for _, source := range sources {
+		if source.URL == u {
+			return sources
+		}
+	}
+	return append(sources, Source{Title: cleanTitle(title), URL: u})
+}
+
+// stringField returns the first of the named fields that holds a string
+func stringField(object map[string]any, names ...string) string {
+	for _, name := range names {
+		if s, ok := object[name].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// isWeb reports whether u is an http or https URL
+func isWeb(u string) bool {
+	parsed, err := url.Parse(u)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// cleanTitle puts a title on one line and shortens it
+func cleanTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	if runes := []rune(title); len(runes) > maxTitleLength {
+		title = string(runes[:maxTitleLength]) + "…"
+	}
+	return title
+}
+
+// Markdown renders sources as a numbered list under a Sources heading, or
+// returns "" if there are none
+func Markdown(sources []Source) string {
+	if len(sources) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("**Sources**\n\n")
+	for i, source := range sources {
+		switch {
+		case !isWeb(source.URL) && source.Title != "":
+			fmt.Fprintf(&b, "%d. %s (`%s`)\n", i+1, source.Title, source.URL)
+		case !isWeb(source.URL):
+			fmt.Fprintf(&b, "%d. `%s`\n", i+1, source.URL)
+		case source.Title != "":
+			fmt.Fprintf(&b, "%d. [%s](<%s>)\n", i+1, escapeLinkText(source.Title), source.URL)
+		default:
+			fmt.Fprintf(&b, "%d. <%s>\n", i+1, source.URL)
+		}
+	}
+	return b.String()
+}
+
+// WithFooter appends the Markdown list of sources to an answer
+func WithFooter(answer string, sources []Source) string {
+	footer := Markdown(sources)
+	if footer == "" {
+		return answer
+	}
+	return strings.TrimRight(answer, "\n") + "\n\n" + footer
+}
+
+var linkTextSpecial = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`)
+
+func escapeLinkText(text string) string {
+	return linkTextSpecial.Replace(text)
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcphost/internal/paths"
+	"gopkg.in/yaml.v3"
+)
+
+// agentExtensions are the file extensions tried when loading an agent
+// definition, in order. JSON is parsed as YAML, of which it is a subset.
+var agentExtensions = []string{".yaml", ".yml", ".json"}
+
+// AgentsDir returns the directory holding named agent definitions
+func AgentsDir() (string, error) {
+	configDir, err := paths.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "agents"), nil
+}
+
+// LoadAgent loads the named agent definition from the agents directory. An
+// agent definition has the same fields as a script's frontmatter. Relative
+// system prompt paths are resolved against the agents directory.
+func LoadAgent(name string) (*Config, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid agent name %q", name)
+	}
+
+	dir, err := AgentsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ext := range agentExtensions {
+		path := filepath.Join(dir, name+ext)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading agent %s: %v", name, err)
+		}
+
+		var agentConfig Config
+		if err := yaml.Unmarshal(data, &agentConfig); err != nil {
+			return nil, fmt.Errorf("error parsing agent %s: %v", path, err)
+		}
+		if agentConfig.SystemPrompt != "" && !filepath.IsAbs(agentConfig.SystemPrompt) {
+			agentConfig.SystemPrompt = filepath.Join(dir, agentConfig.SystemPrompt)
+		}
+		for i, source := range agentConfig.SystemPrompts {
+			if source.File != "" && !filepath.IsAbs(source.File) {
+				agentConfig.SystemPrompts[i].File = filepath.Join(dir, source.File)
+			}
+		}
+		return &agentConfig, nil
+	}
+
+	return nil, fmt.Errorf("agent %q not found in %s", name, dir)
+}
+
+// ListAgents returns the names of the agent definitions in the agents
+// directory, sorted alphabetically
+func ListAgents() ([]string, error) {
+	dir, err := AgentsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading agents directory: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := filepath.Ext(entry.Name())
+		name := strings.TrimSuffix(entry.Name(), ext)
+		if slices.Contains(agentExtensions, ext) && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
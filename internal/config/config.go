@@ -4,42 +4,161 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
 
+	"github.com/mark3labs/mcphost/internal/paths"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
 // MCPServerConfig represents configuration for an MCP server
 type MCPServerConfig struct {
-	Command       string   `json:"command,omitempty"`
-	Args          []string `json:"args,omitempty"`
-	URL           string   `json:"url,omitempty"`
-	Headers       []string `json:"headers,omitempty"`
-	AllowedTools  []string `json:"allowedTools,omitempty"`
-	ExcludedTools []string `json:"excludedTools,omitempty"`
+	Command       string   `json:"command,omitempty" yaml:"command,omitempty"`
+	Args          []string `json:"args,omitempty" yaml:"args,omitempty"`
+	URL           string   `json:"url,omitempty" yaml:"url,omitempty"`
+	Headers       []string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	AllowedTools  []string `json:"allowedTools,omitempty" yaml:"allowedTools,omitempty"`
+	ExcludedTools []string `json:"excludedTools,omitempty" yaml:"excludedTools,omitempty"`
+	AutoApprove   []string `json:"autoApprove,omitempty" yaml:"autoApprove,omitempty"`
+	// ResultFilters maps tool names, or "*" for all of the server's tools, to
+	// jq expressions applied to their JSON results
+	ResultFilters map[string]string `json:"resultFilters,omitempty" yaml:"resultFilters,omitempty"`
+	// Builtin names a server that runs inside mcphost instead of command or
+	// url, configured by Options
+	Builtin string         `json:"builtin,omitempty" yaml:"builtin,omitempty"`
+	Options map[string]any `json:"options,omitempty" yaml:"options,omitempty"`
+	// Prefix replaces the server name in front of its tool names; empty
+	// leaves them unprefixed
+	Prefix *string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
 }
 
 // Config represents the application configuration
 type Config struct {
-	MCPServers      map[string]MCPServerConfig `json:"mcpServers" yaml:"mcpServers"`
-	Model           string                     `json:"model,omitempty" yaml:"model,omitempty"`
-	MaxSteps        int                        `json:"max-steps,omitempty" yaml:"max-steps,omitempty"`
-	MessageWindow   int                        `json:"message-window,omitempty" yaml:"message-window,omitempty"`
-	Debug           bool                       `json:"debug,omitempty" yaml:"debug,omitempty"`
-	SystemPrompt    string                     `json:"system-prompt,omitempty" yaml:"system-prompt,omitempty"`
-	OpenAIAPIKey    string                     `json:"openai-api-key,omitempty" yaml:"openai-api-key,omitempty"`
-	AnthropicAPIKey string                     `json:"anthropic-api-key,omitempty" yaml:"anthropic-api-key,omitempty"`
-	GoogleAPIKey    string                     `json:"google-api-key,omitempty" yaml:"google-api-key,omitempty"`
-	OpenAIURL       string                     `json:"openai-url,omitempty" yaml:"openai-url,omitempty"`
-	AnthropicURL    string                     `json:"anthropic-url,omitempty" yaml:"anthropic-url,omitempty"`
-	Prompt          string                     `json:"prompt,omitempty" yaml:"prompt,omitempty"`
+	MCPServers         map[string]MCPServerConfig    `json:"mcpServers" yaml:"mcpServers"`
+	Model              string                        `json:"model,omitempty" yaml:"model,omitempty"`
+	UtilityModel       string                        `json:"utility-model,omitempty" yaml:"utility-model,omitempty"`
+	MaxSteps           int                           `json:"max-steps,omitempty" yaml:"max-steps,omitempty"`
+	StepTimeout        string                        `json:"step-timeout,omitempty" yaml:"step-timeout,omitempty"`
+	MaxDuration        string                        `json:"max-duration,omitempty" yaml:"max-duration,omitempty"`
+	ModelRetries       int                           `json:"model-retries,omitempty" yaml:"model-retries,omitempty"`
+	MessageWindow      int                           `json:"message-window,omitempty" yaml:"message-window,omitempty"`
+	Debug              bool                          `json:"debug,omitempty" yaml:"debug,omitempty"`
+	SystemPrompt       string                        `json:"system-prompt,omitempty" yaml:"system-prompt,omitempty"`
+	SystemPrompts      []PromptSource                `json:"system-prompts,omitempty" yaml:"system-prompts,omitempty"`
+	OpenAIAPIKey       string                        `json:"openai-api-key,omitempty" yaml:"openai-api-key,omitempty"`
+	AnthropicAPIKey    string                        `json:"anthropic-api-key,omitempty" yaml:"anthropic-api-key,omitempty"`
+	GoogleAPIKey       string                        `json:"google-api-key,omitempty" yaml:"google-api-key,omitempty"`
+	OpenAIURL          string                        `json:"openai-url,omitempty" yaml:"openai-url,omitempty"`
+	AnthropicURL       string                        `json:"anthropic-url,omitempty" yaml:"anthropic-url,omitempty"`
+	Prompt             string                        `json:"prompt,omitempty" yaml:"prompt,omitempty"`
+	Temperature        *float32                      `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+	Quiet              string                        `json:"quiet,omitempty" yaml:"quiet,omitempty"`
+	Output             string                        `json:"output,omitempty" yaml:"output,omitempty"`
+	Print              string                        `json:"print,omitempty" yaml:"print,omitempty"`
+	Transcript         string                        `json:"transcript,omitempty" yaml:"transcript,omitempty"`
+	OutputFile         string                        `json:"output-file,omitempty" yaml:"output-file,omitempty"`
+	Interactive        bool                          `json:"interactive,omitempty" yaml:"interactive,omitempty"`
+	Spinner            string                        `json:"spinner,omitempty" yaml:"spinner,omitempty"`
+	ShowToolArgs       *bool                         `json:"show-tool-args,omitempty" yaml:"show-tool-args,omitempty"`
+	Compact            bool                          `json:"compact,omitempty" yaml:"compact,omitempty"`
+	TimeFormat         string                        `json:"time-format,omitempty" yaml:"time-format,omitempty"`
+	NoTimestamps       bool                          `json:"no-timestamps,omitempty" yaml:"no-timestamps,omitempty"`
+	NoTitle            bool                          `json:"no-title,omitempty" yaml:"no-title,omitempty"`
+	Render             string                        `json:"render,omitempty" yaml:"render,omitempty"`
+	Suggestions        bool                          `json:"suggestions,omitempty" yaml:"suggestions,omitempty"`
+	ConfirmTools       bool                          `json:"confirm-tools,omitempty" yaml:"confirm-tools,omitempty"`
+	ReadOnly           bool                          `json:"read-only,omitempty" yaml:"read-only,omitempty"`
+	ReadOnlyTools      []string                      `json:"read-only-tools,omitempty" yaml:"read-only-tools,omitempty"`
+	ToolChoice         string                        `json:"tool-choice,omitempty" yaml:"tool-choice,omitempty"`
+	NoParallelTools    bool                          `json:"no-parallel-tools,omitempty" yaml:"no-parallel-tools,omitempty"`
+	NoJSONRepair       bool                          `json:"no-json-repair,omitempty" yaml:"no-json-repair,omitempty"`
+	ToolSeparator      string                        `json:"tool-separator,omitempty" yaml:"tool-separator,omitempty"`
+	ForceTool          string                        `json:"force-tool,omitempty" yaml:"force-tool,omitempty"`
+	ForceArgs          string                        `json:"force-args,omitempty" yaml:"force-args,omitempty"`
+	ReturnDirectTools  []string                      `json:"returnDirectTools,omitempty" yaml:"returnDirectTools,omitempty"`
+	MessageModifiers   []string                      `json:"message-modifiers,omitempty" yaml:"message-modifiers,omitempty"`
+	PauseBeforeTools   bool                          `json:"pause-before-tools,omitempty" yaml:"pause-before-tools,omitempty"`
+	CheckpointDir      string                        `json:"checkpoint-dir,omitempty" yaml:"checkpoint-dir,omitempty"`
+	DataDir            string                        `json:"data-dir,omitempty" yaml:"data-dir,omitempty"`
+	Workdir            string                        `json:"workdir,omitempty" yaml:"workdir,omitempty"`
+	Sandbox            bool                          `json:"sandbox,omitempty" yaml:"sandbox,omitempty"`
+	SandboxApply       bool                          `json:"sandbox-apply,omitempty" yaml:"sandbox-apply,omitempty"`
+	Snapshots          bool                          `json:"snapshots,omitempty" yaml:"snapshots,omitempty"`
+	Preflight          bool                          `json:"preflight,omitempty" yaml:"preflight,omitempty"`
+	IgnoreCaps         bool                          `json:"ignore-capabilities,omitempty" yaml:"ignore-capabilities,omitempty"`
+	Step               bool                          `json:"step,omitempty" yaml:"step,omitempty"`
+	Voice              bool                          `json:"voice,omitempty" yaml:"voice,omitempty"`
+	SpeechToText       *SpeechToTextConfig           `json:"speech-to-text,omitempty" yaml:"speech-to-text,omitempty"`
+	Speak              bool                          `json:"speak,omitempty" yaml:"speak,omitempty"`
+	TextToSpeech       *TextToSpeechConfig           `json:"text-to-speech,omitempty" yaml:"text-to-speech,omitempty"`
+	NativeSearch       bool                          `json:"native-search,omitempty" yaml:"native-search,omitempty"`
+	ContextFiles       []string                      `json:"context-files,omitempty" yaml:"context-files,omitempty"`
+	Context            []string                      `json:"context,omitempty" yaml:"context,omitempty"`
+	ContextBudget      int                           `json:"context-budget,omitempty" yaml:"context-budget,omitempty"`
+	NoEnvironment      bool                          `json:"no-environment,omitempty" yaml:"no-environment,omitempty"`
+	ServerInstructions bool                          `json:"server-instructions,omitempty" yaml:"server-instructions,omitempty"`
+	Language           string                        `json:"language,omitempty" yaml:"language,omitempty"`
+	ShareEndpoint      string                        `json:"share-endpoint,omitempty" yaml:"share-endpoint,omitempty"`
+	DumpLLMTraffic     string                        `json:"dump-llm-traffic,omitempty" yaml:"dump-llm-traffic,omitempty"`
+	Guardrails         []Guardrail                   `json:"guardrails,omitempty" yaml:"guardrails,omitempty"`
+	Providers          map[string]ProviderDefinition `json:"providers,omitempty" yaml:"providers,omitempty"`
+	Conversation       []ConversationTurn            `json:"conversation,omitempty" yaml:"conversation,omitempty"`
 }
 
+// ConversationTurn is a single user turn of a scripted conversation
+type ConversationTurn struct {
+	Prompt string `json:"prompt" yaml:"prompt"`
+	// Expect lists substrings the assistant's response must contain
+	Expect []string `json:"expect,omitempty" yaml:"expect,omitempty"`
+}
+
+// DefaultContextFiles are the project instruction files included in the
+// system prompt when found in the working directory
+var DefaultContextFiles = []string{"MCPHOST.md", "AGENTS.md", ".mcphost/instructions.md"}
+
+// maxContextFileSize limits how much of a single context file is included
+const maxContextFileSize = 64 * 1024
+
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	for serverName, serverConfig := range c.MCPServers {
 		if len(serverConfig.AllowedTools) > 0 && len(serverConfig.ExcludedTools) > 0 {
 			return fmt.Errorf("server %s: allowedTools and excludedTools are mutually exclusive", serverName)
 		}
+		if serverConfig.Builtin != "" && (serverConfig.Command != "" || serverConfig.URL != "") {
+			return fmt.Errorf("server %s: builtin can't be combined with command or url", serverName)
+		}
+		if serverConfig.Builtin == "" && len(serverConfig.Options) > 0 {
+			return fmt.Errorf("server %s: options are only used by builtin servers", serverName)
+		}
+	}
+	if c.StepTimeout != "" {
+		if _, err := time.ParseDuration(c.StepTimeout); err != nil {
+			return fmt.Errorf("invalid step-timeout %q: %v", c.StepTimeout, err)
+		}
+	}
+	if c.MaxDuration != "" {
+		if _, err := time.ParseDuration(c.MaxDuration); err != nil {
+			return fmt.Errorf("invalid max-duration %q: %v", c.MaxDuration, err)
+		}
+	}
+	if c.ModelRetries < 0 {
+		return fmt.Errorf("invalid model-retries %d: must not be negative", c.ModelRetries)
+	}
+	if err := validatePatterns(c.ReadOnlyTools); err != nil {
+		return fmt.Errorf("read-only-tools: %v", err)
+	}
+	for i, guardrail := range c.Guardrails {
+		if err := guardrail.validate(); err != nil {
+			return fmt.Errorf("guardrails[%d]: %v", i, err)
+		}
+	}
+	for name, provider := range c.Providers {
+		if err := provider.validate(); err != nil {
+			return fmt.Errorf("provider %s: %v", name, err)
+		}
 	}
 	return nil
 }
@@ -49,63 +168,116 @@ type SystemPromptConfig struct {
 	SystemPrompt string `json:"systemPrompt"`
 }
 
-// LoadMCPConfig loads MCP configuration from file
+// ServersEnv is the environment variable holding MCP servers in JSON or
+// YAML, in the format of mcpServers. They are added to the servers of the
+// config file, replacing those of the same name.
+const ServersEnv = "MCPHOST_MCPSERVERS"
+
+// LoadMCPConfig loads MCP configuration from file and the servers in
+// ServersEnv
 func LoadMCPConfig(configFile string) (*Config, error) {
-	v := viper.New()
+	config, err := loadConfigFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := addEnvServers(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
 
-	if configFile == "" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return nil, fmt.Errorf("error getting home directory: %v", err)
-		}
+// addEnvServers adds the servers in ServersEnv to config
+func addEnvServers(config *Config) error {
+	value := os.Getenv(ServersEnv)
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
 
-		// Try .mcphost files first (new format), then .mcp files (backwards compatibility)
-		configNames := []string{".mcphost", ".mcp"}
-		configTypes := []string{"yaml", "json"}
-
-		var configFound bool
-		for _, configName := range configNames {
-			for _, configType := range configTypes {
-				v.SetConfigName(configName)
-				v.SetConfigType(configType)
-				v.AddConfigPath(homeDir)
-
-				if err := v.ReadInConfig(); err == nil {
-					configFound = true
-					break
-				}
-			}
-			if configFound {
-				break
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+		return fmt.Errorf("invalid %s: %v", ServersEnv, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	s := &schemaCheck{path: ServersEnv}
+	s.check(doc.Content[0], reflect.TypeOf(config.MCPServers), "mcpServers")
+	if len(s.problems) > 0 {
+		return fmt.Errorf("invalid %s:\n  %s", ServersEnv, strings.Join(s.problems, "\n  "))
+	}
+
+	var servers map[string]MCPServerConfig
+	if err := doc.Decode(&servers); err != nil {
+		return fmt.Errorf("invalid %s: %v", ServersEnv, err)
+	}
+	if config.MCPServers == nil {
+		config.MCPServers = make(map[string]MCPServerConfig)
+	}
+	for name, server := range servers {
+		config.MCPServers[name] = server
+	}
+	return config.Validate()
+}
+
+// DefaultConfigFile returns the config file used without --config, or "" if
+// there is none: config.yml, config.yaml or config.json in the config
+// directory, or else one of the files earlier versions used in the home
+// directory, ~/.mcphost.yml and ~/.mcp.yml with the same extensions
+func DefaultConfigFile() (string, error) {
+	configDir, err := paths.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting home directory: %v", err)
+	}
+
+	candidates := []string{
+		filepath.Join(configDir, "config"),
+		filepath.Join(homeDir, ".mcphost"),
+		filepath.Join(homeDir, ".mcp"),
+	}
+	for _, candidate := range candidates {
+		for _, ext := range paths.ConfigExtensions {
+			if _, err := os.Stat(candidate + ext); err == nil {
+				return candidate + ext, nil
 			}
 		}
+	}
+	return "", nil
+}
 
-		if !configFound {
+// loadConfigFile loads the configuration from file, or from the default
+// config file if file is empty
+func loadConfigFile(configFile string) (*Config, error) {
+	v := viper.New()
+
+	if configFile == "" {
+		path, err := DefaultConfigFile()
+		if err != nil {
+			return nil, err
+		}
+		if path == "" {
 			// Create default config file
-			if err := createDefaultConfig(homeDir); err != nil {
+			if path, err = createDefaultConfig(); err != nil {
 				// If we can't create the file, just return default config
 				return &Config{
 					MCPServers: make(map[string]MCPServerConfig),
 				}, nil
 			}
-
-			// Try to load the newly created config
-			v.SetConfigName(".mcphost")
-			v.SetConfigType("yaml")
-			v.AddConfigPath(homeDir)
-			if err := v.ReadInConfig(); err != nil {
-				// If we still can't read it, return default config
-				return &Config{
-					MCPServers: make(map[string]MCPServerConfig),
-				}, nil
-			}
-		}
-	} else {
-		// Use specified config file
-		v.SetConfigFile(configFile)
-		if err := v.ReadInConfig(); err != nil {
-			return nil, fmt.Errorf("error reading config file: %v", err)
 		}
+		configFile = path
+	}
+
+	v.SetConfigFile(configFile)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("error reading config file: %v", err)
+	}
+
+	// The loader ignores keys it doesn't know, so typos would go unnoticed
+	if err := ValidateFile(configFile); err != nil {
+		return nil, err
 	}
 
 	var config Config
@@ -142,14 +314,64 @@ func LoadSystemPrompt(filePath string) (string, error) {
 	return systemPrompt, nil
 }
 
-// createDefaultConfig creates a default .mcphost.yml file in the user's home directory
-func createDefaultConfig(homeDir string) error {
-	configPath := filepath.Join(homeDir, ".mcphost.yml")
+// LoadContextFiles reads the given project instruction files relative to dir
+// and returns them formatted for inclusion in the system prompt. Missing files
+// are skipped; an empty string is returned if none exist.
+func LoadContextFiles(dir string, names []string) (string, error) {
+	var sections []string
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, name)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", fmt.Errorf("error reading context file %s: %v", name, err)
+		}
+
+		content := strings.TrimSpace(string(data))
+		if len(content) > maxContextFileSize {
+			content = content[:maxContextFileSize] + "\n... (truncated)"
+		}
+		if content == "" {
+			continue
+		}
+
+		sections = append(sections, fmt.Sprintf("## %s\n\n%s", name, content))
+	}
+
+	if len(sections) == 0 {
+		return "", nil
+	}
+
+	return "# Project instructions\n\nThe following instructions were loaded from files in the working directory.\n\n" +
+		strings.Join(sections, "\n\n"), nil
+}
+
+// createDefaultConfig creates a default config.yml file in the config
+// directory and returns its path
+func createDefaultConfig() (string, error) {
+	configDir, err := paths.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		return "", fmt.Errorf("error creating config directory: %v", err)
+	}
+	configPath := filepath.Join(configDir, "config.yml")
 
 	// Create the file
 	file, err := os.Create(configPath)
 	if err != nil {
-		return fmt.Errorf("error creating config file: %v", err)
+		return "", fmt.Errorf("error creating config file: %v", err)
 	}
 	defer file.Close()
 
@@ -167,15 +389,97 @@ func createDefaultConfig(homeDir string) error {
 #   sqlite:
 #     command: uvx
 #     args: ["mcp-server-sqlite", "--db-path", "/tmp/example.db"]
+#     autoApprove: ["read_query", "list_tables"]  # never ask before these tools
+#   screen:
+#     builtin: screenshot                      # Runs inside mcphost; asks before every screenshot
+#     options:
+#       windows: ["Firefox"]                   # Only capture windows with these titles
+#   web:
+#     builtin: fetch                           # Fetches web pages as Markdown
+#     options:
+#       allow: ["go.dev"]                      # Only these domains (default: any); deny lists blocked ones
+#   github:
+#     url: "https://mcp.example.com/sse"
+#     prefix: gh                               # Tools are named gh__<tool>; "" leaves them unprefixed
 
 mcpServers:
 
 # Application settings (all optional)
 # model: "anthropic:claude-sonnet-4-20250514"  # Default model to use
+# utility-model: "ollama:qwen2.5:3b"           # Model for internal tasks such as follow-up suggestions (default: model)
 # max-steps: 20                                # Maximum agent steps (0 for unlimited)
+# step-timeout: 2m                             # Limit on each model call and tool call, e.g. 90s or 5m (default: none)
+# model-retries: 2                             # Retry failed model calls, waiting 1s, 2s, 4s... (default: 0)
 # message-window: 40                           # Number of messages to keep in context
 # debug: false                                 # Enable debug logging
-# system-prompt: "/path/to/system-prompt.json" # System prompt file
+# system-prompt: "/path/to/system-prompt.json" # System prompt file (JSON with a systemPrompt field, or plain text)
+# system-prompts:                              # Ordered system prompt fragments (default: system-prompt, environment, context-files, server-instructions, guardrails)
+#   - builtin: system-prompt
+#   - text: "You are working in {{.Cwd}} on {{.OS}}."
+#   - file: "/path/to/style.md"
+#   - builtin: context-files
+# context-files: ["MCPHOST.md", "AGENTS.md"]   # Project instruction files added to the system prompt
+# context: ["docs/", "src/**/*.go"]            # Files loaded as context at the start of every session
+# context-budget: 204800                       # Maximum bytes of file content loaded by context
+# no-environment: false                       # Leave the date, OS and cwd out of the system prompt
+# server-instructions: false                   # Add the instructions MCP servers give for their tools to the system prompt
+# language: de                                 # Always respond in this language (ISO 639-1 code)
+# message-modifiers: [datetime, strip-base64]  # Applied to the messages before each model call: datetime, strip-base64, project-context, tool-images
+# dump-llm-traffic: "./traffic"               # Write raw provider requests/responses here (API keys redacted)
+# providers:                                   # Extra model providers, used as <name>:<model>
+#   local:
+#     command: /usr/local/bin/my-llm-shim        # Executable speaking the provider shim protocol
+#   corp:
+#     plugin: /opt/mcphost/corp-provider.so      # Go plugin exporting a NewChatModel function
+#   litellm:
+#     baseURL: "https://llm-proxy.internal/v1"   # OpenAI-compatible proxy; model names are passed through
+#     apiKeyEnv: LITELLM_API_KEY
+#     headers: {X-Team: platform}
+# guardrails:                                  # Safety instructions for categories of tools
+#   - tools: ["*write*", "*delete*"]
+#     prompt: "Confirm the exact path with the user before changing or removing files."
+
+# Display settings (all optional)
+# spinner: dots                                # Spinner style: dots, line or none
+# show-tool-args: true                         # Show tool arguments while tools execute
+# compact: false                               # One line per tool call and result
+# time-format: 12h                             # Timestamps: 12h, 24h, iso, us, eu, time or a Go layout
+# no-timestamps: false                         # Hide message timestamps
+# no-title: false                              # Don't show session, model and status in the terminal title
+# render: auto                                 # auto, fancy or simple (no cursor movement, for tmux/screen)
+# suggestions: false                           # Suggest follow-up prompts after each response
+# output-file: "answer.md"                     # Write the final answer here as Markdown
+# confirm-tools: false                         # Ask before running tools not in autoApprove
+# read-only: false                             # Block tools that look like they change something
+# read-only-tools: ["*write*", "*delete*"]     # Tool name patterns read-only mode blocks (default: write, delete, exec and similar)
+# tool-choice: auto                            # auto, any, none or a tool name the model must call first
+# no-parallel-tools: false                     # One tool call per model response
+# no-json-repair: false                        # Don't repair malformed JSON in tool call arguments
+# tool-separator: "__"                         # Between the server prefix and the tool name, e.g. filesystem__read_file
+# returnDirectTools: ["reports__generate"]     # Tools whose result is the answer, without another model call
+# pause-before-tools: false                    # With --prompt, pause before running tools; continue with mcphost resume
+# checkpoint-dir: "./checkpoints"              # Where paused runs are saved (default: checkpoints in data-dir)
+# data-dir: "/var/lib/mcphost"                 # Checkpoints, snapshots and crash reports (default ~/.local/share/mcphost)
+# workdir: "/path/to/project"                  # Directory MCP servers are started in and file references are resolved against
+# sandbox: false                               # Work in a temporary copy of the workdir and summarize the changes at the end
+# sandbox-apply: false                         # With sandbox, copy the changes back to the workdir at the end
+# snapshots: false                             # Snapshot the workdir before each tool call; see mcphost snapshots
+# preflight: false                             # Check the model and API key at startup instead of on the first prompt
+# ignore-capabilities: false                   # Don't disable tools for models known not to support them
+# step: false                                  # Pause after each model response and tool result until enter is pressed
+# native-search: false                         # Let Gemini or OpenAI search models search the web themselves, without MCP tools
+# voice: false                                 # Speak prompts instead of typing them; see speech-to-text
+# speech-to-text:
+#   backend: openai                            # openai (uses openai-api-key and openai-url) or whisper-cpp
+#   model: whisper-1                           # OpenAI model, or the whisper.cpp model file
+#   language: en                               # Spoken language; detected if empty
+#   record: ["rec", "-q", "-c", "1", "-r", "16000", "{file}", "silence", "1", "0.1", "3%", "1", "2.0", "3%"]
+# speak: false                                 # Read answers aloud; see text-to-speech
+# text-to-speech:
+#   backend: system                            # system (say, espeak-ng or Windows speech) or openai
+#   voice: alloy                               # System or OpenAI voice
+#   model: tts-1                               # OpenAI speech model
+# share-endpoint: "https://paste.example.com"  # Where /share endpoint uploads session pages
 
 # API Configuration (can also use environment variables)
 # openai-api-key: "your-openai-key"
@@ -183,12 +487,16 @@ mcpServers:
 # google-api-key: "your-google-key"
 # openai-url: "https://api.openai.com/v1"
 # anthropic-url: "https://api.anthropic.com"
+# lmstudio-host: "http://localhost:1234/v1"
+# llamacpp-host: "http://localhost:8080/v1"
+# azure-endpoint: "https://myresource.openai.azure.com"
+# azure-api-key: "your-azure-key"
 `
 
 	_, err = file.WriteString(content)
 	if err != nil {
-		return fmt.Errorf("error writing config content: %v", err)
+		return "", fmt.Errorf("error writing config content: %v", err)
 	}
 
-	return nil
+	return configPath, nil
 }
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// EnvironmentInfo describes the environment mcphost runs in for the system
+// prompt, since models often get the date, OS or working directory wrong
+func EnvironmentInfo(now time.Time, cwd string) string {
+	zone, _ := now.Zone()
+
+	lines := []string{
+		"# Environment",
+		"",
+		fmt.Sprintf("- Current date and time: %s (%s, UTC%s)",
+			now.Format("Monday, 2 January 2006 15:04"), zone, now.Format("-07:00")),
+		fmt.Sprintf("- Operating system: %s/%s", runtime.GOOS, runtime.GOARCH),
+	}
+	if shell := currentShell(); shell != "" {
+		lines = append(lines, fmt.Sprintf("- Shell: %s", shell))
+	}
+	if cwd != "" {
+		lines = append(lines, fmt.Sprintf("- Working directory: %s", cwd))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// currentShell returns the user's shell, if known
+func currentShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	if runtime.GOOS == "windows" {
+		return os.Getenv("ComSpec")
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// AddFlagServers adds the MCP servers given on the command line for a single
+// run, replacing servers of the config with the same name. commands are
+// --mcp values, "name=command arg1 arg2", whose arguments are split like a
+// shell does, so quotes keep spaces. urls are --mcp-url values,
+// "name=url".
+func (c *Config) AddFlagServers(commands, urls []string) error {
+	if len(commands) == 0 && len(urls) == 0 {
+		return nil
+	}
+	// Copies of c made before, such as the config watcher's, keep their
+	// servers
+	c.MCPServers = maps.Clone(c.MCPServers)
+	if c.MCPServers == nil {
+		c.MCPServers = make(map[string]MCPServerConfig)
+	}
+
+	added := make(map[string]bool)
+	add := func(flag, form, value string, server func(rest string) (MCPServerConfig, error)) error {
+		name, rest, ok := strings.Cut(value, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " /:") {
+			return fmt.Errorf("invalid %s %q: expected %s", flag, value, form)
+		}
+		if added[name] {
+			return fmt.Errorf("invalid %s %q: server %s is given twice", flag, value, name)
+		}
+		s, err := server(strings.TrimSpace(rest))
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %v", flag, value, err)
+		}
+		added[name] = true
+		c.MCPServers[name] = s
+		return nil
+	}
+
+	for _, value := range commands {
+		err := add("--mcp", "name=command", value, func(rest string) (MCPServerConfig, error) {
+			args, err := splitCommandLine(rest)
+			if err != nil {
+				return MCPServerConfig{}, err
+			}
+			if len(args) == 0 {
+				return MCPServerConfig{}, fmt.Errorf("no command")
+			}
+			return MCPServerConfig{Command: args[0], Args: args[1:]}, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, value := range urls {
+		err := add("--mcp-url", "name=url", value, func(rest string) (MCPServerConfig, error) {
+			if !strings.HasPrefix(rest, "http://") && !strings.HasPrefix(rest, "https://") {
+				return MCPServerConfig{}, fmt.Errorf("expected an http or https URL")
+			}
+			return MCPServerConfig{URL: rest}, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return c.Validate()
+}
+
+// splitCommandLine splits a command line into words at unquoted spaces.
+// Single quotes keep everything up to the next one, double quotes allow
+// backslash escapes of " and \, and a backslash outside quotes escapes the
+// next character.
+func splitCommandLine(line string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Guardrail attaches safety instructions to a category of tools, given as
+// glob patterns such as "*write*" or "filesystem__*"
+type Guardrail struct {
+	Tools  []string `json:"tools" yaml:"tools"`
+	Prompt string   `json:"prompt" yaml:"prompt"`
+}
+
+// Matches reports whether the guardrail applies to a tool. Patterns are
+// matched case-insensitively against both the server-prefixed name and the
+// tool's own name.
+func (g Guardrail) Matches(toolName string) bool {
+	names := []string{toolName}
+	if _, name, ok := strings.Cut(toolName, "__"); ok {
+		names = append(names, name)
+	}
+	_, matched := MatchTool(g.Tools, names...)
+	return matched
+}
+
+// validate checks the guardrail's patterns
+func (g Guardrail) validate() error {
+	if len(g.Tools) == 0 {
+		return fmt.Errorf("no tool patterns")
+	}
+	if strings.TrimSpace(g.Prompt) == "" {
+		return fmt.Errorf("empty prompt")
+	}
+	return validatePatterns(g.Tools)
+}
+
+// GuardrailsPrompt returns the system prompt section listing the guardrails
+// that apply to the given tools, or an empty string if none do
+func GuardrailsPrompt(guardrails []Guardrail, tools []PromptTool) string {
+	var sections []string
+	for _, guardrail := range guardrails {
+		var matched []string
+		for _, tool := range tools {
+			if guardrail.Matches(tool.Name) {
+				matched = append(matched, tool.Name)
+			}
+		}
+		if len(matched) > 0 {
+			sections = append(sections, fmt.Sprintf("## %s\n\n%s",
+				strings.Join(matched, ", "), strings.TrimSpace(guardrail.Prompt)))
+		}
+	}
+
+	if len(sections) == 0 {
+		return ""
+	}
+
+	return "# Tool guardrails\n\nFollow these rules whenever you use the tools listed.\n\n" +
+		strings.Join(sections, "\n\n")
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// DefaultToolSeparator joins a server's prefix and the names of its tools
+// unless the config sets tool-separator
+const DefaultToolSeparator = "__"
+
+// MaxToolNameLength is the longest tool name all providers accept. OpenAI
+// and Anthropic also only accept letters, digits, _ and -, and Gemini wants
+// names to start with a letter or _.
+const MaxToolNameLength = 64
+
+// Separator returns the separator between tool prefixes and names
+func (c *Config) Separator() string {
+	if c.ToolSeparator == "" {
+		return DefaultToolSeparator
+	}
+	return c.ToolSeparator
+}
+
+// ToolPrefix returns the prefix of the tools of a server: its prefix
+// setting, or else its name. An empty prefix leaves the names unprefixed.
+func (c *Config) ToolPrefix(serverName string) string {
+	if server, ok := c.MCPServers[serverName]; ok && server.Prefix != nil {
+		return *server.Prefix
+	}
+	return serverName
+}
+
+// ToolName returns the name a server's tool is offered to the model under,
+// made acceptable to providers with SanitizeToolName. Tools already named
+// with the prefix keep their names.
+func (c *Config) ToolName(serverName, toolName string) string {
+	prefix := c.ToolPrefix(serverName)
+	if prefix == "" {
+		return SanitizeToolName(toolName)
+	}
+	prefix += c.Separator()
+	if len(toolName) > len(prefix) && strings.HasPrefix(toolName, prefix) {
+		return SanitizeToolName(toolName)
+	}
+	return SanitizeToolName(prefix + toolName)
+}
+
+// ToolWildcard returns the rule matching all tools of a server, e.g.
+// filesystem__*, or "" if its tools are unprefixed
+func (c *Config) ToolWildcard(serverName string) string {
+	prefix := c.ToolPrefix(serverName)
+	if prefix == "" {
+		return ""
+	}
+	return sanitizeChars(prefix+c.Separator()) + "*"
+}
+
+// SanitizeToolName makes name acceptable as a tool name to all providers.
+// Characters other than letters, digits, _ and - become _, and names longer
+// than MaxToolNameLength are shortened, ending in a hash of the whole name
+// to keep them apart.
+func SanitizeToolName(name string) string {
+	sanitized := sanitizeChars(name)
+	if sanitized == "" || !isLetter(rune(sanitized[0])) && sanitized[0] != '_' {
+		sanitized = "_" + sanitized
+	}
+	if len(sanitized) <= MaxToolNameLength {
+		return sanitized
+	}
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:4])
+	return sanitized[:MaxToolNameLength-len(hash)-1] + "_" + hash
+}
+
+// sanitizeChars replaces the characters providers don't accept in tool
+// names with _
+func sanitizeChars(name string) string {
+	return strings.Map(func(r rune) rune {
+		if isLetter(r) || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// isLetter reports whether r is an ASCII letter
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
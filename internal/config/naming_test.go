@@ -0,0 +1,38 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeToolName(t *testing.T) {
+	long := strings.Repeat("a", MaxToolNameLength+10)
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "valid", in: "filesystem__read_file", want: "filesystem__read_file"},
+		{name: "dashes kept", in: "web-search", want: "web-search"},
+		{name: "other characters", in: "my server.read file", want: "my_server_read_file"},
+		{name: "non-ASCII", in: "größe", want: "gr__e"},
+		{name: "leading digit", in: "1password__get", want: "_1password__get"},
+		{name: "leading dash", in: "-tool", want: "_-tool"},
+		{name: "empty", in: "", want: "_"},
+		{name: "longest accepted", in: long[:MaxToolNameLength], want: long[:MaxToolNameLength]},
+		{name: "too long", in: long, want: long[:MaxToolNameLength-9] + "_f638359d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SanitizeToolName(tt.in)
+			if got != tt.want {
+				t.Errorf("SanitizeToolName(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if len(got) > MaxToolNameLength {
+				t.Errorf("SanitizeToolName(%q) is %d long, more than %d", tt.in, len(got), MaxToolNameLength)
+			}
+		})
+	}
+}
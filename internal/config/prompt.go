@@ -0,0 +1,233 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Built-in system prompt sections
+const (
+	BuiltinSystemPrompt = "system-prompt"
+	BuiltinEnvironment  = "environment"
+	BuiltinContextFiles = "context-files"
+	BuiltinGuardrails   = "guardrails"
+	// BuiltinServerInstructions holds the instructions MCP servers give in
+	// their initialize results
+	BuiltinServerInstructions = "server-instructions"
+)
+
+// PromptSource is one fragment of the system prompt. Exactly one of File,
+// Text or Builtin must be set.
+type PromptSource struct {
+	// File is a prompt file: JSON or YAML with a systemPrompt field, or plain text
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	// Text is an inline prompt
+	Text string `json:"text,omitempty" yaml:"text,omitempty"`
+	// Builtin is a generated section: system-prompt, environment,
+	// context-files, server-instructions or guardrails
+	Builtin string `json:"builtin,omitempty" yaml:"builtin,omitempty"`
+}
+
+// DefaultPromptSources is the system prompt used when no system-prompts are
+// configured: the --system-prompt file, then the environment, the project
+// instruction files, the MCP server instructions and the tool guardrails
+var DefaultPromptSources = []PromptSource{
+	{Builtin: BuiltinSystemPrompt},
+	{Builtin: BuiltinEnvironment},
+	{Builtin: BuiltinContextFiles},
+	{Builtin: BuiltinServerInstructions},
+	{Builtin: BuiltinGuardrails},
+}
+
+// PromptOptions holds the settings the built-in sections are generated from
+type PromptOptions struct {
+	SystemPromptFile string
+	Dir              string
+	Now              time.Time
+	// NoEnvironment and NoContextFiles leave out the built-in sections even
+	// when they are listed
+	NoEnvironment  bool
+	NoContextFiles bool
+	ContextFiles   []string
+	Guardrails     []Guardrail
+	// ServerInstructions are the instructions of MCP servers, by server
+	// name; the section is empty without them
+	ServerInstructions map[string]string
+
+	// Model, Servers and Tools describe the agent for prompt templates
+	Model   string
+	Servers []string
+	Tools   []PromptTool
+}
+
+// PromptTool describes an available tool to prompt templates
+type PromptTool struct {
+	Name        string
+	Server      string
+	Description string
+}
+
+// PromptData is the data available to system prompt templates
+type PromptData struct {
+	Date    string
+	Time    string
+	OS      string
+	Arch    string
+	Cwd     string
+	Env     map[string]string
+	Model   string
+	Servers []string
+	Tools   []PromptTool
+}
+
+// BuildSystemPrompt concatenates the prompt sources in order. The system
+// prompt file and file and inline fragments are rendered as Go templates with
+// PromptData.
+func BuildSystemPrompt(sources []PromptSource, opts PromptOptions) (string, error) {
+	if len(sources) == 0 {
+		sources = DefaultPromptSources
+	}
+
+	data := newPromptData(opts)
+	var sections []string
+	for i, source := range sources {
+		section, err := loadPromptSource(source, opts, data)
+		if err != nil {
+			return "", fmt.Errorf("system-prompts[%d]: %v", i, err)
+		}
+		if strings.TrimSpace(section) != "" {
+			sections = append(sections, section)
+		}
+	}
+
+	return strings.Join(sections, "\n\n"), nil
+}
+
+// loadPromptSource returns the text of a single prompt source
+func loadPromptSource(source PromptSource, opts PromptOptions, data PromptData) (string, error) {
+	set := 0
+	for _, value := range []string{source.File, source.Text, source.Builtin} {
+		if value != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return "", fmt.Errorf("exactly one of file, text or builtin must be set")
+	}
+
+	switch {
+	case source.File != "":
+		text, err := LoadPromptFile(source.File)
+		if err != nil {
+			return "", err
+		}
+		return renderPromptTemplate(source.File, text, data)
+	case source.Text != "":
+		return renderPromptTemplate("text", source.Text, data)
+	}
+
+	switch source.Builtin {
+	case BuiltinSystemPrompt:
+		if opts.SystemPromptFile == "" {
+			return "", nil
+		}
+		text, err := LoadPromptFile(opts.SystemPromptFile)
+		if err != nil {
+			return "", err
+		}
+		return renderPromptTemplate(opts.SystemPromptFile, text, data)
+	case BuiltinEnvironment:
+		if opts.NoEnvironment {
+			return "", nil
+		}
+		return EnvironmentInfo(opts.Now, opts.Dir), nil
+	case BuiltinContextFiles:
+		if opts.NoContextFiles {
+			return "", nil
+		}
+		return LoadContextFiles(opts.Dir, opts.ContextFiles)
+	case BuiltinServerInstructions:
+		return ServerInstructionsPrompt(opts.ServerInstructions), nil
+	case BuiltinGuardrails:
+		return GuardrailsPrompt(opts.Guardrails, opts.Tools), nil
+	default:
+		return "", fmt.Errorf("unknown builtin %q (expected %s, %s, %s, %s or %s)", source.Builtin,
+			BuiltinSystemPrompt, BuiltinEnvironment, BuiltinContextFiles, BuiltinServerInstructions, BuiltinGuardrails)
+	}
+}
+
+// ServerInstructionsPrompt returns the system prompt section with the
+// instructions of MCP servers, or an empty string if there are none
+func ServerInstructionsPrompt(instructions map[string]string) string {
+	var sections []string
+	for _, server := range slices.Sorted(maps.Keys(instructions)) {
+		if text := strings.TrimSpace(instructions[server]); text != "" {
+			sections = append(sections, fmt.Sprintf("## %s\n\n%s", server, text))
+		}
+	}
+
+	if len(sections) == 0 {
+		return ""
+	}
+
+	return "# MCP server instructions\n\nThe MCP servers providing your tools gave these instructions for using them.\n\n" +
+		strings.Join(sections, "\n\n")
+}
+
+// LoadPromptFile reads a prompt file. JSON and YAML files must have a
+// systemPrompt field; any other file is used as-is.
+func LoadPromptFile(filePath string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json", ".yaml", ".yml":
+		return LoadSystemPrompt(filePath)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("error reading system prompt file: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// newPromptData returns the template data for the given options
+func newPromptData(opts PromptOptions) PromptData {
+	env := make(map[string]string)
+	for _, entry := range os.Environ() {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			env[key] = value
+		}
+	}
+
+	return PromptData{
+		Date:    opts.Now.Format("2006-01-02"),
+		Time:    opts.Now.Format("15:04"),
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+		Cwd:     opts.Dir,
+		Env:     env,
+		Model:   opts.Model,
+		Servers: opts.Servers,
+		Tools:   opts.Tools,
+	}
+}
+
+// renderPromptTemplate renders a prompt fragment as a Go template
+func renderPromptTemplate(name, text string, data PromptData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("error parsing prompt template: %v", err)
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("error rendering prompt template: %v", err)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestBuildSystemPrompt(t *testing.T) {
+	dir := t.TempDir()
+	promptFile := filepath.Join(dir, "prompt.txt")
+	if err := os.WriteFile(promptFile, []byte("From a file on {{.Date}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := PromptOptions{
+		SystemPromptFile: promptFile,
+		Dir:              dir,
+		Now:              time.Date(2025, 6, 1, 9, 30, 0, 0, time.UTC),
+		NoEnvironment:    true,
+		NoContextFiles:   true,
+		Model:            "openai:gpt-4o",
+		Servers:          []string{"filesystem"},
+		Tools: []PromptTool{
+			{Name: "filesystem__read_file", Server: "filesystem"},
+			{Name: "filesystem__write_file", Server: "filesystem"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		sources []PromptSource
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "fragments in order",
+			sources: []PromptSource{{Text: "First"}, {Text: "Second"}},
+			want:    "First\n\nSecond",
+		},
+		{
+			name:    "templates",
+			sources: []PromptSource{{Text: "{{.Date}} {{.Time}} {{.OS}}"}},
+			want:    "2025-06-01 09:30 " + runtime.GOOS,
+		},
+		{
+			name:    "missing keys are empty",
+			sources: []PromptSource{{Text: "[{{.Env.MCPHOST_TEST_UNSET}}]"}},
+			want:    "[]",
+		},
+		{
+			name:    "file",
+			sources: []PromptSource{{File: promptFile}},
+			want:    "From a file on 2025-06-01",
+		},
+		{
+			name:    "default sources",
+			sources: nil,
+			want:    "From a file on 2025-06-01",
+		},
+		{
+			name:    "agent data",
+			sources: []PromptSource{{Text: "{{.Model}} with {{range .Tools}}{{.Name}} {{end}}from {{index .Servers 0}}"}},
+			want:    "openai:gpt-4o with filesystem__read_file filesystem__write_file from filesystem",
+		},
+		{
+			name:    "empty sections left out",
+			sources: []PromptSource{{Text: "  "}, {Builtin: BuiltinEnvironment}, {Text: "Only"}},
+			want:    "Only",
+		},
+		{
+			name:    "more than one field",
+			sources: []PromptSource{{Text: "a", File: promptFile}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown builtin",
+			sources: []PromptSource{{Builtin: "weather"}},
+			wantErr: true,
+		},
+		{
+			name:    "bad template",
+			sources: []PromptSource{{Text: "{{.Date"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildSystemPrompt(tt.sources, opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("BuildSystemPrompt() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildSystemPrompt() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("BuildSystemPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
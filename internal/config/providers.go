@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ProviderDefinition configures an external LLM provider: a Go plugin
+// exporting a NewChatModel function, an executable speaking the provider shim
+// protocol, or an OpenAI-compatible proxy such as LiteLLM. The map key in the
+// config file is the provider name used in model strings, e.g. "mine" for
+// "mine:some-model". A definition with none of plugin, command and baseURL
+// only sets defaults for the built-in provider of that name.
+type ProviderDefinition struct {
+	Plugin string `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	// Options are passed to a plugin's NewChatModel
+	Options map[string]string `json:"options,omitempty" yaml:"options,omitempty"`
+
+	Command string            `json:"command,omitempty" yaml:"command,omitempty"`
+	Args    []string          `json:"args,omitempty" yaml:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+
+	BaseURL      string            `json:"baseURL,omitempty" yaml:"baseURL,omitempty"`
+	APIKeyEnv    string            `json:"apiKeyEnv,omitempty" yaml:"apiKeyEnv,omitempty"`
+	Organization string            `json:"organization,omitempty" yaml:"organization,omitempty"`
+	Project      string            `json:"project,omitempty" yaml:"project,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+
+	// Defaults for the provider's models. Temperature applies unless
+	// --temperature is given.
+	MaxTokens   int      `json:"maxTokens,omitempty" yaml:"maxTokens,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+	TopP        *float32 `json:"topP,omitempty" yaml:"topP,omitempty"`
+	// Timeout limits each request, e.g. "2m"
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// validate checks that at most one kind of provider is configured and that
+// the defaults are valid
+func (p ProviderDefinition) validate() error {
+	kinds := 0
+	for _, v := range []string{p.Plugin, p.Command, p.BaseURL} {
+		if v != "" {
+			kinds++
+		}
+	}
+	if kinds > 1 {
+		return fmt.Errorf("only one of plugin, command or baseURL may be set")
+	}
+	if p.MaxTokens < 0 {
+		return fmt.Errorf("invalid maxTokens %d: must not be negative", p.MaxTokens)
+	}
+	if p.TopP != nil && (*p.TopP < 0 || *p.TopP > 1) {
+		return fmt.Errorf("invalid topP %v: must be between 0 and 1", *p.TopP)
+	}
+	if p.Timeout != "" {
+		if _, err := time.ParseDuration(p.Timeout); err != nil {
+			return fmt.Errorf("invalid timeout %q: %v", p.Timeout, err)
+		}
+	}
+	return nil
+}
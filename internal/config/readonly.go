@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DefaultReadOnlyTools are the tool name patterns blocked in read-only mode
+// unless read-only-tools replaces them: tools that look like they write,
+// delete or run something
+var DefaultReadOnlyTools = []string{
+	"*write*", "*edit*", "*create*", "*update*", "*patch*", "*insert*", "*upload*",
+	"*delete*", "*remove*", "*drop*", "*move*", "*rename*", "*kill*",
+	"*exec*", "*run*", "*shell*", "*command*", "*install*",
+	"*commit*", "*push*", "*merge*", "*send*",
+}
+
+// ReadOnlyPatterns returns the tool name patterns blocked in read-only mode
+func (c *Config) ReadOnlyPatterns() []string {
+	if len(c.ReadOnlyTools) > 0 {
+		return c.ReadOnlyTools
+	}
+	return DefaultReadOnlyTools
+}
+
+// MatchTool returns the first of the glob patterns that matches one of a
+// tool's names, e.g. its prefixed name and its own name, ignoring case
+func MatchTool(patterns []string, names ...string) (string, bool) {
+	for _, pattern := range patterns {
+		lower := strings.ToLower(pattern)
+		for _, name := range names {
+			if matched, _ := path.Match(lower, strings.ToLower(name)); matched {
+				return pattern, true
+			}
+		}
+	}
+	return "", false
+}
+
+// validatePatterns checks tool name glob patterns
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
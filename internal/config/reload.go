@@ -0,0 +1,101 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"sort"
+)
+
+// Change is how a reloaded configuration differs from the current one,
+// split into what can be applied to a running session and what needs a
+// restart
+type Change struct {
+	// AutoApprove is set if the autoApprove list of a server that exists in
+	// both configurations changed
+	AutoApprove bool
+	// Temperature is set if the temperature changed
+	Temperature bool
+	// NewServers are the MCP servers added, sorted
+	NewServers []string
+	// Restart lists the settings that changed but only take effect after a
+	// restart, sorted, e.g. model or mcpServers.filesystem
+	Restart []string
+}
+
+// IsEmpty reports whether nothing changed
+func (c Change) IsEmpty() bool {
+	return !c.AutoApprove && !c.Temperature && len(c.NewServers) == 0 && len(c.Restart) == 0
+}
+
+// Diff compares a reloaded configuration with the current one
+func Diff(current, reloaded *Config) Change {
+	var change Change
+
+	for name, server := range reloaded.MCPServers {
+		old, ok := current.MCPServers[name]
+		if !ok {
+			change.NewServers = append(change.NewServers, name)
+			continue
+		}
+		if !slices.Equal(old.AutoApprove, server.AutoApprove) {
+			change.AutoApprove = true
+		}
+		old.AutoApprove, server.AutoApprove = nil, nil
+		if !sameJSON(old, server) {
+			change.Restart = append(change.Restart, "mcpServers."+name)
+		}
+	}
+	for name := range current.MCPServers {
+		if _, ok := reloaded.MCPServers[name]; !ok {
+			change.Restart = append(change.Restart, "mcpServers."+name)
+		}
+	}
+
+	if !reflect.DeepEqual(current.Temperature, reloaded.Temperature) {
+		change.Temperature = true
+	}
+
+	// Compare the other settings by their config file keys
+	currentSettings, reloadedSettings := settings(current), settings(reloaded)
+	for key := range reloadedSettings {
+		if _, ok := currentSettings[key]; !ok {
+			currentSettings[key] = nil
+		}
+	}
+	for key, value := range currentSettings {
+		if !reflect.DeepEqual(value, reloadedSettings[key]) {
+			change.Restart = append(change.Restart, key)
+		}
+	}
+
+	sort.Strings(change.NewServers)
+	sort.Strings(change.Restart)
+	return change
+}
+
+// settings returns the settings of a configuration other than the MCP
+// servers and the temperature, decoded from JSON by config file key
+func settings(c *Config) map[string]any {
+	rest := *c
+	rest.MCPServers = nil
+	rest.Temperature = nil
+
+	values := make(map[string]any)
+	data, err := json.Marshal(rest)
+	if err == nil {
+		err = json.Unmarshal(data, &values)
+	}
+	if err != nil {
+		return values
+	}
+	delete(values, "mcpServers")
+	return values
+}
+
+// sameJSON reports whether two values encode to the same JSON
+func sameJSON(a, b any) bool {
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(dataA) == string(dataB)
+}
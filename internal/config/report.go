@@ -0,0 +1,87 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Report formats of a query's answer
+const (
+	ReportFormatText = "text"
+	ReportFormatJSON = "json"
+)
+
+// Report is a report definition: the queries whose answers fill a report
+// template, and values used by both
+type Report struct {
+	// Vars are available to the prompts and the template as .Vars
+	Vars    map[string]any `json:"vars,omitempty" yaml:"vars,omitempty"`
+	Queries []ReportQuery  `json:"queries" yaml:"queries"`
+}
+
+// ReportQuery is a prompt run in its own conversation. Its answer is
+// available to the template, and to the prompts of later queries, as
+// .Results.<name>.
+type ReportQuery struct {
+	Name string `json:"name" yaml:"name"`
+	// Prompt is a template rendered with the vars and the earlier results
+	Prompt string `json:"prompt" yaml:"prompt"`
+	// Format is text (default) for the answer as is, or json for an answer
+	// parsed as JSON
+	Format string `json:"format,omitempty" yaml:"format,omitempty"`
+	// Schema is a JSON Schema the model is asked to follow. It implies the
+	// json format.
+	Schema map[string]any `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+// queryName is a name usable as .Results.<name> in a template
+var queryName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// LoadReport loads and validates a report definition. JSON is parsed as
+// YAML, of which it is a subset.
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading report definition: %v", err)
+	}
+
+	var report Report
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&report); err != nil {
+		return nil, fmt.Errorf("error parsing report definition %s: %v", path, err)
+	}
+
+	if len(report.Queries) == 0 {
+		return nil, fmt.Errorf("report definition %s has no queries", path)
+	}
+	seen := make(map[string]bool)
+	for i := range report.Queries {
+		query := &report.Queries[i]
+		if !queryName.MatchString(query.Name) {
+			return nil, fmt.Errorf("query %d: invalid name %q (use letters, digits and underscores)", i+1, query.Name)
+		}
+		if seen[query.Name] {
+			return nil, fmt.Errorf("query %s: duplicate name", query.Name)
+		}
+		seen[query.Name] = true
+		if query.Prompt == "" {
+			return nil, fmt.Errorf("query %s: prompt is required", query.Name)
+		}
+		switch {
+		case query.Format == "" && query.Schema != nil:
+			query.Format = ReportFormatJSON
+		case query.Format == "":
+			query.Format = ReportFormatText
+		case query.Format == ReportFormatText && query.Schema != nil:
+			return nil, fmt.Errorf("query %s: a schema needs the json format", query.Name)
+		case query.Format != ReportFormatText && query.Format != ReportFormatJSON:
+			return nil, fmt.Errorf("query %s: invalid format %q (expected text or json)", query.Name, query.Format)
+		}
+	}
+	return &report, nil
+}
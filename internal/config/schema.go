@@ -0,0 +1,280 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// extraKeys are top-level settings that aren't fields of Config, keyed in
+// lower case
+var extraKeys = make(map[string]bool)
+
+// AllowKeys lets the config file contain top-level settings that aren't
+// fields of Config, such as the options of model providers
+func AllowKeys(keys ...string) {
+	for _, key := range keys {
+		extraKeys[strings.ToLower(key)] = true
+	}
+}
+
+// ValidateFile checks the config file at path against the schema of Config:
+// unknown keys, values of the wrong type and invalid server entries. Each
+// problem is reported as path:line:column. Files that aren't YAML or JSON
+// are left to the config loader.
+func ValidateFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+	return validateSchema(path, data)
+}
+
+// validateSchema checks the config file contents data, read from path
+func validateSchema(path string, data []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+
+	s := &schemaCheck{path: path}
+	s.check(doc.Content[0], reflect.TypeOf(Config{}), "")
+	if len(s.problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid config file:\n  %s", strings.Join(s.problems, "\n  "))
+}
+
+// schemaCheck collects the problems found in a config file
+type schemaCheck struct {
+	path     string
+	problems []string
+}
+
+// report adds a problem found at node
+func (s *schemaCheck) report(node *yaml.Node, format string, args ...any) {
+	s.problems = append(s.problems, fmt.Sprintf("%s:%d:%d: %s", s.path, node.Line, node.Column, fmt.Sprintf(format, args...)))
+}
+
+// check checks node against type t. where is the node's key path, e.g.
+// mcpServers.filesystem, and empty for the top level.
+func (s *schemaCheck) check(node *yaml.Node, t reflect.Type, where string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Tag == "!!null" {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return
+	case reflect.Struct:
+		if !s.expect(node, yaml.MappingNode, where, "a mapping") {
+			return
+		}
+		s.checkFields(node, t, where)
+		if t == reflect.TypeOf(MCPServerConfig{}) {
+			s.checkServer(node, where)
+		}
+	case reflect.Map:
+		if !s.expect(node, yaml.MappingNode, where, "a mapping") {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			s.check(node.Content[i+1], t.Elem(), join(where, node.Content[i].Value))
+		}
+	case reflect.Slice:
+		if !s.expect(node, yaml.SequenceNode, where, "a list") {
+			return
+		}
+		for i, item := range node.Content {
+			s.check(item, t.Elem(), fmt.Sprintf("%s[%d]", where, i))
+		}
+	case reflect.String:
+		s.expect(node, yaml.ScalarNode, where, "a string")
+	case reflect.Bool:
+		if s.expect(node, yaml.ScalarNode, where, "true or false") {
+			if _, err := strconv.ParseBool(node.Value); err != nil {
+				s.report(node, "%s: expected true or false, got %q", where, node.Value)
+			}
+		}
+	case reflect.Int, reflect.Int64:
+		if s.expect(node, yaml.ScalarNode, where, "an integer") {
+			if _, err := strconv.ParseInt(node.Value, 0, 64); err != nil {
+				s.report(node, "%s: expected an integer, got %q", where, node.Value)
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		if s.expect(node, yaml.ScalarNode, where, "a number") {
+			if _, err := strconv.ParseFloat(node.Value, 64); err != nil {
+				s.report(node, "%s: expected a number, got %q", where, node.Value)
+			}
+		}
+	}
+}
+
+// expect reports whether node is of the given kind, and reports a problem
+// if it isn't
+func (s *schemaCheck) expect(node *yaml.Node, kind yaml.Kind, where, want string) bool {
+	if node.Kind == kind {
+		return true
+	}
+	got := map[yaml.Kind]string{
+		yaml.MappingNode:  "a mapping",
+		yaml.SequenceNode: "a list",
+		yaml.ScalarNode:   fmt.Sprintf("%q", node.Value),
+	}[node.Kind]
+	if where == "" {
+		where = "config"
+	}
+	s.report(node, "%s: expected %s, got %s", where, want, got)
+	return false
+}
+
+// checkFields checks the keys of a mapping against the yaml tags of the
+// struct type t. Keys match case-insensitively, as the config loader
+// matches them.
+func (s *schemaCheck) checkFields(node *yaml.Node, t reflect.Type, where string) {
+	fields := make(map[string]reflect.StructField)
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[strings.ToLower(name)] = field
+		names = append(names, name)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Value == "<<" {
+			// A YAML merge key brings in the keys of another mapping
+			s.check(value, t, where)
+			continue
+		}
+		field, ok := fields[strings.ToLower(key.Value)]
+		if !ok {
+			if where == "" && extraKeys[strings.ToLower(key.Value)] {
+				continue
+			}
+			s.reportUnknown(key, where, names)
+			continue
+		}
+		s.check(value, field.Type, join(where, key.Value))
+	}
+}
+
+// reportUnknown reports an unknown key, suggesting the closest known one
+func (s *schemaCheck) reportUnknown(key *yaml.Node, where string, names []string) {
+	in := ""
+	if where != "" {
+		in = " in " + where
+	}
+	if suggestion := closest(key.Value, names); suggestion != "" {
+		s.report(key, "unknown key %q%s (did you mean %q?)", key.Value, in, suggestion)
+		return
+	}
+	s.report(key, "unknown key %q%s", key.Value, in)
+}
+
+// checkServer checks the constraints between the settings of an MCP server
+func (s *schemaCheck) checkServer(node *yaml.Node, where string) {
+	var server MCPServerConfig
+	if err := node.Decode(&server); err != nil {
+		// Type problems are reported by check
+		return
+	}
+
+	switch {
+	case server.Command == "" && server.URL == "" && server.Builtin == "":
+		s.report(node, "%s: one of command, url or builtin is required", where)
+	case server.Command != "" && server.URL != "":
+		s.report(valueNode(node, "url"), "%s: command and url can't both be set", where)
+	case server.Builtin != "" && (server.Command != "" || server.URL != ""):
+		s.report(valueNode(node, "builtin"), "%s: builtin can't be combined with command or url", where)
+	}
+
+	if server.URL != "" {
+		if u, err := url.Parse(server.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			s.report(valueNode(node, "url"), "%s.url: expected an http or https URL, got %q", where, server.URL)
+		}
+	}
+	if len(server.AllowedTools) > 0 && len(server.ExcludedTools) > 0 {
+		s.report(valueNode(node, "excludedTools"), "%s: allowedTools and excludedTools are mutually exclusive", where)
+	}
+	if server.Builtin == "" && len(server.Options) > 0 {
+		s.report(valueNode(node, "options"), "%s: options are only used by builtin servers", where)
+	}
+}
+
+// valueNode returns the value of key in a mapping, or the mapping itself if
+// the key isn't there
+func valueNode(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, key) {
+			return mapping.Content[i+1]
+		}
+	}
+	return mapping
+}
+
+// join appends key to the key path where
+func join(where, key string) string {
+	if where == "" {
+		return key
+	}
+	return where + "." + key
+}
+
+// closest returns the name most like key, or "" if none is close enough to
+// be a typo of it
+func closest(key string, names []string) string {
+	key = strings.ToLower(key)
+	best, bestDistance := "", 0
+	for _, name := range names {
+		d := distance(key, strings.ToLower(name))
+		if best == "" || d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	if best == "" || bestDistance > max(2, len(key)/3) {
+		return ""
+	}
+	return best
+}
+
+// distance returns the Levenshtein distance between a and b
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,39 @@
+package config
+
+// SpeechToTextConfig configures how spoken prompts are recorded and
+// transcribed
+type SpeechToTextConfig struct {
+	// Backend transcribes recordings: openai (default), which also works
+	// with OpenAI-compatible servers at openai-url, or whisper-cpp
+	Backend string `json:"backend,omitempty" yaml:"backend,omitempty"`
+	// Model is the OpenAI transcription model (default whisper-1), or the
+	// path of the whisper.cpp model file
+	Model string `json:"model,omitempty" yaml:"model,omitempty"`
+	// Language is the ISO 639-1 code of the spoken language; empty lets
+	// the backend detect it
+	Language string `json:"language,omitempty" yaml:"language,omitempty"`
+	// Command is the whisper.cpp executable (default whisper-cli)
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+	// Record is the command recording a WAV file, with {file} standing for
+	// its path. It should stop by itself, e.g. after a pause. The default
+	// uses sox's rec and stops after two seconds of silence. A command that
+	// has written no samples after 10 seconds is stopped.
+	Record []string `json:"record,omitempty" yaml:"record,omitempty"`
+}
+
+// TextToSpeechConfig configures how answers are read aloud
+type TextToSpeechConfig struct {
+	// Backend reads text aloud: system (default), the operating system's
+	// speech synthesizer or Command, or openai, which also works with
+	// OpenAI-compatible servers at openai-url
+	Backend string `json:"backend,omitempty" yaml:"backend,omitempty"`
+	// Voice is the system or OpenAI voice; empty is the default voice
+	Voice string `json:"voice,omitempty" yaml:"voice,omitempty"`
+	// Model is the OpenAI speech model (default tts-1)
+	Model string `json:"model,omitempty" yaml:"model,omitempty"`
+	// Command replaces the system synthesizer; it reads the text on stdin
+	Command []string `json:"command,omitempty" yaml:"command,omitempty"`
+	// Play is the command playing the WAV file the openai backend returns,
+	// with {file} standing for its path
+	Play []string `json:"play,omitempty" yaml:"play,omitempty"`
+}
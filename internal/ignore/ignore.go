@@ -0,0 +1,222 @@
+// Package ignore implements .gitignore-style ignore files, so that code
+// reading files on the model's behalf skips dependencies and build output.
+package ignore
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Files are the ignore files read from every directory, in order; rules in
+// later files take precedence
+var Files = []string{".gitignore", ".mcphostignore"}
+
+// defaultPatterns are always ignored, even without an ignore file
+var defaultPatterns = []string{".git/", ".hg/", ".svn/", "node_modules/"}
+
+// rule is one pattern from an ignore file
+type rule struct {
+	// base is the slash-separated directory, relative to the matcher's root,
+	// of the ignore file the rule came from
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// Matcher decides whether paths below a root directory are ignored
+type Matcher struct {
+	root   string
+	rules  []rule
+	loaded map[string]bool
+}
+
+// New returns a matcher for root with the default patterns and the ignore
+// files in root, including .git/info/exclude
+func New(root string) *Matcher {
+	m := &Matcher{root: root, loaded: make(map[string]bool)}
+	for _, p := range defaultPatterns {
+		m.addPattern("", p)
+	}
+	m.addFile("", filepath.Join(root, ".git", "info", "exclude"))
+	m.LoadDir(root)
+	return m
+}
+
+// LoadDir adds the rules of the ignore files in dir, a directory below the
+// root. Each directory is only read once.
+func (m *Matcher) LoadDir(dir string) {
+	base, ok := m.rel(dir)
+	if !ok || m.loaded[base] {
+		return
+	}
+	m.loaded[base] = true
+	if base == "." {
+		base = ""
+	}
+	for _, name := range Files {
+		m.addFile(base, filepath.Join(dir, name))
+	}
+}
+
+// Ignored reports whether a path below the root, or any directory it is in,
+// is ignored. Paths outside the root are never ignored.
+func (m *Matcher) Ignored(name string, isDir bool) bool {
+	rel, ok := m.rel(name)
+	if !ok || rel == "." {
+		return false
+	}
+
+	segments := strings.Split(rel, "/")
+	for i := 1; i < len(segments); i++ {
+		if m.match(strings.Join(segments[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.match(rel, isDir)
+}
+
+// Walk calls fn for every regular file below dir that isn't ignored, in
+// lexical order, reading ignore files from the root down to each directory
+func (m *Matcher) Walk(dir string, fn func(file string)) error {
+	// Rules from the directories between the root and dir apply too
+	if rel, ok := m.rel(dir); ok && rel != "." {
+		current := m.root
+		for _, seg := range strings.Split(rel, "/") {
+			current = filepath.Join(current, seg)
+			m.LoadDir(current)
+		}
+	}
+
+	return filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if file != dir && m.Ignored(file, true) {
+				return filepath.SkipDir
+			}
+			m.LoadDir(file)
+			return nil
+		}
+		if d.Type().IsRegular() && !m.Ignored(file, false) {
+			fn(file)
+		}
+		return nil
+	})
+}
+
+// match applies the rules to a slash-separated path relative to the root;
+// the last matching rule decides
+func (m *Matcher) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+
+		name := rel
+		if r.base != "" {
+			if !strings.HasPrefix(rel, r.base+"/") {
+				continue
+			}
+			name = rel[len(r.base)+1:]
+		}
+
+		var matched bool
+		if r.anchored {
+			matched = MatchGlob(r.pattern, name)
+		} else {
+			matched, _ = path.Match(r.pattern, path.Base(name))
+		}
+		if matched {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// rel returns name relative to the root with forward slashes
+func (m *Matcher) rel(name string) (string, bool) {
+	rel, err := filepath.Rel(m.root, name)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// addFile adds the rules of an ignore file, if it exists
+func (m *Matcher) addFile(base, name string) {
+	file, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		m.addPattern(base, scanner.Text())
+	}
+}
+
+// addPattern parses one line of an ignore file
+func (m *Matcher) addPattern(base, line string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	r := rule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	// A slash anywhere but at the end anchors the pattern to its directory
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return
+	}
+	r.pattern = line
+	m.rules = append(m.rules, r)
+}
+
+// MatchGlob matches a slash-separated name against pattern, where ** matches
+// zero or more path segments and other segments follow path.Match
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,197 @@
+// Package jsonrepair fixes the small mistakes models make when writing JSON
+// tool arguments, such as trailing commas, single quotes and unquoted keys
+package jsonrepair
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrTruncated means the JSON ends before its strings, objects or
+	// arrays are closed, e.g. because the model's output was cut off. Its
+	// last value may be incomplete, such as a shortened path, so it isn't
+	// repaired.
+	ErrTruncated = errors.New("the JSON arguments end before they are complete")
+	// ErrInvalid means the JSON has mistakes Repair doesn't fix
+	ErrInvalid = errors.New("the JSON arguments are invalid")
+)
+
+// Repair returns s unchanged if it is valid JSON, and the repaired JSON
+// otherwise. Repairs are:
+//   - code fences around the JSON and comments in it are removed
+//   - single-quoted strings are double-quoted
+//   - unquoted keys are quoted, and True, False and None become JSON literals
+//   - trailing commas are removed
+//
+// Empty arguments become {}. JSON that was cut off returns ErrTruncated,
+// and JSON with other mistakes ErrInvalid.
+func Repair(s string) (string, error) {
+	if json.Valid([]byte(s)) {
+		return s, nil
+	}
+	if strings.TrimSpace(s) == "" {
+		return "{}", nil
+	}
+
+	repaired, err := repair(stripFences(s))
+	if err != nil {
+		return s, err
+	}
+	if !json.Valid([]byte(repaired)) {
+		return s, ErrInvalid
+	}
+	return repaired, nil
+}
+
+// stripFences removes a Markdown code fence around s
+func stripFences(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	if i := strings.Index(s, "\n"); i >= 0 {
+		s = s[i+1:]
+	} else {
+		s = strings.TrimLeft(s, "`")
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
+
+// repair rewrites s token by token
+func repair(s string) (string, error) {
+	var out strings.Builder
+	var open []rune // the brackets still open
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '"' || c == '\'':
+			end := writeString(&out, runes, i)
+			if end == len(runes) {
+				return "", ErrTruncated
+			}
+			i = end
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			for i += 2; i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/'); i++ {
+			}
+			if i == len(runes) {
+				return "", ErrTruncated
+			}
+			i++
+		case c == '{' || c == '[':
+			open = append(open, c)
+			out.WriteRune(c)
+		case c == '}' || c == ']':
+			trimTrailingComma(&out)
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			out.WriteRune(c)
+		case c == '-' || unicode.IsDigit(c):
+			// Numbers are copied as they are, exponents included
+			for i+1 < len(runes) && strings.ContainsRune("0123456789.eE+-", runes[i+1]) {
+				out.WriteRune(c)
+				i++
+				c = runes[i]
+			}
+			out.WriteRune(c)
+		case c == '_' || c == '$' || unicode.IsLetter(c):
+			start := i
+			for i+1 < len(runes) && (runes[i+1] == '_' || runes[i+1] == '$' || unicode.IsLetter(runes[i+1]) || unicode.IsDigit(runes[i+1])) {
+				i++
+			}
+			out.WriteString(bareWord(string(runes[start:i+1]), isKey(runes, i+1)))
+		default:
+			out.WriteRune(c)
+		}
+	}
+
+	if len(open) > 0 {
+		return "", ErrTruncated
+	}
+	return out.String(), nil
+}
+
+// writeString writes the string starting at runes[start] double-quoted and
+// returns the index of its closing quote, or len(runes) for a string left
+// open.
+func writeString(out *strings.Builder, runes []rune, start int) int {
+	quote := runes[start]
+	out.WriteRune('"')
+	i := start + 1
+	for ; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\\' && i+1 < len(runes):
+			// \' isn't a JSON escape
+			if runes[i+1] == '\'' {
+				out.WriteRune('\'')
+			} else {
+				out.WriteRune(c)
+				out.WriteRune(runes[i+1])
+			}
+			i++
+		case c == quote:
+			out.WriteRune('"')
+			return i
+		case c == '"':
+			out.WriteString(`\"`)
+		case c == '\n':
+			out.WriteString(`\n`)
+		case c == '\t':
+			out.WriteString(`\t`)
+		case c == '\r':
+			out.WriteString(`\r`)
+		default:
+			out.WriteRune(c)
+		}
+	}
+	return i
+}
+
+// isKey reports whether the next character after whitespace is a colon
+func isKey(runes []rune, i int) bool {
+	for ; i < len(runes); i++ {
+		if !unicode.IsSpace(runes[i]) {
+			return runes[i] == ':'
+		}
+	}
+	return false
+}
+
+// bareWord returns an unquoted word as JSON: a key is quoted and a value is
+// a literal, or a string if it isn't one
+func bareWord(word string, key bool) string {
+	if key {
+		return `"` + word + `"`
+	}
+	switch word {
+	case "true", "True", "TRUE":
+		return "true"
+	case "false", "False", "FALSE":
+		return "false"
+	case "null", "None", "NULL", "nil", "undefined":
+		return "null"
+	}
+	quoted, _ := json.Marshal(word)
+	return string(quoted)
+}
+
+// trimTrailingComma removes a comma, and the whitespace after it, from the
+// end of out
+func trimTrailingComma(out *strings.Builder) {
+	s := out.String()
+	trimmed := strings.TrimRightFunc(s, unicode.IsSpace)
+	if strings.HasSuffix(trimmed, ",") {
+		out.Reset()
+		out.WriteString(strings.TrimSuffix(trimmed, ","))
+	}
+}
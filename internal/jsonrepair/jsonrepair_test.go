@@ -0,0 +1,52 @@
+package jsonrepair
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr error
+	}{
+		{name: "valid", in: `{"path": "a.txt"}`, want: `{"path": "a.txt"}`},
+		{name: "empty", in: "  ", want: `{}`},
+		{name: "code fence", in: "```json\n{\"a\": 1}\n```", want: `{"a": 1}`},
+		{name: "line comment", in: "{\"a\": 1 // one\n}", want: `{"a": 1 }`},
+		{name: "block comment", in: `{"a": /* one */ 1}`, want: `{"a":  1}`},
+		{name: "single quotes", in: `{'path': 'it\'s "here"'}`, want: `{"path": "it's \"here\""}`},
+		{name: "unquoted keys", in: `{path: "a.txt", max_lines: 10}`, want: `{"path": "a.txt", "max_lines": 10}`},
+		{name: "Python literals", in: `{"a": True, "b": False, "c": None}`, want: `{"a": true, "b": false, "c": null}`},
+		{name: "bare word value", in: `{"mode": fast}`, want: `{"mode": "fast"}`},
+		{name: "trailing commas", in: `{"a": [1, 2, ], "b": 3, }`, want: `{"a": [1, 2], "b": 3}`},
+		{name: "newline in string", in: "{'a': 'one\ntwo'}", want: `{"a": "one\ntwo"}`},
+		{name: "string cut off", in: `{"path": "/home/user/pro`, wantErr: ErrTruncated},
+		{name: "object cut off", in: `{"command": "ls", "args": ["-l"]`, wantErr: ErrTruncated},
+		{name: "array cut off", in: `{"paths": ["a.txt", "b.txt",`, wantErr: ErrTruncated},
+		{name: "value missing", in: `{"path":`, wantErr: ErrTruncated},
+		{name: "escape cut off", in: `{"a": "b\`, wantErr: ErrTruncated},
+		{name: "comment cut off", in: `{"a": 1 /* more`, wantErr: ErrTruncated},
+		{name: "not repairable", in: `{"a" 1}`, wantErr: ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Repair(tt.in)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Repair(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+				}
+				if got != tt.in {
+					t.Errorf("Repair(%q) = %q, want the input unchanged", tt.in, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Repair(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// Package language provides lightweight detection of the natural language a
+// response is written in, so answers in the wrong language can be retried
+package language
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// names maps the supported ISO 639-1 codes to English language names
+var names = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"el": "Greek",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"he": "Hebrew",
+	"hi": "Hindi",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"th": "Thai",
+	"uk": "Ukrainian",
+	"zh": "Chinese",
+}
+
+// stopwords are frequent words used to tell Latin-script languages apart
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "to", "of", "that", "it", "for", "with", "this", "you", "was", "be", "on", "not", "have", "can", "will", "which"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "mit", "sie", "ich", "auf", "für", "den", "dem", "von", "sich", "auch", "wird", "sind", "es", "im"},
+	"fr": {"le", "la", "les", "et", "est", "des", "une", "un", "du", "pour", "que", "qui", "dans", "pas", "sur", "avec", "vous", "ce", "il", "sont", "au", "ne"},
+	"es": {"el", "la", "los", "las", "y", "es", "que", "de", "en", "un", "una", "por", "con", "para", "no", "se", "del", "está", "son", "como", "lo"},
+	"it": {"il", "lo", "la", "gli", "le", "e", "è", "che", "di", "un", "una", "per", "con", "non", "sono", "del", "della", "nel", "si", "come"},
+	"pt": {"o", "a", "os", "as", "e", "é", "que", "de", "um", "uma", "para", "com", "não", "do", "da", "em", "são", "no", "na", "se"},
+	"nl": {"de", "het", "een", "en", "is", "van", "dat", "niet", "op", "te", "met", "zijn", "voor", "er", "ik", "je", "die", "wordt", "ook", "naar"},
+}
+
+// minWords is the number of words below which Latin-script text is too short
+// to classify
+const minWords = 8
+
+var (
+	codeBlockPattern  = regexp.MustCompile("(?s)```.*?```")
+	inlineCodePattern = regexp.MustCompile("`[^`\n]*`")
+	urlPattern        = regexp.MustCompile(`https?://\S+`)
+)
+
+// Name returns the English name of a language code, or the code itself if
+// it isn't known
+func Name(code string) string {
+	if name, ok := names[strings.ToLower(code)]; ok {
+		return name
+	}
+	return code
+}
+
+// Supported reports whether Detect can recognize the language
+func Supported(code string) bool {
+	_, ok := names[strings.ToLower(code)]
+	return ok
+}
+
+// Directive returns the system prompt instruction to answer in a language
+func Directive(code string) string {
+	return "# Response language\n\nAlways write your responses in " + Name(code) +
+		", regardless of the language of the user's messages or of tool results. " +
+		"Code, identifiers and quoted text may stay in their original language."
+}
+
+// Retry returns the instruction sent after a response in the wrong language
+func Retry(code string) string {
+	return "Your previous response was not written in " + Name(code) +
+		". Write the complete response again, in " + Name(code) + " only."
+}
+
+// Matches reports whether text is written in the language. Text that can't be
+// classified, and languages Detect doesn't know, always match.
+func Matches(text, code string) bool {
+	code = strings.ToLower(code)
+	if !Supported(code) {
+		return true
+	}
+	detected := Detect(text)
+	return detected == "" || detected == code
+}
+
+// Detect guesses the language of text, ignoring code and URLs. It returns an
+// ISO 639-1 code, or "" if the text is too short or ambiguous.
+func Detect(text string) string {
+	text = codeBlockPattern.ReplaceAllString(text, " ")
+	text = inlineCodePattern.ReplaceAllString(text, " ")
+	text = urlPattern.ReplaceAllString(text, " ")
+
+	if code := detectScript(text); code != "" {
+		return code
+	}
+	return detectLatin(text)
+}
+
+// detectScript identifies languages by their writing system, returning "" if
+// most letters are Latin
+func detectScript(text string) string {
+	counts := make(map[string]int)
+	letters := 0
+	ukrainian := false
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			counts["ja"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.Is(unicode.Han, r):
+			counts["zh"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["ru"]++
+			ukrainian = ukrainian || strings.ContainsRune("іїєґІЇЄҐ", r)
+		case unicode.Is(unicode.Greek, r):
+			counts["el"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			counts["he"]++
+		case unicode.Is(unicode.Devanagari, r):
+			counts["hi"]++
+		case unicode.Is(unicode.Thai, r):
+			counts["th"]++
+		}
+	}
+
+	nonLatin := 0
+	for _, count := range counts {
+		nonLatin += count
+	}
+	if letters == 0 || nonLatin*2 < letters {
+		return ""
+	}
+
+	// Japanese mixes kana with Han characters
+	if counts["ja"] > 0 {
+		return "ja"
+	}
+	best := ""
+	for code, count := range counts {
+		if best == "" || count > counts[best] || count == counts[best] && code < best {
+			best = code
+		}
+	}
+	if best == "ru" && ukrainian {
+		return "uk"
+	}
+	return best
+}
+
+// detectLatin identifies Latin-script languages by counting stopwords
+func detectLatin(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) < minWords {
+		return ""
+	}
+
+	scores := make(map[string]int)
+	for _, word := range words {
+		for code, list := range stopwords {
+			for _, stopword := range list {
+				if word == stopword {
+					scores[code]++
+					break
+				}
+			}
+		}
+	}
+
+	best, second := "", 0
+	for code, score := range scores {
+		if best == "" || score > scores[best] || score == scores[best] && code < best {
+			if best != "" {
+				second = max(second, scores[best])
+			}
+			best = code
+		} else {
+			second = max(second, score)
+		}
+	}
+
+	// Require a clear winner among a reasonable number of stopwords
+	if best == "" || scores[best] < 3 || scores[best]*4 < second*5 {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// providerAPI is the HTTP API of a provider, used to look up and list models
+// without going through a chat model
+type providerAPI struct {
+	provider string
+	baseURL  string
+	header   http.Header
+	client   *http.Client
+	// keyHint names where the API key is set
+	keyHint string
+	// offlineHint says what to do when the provider can't be reached
+	offlineHint string
+}
+
+func anthropicAPI(config *ProviderConfig) (*providerAPI, error) {
+	apiKey := config.anthropicAPIKey()
+	if apiKey == "" {
+		return nil, fmt.Errorf("Anthropic API key not provided. Use --anthropic-api-key flag or ANTHROPIC_API_KEY environment variable")
+	}
+	api := &providerAPI{
+		provider: "Anthropic",
+		baseURL:  "https://api.anthropic.com",
+		header:   http.Header{"X-Api-Key": {apiKey}, "Anthropic-Version": {"2023-06-01"}},
+		client:   config.HTTPClient(),
+		keyHint:  "--anthropic-api-key or ANTHROPIC_API_KEY",
+	}
+	if config.AnthropicBaseURL != "" {
+		api.baseURL = config.AnthropicBaseURL
+	}
+	return api, nil
+}
+
+func openAIAPI(config *ProviderConfig) (*providerAPI, error) {
+	apiKey := config.openAIAPIKey()
+	if apiKey == "" {
+		return nil, fmt.Errorf("OpenAI API key not provided. Use --openai-api-key flag or OPENAI_API_KEY environment variable")
+	}
+	api := &providerAPI{
+		provider: "OpenAI",
+		baseURL:  "https://api.openai.com/v1",
+		header:   http.Header{"Authorization": {"Bearer " + apiKey}},
+		client:   config.HTTPClient(),
+		keyHint:  "--openai-api-key or OPENAI_API_KEY",
+	}
+	if config.OpenAIBaseURL != "" {
+		api.baseURL = config.OpenAIBaseURL
+	}
+	return api, nil
+}
+
+func googleAPI(config *ProviderConfig) (*providerAPI, error) {
+	apiKey := config.googleAPIKey()
+	if apiKey == "" {
+		return nil, fmt.Errorf("Google API key not provided. Use --google-api-key flag or GOOGLE_API_KEY/GEMINI_API_KEY environment variable")
+	}
+	return &providerAPI{
+		provider: "Google",
+		baseURL:  "https://generativelanguage.googleapis.com/v1beta",
+		header:   http.Header{"X-Goog-Api-Key": {apiKey}},
+		client:   config.HTTPClient(),
+		keyHint:  "--google-api-key or GOOGLE_API_KEY/GEMINI_API_KEY",
+	}, nil
+}
+
+func ollamaAPI(config *ProviderConfig) *providerAPI {
+	host := config.ollamaHost()
+	return &providerAPI{
+		provider:    "Ollama",
+		baseURL:     host,
+		header:      http.Header{},
+		client:      config.HTTPClient(),
+		offlineHint: fmt.Sprintf("is Ollama running at %s? Start it with: ollama serve", host),
+	}
+}
+
+// do sends a request with an optional JSON body and decodes the JSON
+// response into out, if it isn't nil. A failure is turned into an error
+// saying how to fix it; notFound, if set, is the error for a 404 response.
+func (api *providerAPI) do(ctx context.Context, method, path string, body, out any, notFound string) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(api.baseURL, "/")+"/"+path, reqBody)
+	if err != nil {
+		return err
+	}
+	for k, v := range api.header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := api.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var netErr *net.OpError
+		if api.offlineHint != "" && errors.As(err, &netErr) {
+			return fmt.Errorf("cannot reach %s: %v; %s", api.provider, err, api.offlineHint)
+		}
+		return fmt.Errorf("cannot reach %s: %v", api.provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if out == nil {
+			return nil
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("invalid response from %s: %v", api.provider, err)
+		}
+		return nil
+	}
+
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden ||
+		// Google answers an invalid key with a bad request
+		(resp.StatusCode == http.StatusBadRequest && bytes.Contains(detail, []byte("API_KEY_INVALID"))):
+		msg := fmt.Sprintf("%s rejected the API key (HTTP %d)", api.provider, resp.StatusCode)
+		if api.keyHint != "" {
+			msg += "; check " + api.keyHint
+		}
+		return errors.New(msg)
+	case resp.StatusCode == http.StatusNotFound && notFound != "":
+		return errors.New(notFound)
+	default:
+		return fmt.Errorf("%s returned HTTP %d: %s", api.provider, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+}
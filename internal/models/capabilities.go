@@ -0,0 +1,109 @@
+package models
+
+import (
+	"strings"
+)
+
+// Capabilities describes what a model supports
+type Capabilities struct {
+	ToolCalling bool
+	Vision      bool
+	// ContextWindow is the model's context size in tokens
+	ContextWindow int
+}
+
+// capability is an entry of the capability table. Prefix matches model
+// names it starts, up to a "-" or ":" or the end of the name, so that
+// "gpt-4o" matches "gpt-4o-mini" but "llama3" doesn't match "llama3.1".
+type capability struct {
+	provider string
+	prefix   string
+	Capabilities
+}
+
+// capabilityTable lists the capabilities of known models. Ollama entries
+// give the model's own context size, which is more than Ollama uses unless
+// its num_ctx is raised.
+var capabilityTable = []capability{
+	{"anthropic", "claude", Capabilities{ToolCalling: true, Vision: true, ContextWindow: 200_000}},
+
+	{"openai", "gpt-4.1", Capabilities{ToolCalling: true, Vision: true, ContextWindow: 1_047_576}},
+	{"openai", "gpt-4o", Capabilities{ToolCalling: true, Vision: true, ContextWindow: 128_000}},
+	{"openai", "gpt-4-turbo", Capabilities{ToolCalling: true, Vision: true, ContextWindow: 128_000}},
+	{"openai", "gpt-4", Capabilities{ToolCalling: true, ContextWindow: 8_192}},
+	{"openai", "gpt-3.5-turbo", Capabilities{ToolCalling: true, ContextWindow: 16_385}},
+	{"openai", "o1", Capabilities{ToolCalling: true, Vision: true, ContextWindow: 200_000}},
+	{"openai", "o1-mini", Capabilities{ContextWindow: 128_000}},
+	{"openai", "o1-preview", Capabilities{ContextWindow: 128_000}},
+	{"openai", "o3", Capabilities{ToolCalling: true, Vision: true, ContextWindow: 200_000}},
+	{"openai", "o3-mini", Capabilities{ToolCalling: true, ContextWindow: 200_000}},
+	{"openai", "o4-mini", Capabilities{ToolCalling: true, Vision: true, ContextWindow: 200_000}},
+
+	{"google", "gemini-2.5", Capabilities{ToolCalling: true, Vision: true, ContextWindow: 1_048_576}},
+	{"google", "gemini-2.0", Capabilities{ToolCalling: true, Vision: true, ContextWindow: 1_048_576}},
+	{"google", "gemini-1.5-pro", Capabilities{ToolCalling: true, Vision: true, ContextWindow: 2_097_152}},
+	{"google", "gemini-1.5-flash", Capabilities{ToolCalling: true, Vision: true, ContextWindow: 1_048_576}},
+
+	{"ollama", "llama3.1", Capabilities{ToolCalling: true, ContextWindow: 131_072}},
+	{"ollama", "llama3.2", Capabilities{ToolCalling: true, ContextWindow: 131_072}},
+	{"ollama", "llama3.2-vision", Capabilities{Vision: true, ContextWindow: 131_072}},
+	{"ollama", "llama3.3", Capabilities{ToolCalling: true, ContextWindow: 131_072}},
+	{"ollama", "llama3", Capabilities{ContextWindow: 8_192}},
+	{"ollama", "llama2", Capabilities{ContextWindow: 4_096}},
+	{"ollama", "qwen2.5", Capabilities{ToolCalling: true, ContextWindow: 32_768}},
+	{"ollama", "qwen3", Capabilities{ToolCalling: true, ContextWindow: 40_960}},
+	{"ollama", "mistral", Capabilities{ToolCalling: true, ContextWindow: 32_768}},
+	{"ollama", "mistral-nemo", Capabilities{ToolCalling: true, ContextWindow: 131_072}},
+	{"ollama", "mixtral", Capabilities{ToolCalling: true, ContextWindow: 32_768}},
+	{"ollama", "command-r", Capabilities{ToolCalling: true, ContextWindow: 131_072}},
+	{"ollama", "gemma", Capabilities{ContextWindow: 8_192}},
+	{"ollama", "gemma2", Capabilities{ContextWindow: 8_192}},
+	{"ollama", "gemma3", Capabilities{Vision: true, ContextWindow: 131_072}},
+	{"ollama", "phi3", Capabilities{ContextWindow: 131_072}},
+	{"ollama", "phi4", Capabilities{ContextWindow: 16_384}},
+	{"ollama", "deepseek-r1", Capabilities{ContextWindow: 131_072}},
+	{"ollama", "codellama", Capabilities{ContextWindow: 16_384}},
+	{"ollama", "llava", Capabilities{Vision: true, ContextWindow: 4_096}},
+}
+
+// LookupCapabilities returns the capabilities of a "provider:model" model
+// string, and false if the model isn't in the capability table
+func LookupCapabilities(modelString string) (Capabilities, bool) {
+	parts := strings.SplitN(modelString, ":", 2)
+	if len(parts) < 2 {
+		return Capabilities{}, false
+	}
+	provider, name := parts[0], strings.ToLower(parts[1])
+	// Ollama model names may carry a tag, as in llama3.1:8b
+	if provider == "ollama" {
+		name, _, _ = strings.Cut(name, ":")
+	}
+	// Azure deployments are usually named after their OpenAI model
+	if provider == "azure" {
+		provider = "openai"
+	}
+
+	var best *capability
+	for i, c := range capabilityTable {
+		if c.provider != provider || !matchesPrefix(name, c.prefix) {
+			continue
+		}
+		if best == nil || len(c.prefix) > len(best.prefix) {
+			best = &capabilityTable[i]
+		}
+	}
+	if best == nil {
+		return Capabilities{}, false
+	}
+	return best.Capabilities, true
+}
+
+// matchesPrefix reports whether name starts with prefix followed by "-",
+// ":" or the end of name
+func matchesPrefix(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	rest := name[len(prefix):]
+	return rest == "" || rest[0] == '-' || rest[0] == ':'
+}
@@ -3,39 +3,65 @@ package models
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
 	"strings"
 
 	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/schema"
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mark3labs/mcphost/internal/jsonrepair"
 	"google.golang.org/genai"
 )
 
-// GeminiChatModel implements the eino ToolCallingChatModel interface for Google Gemini
+// GeminiChatModel implements the eino ToolCallingChatModel interface for Google Gemini.
+// It is the only Gemini implementation: CreateProvider builds it for every
+// google: model, in all modes.
 type GeminiChatModel struct {
 	client    *genai.Client
 	model     string
 	tools     []*genai.Tool
 	origTools []*schema.ToolInfo
+
+	// temperature is nil to use the model default
+	temperature *float32
+	// maxTokens limits the length of responses; 0 for the model default
+	maxTokens int32
+	// topP is nil to use the model default
+	topP *float32
+
+	// toolChoice is auto, any, none or a tool name; empty means auto
+	toolChoice string
+	// toolSeparator is the separator between tool prefixes and names
+	toolSeparator string
+
+	// nativeSearch grounds answers with Google Search
+	nativeSearch bool
+
+	// repairToolArgs repairs malformed JSON in tool call arguments
+	repairToolArgs bool
 }
 
-func NewGeminiChatModel(ctx context.Context, apiKey, modelName string) (*GeminiChatModel, error) {
+func NewGeminiChatModel(ctx context.Context, apiKey, modelName string, temperature *float32, httpClient *http.Client) (*GeminiChatModel, error) {
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey: apiKey,
+		APIKey:     apiKey,
+		HTTPClient: httpClient,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
 	}
 
 	return &GeminiChatModel{
-		client: client,
-		model:  modelName,
+		client:      client,
+		model:       modelName,
+		temperature: temperature,
 	}, nil
 }
 
 func (g *GeminiChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
-	chat, err := g.initChat(ctx, opts...)
+	chat, err := g.initChat(ctx, input, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +84,7 @@ func (g *GeminiChatModel) Generate(ctx context.Context, input []*schema.Message,
 }
 
 func (g *GeminiChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
-	chat, err := g.initChat(ctx, opts...)
+	chat, err := g.initChat(ctx, input, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -139,7 +165,7 @@ func (g *GeminiChatModel) IsCallbacksEnabled() bool {
 	return false
 }
 
-func (g *GeminiChatModel) initChat(ctx context.Context, opts ...model.Option) (*genai.Chat, error) {
+func (g *GeminiChatModel) initChat(ctx context.Context, input []*schema.Message, opts ...model.Option) (*genai.Chat, error) {
 	// Process options to get tools
 	commonOptions := model.GetCommonOptions(&model.Options{}, opts...)
 	
@@ -161,16 +187,72 @@ func (g *GeminiChatModel) initChat(ctx context.Context, opts ...model.Option) (*
 		config = &genai.GenerateContentConfig{
 			Tools: tools,
 			ToolConfig: &genai.ToolConfig{
-				FunctionCallingConfig: &genai.FunctionCallingConfig{
-					Mode: genai.FunctionCallingConfigModeAuto,
-				},
+				FunctionCallingConfig: g.functionCallingConfig(tools, input),
 			},
 		}
 	}
+	if g.nativeSearch {
+		if config == nil {
+			config = &genai.GenerateContentConfig{}
+		}
+		config.Tools = append(config.Tools, &genai.Tool{GoogleSearch: &genai.GoogleSearch{}})
+	}
+	temperature := g.temperature
+	if commonOptions.Temperature != nil {
+		temperature = commonOptions.Temperature
+	}
+	if temperature != nil {
+		if config == nil {
+			config = &genai.GenerateContentConfig{}
+		}
+		config.Temperature = temperature
+	}
+	if g.maxTokens > 0 || g.topP != nil {
+		if config == nil {
+			config = &genai.GenerateContentConfig{}
+		}
+		config.MaxOutputTokens = g.maxTokens
+		config.TopP = g.topP
+	}
+
+	// The system prompt goes in the system instruction rather than being
+	// sent as text like the rest of the conversation
+	if prompt, _ := splitSystemPrompt(input); prompt != "" {
+		if config == nil {
+			config = &genai.GenerateContentConfig{}
+		}
+		config.SystemInstruction = genai.NewContentFromText(prompt, genai.RoleUser)
+	}
 
 	return g.client.Chats.Create(ctx, g.model, config, nil)
 }
 
+// functionCallingConfig applies the tool choice. Like the other providers, a
+// forced choice only applies to the first model call of a turn.
+func (g *GeminiChatModel) functionCallingConfig(tools []*genai.Tool, input []*schema.Message) *genai.FunctionCallingConfig {
+	cfg := &genai.FunctionCallingConfig{Mode: genai.FunctionCallingConfigModeAuto}
+	if g.toolChoice == ToolChoiceNone {
+		cfg.Mode = genai.FunctionCallingConfigModeNone
+		return cfg
+	}
+	if g.toolChoice == "" || g.toolChoice == ToolChoiceAuto ||
+		(len(input) > 0 && input[len(input)-1].Role == schema.Tool) {
+		return cfg
+	}
+
+	cfg.Mode = genai.FunctionCallingConfigModeAny
+	if g.toolChoice != ToolChoiceAny {
+		var names []string
+		for _, tool := range tools {
+			for _, decl := range tool.FunctionDeclarations {
+				names = append(names, decl.Name)
+			}
+		}
+		cfg.AllowedFunctionNames = []string{resolveToolName(names, g.toolChoice, g.toolSeparator)}
+	}
+	return cfg
+}
+
 func (g *GeminiChatModel) convertTools(tools []*schema.ToolInfo) ([]*genai.Tool, error) {
 	if len(tools) == 0 {
 		return nil, nil
@@ -281,6 +363,17 @@ func (g *GeminiChatModel) convertMessagesToParts(messages []*schema.Message) ([]
 			for _, call := range message.ToolCalls {
 				args := make(map[string]any)
 				err := json.Unmarshal([]byte(call.Function.Arguments), &args)
+				if err != nil && g.repairToolArgs {
+					repaired, repairErr := jsonrepair.Repair(call.Function.Arguments)
+					switch {
+					case repairErr == nil:
+						log.Printf("repaired the JSON arguments of %s: %s", call.Function.Name, repaired)
+						err = json.Unmarshal([]byte(repaired), &args)
+					case errors.Is(repairErr, jsonrepair.ErrTruncated):
+						// The call wasn't run and its result asks for it again
+						args, err = map[string]any{}, nil
+					}
+				}
 				if err != nil {
 					return nil, fmt.Errorf("unmarshal tool call arguments failed: %w", err)
 				}
@@ -288,13 +381,12 @@ func (g *GeminiChatModel) convertMessagesToParts(messages []*schema.Message) ([]
 			}
 		}
 
+		if message.Role == schema.System {
+			// Sent as the system instruction
+			continue
+		}
 		if message.Role == schema.Tool {
-			response := make(map[string]any)
-			err := json.Unmarshal([]byte(message.Content), &response)
-			if err != nil {
-				return nil, fmt.Errorf("unmarshal tool response failed: %w", err)
-			}
-			parts = append(parts, *genai.NewPartFromFunctionResponse(message.ToolCallID, response))
+			parts = append(parts, *genai.NewPartFromFunctionResponse(message.ToolCallID, toolResponse(message.Content)))
 		} else if message.Content != "" {
 			parts = append(parts, *genai.NewPartFromText(message.Content))
 		}
@@ -303,6 +395,21 @@ func (g *GeminiChatModel) convertMessagesToParts(messages []*schema.Message) ([]
 	return parts, nil
 }
 
+// toolResponse returns a tool result as the JSON object Gemini expects.
+// Results that aren't JSON objects, such as the plain text most MCP tools
+// return, are wrapped as {"result": ...}.
+func toolResponse(content string) map[string]any {
+	response := make(map[string]any)
+	if err := json.Unmarshal([]byte(content), &response); err == nil && response != nil {
+		return response
+	}
+	var value any
+	if err := json.Unmarshal([]byte(content), &value); err == nil {
+		return map[string]any{"result": value}
+	}
+	return map[string]any{"result": content}
+}
+
 func (g *GeminiChatModel) convertResponse(resp *genai.GenerateContentResponse) (*schema.Message, error) {
 	if len(resp.Candidates) == 0 {
 		return nil, fmt.Errorf("no candidates in response")
@@ -338,5 +445,33 @@ func (g *GeminiChatModel) convertResponse(resp *genai.GenerateContentResponse) (
 		}
 	}
 
+	// List the pages a grounded answer is based on, so they can be cited
+	if sources := groundingSources(candidate.GroundingMetadata); sources != "" {
+		message.Content += sources
+	}
+
 	return message, nil
-}
\ No newline at end of file
+}
+
+// groundingSources lists the web pages of Google Search grounding as a
+// Markdown list, or returns "" if the answer wasn't grounded
+func groundingSources(metadata *genai.GroundingMetadata) string {
+	if metadata == nil {
+		return ""
+	}
+	var lines []string
+	for _, chunk := range metadata.GroundingChunks {
+		if chunk == nil || chunk.Web == nil || chunk.Web.URI == "" {
+			continue
+		}
+		title := chunk.Web.Title
+		if title == "" {
+			title = chunk.Web.URI
+		}
+		lines = append(lines, fmt.Sprintf("%d. [%s](%s)", len(lines)+1, title, chunk.Web.URI))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n\nSources:\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ModelInfo describes a model a provider offers
+type ModelInfo struct {
+	Name string
+	// ContextWindow is the context size in tokens reported by the provider,
+	// or 0 if it doesn't report one
+	ContextWindow int
+}
+
+// ListModels returns the models a provider offers, sorted by name
+func ListModels(ctx context.Context, config *ProviderConfig, providerName string) ([]ModelInfo, error) {
+	provider, ok := Lookup(providerName)
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider: %s (available: %s)", providerName, strings.Join(providerNames(), ", "))
+	}
+	if provider.List == nil {
+		return nil, fmt.Errorf("provider %s can't list its models", providerName)
+	}
+
+	config, _, err := config.prepare(provider.Name)
+	if err != nil {
+		return nil, err
+	}
+	list, err := provider.List(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+func listAnthropicModels(ctx context.Context, config *ProviderConfig) ([]ModelInfo, error) {
+	api, err := anthropicAPI(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []ModelInfo
+	query := url.Values{"limit": {"1000"}}
+	for {
+		var page struct {
+			Data []struct {
+				ID string `json:"id"`
+			} `json:"data"`
+			HasMore bool   `json:"has_more"`
+			LastID  string `json:"last_id"`
+		}
+		if err := api.do(ctx, http.MethodGet, "v1/models?"+query.Encode(), nil, &page, ""); err != nil {
+			return nil, err
+		}
+		for _, m := range page.Data {
+			list = append(list, ModelInfo{Name: m.ID})
+		}
+		if !page.HasMore || page.LastID == "" {
+			return list, nil
+		}
+		query.Set("after_id", page.LastID)
+	}
+}
+
+func listOpenAIModels(ctx context.Context, config *ProviderConfig) ([]ModelInfo, error) {
+	api, err := openAIAPI(config)
+	if err != nil {
+		return nil, err
+	}
+	return listOpenAICompatibleModels(ctx, api)
+}
+
+// listOpenAICompatibleModels lists the models of an OpenAI-compatible API
+func listOpenAICompatibleModels(ctx context.Context, api *providerAPI) ([]ModelInfo, error) {
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := api.do(ctx, http.MethodGet, "models", nil, &resp, ""); err != nil {
+		return nil, err
+	}
+	list := make([]ModelInfo, 0, len(resp.Data))
+	for _, m := range resp.Data {
+		list = append(list, ModelInfo{Name: m.ID})
+	}
+	return list, nil
+}
+
+func listGoogleModels(ctx context.Context, config *ProviderConfig) ([]ModelInfo, error) {
+	api, err := googleAPI(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []ModelInfo
+	query := url.Values{"pageSize": {"1000"}}
+	for {
+		var page struct {
+			Models []struct {
+				Name                       string   `json:"name"`
+				InputTokenLimit            int      `json:"inputTokenLimit"`
+				SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+			} `json:"models"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := api.do(ctx, http.MethodGet, "models?"+query.Encode(), nil, &page, ""); err != nil {
+			return nil, err
+		}
+		// Embedding and other models that can't chat are left out
+		for _, m := range page.Models {
+			if !slices.Contains(m.SupportedGenerationMethods, "generateContent") {
+				continue
+			}
+			list = append(list, ModelInfo{Name: strings.TrimPrefix(m.Name, "models/"), ContextWindow: m.InputTokenLimit})
+		}
+		if page.NextPageToken == "" {
+			return list, nil
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+}
+
+func listOllamaModels(ctx context.Context, config *ProviderConfig) ([]ModelInfo, error) {
+	var resp struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := ollamaAPI(config).do(ctx, http.MethodGet, "api/tags", nil, &resp, ""); err != nil {
+		return nil, err
+	}
+	list := make([]ModelInfo, 0, len(resp.Models))
+	for _, m := range resp.Models {
+		list = append(list, ModelInfo{Name: m.Name})
+	}
+	return list, nil
+}
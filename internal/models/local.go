@@ -0,0 +1,158 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+)
+
+// localServer is an OpenAI-compatible server running a model on the user's
+// machine, such as LM Studio or llama.cpp's llama-server
+type localServer struct {
+	name    string
+	display string
+	host    ProviderOption
+	// defaultURL is the server's address when host isn't set
+	defaultURL string
+	// apiKey is the option holding the key the server requires, if any
+	apiKey *ProviderOption
+	// singleModel servers answer with the model they were started with,
+	// whatever model a request names
+	singleModel bool
+	// startHint says how to start the server
+	startHint string
+	// missingHint says how to make a model available
+	missingHint func(modelName string) string
+}
+
+var lmStudio = localServer{
+	name:       "lmstudio",
+	display:    "LM Studio",
+	host:       ProviderOption{Name: "lmstudio-host", Env: "LMSTUDIO_HOST", Usage: "LM Studio server URL"},
+	defaultURL: "http://localhost:1234/v1",
+	startHint:  "start it in LM Studio's Developer tab or with: lms server start",
+	missingHint: func(modelName string) string {
+		return fmt.Sprintf("download it in LM Studio or with: lms get %s", modelName)
+	},
+}
+
+var llamaCpp = localServer{
+	name:        "llamacpp",
+	display:     "llama.cpp",
+	host:        ProviderOption{Name: "llamacpp-host", Env: "LLAMACPP_HOST", Usage: "llama.cpp server URL"},
+	defaultURL:  "http://localhost:8080/v1",
+	apiKey:      &ProviderOption{Name: "llamacpp-api-key", Env: "LLAMACPP_API_KEY", Usage: "API key llama-server was started with (--api-key)"},
+	singleModel: true,
+	startHint:   "start it with: llama-server -m model.gguf --jinja",
+}
+
+// provider returns the registry entry of the server
+func (s localServer) provider() Provider {
+	options := []ProviderOption{s.host}
+	if s.apiKey != nil {
+		options = append(options, *s.apiKey)
+	}
+	return Provider{
+		Name:    s.name,
+		Options: options,
+		New:     s.create,
+		Check:   s.check,
+		List: func(ctx context.Context, config *ProviderConfig) ([]ModelInfo, error) {
+			return listOpenAICompatibleModels(ctx, s.api(config))
+		},
+	}
+}
+
+func (s localServer) create(ctx context.Context, config *ProviderConfig, modelName string, httpClient *http.Client) (model.ToolCallingChatModel, error) {
+	if config.controlsToolUse() {
+		httpClient = withRequestPatch(httpClient, grammarToolChoice(config.ToolChoice, config.ToolSeparator, config.DisableParallelToolUse))
+	}
+
+	openaiConfig := &openai.ChatModelConfig{
+		APIKey:     s.key(config),
+		BaseURL:    s.baseURL(config),
+		Model:      modelName,
+		HTTPClient: httpClient,
+	}
+	config.setOpenAISampling(openaiConfig)
+	return openai.NewChatModel(ctx, openaiConfig)
+}
+
+// check verifies that the server is running and, unless it serves a single
+// model, that it has the model
+func (s localServer) check(ctx context.Context, config *ProviderConfig, modelName string) error {
+	list, err := listOpenAICompatibleModels(ctx, s.api(config))
+	if err != nil || s.singleModel {
+		return err
+	}
+	for _, m := range list {
+		if m.Name == modelName {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s has no model %s; %s", s.display, modelName, s.missingHint(modelName))
+}
+
+// api returns the server's model API
+func (s localServer) api(config *ProviderConfig) *providerAPI {
+	api := &providerAPI{
+		provider:    s.display,
+		baseURL:     s.baseURL(config),
+		header:      http.Header{},
+		client:      config.HTTPClient(),
+		offlineHint: fmt.Sprintf("is the %s server running at %s? %s", s.display, s.baseURL(config), s.startHint),
+	}
+	if key := s.key(config); key != "" {
+		api.header.Set("Authorization", "Bearer "+key)
+	}
+	if s.apiKey != nil {
+		api.keyHint = fmt.Sprintf("%s in the config or %s", s.apiKey.Name, s.apiKey.Env)
+	}
+	return api
+}
+
+func (s localServer) baseURL(config *ProviderConfig) string {
+	if host := config.Option(s.host); host != "" {
+		return host
+	}
+	return s.defaultURL
+}
+
+func (s localServer) key(config *ProviderConfig) string {
+	if s.apiKey == nil {
+		return ""
+	}
+	return config.Option(*s.apiKey)
+}
+
+// grammarToolChoice is openAIToolChoice for servers that constrain tool
+// calls with a grammar and only accept tool_choice auto, none or required.
+// A named tool is forced by offering only that tool and requiring a call.
+func grammarToolChoice(choice, separator string, disableParallel bool) requestPatch {
+	patch := openAIToolChoice(choice, separator, disableParallel)
+	return func(body map[string]any) {
+		patch(body)
+		forced, ok := body["tool_choice"].(map[string]any)
+		if !ok {
+			return
+		}
+		fn, _ := forced["function"].(map[string]any)
+
+		tools, _ := body["tools"].([]any)
+		var kept []any
+		for _, item := range tools {
+			tool, _ := item.(map[string]any)
+			if f, ok := tool["function"].(map[string]any); ok && f["name"] == fn["name"] {
+				kept = append(kept, item)
+			}
+		}
+		if len(kept) == 0 {
+			return
+		}
+		body["tools"] = kept
+		body["tool_choice"] = "required"
+	}
+}
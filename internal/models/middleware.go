@@ -0,0 +1,480 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// Call is a model call as middleware sees it
+type Call struct {
+	// Provider is the registered name of the model's provider
+	Provider string
+	// Model is the model name, without the provider
+	Model    string
+	Messages []*schema.Message
+	// Tools are the tools bound to the model with WithTools
+	Tools   []*schema.ToolInfo
+	Options []model.Option
+}
+
+// GenerateHandler makes a model call and returns the whole response
+type GenerateHandler func(ctx context.Context, call *Call) (*schema.Message, error)
+
+// StreamHandler makes a model call and returns the response as a stream
+type StreamHandler func(ctx context.Context, call *Call) (*schema.StreamReader[*schema.Message], error)
+
+// Middleware wraps the calls of chat models, for concerns such as logging and
+// retries that are the same for every provider. Generate and Stream each
+// wrap the next handler of the chain; either may be nil to pass those calls
+// through.
+type Middleware struct {
+	Name     string
+	Generate func(next GenerateHandler) GenerateHandler
+	Stream   func(next StreamHandler) StreamHandler
+}
+
+// registeredMiddleware is a middleware and the providers it applies to, all
+// of them if empty
+type registeredMiddleware struct {
+	Middleware
+	providers []string
+}
+
+var (
+	middlewareMu sync.RWMutex
+	middlewares  []registeredMiddleware
+)
+
+// Use adds a middleware to the chain of the models CreateProvider creates
+// from then on. It applies to the named providers, or to all of them if none
+// are named. Middleware added first is outermost: it sees a call first and
+// its response last.
+func Use(mw Middleware, providers ...string) error {
+	if mw.Name == "" {
+		return fmt.Errorf("middleware has no name")
+	}
+	if mw.Generate == nil && mw.Stream == nil {
+		return fmt.Errorf("middleware %s wraps neither Generate nor Stream", mw.Name)
+	}
+
+	middlewareMu.Lock()
+	defer middlewareMu.Unlock()
+	middlewares = append(middlewares, registeredMiddleware{Middleware: mw, providers: providers})
+	return nil
+}
+
+// middlewareFor returns the middleware chain of a provider
+func middlewareFor(provider string) []Middleware {
+	middlewareMu.RLock()
+	defer middlewareMu.RUnlock()
+
+	var chain []Middleware
+	for _, mw := range middlewares {
+		if len(mw.providers) == 0 || slices.Contains(mw.providers, provider) {
+			chain = append(chain, mw.Middleware)
+		}
+	}
+	return chain
+}
+
+// withMiddleware wraps a chat model in its provider's middleware chain
+func withMiddleware(chatModel model.ToolCallingChatModel, provider, modelName string) model.ToolCallingChatModel {
+	chain := middlewareFor(provider)
+	if len(chain) == 0 {
+		return chatModel
+	}
+	return newMiddlewareModel(chatModel, chain, provider, modelName, nil)
+}
+
+// middlewareModel is a chat model whose calls go through a middleware chain
+type middlewareModel struct {
+	model     model.ToolCallingChatModel
+	chain     []Middleware
+	provider  string
+	modelName string
+	tools     []*schema.ToolInfo
+
+	generate GenerateHandler
+	stream   StreamHandler
+}
+
+func newMiddlewareModel(chatModel model.ToolCallingChatModel, chain []Middleware, provider, modelName string, tools []*schema.ToolInfo) *middlewareModel {
+	m := &middlewareModel{
+		model:     chatModel,
+		chain:     chain,
+		provider:  provider,
+		modelName: modelName,
+		tools:     tools,
+		generate: func(ctx context.Context, call *Call) (*schema.Message, error) {
+			return chatModel.Generate(ctx, call.Messages, call.Options...)
+		},
+		stream: func(ctx context.Context, call *Call) (*schema.StreamReader[*schema.Message], error) {
+			return chatModel.Stream(ctx, call.Messages, call.Options...)
+		},
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].Generate != nil {
+			m.generate = chain[i].Generate(m.generate)
+		}
+		if chain[i].Stream != nil {
+			m.stream = chain[i].Stream(m.stream)
+		}
+	}
+	return m
+}
+
+func (m *middlewareModel) call(input []*schema.Message, opts []model.Option) *Call {
+	return &Call{Provider: m.provider, Model: m.modelName, Messages: input, Tools: m.tools, Options: opts}
+}
+
+func (m *middlewareModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return m.generate(ctx, m.call(input, opts))
+}
+
+func (m *middlewareModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return m.stream(ctx, m.call(input, opts))
+}
+
+func (m *middlewareModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	bound, err := m.model.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return newMiddlewareModel(bound, m.chain, m.provider, m.modelName, tools), nil
+}
+
+// GetType and IsCallbacksEnabled are the wrapped model's, so that eino
+// names and instruments the model as it would without middleware
+func (m *middlewareModel) GetType() string {
+	typ, _ := components.GetType(m.model)
+	return typ
+}
+
+func (m *middlewareModel) IsCallbacksEnabled() bool {
+	return components.IsCallbacksEnabled(m.model)
+}
+
+// LoggingMiddleware logs each model call: the number of messages, how long
+// the call took, or took to start streaming, and its token usage or error
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	logCall := func(call *Call, kind string, start time.Time, response *schema.Message, err error) {
+		prefix := fmt.Sprintf("%s:%s: %s of %d messages", call.Provider, call.Model, kind, len(call.Messages))
+		switch {
+		case err != nil:
+			logger.Printf("%s failed after %s: %v", prefix, time.Since(start).Round(time.Millisecond), err)
+		case response != nil && response.ResponseMeta != nil && response.ResponseMeta.Usage != nil:
+			usage := response.ResponseMeta.Usage
+			logger.Printf("%s took %s, %d prompt and %d completion tokens", prefix, time.Since(start).Round(time.Millisecond), usage.PromptTokens, usage.CompletionTokens)
+		default:
+			logger.Printf("%s took %s", prefix, time.Since(start).Round(time.Millisecond))
+		}
+	}
+
+	return Middleware{
+		Name: "logging",
+		Generate: func(next GenerateHandler) GenerateHandler {
+			return func(ctx context.Context, call *Call) (*schema.Message, error) {
+				start := time.Now()
+				response, err := next(ctx, call)
+				logCall(call, "generate", start, response, err)
+				return response, err
+			}
+		},
+		Stream: func(next StreamHandler) StreamHandler {
+			return func(ctx context.Context, call *Call) (*schema.StreamReader[*schema.Message], error) {
+				start := time.Now()
+				stream, err := next(ctx, call)
+				logCall(call, "stream", start, nil, err)
+				return stream, err
+			}
+		},
+	}
+}
+
+// RetryMiddleware retries model calls that fail, up to retries more times,
+// waiting delay before the first retry and twice as long before each
+// following one. Streams are retried only if they fail to start. Calls
+// whose context is done aren't retried.
+func RetryMiddleware(retries int, delay time.Duration) Middleware {
+	retry := func(ctx context.Context, call *Call, attempt func() error) error {
+		wait := delay
+		for n := 0; ; n++ {
+			err := attempt()
+			if err == nil || n >= retries || ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return err
+			}
+			log.Printf("%s:%s: retrying after error: %v", call.Provider, call.Model, err)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return err
+			}
+			wait *= 2
+		}
+	}
+
+	return Middleware{
+		Name: "retry",
+		Generate: func(next GenerateHandler) GenerateHandler {
+			return func(ctx context.Context, call *Call) (response *schema.Message, err error) {
+				err = retry(ctx, call, func() error {
+					response, err = next(ctx, call)
+					return err
+				})
+				return response, err
+			}
+		},
+		Stream: func(next StreamHandler) StreamHandler {
+			return func(ctx context.Context, call *Call) (stream *schema.StreamReader[*schema.Message], err error) {
+				err = retry(ctx, call, func() error {
+					stream, err = next(ctx, call)
+					return err
+				})
+				return stream, err
+			}
+		},
+	}
+}
+
+// CacheMiddleware answers a call from memory when an identical call, with
+// the same model, messages, tools and common options such as the
+// temperature, was answered before. A cached answer to a streamed call is
+// replayed as a single chunk.
+func CacheMiddleware() Middleware {
+	var (
+		mu    sync.Mutex
+		cache = make(map[string]*schema.Message)
+	)
+	lookup := func(key string) (*schema.Message, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		response, ok := cache[key]
+		return response, ok
+	}
+	store := func(key string, response *schema.Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		cache[key] = response
+	}
+
+	return Middleware{
+		Name: "cache",
+		Generate: func(next GenerateHandler) GenerateHandler {
+			return func(ctx context.Context, call *Call) (*schema.Message, error) {
+				key, ok := cacheKey(call)
+				if !ok {
+					return next(ctx, call)
+				}
+				if response, ok := lookup(key); ok {
+					return response, nil
+				}
+				response, err := next(ctx, call)
+				if err == nil {
+					store(key, response)
+				}
+				return response, err
+			}
+		},
+		Stream: func(next StreamHandler) StreamHandler {
+			return func(ctx context.Context, call *Call) (*schema.StreamReader[*schema.Message], error) {
+				key, ok := cacheKey(call)
+				if !ok {
+					return next(ctx, call)
+				}
+				if response, ok := lookup(key); ok {
+					return schema.StreamReaderFromArray([]*schema.Message{response}), nil
+				}
+				stream, err := next(ctx, call)
+				if err != nil {
+					return nil, err
+				}
+
+				// Read a copy of the stream to the end to cache the whole answer
+				copies := stream.Copy(2)
+				go func() {
+					defer copies[1].Close()
+					var chunks []*schema.Message
+					for {
+						chunk, err := copies[1].Recv()
+						if err == io.EOF {
+							break
+						}
+						if err != nil {
+							return
+						}
+						chunks = append(chunks, chunk)
+					}
+					if response, err := schema.ConcatMessages(chunks); err == nil {
+						store(key, response)
+					}
+				}()
+				return copies[0], nil
+			}
+		},
+	}
+}
+
+// cacheKey returns a hash of what determines a call's answer, and false if
+// the call can't be encoded
+func cacheKey(call *Call) (string, bool) {
+	data, err := json.Marshal(struct {
+		Provider string
+		Model    string
+		Messages []*schema.Message
+		Tools    []*schema.ToolInfo
+		Options  *model.Options
+	}{call.Provider, call.Model, call.Messages, call.Tools, model.GetCommonOptions(nil, call.Options...)})
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// RedactMiddleware replaces each of the secrets, wherever it appears in the
+// messages sent to the model, with [redacted]. The caller's messages are
+// left as they are.
+func RedactMiddleware(secrets ...string) Middleware {
+	secrets = slices.DeleteFunc(slices.Clone(secrets), func(s string) bool { return s == "" })
+	redact := func(call *Call) *Call {
+		if len(secrets) == 0 {
+			return call
+		}
+		redacted := *call
+		redacted.Messages = make([]*schema.Message, len(call.Messages))
+		for i, msg := range call.Messages {
+			redacted.Messages[i] = redactMessage(msg, secrets)
+		}
+		return &redacted
+	}
+
+	return Middleware{
+		Name: "redact",
+		Generate: func(next GenerateHandler) GenerateHandler {
+			return func(ctx context.Context, call *Call) (*schema.Message, error) {
+				return next(ctx, redact(call))
+			}
+		},
+		Stream: func(next StreamHandler) StreamHandler {
+			return func(ctx context.Context, call *Call) (*schema.StreamReader[*schema.Message], error) {
+				return next(ctx, redact(call))
+			}
+		},
+	}
+}
+
+// redactMessage returns a copy of msg with the secrets replaced in its
+// content, text parts and tool call arguments
+func redactMessage(msg *schema.Message, secrets []string) *schema.Message {
+	replace := func(s string) string {
+		for _, secret := range secrets {
+			s = strings.ReplaceAll(s, secret, "[redacted]")
+		}
+		return s
+	}
+
+	redacted := *msg
+	redacted.Content = replace(msg.Content)
+	if msg.MultiContent != nil {
+		redacted.MultiContent = slices.Clone(msg.MultiContent)
+		for i := range redacted.MultiContent {
+			redacted.MultiContent[i].Text = replace(redacted.MultiContent[i].Text)
+		}
+	}
+	if msg.ToolCalls != nil {
+		redacted.ToolCalls = slices.Clone(msg.ToolCalls)
+		for i := range redacted.ToolCalls {
+			redacted.ToolCalls[i].Function.Arguments = replace(redacted.ToolCalls[i].Function.Arguments)
+		}
+	}
+	return &redacted
+}
+
+// UsageCounter sums the token usage of model calls. It is safe for
+// concurrent use.
+type UsageCounter struct {
+	mu    sync.Mutex
+	calls int
+	usage schema.TokenUsage
+}
+
+// Calls returns how many model calls reported their usage
+func (c *UsageCounter) Calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+// Usage returns the token usage summed over all calls
+func (c *UsageCounter) Usage() schema.TokenUsage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usage
+}
+
+// add adds usage, less what was already added for the same call, which
+// streams report as a running total
+func (c *UsageCounter) add(usage, added *schema.TokenUsage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if added == nil {
+		c.calls++
+		added = &schema.TokenUsage{}
+	}
+	c.usage.PromptTokens += usage.PromptTokens - added.PromptTokens
+	c.usage.CompletionTokens += usage.CompletionTokens - added.CompletionTokens
+	c.usage.TotalTokens += usage.TotalTokens - added.TotalTokens
+}
+
+// UsageMiddleware adds the token usage each model call reports to counter
+func UsageMiddleware(counter *UsageCounter) Middleware {
+	usageOf := func(msg *schema.Message) *schema.TokenUsage {
+		if msg == nil || msg.ResponseMeta == nil {
+			return nil
+		}
+		return msg.ResponseMeta.Usage
+	}
+
+	return Middleware{
+		Name: "usage",
+		Generate: func(next GenerateHandler) GenerateHandler {
+			return func(ctx context.Context, call *Call) (*schema.Message, error) {
+				response, err := next(ctx, call)
+				if usage := usageOf(response); usage != nil {
+					counter.add(usage, nil)
+				}
+				return response, err
+			}
+		},
+		Stream: func(next StreamHandler) StreamHandler {
+			return func(ctx context.Context, call *Call) (*schema.StreamReader[*schema.Message], error) {
+				stream, err := next(ctx, call)
+				if err != nil {
+					return nil, err
+				}
+				var added *schema.TokenUsage
+				return schema.StreamReaderWithConvert(stream, func(chunk *schema.Message) (*schema.Message, error) {
+					if usage := usageOf(chunk); usage != nil {
+						counter.add(usage, added)
+						added = usage
+					}
+					return chunk, nil
+				}), nil
+			}
+		},
+	}
+}
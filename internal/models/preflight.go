@@ -0,0 +1,86 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// Preflight verifies that the configured model exists and its provider
+// accepts the credentials, so that a bad model name or API key fails at
+// startup rather than on the first prompt. Providers with a Check look the
+// model up; others are asked for a one-token response. The provider's
+// defaults, timeout and headers apply as they do for CreateProvider. It
+// returns how long the provider took to answer.
+func Preflight(ctx context.Context, config *ProviderConfig) (time.Duration, error) {
+	parts := strings.SplitN(config.ModelString, ":", 2)
+	if len(parts) < 2 {
+		return 0, fmt.Errorf("invalid model format. Expected provider:model, got %s", config.ModelString)
+	}
+	provider, ok := Lookup(parts[0])
+	if !ok {
+		return 0, fmt.Errorf("unsupported provider: %s (available: %s)", parts[0], strings.Join(providerNames(), ", "))
+	}
+
+	config, httpClient, err := config.prepare(provider.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	if provider.Check != nil {
+		err := provider.Check(ctx, config, parts[1])
+		return time.Since(start), err
+	}
+
+	chatModel, err := provider.New(ctx, config, parts[1], httpClient)
+	if err != nil {
+		return 0, err
+	}
+	start = time.Now()
+	if _, err := chatModel.Generate(ctx, []*schema.Message{schema.UserMessage("ping")}, model.WithMaxTokens(1)); err != nil {
+		return time.Since(start), fmt.Errorf("%s did not answer a test request for %s: %v", provider.Name, parts[1], err)
+	}
+	return time.Since(start), nil
+}
+
+func checkAnthropicModel(ctx context.Context, config *ProviderConfig, modelName string) error {
+	api, err := anthropicAPI(config)
+	if err != nil {
+		return err
+	}
+	return api.do(ctx, http.MethodGet, "v1/models/"+url.PathEscape(modelName), nil, nil, noModel(api, modelName))
+}
+
+func checkOpenAIModel(ctx context.Context, config *ProviderConfig, modelName string) error {
+	api, err := openAIAPI(config)
+	if err != nil {
+		return err
+	}
+	return api.do(ctx, http.MethodGet, "models/"+url.PathEscape(modelName), nil, nil, noModel(api, modelName))
+}
+
+func checkGoogleModel(ctx context.Context, config *ProviderConfig, modelName string) error {
+	api, err := googleAPI(config)
+	if err != nil {
+		return err
+	}
+	return api.do(ctx, http.MethodGet, "models/"+url.PathEscape(modelName), nil, nil, noModel(api, modelName))
+}
+
+func checkOllamaModel(ctx context.Context, config *ProviderConfig, modelName string) error {
+	api := ollamaAPI(config)
+	return api.do(ctx, http.MethodPost, "api/show", map[string]string{"name": modelName}, nil,
+		fmt.Sprintf("Ollama has no model %s; pull it with: ollama pull %s", modelName, modelName))
+}
+
+// noModel is the error message for a model the provider doesn't have
+func noModel(api *providerAPI, modelName string) string {
+	return fmt.Sprintf("%s has no model %s; check the model name after the provider prefix in --model, or run mcphost models", api.provider, modelName)
+}
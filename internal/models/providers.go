@@ -3,13 +3,16 @@ package models
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/cloudwego/eino-ext/components/model/claude"
 	"github.com/cloudwego/eino-ext/components/model/ollama"
 	"github.com/cloudwego/eino-ext/components/model/openai"
 	"github.com/cloudwego/eino/components/model"
+	"github.com/ollama/ollama/api"
 )
 
 // ProviderConfig holds configuration for creating LLM providers
@@ -21,100 +24,483 @@ type ProviderConfig struct {
 	OpenAIAPIKey     string
 	OpenAIBaseURL    string
 	GoogleAPIKey     string
+	Temperature      *float32
+
+	// TrafficDir, if set, receives a JSON dump of every provider request and response
+	TrafficDir string
+
+	// ToolChoice is auto, any, none or the name of a tool the model must call
+	// first; empty leaves the provider default
+	ToolChoice string
+	// DisableParallelToolUse limits the model to one tool call per response
+	DisableParallelToolUse bool
+	// ToolSeparator is the separator between tool prefixes and names, used
+	// to find a ToolChoice given by its bare name; empty means "__"
+	ToolSeparator string
+
+	// NativeSearch lets the model search the web itself, with Google Search
+	// grounding for Gemini or an OpenAI search model's web search
+	NativeSearch bool
+
+	// RepairToolArgs repairs malformed JSON in the tool call arguments sent
+	// back to providers that parse them, e.g. trailing commas
+	RepairToolArgs bool
+
+	// MaxTokens limits the length of responses; 0 leaves the provider default
+	MaxTokens int
+	// TopP is the nucleus sampling probability; nil leaves the provider default
+	TopP *float32
+	// Timeout limits each request, including reading a streamed response;
+	// 0 for none
+	Timeout time.Duration
+	// Headers are added to every request; values may reference environment
+	// variables as $VAR or ${VAR}
+	Headers map[string]string
+
+	// Defaults holds settings for the models of each provider, keyed by
+	// provider name, which apply where the settings above are unset
+	Defaults map[string]ProviderDefaults
+
+	// Options holds settings declared by registered providers, keyed by
+	// ProviderOption.Name
+	Options map[string]string
+
+	// httpClient is the client the provider's requests go through, set by
+	// prepare
+	httpClient *http.Client
+}
+
+// HTTPClient returns the client requests to the provider go through, with
+// the timeout, headers and traffic dump applied. Checks and listers use it
+// so they reach the provider the way its models do.
+func (c *ProviderConfig) HTTPClient() *http.Client {
+	if c.httpClient == nil {
+		return http.DefaultClient
+	}
+	return c.httpClient
+}
+
+// ProviderDefaults are the settings for the models of one provider, from
+// the providers section of the config file
+type ProviderDefaults struct {
+	MaxTokens   int
+	Temperature *float32
+	TopP        *float32
+	Timeout     time.Duration
+	Headers     map[string]string
+}
+
+// forProvider returns a copy of the config with the defaults of provider
+// filling in the settings that aren't set
+func (c *ProviderConfig) forProvider(provider string) *ProviderConfig {
+	merged := *c
+	defaults, ok := c.Defaults[provider]
+	if !ok {
+		return &merged
+	}
+	if merged.MaxTokens == 0 {
+		merged.MaxTokens = defaults.MaxTokens
+	}
+	if merged.Temperature == nil {
+		merged.Temperature = defaults.Temperature
+	}
+	if merged.TopP == nil {
+		merged.TopP = defaults.TopP
+	}
+	if merged.Timeout == 0 {
+		merged.Timeout = defaults.Timeout
+	}
+	if len(defaults.Headers) > 0 {
+		merged.Headers = make(map[string]string)
+		for k, v := range defaults.Headers {
+			merged.Headers[k] = v
+		}
+		for k, v := range c.Headers {
+			merged.Headers[k] = v
+		}
+	}
+	return &merged
+}
+
+// ollamaHostOption overrides the default local Ollama server URL
+var ollamaHostOption = ProviderOption{Name: "ollama-host", Env: "OLLAMA_HOST", Usage: "Ollama server URL"}
+
+// Azure OpenAI settings; the model name after "azure:" is the deployment
+var (
+	azureEndpointOption   = ProviderOption{Name: "azure-endpoint", Env: "AZURE_OPENAI_ENDPOINT", Usage: "Azure OpenAI resource endpoint, e.g. https://myresource.openai.azure.com"}
+	azureAPIKeyOption     = ProviderOption{Name: "azure-api-key", Env: "AZURE_OPENAI_API_KEY", Usage: "Azure OpenAI API key"}
+	azureAPIVersionOption = ProviderOption{Name: "azure-api-version", Env: "AZURE_OPENAI_API_VERSION", Usage: "Azure OpenAI API version"}
+)
+
+// defaultAzureAPIVersion is the Azure OpenAI API version used unless
+// azure-api-version is set
+const defaultAzureAPIVersion = "2024-10-21"
+
+func init() {
+	builtins := []Provider{
+		{
+			Name: "anthropic",
+			Options: []ProviderOption{
+				{Name: "anthropic-api-key", Env: "ANTHROPIC_API_KEY", Usage: "Anthropic API key"},
+				{Name: "anthropic-url", Usage: "base URL for Anthropic API"},
+			},
+			New:   createAnthropicProvider,
+			Check: checkAnthropicModel,
+			List:  listAnthropicModels,
+		},
+		{
+			Name: "openai",
+			Options: []ProviderOption{
+				{Name: "openai-api-key", Env: "OPENAI_API_KEY", Usage: "OpenAI API key"},
+				{Name: "openai-url", Usage: "base URL for OpenAI API"},
+			},
+			New:   createOpenAIProvider,
+			Check: checkOpenAIModel,
+			List:  listOpenAIModels,
+		},
+		{
+			Name: "google",
+			Options: []ProviderOption{
+				{Name: "google-api-key", Env: "GOOGLE_API_KEY", Usage: "Google (Gemini) API key, or GEMINI_API_KEY"},
+			},
+			New:   createGoogleProvider,
+			Check: checkGoogleModel,
+			List:  listGoogleModels,
+		},
+		{
+			Name:    "ollama",
+			Options: []ProviderOption{ollamaHostOption},
+			New:     createOllamaProvider,
+			Check:   checkOllamaModel,
+			List:    listOllamaModels,
+		},
+		{
+			Name:    "azure",
+			Options: []ProviderOption{azureEndpointOption, azureAPIKeyOption, azureAPIVersionOption},
+			New:     createAzureProvider,
+		},
+		lmStudio.provider(),
+		llamaCpp.provider(),
+	}
+	for _, p := range builtins {
+		Register(p)
+	}
 }
 
-// CreateProvider creates an eino ToolCallingChatModel based on the provider configuration
+// CreateProvider creates an eino ToolCallingChatModel based on the provider
+// configuration, wrapped in the middleware registered with Use
 func CreateProvider(ctx context.Context, config *ProviderConfig) (model.ToolCallingChatModel, error) {
 	parts := strings.SplitN(config.ModelString, ":", 2)
 	if len(parts) < 2 {
 		return nil, fmt.Errorf("invalid model format. Expected provider:model, got %s", config.ModelString)
 	}
 
-	provider := parts[0]
+	provider, ok := Lookup(parts[0])
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider: %s (available: %s)", parts[0], strings.Join(providerNames(), ", "))
+	}
 	modelName := parts[1]
+	config, httpClient, err := config.prepare(provider.Name)
+	if err != nil {
+		return nil, err
+	}
+	if config.NativeSearch && parts[0] != "google" && parts[0] != "openai" {
+		return nil, fmt.Errorf("%s has no native web search (google and openai do); use the web_search builtin tool instead", parts[0])
+	}
 
-	switch provider {
-	case "anthropic":
-		return createAnthropicProvider(ctx, config, modelName)
-	case "openai":
-		return createOpenAIProvider(ctx, config, modelName)
-	case "google":
-		return createGoogleProvider(ctx, config, modelName)
-	case "ollama":
-		return createOllamaProvider(ctx, config, modelName)
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	chatModel, err := provider.New(ctx, config, modelName, httpClient)
+	if err != nil {
+		return nil, err
 	}
+	return withMiddleware(chatModel, provider.Name, modelName), nil
 }
 
-func createAnthropicProvider(ctx context.Context, config *ProviderConfig, modelName string) (model.ToolCallingChatModel, error) {
-	apiKey := config.AnthropicAPIKey
-	if apiKey == "" {
-		apiKey = os.Getenv("ANTHROPIC_API_KEY")
+// prepare returns the config for the models of provider, with the
+// provider's defaults filled in, and the HTTP client their requests go
+// through, which dumps the traffic and applies the timeout and headers. The
+// client is nil if none of these are set.
+func (c *ProviderConfig) prepare(provider string) (*ProviderConfig, *http.Client, error) {
+	config := c.forProvider(provider)
+
+	var httpClient *http.Client
+	if config.TrafficDir != "" {
+		var err error
+		httpClient, err = NewTrafficClient(config.TrafficDir,
+			config.AnthropicAPIKey, config.OpenAIAPIKey, config.GoogleAPIKey,
+			os.Getenv("ANTHROPIC_API_KEY"), os.Getenv("OPENAI_API_KEY"),
+			os.Getenv("GOOGLE_API_KEY"), os.Getenv("GEMINI_API_KEY"),
+			config.Option(azureAPIKeyOption))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if config.Timeout > 0 || len(config.Headers) > 0 {
+		httpClient = withRequestSettings(httpClient, config.Timeout, config.Headers)
 	}
+	config.httpClient = httpClient
+	return config, httpClient, nil
+}
+
+// withRequestSettings returns a copy of client that adds headers to every
+// request and gives up on requests after timeout, unless it is 0. A nil
+// client stands for http.DefaultClient.
+func withRequestSettings(client *http.Client, timeout time.Duration, headers map[string]string) *http.Client {
+	configured := &http.Client{}
+	if client != nil {
+		*configured = *client
+	}
+	if timeout > 0 {
+		configured.Timeout = timeout
+	}
+	if len(headers) > 0 {
+		expanded := make(map[string]string, len(headers))
+		for k, v := range headers {
+			expanded[k] = os.ExpandEnv(v)
+		}
+		configured.Transport = &headerTransport{headers: expanded, next: configured.Transport}
+	}
+	return configured
+}
+
+// setOpenAISampling applies the sampling settings to the config of an
+// OpenAI-compatible chat model
+func (c *ProviderConfig) setOpenAISampling(openaiConfig *openai.ChatModelConfig) {
+	if c.Temperature != nil {
+		openaiConfig.Temperature = c.Temperature
+	}
+	if c.MaxTokens > 0 {
+		maxTokens := c.MaxTokens
+		openaiConfig.MaxTokens = &maxTokens
+	}
+	if c.TopP != nil {
+		openaiConfig.TopP = c.TopP
+	}
+}
+
+func createAnthropicProvider(ctx context.Context, config *ProviderConfig, modelName string, httpClient *http.Client) (model.ToolCallingChatModel, error) {
+	apiKey := config.anthropicAPIKey()
 	if apiKey == "" {
 		return nil, fmt.Errorf("Anthropic API key not provided. Use --anthropic-api-key flag or ANTHROPIC_API_KEY environment variable")
 	}
 
+	if config.controlsToolUse() {
+		httpClient = withRequestPatch(httpClient, anthropicToolChoice(config.ToolChoice, config.ToolSeparator, config.DisableParallelToolUse))
+	}
+
 	claudeConfig := &claude.Config{
-		APIKey:    apiKey,
-		Model:     modelName,
-		MaxTokens: 4096,
+		APIKey:     apiKey,
+		Model:      modelName,
+		MaxTokens:  4096,
+		HTTPClient: httpClient,
 	}
 
 	if config.AnthropicBaseURL != "" {
 		claudeConfig.BaseURL = &config.AnthropicBaseURL
 	}
+	if config.Temperature != nil {
+		claudeConfig.Temperature = config.Temperature
+	}
+	if config.MaxTokens > 0 {
+		claudeConfig.MaxTokens = config.MaxTokens
+	}
+	if config.TopP != nil {
+		claudeConfig.TopP = config.TopP
+	}
 
 	return claude.NewChatModel(ctx, claudeConfig)
 }
 
-func createOpenAIProvider(ctx context.Context, config *ProviderConfig, modelName string) (model.ToolCallingChatModel, error) {
-	apiKey := config.OpenAIAPIKey
-	if apiKey == "" {
-		apiKey = os.Getenv("OPENAI_API_KEY")
-	}
+func createOpenAIProvider(ctx context.Context, config *ProviderConfig, modelName string, httpClient *http.Client) (model.ToolCallingChatModel, error) {
+	apiKey := config.openAIAPIKey()
 	if apiKey == "" {
 		return nil, fmt.Errorf("OpenAI API key not provided. Use --openai-api-key flag or OPENAI_API_KEY environment variable")
 	}
 
+	if config.controlsToolUse() {
+		httpClient = withRequestPatch(httpClient, openAIToolChoice(config.ToolChoice, config.ToolSeparator, config.DisableParallelToolUse))
+	}
+	if config.NativeSearch {
+		if !strings.Contains(modelName, "search") {
+			return nil, fmt.Errorf("OpenAI web search needs a search model, such as openai:gpt-4o-search-preview")
+		}
+		httpClient = withRequestPatch(httpClient, func(body map[string]any) {
+			body["web_search_options"] = map[string]any{}
+		})
+	}
+
 	openaiConfig := &openai.ChatModelConfig{
-		APIKey: apiKey,
-		Model:  modelName,
+		APIKey:     apiKey,
+		Model:      modelName,
+		HTTPClient: httpClient,
 	}
 
 	if config.OpenAIBaseURL != "" {
 		openaiConfig.BaseURL = config.OpenAIBaseURL
 	}
+	config.setOpenAISampling(openaiConfig)
 
 	return openai.NewChatModel(ctx, openaiConfig)
 }
 
-func createGoogleProvider(ctx context.Context, config *ProviderConfig, modelName string) (model.ToolCallingChatModel, error) {
-	apiKey := config.GoogleAPIKey
-	if apiKey == "" {
-		apiKey = os.Getenv("GOOGLE_API_KEY")
+// createAzureProvider creates a model for an Azure OpenAI deployment, which
+// is named by modelName
+func createAzureProvider(ctx context.Context, config *ProviderConfig, modelName string, httpClient *http.Client) (model.ToolCallingChatModel, error) {
+	endpoint := config.Option(azureEndpointOption)
+	if endpoint == "" {
+		return nil, fmt.Errorf("Azure OpenAI endpoint not provided. Use --azure-endpoint flag or AZURE_OPENAI_ENDPOINT environment variable")
 	}
+	apiKey := config.Option(azureAPIKeyOption)
 	if apiKey == "" {
-		apiKey = os.Getenv("GEMINI_API_KEY")
+		return nil, fmt.Errorf("Azure OpenAI API key not provided. Use --azure-api-key flag or AZURE_OPENAI_API_KEY environment variable")
+	}
+	apiVersion := config.Option(azureAPIVersionOption)
+	if apiVersion == "" {
+		apiVersion = defaultAzureAPIVersion
+	}
+
+	if config.controlsToolUse() {
+		httpClient = withRequestPatch(httpClient, openAIToolChoice(config.ToolChoice, config.ToolSeparator, config.DisableParallelToolUse))
+	}
+	// The OpenAI client takes the deployment to be the model name without
+	// "." and ":", which deployment names such as gpt-4.1 may contain
+	if mapped := strings.NewReplacer(".", "", ":", "").Replace(modelName); mapped != modelName {
+		httpClient = withDeployment(httpClient, mapped, modelName)
 	}
+
+	openaiConfig := &openai.ChatModelConfig{
+		ByAzure:    true,
+		BaseURL:    strings.TrimRight(endpoint, "/"),
+		APIVersion: apiVersion,
+		APIKey:     apiKey,
+		Model:      modelName,
+		HTTPClient: httpClient,
+	}
+	config.setOpenAISampling(openaiConfig)
+
+	return openai.NewChatModel(ctx, openaiConfig)
+}
+
+// withDeployment returns a copy of client that sends requests for the
+// deployment mapped to the deployment named instead. A nil client stands
+// for http.DefaultClient.
+func withDeployment(client *http.Client, mapped, name string) *http.Client {
+	rewritten := &http.Client{}
+	if client != nil {
+		*rewritten = *client
+	}
+	rewritten.Transport = &deploymentTransport{
+		from: "/deployments/" + mapped + "/",
+		to:   "/deployments/" + name + "/",
+		next: rewritten.Transport,
+	}
+	return rewritten
+}
+
+// deploymentTransport replaces the deployment in Azure OpenAI request paths
+type deploymentTransport struct {
+	from, to string
+	next     http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *deploymentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.Contains(req.URL.Path, t.from) {
+		req = req.Clone(req.Context())
+		req.URL.Path = strings.Replace(req.URL.Path, t.from, t.to, 1)
+		req.URL.RawPath = ""
+	}
+
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return next.RoundTrip(req)
+}
+
+func createGoogleProvider(ctx context.Context, config *ProviderConfig, modelName string, httpClient *http.Client) (model.ToolCallingChatModel, error) {
+	apiKey := config.googleAPIKey()
 	if apiKey == "" {
 		return nil, fmt.Errorf("Google API key not provided. Use --google-api-key flag or GOOGLE_API_KEY/GEMINI_API_KEY environment variable")
 	}
 
-	return NewGeminiChatModel(ctx, apiKey, modelName)
+	if config.DisableParallelToolUse {
+		return nil, fmt.Errorf("Google does not support disabling parallel tool use")
+	}
+
+	gemini, err := NewGeminiChatModel(ctx, apiKey, modelName, config.Temperature, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	gemini.toolChoice = config.ToolChoice
+	gemini.toolSeparator = config.ToolSeparator
+	gemini.nativeSearch = config.NativeSearch
+	gemini.repairToolArgs = config.RepairToolArgs
+	gemini.maxTokens = int32(config.MaxTokens)
+	gemini.topP = config.TopP
+	return gemini, nil
 }
 
-func createOllamaProvider(ctx context.Context, config *ProviderConfig, modelName string) (model.ToolCallingChatModel, error) {
+func createOllamaProvider(ctx context.Context, config *ProviderConfig, modelName string, httpClient *http.Client) (model.ToolCallingChatModel, error) {
+	if err := checkToolChoice("Ollama", config); err != nil {
+		return nil, err
+	}
+
 	ollamaConfig := &ollama.ChatModelConfig{
-		BaseURL: "http://localhost:11434", // Default Ollama URL
-		Model:   modelName,
+		BaseURL:    config.ollamaHost(),
+		Model:      modelName,
+		HTTPClient: httpClient,
+	}
+	if config.Temperature != nil || config.MaxTokens > 0 || config.TopP != nil {
+		options := &api.Options{NumPredict: config.MaxTokens}
+		if config.Temperature != nil {
+			options.Temperature = *config.Temperature
+		}
+		if config.TopP != nil {
+			options.TopP = *config.TopP
+		}
+		ollamaConfig.Options = options
 	}
 
-	// Check for custom Ollama host
-	if host := os.Getenv("OLLAMA_HOST"); host != "" {
-		ollamaConfig.BaseURL = host
+	chatModel, err := ollama.NewChatModel(ctx, ollamaConfig)
+	if err != nil {
+		return nil, err
 	}
+	return &leadingSystemModel{model: chatModel}, nil
+}
+
+// anthropicAPIKey returns the Anthropic API key from the config or the
+// environment
+func (c *ProviderConfig) anthropicAPIKey() string {
+	if c.AnthropicAPIKey != "" {
+		return c.AnthropicAPIKey
+	}
+	return os.Getenv("ANTHROPIC_API_KEY")
+}
+
+// openAIAPIKey returns the OpenAI API key from the config or the environment
+func (c *ProviderConfig) openAIAPIKey() string {
+	if c.OpenAIAPIKey != "" {
+		return c.OpenAIAPIKey
+	}
+	return os.Getenv("OPENAI_API_KEY")
+}
+
+// googleAPIKey returns the Google API key from the config or the environment
+func (c *ProviderConfig) googleAPIKey() string {
+	if c.GoogleAPIKey != "" {
+		return c.GoogleAPIKey
+	}
+	if key := os.Getenv("GOOGLE_API_KEY"); key != "" {
+		return key
+	}
+	return os.Getenv("GEMINI_API_KEY")
+}
 
-	return ollama.NewChatModel(ctx, ollamaConfig)
+// ollamaHost returns the URL of the Ollama server, by default the local one
+func (c *ProviderConfig) ollamaHost() string {
+	if host := c.Option(ollamaHostOption); host != "" {
+		return host
+	}
+	return "http://localhost:11434"
 }
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+)
+
+// ProxyConfig configures a provider for an OpenAI-compatible proxy such as
+// LiteLLM. Model names are passed through to the proxy unchanged.
+type ProxyConfig struct {
+	BaseURL string
+	// APIKeyEnv names the environment variable holding the proxy's API key
+	APIKeyEnv    string
+	Organization string
+	Project      string
+	// Headers are added to every request; values may reference environment
+	// variables as $VAR or ${VAR}
+	Headers map[string]string
+}
+
+// NewProxyProvider returns a provider that sends requests to an
+// OpenAI-compatible proxy
+func NewProxyProvider(name string, proxy ProxyConfig) Provider {
+	return Provider{
+		Name: name,
+		New: func(ctx context.Context, config *ProviderConfig, modelName string, httpClient *http.Client) (model.ToolCallingChatModel, error) {
+			apiKey, err := proxy.apiKey(name)
+			if err != nil {
+				return nil, err
+			}
+
+			client := &http.Client{}
+			if httpClient != nil {
+				*client = *httpClient
+			}
+			client.Transport = &headerTransport{headers: proxy.headers(), next: client.Transport}
+			if config.controlsToolUse() {
+				client = withRequestPatch(client, openAIToolChoice(config.ToolChoice, config.ToolSeparator, config.DisableParallelToolUse))
+			}
+
+			openaiConfig := &openai.ChatModelConfig{
+				APIKey:     apiKey,
+				BaseURL:    proxy.BaseURL,
+				Model:      modelName,
+				HTTPClient: client,
+			}
+			config.setOpenAISampling(openaiConfig)
+
+			return openai.NewChatModel(ctx, openaiConfig)
+		},
+		List: func(ctx context.Context, config *ProviderConfig) ([]ModelInfo, error) {
+			apiKey, err := proxy.apiKey(name)
+			if err != nil {
+				return nil, err
+			}
+
+			api := &providerAPI{provider: name, baseURL: proxy.BaseURL, header: http.Header{}, client: config.HTTPClient(), keyHint: proxy.APIKeyEnv}
+			for k, v := range proxy.headers() {
+				api.header.Set(k, v)
+			}
+			if apiKey != "" {
+				api.header.Set("Authorization", "Bearer "+apiKey)
+			}
+			return listOpenAICompatibleModels(ctx, api)
+		},
+	}
+}
+
+// apiKey returns the proxy's API key, which is empty if the proxy doesn't
+// need one
+func (proxy ProxyConfig) apiKey(name string) (string, error) {
+	if proxy.APIKeyEnv == "" {
+		return "", nil
+	}
+	apiKey := os.Getenv(proxy.APIKeyEnv)
+	if apiKey == "" {
+		return "", fmt.Errorf("provider %s: API key not set. Set the %s environment variable", name, proxy.APIKeyEnv)
+	}
+	return apiKey, nil
+}
+
+// headers returns the headers added to every request to the proxy
+func (proxy ProxyConfig) headers() map[string]string {
+	headers := make(map[string]string)
+	if proxy.Organization != "" {
+		headers["OpenAI-Organization"] = proxy.Organization
+	}
+	if proxy.Project != "" {
+		headers["OpenAI-Project"] = proxy.Project
+	}
+	for k, v := range proxy.Headers {
+		headers[k] = os.ExpandEnv(v)
+	}
+	return headers
+}
+
+// headerTransport sets fixed headers on every request. They are marked as
+// configured, so the traffic dump redacts them.
+type headerTransport struct {
+	headers map[string]string
+	next    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(withConfiguredHeaders(req.Context(), slices.Collect(maps.Keys(t.headers))))
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return next.RoundTrip(req)
+}
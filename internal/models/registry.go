@@ -0,0 +1,138 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"plugin"
+	"sort"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+)
+
+// ProviderFactory creates a chat model for modelName, the part of the model
+// string after "provider:". httpClient is nil unless requests should go
+// through a custom client, e.g. for --dump-llm-traffic.
+type ProviderFactory func(ctx context.Context, config *ProviderConfig, modelName string, httpClient *http.Client) (model.ToolCallingChatModel, error)
+
+// ProviderCheck verifies that modelName exists and the provider accepts the
+// configured credentials, without generating a response
+type ProviderCheck func(ctx context.Context, config *ProviderConfig, modelName string) error
+
+// ProviderLister lists the models a provider offers
+type ProviderLister func(ctx context.Context, config *ProviderConfig) ([]ModelInfo, error)
+
+// ProviderOption describes a setting a provider reads, such as an API key,
+// by its config file key and environment variable
+type ProviderOption struct {
+	Name  string
+	Env   string
+	Usage string
+}
+
+// Provider is a registered LLM backend. Check and List are optional;
+// providers without Check are checked by asking the model for a one-token
+// response.
+type Provider struct {
+	Name    string
+	Options []ProviderOption
+	New     ProviderFactory
+	Check   ProviderCheck
+	List    ProviderLister
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Provider{}
+)
+
+// Register adds a provider to the registry, replacing any provider of the
+// same name
+func Register(p Provider) error {
+	if p.Name == "" {
+		return fmt.Errorf("provider has no name")
+	}
+	if p.New == nil {
+		return fmt.Errorf("provider %s has no factory", p.Name)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[p.Name] = p
+	return nil
+}
+
+// Lookup returns the provider registered under name
+func Lookup(name string) (Provider, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	p, ok := registry[name]
+	return p, ok
+}
+
+// Providers returns all registered providers sorted by name
+func Providers() []Provider {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	providers := make([]Provider, 0, len(registry))
+	for _, p := range registry {
+		providers = append(providers, p)
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Name < providers[j].Name })
+	return providers
+}
+
+// providerNames lists the registered provider names for error messages
+func providerNames() []string {
+	var names []string
+	for _, p := range Providers() {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// PluginFactory is the signature of the NewChatModel function a provider
+// plugin exports. It uses only eino and standard library types because
+// plugins are built outside this module and can't import its packages.
+type PluginFactory = func(ctx context.Context, modelName string, options map[string]string, httpClient *http.Client) (model.ToolCallingChatModel, error)
+
+// LoadPlugin opens a Go plugin built with -buildmode=plugin and registers the
+// NewChatModel function it exports as provider name. options are passed to
+// the plugin on every call.
+func LoadPlugin(name, path string, options map[string]string) error {
+	plug, err := plugin.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open provider plugin %s: %v", path, err)
+	}
+
+	sym, err := plug.Lookup("NewChatModel")
+	if err != nil {
+		return fmt.Errorf("provider plugin %s: %v", path, err)
+	}
+	factory, ok := sym.(PluginFactory)
+	if !ok {
+		return fmt.Errorf("provider plugin %s: NewChatModel has type %T", path, sym)
+	}
+
+	return Register(Provider{
+		Name: name,
+		New: func(ctx context.Context, config *ProviderConfig, modelName string, httpClient *http.Client) (model.ToolCallingChatModel, error) {
+			return factory(ctx, modelName, options, httpClient)
+		},
+	})
+}
+
+// Option returns the value of a provider option, taken from the config's
+// Options or else from the option's environment variable
+func (c *ProviderConfig) Option(opt ProviderOption) string {
+	if v := c.Options[opt.Name]; v != "" {
+		return v
+	}
+	if opt.Env != "" {
+		return os.Getenv(opt.Env)
+	}
+	return ""
+}
@@ -0,0 +1,235 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// The provider shim protocol lets any executable act as an LLM backend. For
+// every model call mcphost starts the command, writes one shimRequest as JSON
+// to its stdin and closes it. The command replies on stdout with JSON lines,
+// each a shimResponse holding either a message chunk or an error; chunks are
+// streamed to the user as they arrive and concatenated for the final
+// message. Anything written to stderr is reported if the command fails.
+
+// shimRequest is sent to a provider shim on stdin
+type shimRequest struct {
+	Model       string            `json:"model"`
+	Messages    []*schema.Message `json:"messages"`
+	Tools       []shimTool        `json:"tools,omitempty"`
+	Temperature *float32          `json:"temperature,omitempty"`
+	MaxTokens   *int              `json:"max_tokens,omitempty"`
+	TopP        *float32          `json:"top_p,omitempty"`
+	Stream      bool              `json:"stream"`
+	// ToolChoice and DisableParallelToolUse pass on --tool-choice and
+	// --no-parallel-tools
+	ToolChoice             string `json:"tool_choice,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
+}
+
+// shimTool describes a tool with its parameters as a JSON schema
+type shimTool struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Parameters  *openapi3.Schema `json:"parameters,omitempty"`
+}
+
+// shimResponse is one line of a provider shim's output
+type shimResponse struct {
+	Message *schema.Message `json:"message,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// NewShimProvider returns a provider that runs command with args for every
+// model call, speaking the provider shim protocol. env is added to the
+// command's environment.
+func NewShimProvider(name, command string, args []string, env map[string]string) Provider {
+	return Provider{
+		Name: name,
+		New: func(ctx context.Context, config *ProviderConfig, modelName string, httpClient *http.Client) (model.ToolCallingChatModel, error) {
+			if _, err := exec.LookPath(command); err != nil {
+				return nil, fmt.Errorf("provider %s: %v", name, err)
+			}
+			shim := &ShimChatModel{
+				name:        name,
+				command:     command,
+				args:        args,
+				env:         env,
+				model:       modelName,
+				temperature: config.Temperature,
+				topP:        config.TopP,
+				toolChoice:  config.ToolChoice,
+				noParallel:  config.DisableParallelToolUse,
+			}
+			if config.MaxTokens > 0 {
+				maxTokens := config.MaxTokens
+				shim.maxTokens = &maxTokens
+			}
+			return shim, nil
+		},
+	}
+}
+
+// ShimChatModel implements the eino ToolCallingChatModel interface on top of
+// an external provider shim command
+type ShimChatModel struct {
+	name    string
+	command string
+	args    []string
+	env     map[string]string
+	model   string
+	tools   []*schema.ToolInfo
+
+	// temperature, maxTokens and topP are nil to use the model default
+	temperature *float32
+	maxTokens   *int
+	topP        *float32
+
+	toolChoice string
+	noParallel bool
+}
+
+func (s *ShimChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	stream, err := s.call(ctx, input, false, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var chunks []*schema.Message
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("provider %s returned no message", s.name)
+	}
+	return schema.ConcatMessages(chunks)
+}
+
+func (s *ShimChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return s.call(ctx, input, true, opts...)
+}
+
+func (s *ShimChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	newModel := *s
+	newModel.tools = tools
+	return &newModel, nil
+}
+
+func (s *ShimChatModel) GetType() string {
+	return "Shim"
+}
+
+func (s *ShimChatModel) IsCallbacksEnabled() bool {
+	return false
+}
+
+// call starts the shim command and streams the messages it writes
+func (s *ShimChatModel) call(ctx context.Context, input []*schema.Message, stream bool, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	commonOptions := model.GetCommonOptions(&model.Options{Tools: s.tools, Temperature: s.temperature, MaxTokens: s.maxTokens, TopP: s.topP}, opts...)
+
+	req := shimRequest{
+		Model:       s.model,
+		Messages:    input,
+		Temperature: commonOptions.Temperature,
+		MaxTokens:   commonOptions.MaxTokens,
+		TopP:        commonOptions.TopP,
+		Stream:      stream,
+
+		ToolChoice:             s.toolChoice,
+		DisableParallelToolUse: s.noParallel,
+	}
+	for _, tool := range commonOptions.Tools {
+		params, err := tool.ToOpenAPIV3()
+		if err != nil {
+			return nil, fmt.Errorf("get open schema failed: %w", err)
+		}
+		req.Tools = append(req.Tools, shimTool{Name: tool.Name, Description: tool.Desc, Parameters: params})
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode provider request: %v", err)
+	}
+
+	cmd := exec.CommandContext(ctx, s.command, s.args...)
+	cmd.Env = os.Environ()
+	for k, v := range s.env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stdin = bytes.NewReader(body)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start provider %s: %v", s.name, err)
+	}
+
+	sr, sw := schema.Pipe[*schema.Message](1)
+	go func() {
+		defer sw.Close()
+
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var resp shimResponse
+			if err := json.Unmarshal(line, &resp); err != nil {
+				sw.Send(nil, fmt.Errorf("provider %s: invalid response line: %v", s.name, err))
+				break
+			}
+			if resp.Error != "" {
+				sw.Send(nil, fmt.Errorf("provider %s: %s", s.name, resp.Error))
+				break
+			}
+			if resp.Message == nil {
+				continue
+			}
+			if resp.Message.Role == "" {
+				resp.Message.Role = schema.Assistant
+			}
+			if sw.Send(resp.Message, nil) {
+				break
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			sw.Send(nil, fmt.Errorf("provider %s: %v", s.name, err))
+		}
+		// Drain so the command isn't blocked writing when we stopped early
+		io.Copy(io.Discard, stdout)
+
+		if err := cmd.Wait(); err != nil {
+			msg := strings.TrimSpace(stderr.String())
+			if msg == "" {
+				msg = err.Error()
+			}
+			sw.Send(nil, fmt.Errorf("provider %s failed: %s", s.name, msg))
+		}
+	}()
+
+	return sr, nil
+}
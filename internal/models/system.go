@@ -0,0 +1,70 @@
+package models
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// splitSystemPrompt separates the system messages of a conversation from the
+// rest, joining their content into a single system prompt. The agent sends
+// the system prompt as the first message, but message modifiers may add more.
+func splitSystemPrompt(input []*schema.Message) (string, []*schema.Message) {
+	var prompts []string
+	rest := make([]*schema.Message, 0, len(input))
+	for _, msg := range input {
+		if msg.Role != schema.System {
+			rest = append(rest, msg)
+			continue
+		}
+		if content := strings.TrimSpace(msg.Content); content != "" {
+			prompts = append(prompts, content)
+		}
+	}
+	return strings.Join(prompts, "\n\n"), rest
+}
+
+// leadingSystemModel sends the system prompt as a single system message at
+// the start of the conversation, which is where the chat templates of Ollama
+// models put it; many ignore system messages anywhere else
+type leadingSystemModel struct {
+	model model.ToolCallingChatModel
+}
+
+func (m *leadingSystemModel) input(input []*schema.Message) []*schema.Message {
+	prompt, rest := splitSystemPrompt(input)
+	if prompt == "" {
+		return rest
+	}
+	return append([]*schema.Message{schema.SystemMessage(prompt)}, rest...)
+}
+
+func (m *leadingSystemModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return m.model.Generate(ctx, m.input(input), opts...)
+}
+
+func (m *leadingSystemModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return m.model.Stream(ctx, m.input(input), opts...)
+}
+
+func (m *leadingSystemModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	bound, err := m.model.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return &leadingSystemModel{model: bound}, nil
+}
+
+// GetType and IsCallbacksEnabled are the wrapped model's, so that eino
+// names and instruments the model as it would without the wrapper
+func (m *leadingSystemModel) GetType() string {
+	typ, _ := components.GetType(m.model)
+	return typ
+}
+
+func (m *leadingSystemModel) IsCallbacksEnabled() bool {
+	return components.IsCallbacksEnabled(m.model)
+}
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+// Values for ProviderConfig.ToolChoice; any other value names the tool the
+// model must call
+const (
+	ToolChoiceAuto = "auto"
+	ToolChoiceAny  = "any"
+	ToolChoiceNone = "none"
+)
+
+// requestPatch modifies a decoded JSON request body in place
+type requestPatch func(body map[string]any)
+
+// withRequestPatch returns a copy of client whose requests with a JSON body
+// are passed through patch. A nil client stands for http.DefaultClient.
+func withRequestPatch(client *http.Client, patch requestPatch) *http.Client {
+	patched := &http.Client{}
+	if client != nil {
+		*patched = *client
+	}
+	patched.Transport = &patchTransport{patch: patch, next: patched.Transport}
+	return patched
+}
+
+// patchTransport applies a requestPatch to outgoing JSON bodies
+type patchTransport struct {
+	patch requestPatch
+	next  http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *patchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if req.Body == nil || req.Body == http.NoBody || !strings.Contains(req.Header.Get("Content-Type"), "json") {
+		return next.RoundTrip(req)
+	}
+
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(data, &body); err == nil {
+		t.patch(body)
+		if patched, err := json.Marshal(body); err == nil {
+			data = patched
+		}
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.ContentLength = int64(len(data))
+	return next.RoundTrip(req)
+}
+
+// anthropicToolChoice sets tool_choice on Anthropic Messages API requests
+func anthropicToolChoice(choice, separator string, disableParallel bool) requestPatch {
+	return func(body map[string]any) {
+		tools := requestToolNames(body, func(tool map[string]any) any { return tool["name"] })
+		if len(tools) == 0 {
+			return
+		}
+
+		toolChoice := map[string]any{"type": ToolChoiceAuto}
+		if !continuesToolUse(body) {
+			switch choice {
+			case "", ToolChoiceAuto:
+			case ToolChoiceAny, ToolChoiceNone:
+				toolChoice["type"] = choice
+			default:
+				toolChoice["type"] = "tool"
+				toolChoice["name"] = resolveToolName(tools, choice, separator)
+			}
+		} else if choice == ToolChoiceNone {
+			toolChoice["type"] = ToolChoiceNone
+		}
+		if disableParallel && toolChoice["type"] != ToolChoiceNone {
+			toolChoice["disable_parallel_tool_use"] = true
+		}
+		body["tool_choice"] = toolChoice
+	}
+}
+
+// openAIToolChoice sets tool_choice and parallel_tool_calls on OpenAI Chat
+// Completions requests
+func openAIToolChoice(choice, separator string, disableParallel bool) requestPatch {
+	return func(body map[string]any) {
+		tools := requestToolNames(body, func(tool map[string]any) any {
+			if fn, ok := tool["function"].(map[string]any); ok {
+				return fn["name"]
+			}
+			return nil
+		})
+		if len(tools) == 0 {
+			return
+		}
+
+		if disableParallel {
+			body["parallel_tool_calls"] = false
+		}
+		if continuesToolUse(body) && choice != ToolChoiceNone {
+			return
+		}
+		switch choice {
+		case "":
+		case ToolChoiceAuto, ToolChoiceNone:
+			body["tool_choice"] = choice
+		case ToolChoiceAny:
+			body["tool_choice"] = "required"
+		default:
+			body["tool_choice"] = map[string]any{
+				"type":     "function",
+				"function": map[string]any{"name": resolveToolName(tools, choice, separator)},
+			}
+		}
+	}
+}
+
+// requestToolNames returns the names of the tools in a request body
+func requestToolNames(body map[string]any, name func(tool map[string]any) any) []string {
+	list, _ := body["tools"].([]any)
+	var names []string
+	for _, item := range list {
+		tool, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		if n, ok := name(tool).(string); ok {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// continuesToolUse reports whether the request's last message returns tool
+// results. Forced tool choices only apply to the first model call of a turn;
+// forcing every call would keep the model calling tools until max-steps.
+func continuesToolUse(body map[string]any) bool {
+	messages, _ := body["messages"].([]any)
+	if len(messages) == 0 {
+		return false
+	}
+	last, ok := messages[len(messages)-1].(map[string]any)
+	if !ok {
+		return false
+	}
+	if last["role"] == "tool" {
+		return true
+	}
+	// Anthropic returns tool results as tool_result blocks in a user message
+	blocks, _ := last["content"].([]any)
+	for _, b := range blocks {
+		if block, ok := b.(map[string]any); ok && block["type"] == "tool_result" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveToolName matches a tool given by its full "server__tool" name or
+// by its bare name against the request's tools, which may have been renamed
+// by config.SanitizeToolName. separator is the one between tool prefixes and
+// names; empty means "__".
+func resolveToolName(tools []string, choice, separator string) string {
+	if separator == "" {
+		separator = config.DefaultToolSeparator
+	}
+	sanitized := config.SanitizeToolName(choice)
+	for _, name := range tools {
+		if name == choice || name == sanitized {
+			return name
+		}
+	}
+	for _, name := range tools {
+		if strings.HasSuffix(name, separator+choice) || strings.HasSuffix(name, separator+sanitized) {
+			return name
+		}
+	}
+	return choice
+}
+
+// controlsToolUse reports whether the config sets any tool use controls
+func (c *ProviderConfig) controlsToolUse() bool {
+	return c.ToolChoice != "" || c.DisableParallelToolUse
+}
+
+// checkToolChoice rejects tool choice settings for providers that can't
+// honor them
+func checkToolChoice(provider string, config *ProviderConfig) error {
+	if config.controlsToolUse() {
+		return fmt.Errorf("%s does not support tool choice or disabling parallel tool use", provider)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redactedHeaders are request headers that carry provider credentials
+var redactedHeaders = []string{"Authorization", "X-Api-Key", "Api-Key", "X-Goog-Api-Key", "Proxy-Authorization"}
+
+// redactedParams are query parameters that carry provider credentials
+var redactedParams = []string{"key", "api_key", "api-key"}
+
+const redacted = "REDACTED"
+
+// configuredHeadersKey is the context key of the names of the headers a
+// request got from the configuration
+type configuredHeadersKey struct{}
+
+// withConfiguredHeaders marks the named headers of requests made with ctx
+// as set from the configuration. Their values may hold credentials, e.g.
+// from ${ENV}, so the traffic dump redacts them.
+func withConfiguredHeaders(ctx context.Context, names []string) context.Context {
+	existing, _ := ctx.Value(configuredHeadersKey{}).([]string)
+	return context.WithValue(ctx, configuredHeadersKey{}, append(slices.Clip(existing), names...))
+}
+
+// trafficRecord is one request/response pair as written to disk
+type trafficRecord struct {
+	Time       time.Time       `json:"time"`
+	DurationMs int64           `json:"duration_ms"`
+	Request    trafficMessage  `json:"request"`
+	Response   *trafficMessage `json:"response,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// trafficMessage is the sanitized form of an HTTP request or response
+type trafficMessage struct {
+	Method  string              `json:"method,omitempty"`
+	URL     string              `json:"url,omitempty"`
+	Status  int                 `json:"status,omitempty"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    json.RawMessage     `json:"body,omitempty"`
+}
+
+// trafficDumper is an http.RoundTripper that writes every request/response
+// pair it carries to a numbered JSON file in dir
+type trafficDumper struct {
+	dir     string
+	secrets []string
+	next    http.RoundTripper
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewTrafficClient returns an HTTP client that dumps each provider request and
+// response to dir as 0001.json, 0002.json, ... Numbering continues after any
+// files already in dir. Credentials in headers, query parameters and any of
+// the given secrets found in bodies are replaced before writing, as are the
+// values of headers set from the configuration.
+func NewTrafficClient(dir string, secrets ...string) (*http.Client, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create traffic dump directory: %v", err)
+	}
+
+	d := &trafficDumper{dir: dir, next: http.DefaultTransport}
+	for _, secret := range secrets {
+		if secret != "" {
+			d.secrets = append(d.secrets, secret)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read traffic dump directory: %v", err)
+	}
+	for _, entry := range entries {
+		n, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json"))
+		if err == nil && n > d.seq {
+			d.seq = n
+		}
+	}
+
+	return &http.Client{Transport: d}, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (d *trafficDumper) RoundTrip(req *http.Request) (*http.Response, error) {
+	configured, _ := req.Context().Value(configuredHeadersKey{}).([]string)
+	record := &trafficRecord{
+		Time: time.Now(),
+		Request: trafficMessage{
+			Method:  req.Method,
+			URL:     d.sanitizeURL(req),
+			Headers: d.sanitizeHeaders(req.Header, configured),
+		},
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		record.Request.Body = d.sanitizeBody(body)
+	}
+
+	resp, err := d.next.RoundTrip(req)
+	if err != nil {
+		record.Error = err.Error()
+		d.write(record)
+		return nil, err
+	}
+
+	record.Response = &trafficMessage{
+		Status:  resp.StatusCode,
+		Headers: d.sanitizeHeaders(resp.Header, nil),
+	}
+	// Streaming responses are recorded once the caller has consumed them
+	resp.Body = &teeBody{ReadCloser: resp.Body, done: func(body []byte, readErr error) {
+		record.Response.Body = d.sanitizeBody(body)
+		if readErr != nil && readErr != io.EOF {
+			record.Error = readErr.Error()
+		}
+		d.write(record)
+	}}
+	return resp, nil
+}
+
+// write saves a record to the next numbered file, reporting failures on stderr
+// rather than failing the request being dumped
+func (d *trafficDumper) write(record *trafficRecord) {
+	record.DurationMs = time.Since(record.Time).Milliseconds()
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to encode LLM traffic: %v\n", err)
+		return
+	}
+
+	d.mu.Lock()
+	d.seq++
+	name := filepath.Join(d.dir, fmt.Sprintf("%04d.json", d.seq))
+	d.mu.Unlock()
+
+	if err := os.WriteFile(name, append(data, '\n'), 0o600); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write LLM traffic: %v\n", err)
+	}
+}
+
+// sanitizeURL returns the request URL with credential parameters redacted
+func (d *trafficDumper) sanitizeURL(req *http.Request) string {
+	u := *req.URL
+	query := u.Query()
+	for _, param := range redactedParams {
+		if query.Has(param) {
+			query.Set(param, redacted)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return d.scrub(u.String())
+}
+
+// sanitizeHeaders copies headers with credential values, and the values of
+// the configured headers, redacted
+func (d *trafficDumper) sanitizeHeaders(header http.Header, configured []string) map[string][]string {
+	if len(header) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(header))
+	for name, values := range header {
+		sensitive := false
+		for _, h := range slices.Concat(redactedHeaders, configured) {
+			if strings.EqualFold(name, h) {
+				sensitive = true
+				break
+			}
+		}
+		if sensitive {
+			out[name] = []string{redacted}
+			continue
+		}
+		for _, v := range values {
+			out[name] = append(out[name], d.scrub(v))
+		}
+	}
+	return out
+}
+
+// sanitizeBody returns the body as JSON, embedding it directly when it is JSON
+// and as a string otherwise (e.g. server-sent event streams)
+func (d *trafficDumper) sanitizeBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	text := d.scrub(string(body))
+	if json.Valid([]byte(text)) {
+		return json.RawMessage(text)
+	}
+	encoded, _ := json.Marshal(text)
+	return encoded
+}
+
+// scrub replaces any known secret found in s
+func (d *trafficDumper) scrub(s string) string {
+	for _, secret := range d.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
+
+// teeBody buffers a response body as it is read and reports it once, on EOF,
+// error or close
+type teeBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	done func(body []byte, err error)
+	once sync.Once
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.buf.Write(p[:n])
+	if err != nil {
+		t.finish(err)
+	}
+	return n, err
+}
+
+func (t *teeBody) Close() error {
+	t.finish(nil)
+	return t.ReadCloser.Close()
+}
+
+func (t *teeBody) finish(err error) {
+	t.once.Do(func() { t.done(t.buf.Bytes(), err) })
+}
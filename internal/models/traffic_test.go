@@ -0,0 +1,48 @@
+package models
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTrafficRedaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	client, err := NewTrafficClient(dir, "sk-body-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Transport = &headerTransport{headers: map[string]string{"X-Team-Token": "team-secret"}, next: client.Transport}
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v1?api_key=query-secret", strings.NewReader(`{"key": "sk-body-secret"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer header-secret")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	dump, err := os.ReadFile(filepath.Join(dir, "0001.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"team-secret", "query-secret", "sk-body-secret", "header-secret"} {
+		if strings.Contains(string(dump), secret) {
+			t.Errorf("dump contains %s:\n%s", secret, dump)
+		}
+	}
+	if !strings.Contains(string(dump), `"X-Team-Token": [`) {
+		t.Errorf("dump is missing the configured header:\n%s", dump)
+	}
+}
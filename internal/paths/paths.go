@@ -0,0 +1,207 @@
+// Package paths locates the files mcphost keeps between runs, following the
+// XDG base directory specification: the config file and agent definitions
+// in ~/.config/mcphost, data such as checkpoints, snapshots and crash
+// reports in ~/.local/share/mcphost, and caches in ~/.cache/mcphost. The
+// files of earlier versions are brought over the first time a directory is
+// asked for: ~/.mcphost.yml is copied, and the ~/.mcphost directory moved.
+package paths
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// name is the directory mcphost uses within each base directory
+const name = "mcphost"
+
+// ConfigExtensions are the extensions tried for the config file, in order
+var ConfigExtensions = []string{".yml", ".yaml", ".json"}
+
+var (
+	mu sync.Mutex
+	// dataDir replaces the default data directory if set
+	dataDir string
+	// keepConfig leaves the config file of earlier versions alone
+	keepConfig bool
+
+	migration sync.Once
+)
+
+// SetDataDir makes dir the data directory instead of the default; an empty
+// dir restores the default
+func SetDataDir(dir string) {
+	mu.Lock()
+	defer mu.Unlock()
+	dataDir = dir
+}
+
+// SkipConfigMigration leaves the config file of earlier versions where it
+// is, e.g. because another config file was given. It has no effect once a
+// directory was asked for.
+func SkipConfigMigration() {
+	mu.Lock()
+	defer mu.Unlock()
+	keepConfig = true
+}
+
+// ConfigDir returns the directory of the config file and the agent
+// definitions: $XDG_CONFIG_HOME/mcphost, or ~/.config/mcphost
+func ConfigDir() (string, error) {
+	migration.Do(migrate)
+	return baseDir("XDG_CONFIG_HOME", ".config")
+}
+
+// DataDir returns the directory of the files mcphost writes, such as
+// checkpoints and snapshots: the one given to SetDataDir, or else
+// $XDG_DATA_HOME/mcphost, or ~/.local/share/mcphost
+func DataDir() (string, error) {
+	migration.Do(migrate)
+	mu.Lock()
+	dir := dataDir
+	mu.Unlock()
+	if dir != "" {
+		return dir, nil
+	}
+	return baseDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// CacheDir returns the directory of files that can be recreated if deleted:
+// $XDG_CACHE_HOME/mcphost, or ~/.cache/mcphost
+func CacheDir() (string, error) {
+	migration.Do(migrate)
+	return baseDir("XDG_CACHE_HOME", ".cache")
+}
+
+// Data returns the path of elem within the data directory
+func Data(elem ...string) (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{dir}, elem...)...), nil
+}
+
+// baseDir returns the mcphost directory within the base directory named by
+// the environment variable env, or within home/fallback if it is unset.
+// The specification says to ignore relative paths.
+func baseDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, name), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting home directory: %v", err)
+	}
+	return filepath.Join(home, fallback, name), nil
+}
+
+// migrate brings the files of earlier versions to their XDG locations. The
+// config file is copied rather than moved, since the user may still name it
+// with --config; directories are moved. Nothing is copied or moved over a
+// file that already exists at its new location.
+func migrate() {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	configDir, err := baseDir("XDG_CONFIG_HOME", ".config")
+	if err != nil {
+		return
+	}
+	dataDir, err := baseDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+	if err != nil {
+		return
+	}
+
+	mu.Lock()
+	skipConfig := keepConfig
+	mu.Unlock()
+	if !skipConfig && configFile(configDir) == "" {
+		for _, ext := range ConfigExtensions {
+			from := filepath.Join(home, ".mcphost"+ext)
+			to := filepath.Join(configDir, "config"+ext)
+			if copyFile(from, to) {
+				log.Printf("copied %s to %s, which is read from now on; %s is no longer used and can be removed", from, to, from)
+				break
+			}
+		}
+	}
+
+	legacy := filepath.Join(home, ".mcphost")
+	move(filepath.Join(legacy, "agents"), filepath.Join(configDir, "agents"))
+	for _, dir := range []string{"checkpoints", "crash", "snapshots"} {
+		move(filepath.Join(legacy, dir), filepath.Join(dataDir, dir))
+	}
+	// Only removed if nothing else was kept there
+	os.Remove(legacy)
+}
+
+// configFile returns the config file in dir, or "" if there is none
+func configFile(dir string) string {
+	for _, ext := range ConfigExtensions {
+		path := filepath.Join(dir, "config"+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// copyFile copies from to to with the same permissions, unless from doesn't
+// exist or to does, and reports whether it did
+func copyFile(from, to string) bool {
+	info, err := os.Stat(from)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	data, err := os.ReadFile(from)
+	if err != nil {
+		log.Printf("failed to copy %s to %s: %v", from, to, err)
+		return false
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0700); err != nil {
+		log.Printf("failed to copy %s to %s: %v", from, to, err)
+		return false
+	}
+	file, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		if !os.IsExist(err) {
+			log.Printf("failed to copy %s to %s: %v", from, to, err)
+		}
+		return false
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(to)
+		log.Printf("failed to copy %s to %s: %v", from, to, err)
+		return false
+	}
+	return true
+}
+
+// move renames from to to, unless from doesn't exist or to does, and
+// reports whether it did
+func move(from, to string) bool {
+	if _, err := os.Lstat(from); err != nil {
+		return false
+	}
+	if _, err := os.Lstat(to); err == nil {
+		return false
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0700); err != nil {
+		log.Printf("failed to move %s to %s: %v", from, to, err)
+		return false
+	}
+	if err := os.Rename(from, to); err != nil {
+		log.Printf("failed to move %s to %s: %v", from, to, err)
+		return false
+	}
+	log.Printf("moved %s to %s", from, to)
+	return true
+}
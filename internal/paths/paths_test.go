@@ -0,0 +1,97 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name string
+		// skipConfig calls SkipConfigMigration first
+		skipConfig bool
+		// files are created before migrating, relative to the home directory
+		files []string
+		// exist and missing are checked after migrating
+		exist   []string
+		missing []string
+	}{
+		{
+			name:  "config file copied",
+			files: []string{".mcphost.yml"},
+			exist: []string{".config/mcphost/config.yml", ".mcphost.yml"},
+		},
+		{
+			name:       "config file given",
+			skipConfig: true,
+			files:      []string{".mcphost.yml", ".mcphost/agents/review.md"},
+			exist:      []string{".mcphost.yml", ".config/mcphost/agents/review.md"},
+			missing:    []string{".config/mcphost/config.yml"},
+		},
+		{
+			name:    "first config extension",
+			files:   []string{".mcphost.json", ".mcphost.yaml"},
+			exist:   []string{".config/mcphost/config.yaml", ".mcphost.json"},
+			missing: []string{".config/mcphost/config.json"},
+		},
+		{
+			name:    "existing config kept",
+			files:   []string{".mcphost.yml", ".config/mcphost/config.json"},
+			exist:   []string{".mcphost.yml", ".config/mcphost/config.json"},
+			missing: []string{".config/mcphost/config.yml"},
+		},
+		{
+			name:  "legacy directories",
+			files: []string{".mcphost/agents/review.md", ".mcphost/checkpoints/1.json", ".mcphost/snapshots/2/file"},
+			exist: []string{
+				".config/mcphost/agents/review.md",
+				".local/share/mcphost/checkpoints/1.json",
+				".local/share/mcphost/snapshots/2/file",
+			},
+			missing: []string{".mcphost"},
+		},
+		{
+			name:    "existing directory kept",
+			files:   []string{".mcphost/crash/old.txt", ".local/share/mcphost/crash/new.txt"},
+			exist:   []string{".mcphost/crash/old.txt", ".local/share/mcphost/crash/new.txt"},
+			missing: []string{".local/share/mcphost/crash/old.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_CONFIG_HOME", "")
+			t.Setenv("XDG_DATA_HOME", "")
+			keepConfig = false
+			if tt.skipConfig {
+				SkipConfigMigration()
+			}
+			t.Cleanup(func() { keepConfig = false })
+			for _, file := range tt.files {
+				path := filepath.Join(home, filepath.FromSlash(file))
+				if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(file), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			migrate()
+
+			for _, file := range tt.exist {
+				if _, err := os.Stat(filepath.Join(home, filepath.FromSlash(file))); err != nil {
+					t.Errorf("%s should exist: %v", file, err)
+				}
+			}
+			for _, file := range tt.missing {
+				if _, err := os.Stat(filepath.Join(home, filepath.FromSlash(file))); err == nil {
+					t.Errorf("%s should not exist", file)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package prompt
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcphost/internal/ignore"
+)
+
+// DefaultContextBudget is the default total number of bytes of file content
+// loaded by LoadContext
+const DefaultContextBudget = 200 * 1024
+
+// ContextResult describes the files loaded by LoadContext
+type ContextResult struct {
+	// Content is the message with all included files, empty if there were none
+	Content  string
+	Included []string
+	// Omitted lists files left out because the budget was used up
+	Omitted []string
+	Size    int
+}
+
+// LoadContext reads the files named by paths, relative to baseDir, into a
+// single context message. A path may be a file, a directory, which is read
+// recursively, or a glob pattern where ** matches any number of directories.
+// Directories and globs skip files matched by .gitignore and .mcphostignore
+// files; files named explicitly are always read. Binary files are skipped and files are included in order until budget
+// bytes of content have been used.
+func LoadContext(paths []string, baseDir string, budget int) (*ContextResult, error) {
+	if budget <= 0 {
+		budget = DefaultContextBudget
+	}
+
+	files, err := contextFiles(paths, baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ContextResult{}
+	var sections []string
+	for _, file := range files {
+		abs := file
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(baseDir, file)
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read context file %s: %v", file, err)
+		}
+
+		if result.Size >= budget || int(min(info.Size(), MaxFileReferenceSize)) > budget-result.Size {
+			result.Omitted = append(result.Omitted, file)
+			continue
+		}
+
+		if binary, err := isBinaryFile(abs); err != nil || binary {
+			continue
+		}
+		section, err := formatFileReference(file, abs, info.Size())
+		if err != nil {
+			return nil, err
+		}
+
+		sections = append(sections, section)
+		result.Included = append(result.Included, file)
+		result.Size += int(min(info.Size(), MaxFileReferenceSize))
+	}
+
+	if len(sections) > 0 {
+		result.Content = "The following files were provided as context for this session.\n\n" + strings.Join(sections, "\n\n")
+		if len(result.Omitted) > 0 {
+			result.Content += fmt.Sprintf("\n\n(%d more files were left out to stay within the context budget: %s)",
+				len(result.Omitted), strings.Join(result.Omitted, ", "))
+		}
+	}
+	return result, nil
+}
+
+// isBinaryFile reports whether the start of a file looks like binary content
+func isBinaryFile(name string) (bool, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	sample := make([]byte, 8000)
+	n, err := io.ReadFull(file, sample)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false, err
+	}
+	return isBinary(sample[:n]), nil
+}
+
+// contextFiles expands paths into a list of files without duplicates, in the
+// order given, with each directory's files sorted
+func contextFiles(paths []string, baseDir string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	matcher := ignore.New(baseDir)
+	add := func(file string) {
+		file = filepath.Clean(file)
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if strings.HasPrefix(p, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				p = filepath.Join(home, p[2:])
+			}
+		}
+
+		if strings.ContainsAny(p, "*?[") {
+			matches, err := globFiles(matcher, p, baseDir)
+			if err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("context pattern %s matched no files", p)
+			}
+			for _, m := range matches {
+				add(m)
+			}
+			continue
+		}
+
+		abs := p
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(baseDir, p)
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("context path %s: %v", p, err)
+		}
+		if !info.IsDir() {
+			add(p)
+			continue
+		}
+
+		err = walkFiles(matcher, baseDir, abs, func(file string) {
+			rel, err := filepath.Rel(baseDir, file)
+			if err != nil || filepath.IsAbs(p) {
+				rel = file
+			}
+			add(rel)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("context path %s: %v", p, err)
+		}
+	}
+	return files, nil
+}
+
+// globFiles returns the files under baseDir, or under the pattern's
+// non-glob prefix if it is absolute, that match pattern
+func globFiles(matcher *ignore.Matcher, pattern, baseDir string) ([]string, error) {
+	pattern = filepath.ToSlash(pattern)
+
+	// Walk from the longest directory prefix without glob characters
+	root := baseDir
+	prefix := ""
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments[:len(segments)-1] {
+		if strings.ContainsAny(seg, "*?[") {
+			break
+		}
+		prefix = strings.Join(segments[:i+1], "/")
+	}
+	if path.IsAbs(pattern) {
+		root = filepath.FromSlash(prefix)
+		if prefix == "" {
+			root = "/"
+		}
+	} else if prefix != "" {
+		root = filepath.Join(baseDir, filepath.FromSlash(prefix))
+	}
+
+	var matches []string
+	err := walkFiles(matcher, baseDir, root, func(file string) {
+		name := file
+		if !path.IsAbs(pattern) {
+			rel, err := filepath.Rel(baseDir, file)
+			if err != nil {
+				return
+			}
+			name = rel
+		}
+		if ignore.MatchGlob(pattern, filepath.ToSlash(name)) {
+			matches = append(matches, name)
+		}
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// walkFiles calls fn for every file under root that isn't ignored, using
+// the ignore files of root itself when it is outside baseDir
+func walkFiles(matcher *ignore.Matcher, baseDir, root string, fn func(file string)) error {
+	if rel, err := filepath.Rel(baseDir, root); err != nil || strings.HasPrefix(rel, "..") {
+		matcher = ignore.New(root)
+	}
+	return matcher.Walk(root, fn)
+}
@@ -0,0 +1,191 @@
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxFileReferenceSize is the maximum number of bytes of a referenced file
+// that are inlined into a prompt
+const MaxFileReferenceSize = 100 * 1024
+
+// fileReferencePattern matches @path tokens at the start of the input or
+// after whitespace, so e-mail addresses are left alone
+var fileReferencePattern = regexp.MustCompile(`(^|\s)@([^\s@]+)`)
+
+// ExpandFileReferences appends the contents of every file referenced as
+// @path in input, resolved relative to baseDir. Tokens that don't name an
+// existing regular file are left untouched. It returns the expanded prompt
+// and the paths that were included.
+func ExpandFileReferences(input, baseDir string) (string, []string, error) {
+	var (
+		sections []string
+		included []string
+		seen     = make(map[string]bool)
+	)
+
+	for _, match := range fileReferencePattern.FindAllStringSubmatch(input, -1) {
+		ref := strings.TrimRight(match[2], ".,;:!?)")
+		if ref == "" || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+
+		path := ref
+		if strings.HasPrefix(path, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, path[2:])
+			}
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		section, err := formatFileReference(ref, path, info.Size())
+		if err != nil {
+			return "", nil, err
+		}
+		sections = append(sections, section)
+		included = append(included, ref)
+	}
+
+	if len(sections) == 0 {
+		return input, nil, nil
+	}
+
+	return input + "\n\n" + strings.Join(sections, "\n\n"), included, nil
+}
+
+// partialReferencePattern matches an @path token being typed at the end of
+// the input
+var partialReferencePattern = regexp.MustCompile(`(?:^|\s)@([^\s@]*)$`)
+
+// CompleteFileReference completes the @path token at the end of input with
+// the files and directories in baseDir, as far as they agree, like a shell.
+// A directory that is the only match gets a trailing slash. Hidden files are
+// only offered once the name being typed starts with a dot. Input without
+// such a token, or without matches, is returned unchanged.
+func CompleteFileReference(input, baseDir string) string {
+	match := partialReferencePattern.FindStringSubmatchIndex(input)
+	if match == nil {
+		return input
+	}
+	ref := input[match[2]:match[3]]
+	prefix, name := "", ref
+	if i := strings.LastIndex(ref, "/"); i >= 0 {
+		prefix, name = ref[:i+1], ref[i+1:]
+	}
+
+	dir := prefix
+	if strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, dir[2:])
+		}
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(baseDir, dir)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return input
+	}
+
+	var matches []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), name) || strings.HasPrefix(entry.Name(), ".") && !strings.HasPrefix(name, ".") {
+			continue
+		}
+		candidate := entry.Name()
+		if entry.IsDir() {
+			candidate += "/"
+		}
+		matches = append(matches, candidate)
+	}
+	if len(matches) == 0 {
+		return input
+	}
+
+	common := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	return input[:match[2]] + prefix + common
+}
+
+// formatFileReference reads a file and renders it as a fenced block
+func formatFileReference(ref, path string, size int64) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %v", ref, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxFileReferenceSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", ref, err)
+	}
+
+	if isBinary(data) {
+		return fmt.Sprintf("File: %s\n(binary file, %d bytes, contents omitted)", ref, size), nil
+	}
+
+	content := string(data)
+	note := ""
+	if size > MaxFileReferenceSize {
+		// Don't cut a multi-byte character in half
+		for !utf8.ValidString(content) && len(content) > 0 {
+			content = content[:len(content)-1]
+		}
+		note = fmt.Sprintf("\n(truncated to the first %d of %d bytes)", len(content), size)
+	}
+
+	fence := codeFence(content)
+	lang := strings.TrimPrefix(filepath.Ext(path), ".")
+	return fmt.Sprintf("File: %s\n%s%s\n%s\n%s%s", ref, fence, lang, strings.TrimSuffix(content, "\n"), fence, note), nil
+}
+
+// isBinary reports whether data looks like binary content
+func isBinary(data []byte) bool {
+	sample := data
+	if len(sample) > 8000 {
+		sample = sample[:8000]
+	}
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+	// Allow a truncated multi-byte character at the very end
+	for i := 0; i < utf8.UTFMax && len(sample) > 0; i++ {
+		if utf8.Valid(sample) {
+			return false
+		}
+		sample = sample[:len(sample)-1]
+	}
+	return !utf8.Valid(sample)
+}
+
+// codeFence returns a backtick fence longer than any run of backticks in content
+func codeFence(content string) string {
+	longest, current := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
@@ -0,0 +1,45 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompleteFileReference(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"main.go", "main_test.go", "README.md", ".env", "internal/ui/cli.go"} {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "single match", input: "explain @REA", want: "explain @README.md"},
+		{name: "common prefix", input: "@ma", want: "@main"},
+		{name: "ambiguous", input: "@main", want: "@main"},
+		{name: "directory", input: "look at @int", want: "look at @internal/"},
+		{name: "nested", input: "@internal/ui/c", want: "@internal/ui/cli.go"},
+		{name: "hidden files need a dot", input: "@.e", want: "@.env"},
+		{name: "no match", input: "@missing", want: "@missing"},
+		{name: "not at the end", input: "@REA and more", want: "@REA and more"},
+		{name: "e-mail address", input: "mail me@REA", want: "mail me@REA"},
+		{name: "no reference", input: "hello", want: "hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompleteFileReference(tt.input, dir); got != tt.want {
+				t.Errorf("CompleteFileReference(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
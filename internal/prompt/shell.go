@@ -0,0 +1,122 @@
+package prompt
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxShellOutputSize is the maximum number of bytes of command output that
+// are inlined into a prompt
+const MaxShellOutputSize = 20 * 1024
+
+// ShellConfirmFunc is asked before a command is run; returning false leaves
+// the escape in the prompt unchanged
+type ShellConfirmFunc func(command string) bool
+
+// shellEscape is a command escape found in a prompt
+type shellEscape struct {
+	start, end int
+	command    string
+}
+
+// InterpolateShellCommands runs every !`command` and $(command) escape in
+// input and replaces it with the command's combined output. Each command is
+// passed to confirm first; declined commands are left as typed.
+func InterpolateShellCommands(ctx context.Context, input string, confirm ShellConfirmFunc) string {
+	escapes := findShellEscapes(input)
+	if len(escapes) == 0 {
+		return input
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, esc := range escapes {
+		out.WriteString(input[last:esc.start])
+		last = esc.end
+
+		if confirm != nil && !confirm(esc.command) {
+			out.WriteString(input[esc.start:esc.end])
+			continue
+		}
+		out.WriteString(runShellCommand(ctx, esc.command))
+	}
+	out.WriteString(input[last:])
+
+	return out.String()
+}
+
+// findShellEscapes locates command escapes, honouring nested parentheses in $(...)
+func findShellEscapes(input string) []shellEscape {
+	var escapes []shellEscape
+
+	for i := 0; i < len(input); {
+		switch {
+		case strings.HasPrefix(input[i:], "!`"):
+			end := strings.IndexByte(input[i+2:], '`')
+			if end < 0 {
+				i += 2
+				continue
+			}
+			command := input[i+2 : i+2+end]
+			if strings.TrimSpace(command) != "" {
+				escapes = append(escapes, shellEscape{start: i, end: i + 3 + end, command: command})
+			}
+			i += 3 + end
+		case strings.HasPrefix(input[i:], "$("):
+			depth, j := 1, i+2
+			for ; j < len(input) && depth > 0; j++ {
+				switch input[j] {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				}
+			}
+			if depth != 0 {
+				i += 2
+				continue
+			}
+			command := input[i+2 : j-1]
+			if strings.TrimSpace(command) != "" {
+				escapes = append(escapes, shellEscape{start: i, end: j, command: command})
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+
+	return escapes
+}
+
+// runShellCommand runs a command through the platform shell and returns its
+// combined output, or a description of the failure
+func runShellCommand(ctx context.Context, command string) string {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	output, err := cmd.CombinedOutput()
+	result := strings.TrimRight(string(output), "\n")
+	if len(result) > MaxShellOutputSize {
+		// Start at a rune boundary, so no character is cut in half
+		start := len(result) - MaxShellOutputSize
+		for start < len(result) && !utf8.RuneStart(result[start]) {
+			start++
+		}
+		result = result[start:] + "\n(output truncated to the last 20 KB)"
+	}
+	if err != nil {
+		if result == "" {
+			return "(command failed: " + err.Error() + ")"
+		}
+		return result + "\n(command failed: " + err.Error() + ")"
+	}
+	return result
+}
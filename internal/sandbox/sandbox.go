@@ -0,0 +1,298 @@
+// Package sandbox runs a session on a scratch copy of a project, so that
+// tools can't change the project itself, and reports what they changed.
+package sandbox
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcphost/internal/ignore"
+)
+
+// Status is how a file differs between the project and the sandbox
+type Status string
+
+const (
+	Added    Status = "A"
+	Modified Status = "M"
+	Deleted  Status = "D"
+)
+
+// Change is a file that differs between the project and the sandbox
+type Change struct {
+	// Path is slash-separated and relative to the project, for files
+	// inside it
+	Path   string
+	Status Status
+	// Insertions and Deletions count the lines only found in the sandbox's
+	// or the project's version of a text file
+	Insertions int
+	Deletions  int
+	Binary     bool
+	// Conflict is set when the project's file changed since the sandbox
+	// was made. Apply refuses such changes.
+	Conflict bool
+}
+
+// Sandbox is a scratch copy of a project directory
+type Sandbox struct {
+	// Source is the project directory
+	Source string
+	// Dir is the copy in the temporary directory
+	Dir string
+	// copied holds the hashes of the files copied into the sandbox
+	copied map[string][sha256.Size]byte
+}
+
+// New copies source into a new temporary directory. Files ignored by
+// .gitignore or .mcphostignore, and version control directories, are not
+// copied.
+func New(source string) (*Sandbox, error) {
+	source, err := filepath.Abs(source)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "mcphost-sandbox-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox: %v", err)
+	}
+
+	s := &Sandbox{Source: source, Dir: dir, copied: make(map[string][sha256.Size]byte)}
+	files, err := listFiles(source)
+	if err == nil {
+		for _, rel := range files {
+			var data []byte
+			if data, err = os.ReadFile(filepath.Join(source, rel)); err != nil {
+				break
+			}
+			if err = copyFile(filepath.Join(source, rel), filepath.Join(dir, rel)); err != nil {
+				break
+			}
+			s.copied[rel] = sha256.Sum256(data)
+		}
+	}
+	if err != nil {
+		s.Remove()
+		return nil, fmt.Errorf("failed to copy %s to sandbox: %v", source, err)
+	}
+	return s, nil
+}
+
+// Changes compares the sandbox with the project as it was copied and
+// returns the changed files, sorted by path. Files ignored in the sandbox
+// are left out, as are changes made to the project itself during the
+// session.
+func (s *Sandbox) Changes() ([]Change, error) {
+	after, err := listFiles(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	inSandbox := make(map[string]bool, len(after))
+	var changes []Change
+	for _, rel := range after {
+		inSandbox[rel] = true
+		current, err := os.ReadFile(filepath.Join(s.Dir, rel))
+		if err != nil {
+			return nil, err
+		}
+		sum, copied := s.copied[rel]
+		if copied && sum == sha256.Sum256(current) {
+			continue
+		}
+
+		old, conflict, err := s.project(rel)
+		if err != nil {
+			return nil, err
+		}
+		status := Modified
+		if !copied {
+			status = Added
+		}
+		c := Compare(rel, status, old, current)
+		c.Conflict = conflict
+		changes = append(changes, c)
+	}
+	for rel := range s.copied {
+		if inSandbox[rel] {
+			continue
+		}
+		old, conflict, err := s.project(rel)
+		if err != nil {
+			return nil, err
+		}
+		c := Compare(rel, Deleted, old, nil)
+		c.Conflict = conflict
+		changes = append(changes, c)
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// Apply makes the changes in the project. It changes nothing if any of the
+// project's files changed since the sandbox was made.
+func (s *Sandbox) Apply(changes []Change) error {
+	var conflicts []string
+	for _, c := range changes {
+		_, conflict, err := s.project(c.Path)
+		if err != nil {
+			return err
+		}
+		if conflict {
+			conflicts = append(conflicts, c.Path)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("changed in %s since the sandbox was made: %s", s.Source, strings.Join(conflicts, ", "))
+	}
+
+	for _, c := range changes {
+		target := filepath.Join(s.Source, filepath.FromSlash(c.Path))
+		if c.Status == Deleted {
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to delete %s: %v", c.Path, err)
+			}
+			continue
+		}
+		if err := copyFile(filepath.Join(s.Dir, filepath.FromSlash(c.Path)), target); err != nil {
+			return fmt.Errorf("failed to write %s: %v", c.Path, err)
+		}
+	}
+	return nil
+}
+
+// project reads a file from the project and reports whether it changed
+// since the sandbox was made. A missing file reads as nil.
+func (s *Sandbox) project(rel string) ([]byte, bool, error) {
+	sum, copied := s.copied[rel]
+	data, err := os.ReadFile(filepath.Join(s.Source, filepath.FromSlash(rel)))
+	if os.IsNotExist(err) {
+		return nil, copied, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, !copied || sum != sha256.Sum256(data), nil
+}
+
+// Remove deletes the sandbox
+func (s *Sandbox) Remove() error {
+	return os.RemoveAll(s.Dir)
+}
+
+// Summary describes changes like git diff --stat
+func Summary(changes []Change) string {
+	if len(changes) == 0 {
+		return "No files changed"
+	}
+
+	var b strings.Builder
+	insertions, deletions := 0, 0
+	for _, c := range changes {
+		var conflict string
+		if c.Conflict {
+			conflict = " (also changed in the project)"
+		}
+		if c.Binary {
+			fmt.Fprintf(&b, " %s %s (binary)%s\n", c.Status, c.Path, conflict)
+			continue
+		}
+		fmt.Fprintf(&b, " %s %s | +%d -%d%s\n", c.Status, c.Path, c.Insertions, c.Deletions, conflict)
+		insertions += c.Insertions
+		deletions += c.Deletions
+	}
+	fmt.Fprintf(&b, " %d files changed, %d insertions(+), %d deletions(-)", len(changes), insertions, deletions)
+	return b.String()
+}
+
+// listFiles returns the slash-separated paths of the files below root that
+// aren't ignored
+func listFiles(root string) ([]string, error) {
+	var files []string
+	var relErr error
+	err := ignore.New(root).Walk(root, func(file string) {
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			relErr = err
+			return
+		}
+		files = append(files, filepath.ToSlash(rel))
+	})
+	if err == nil {
+		err = relErr
+	}
+	return files, err
+}
+
+// copyFile copies a file with its permissions, creating the directories
+// it is in
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Compare describes how a file changed between two versions, counting the
+// lines only found in one of them. Lines are compared as multisets, which
+// is close to a line diff for typical edits and needs no alignment.
+func Compare(path string, status Status, old, current []byte) Change {
+	c := Change{Path: path, Status: status}
+	if IsBinary(old) || IsBinary(current) {
+		c.Binary = true
+		return c
+	}
+
+	counts := make(map[string]int)
+	for _, line := range splitLines(old) {
+		counts[line]++
+	}
+	for _, line := range splitLines(current) {
+		if counts[line] > 0 {
+			counts[line]--
+		} else {
+			c.Insertions++
+		}
+	}
+	for _, n := range counts {
+		c.Deletions += n
+	}
+	return c
+}
+
+// IsBinary reports whether a file's content looks binary
+func IsBinary(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
@@ -0,0 +1,181 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		// edit changes the sandbox's copy of the project
+		edit func(dir string) error
+		// project, if set, changes the project during the session
+		project func(dir string) error
+		want    []Change
+	}{
+		{
+			name: "nothing changed",
+			edit: func(dir string) error { return nil },
+			want: nil,
+		},
+		{
+			name: "modified",
+			edit: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {\n\tprintln()\n}\n"), 0644)
+			},
+			want: []Change{{Path: "main.go", Status: Modified, Insertions: 1}},
+		},
+		{
+			name: "added and deleted",
+			edit: func(dir string) error {
+				if err := os.Remove(filepath.Join(dir, "docs", "notes.txt")); err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(dir, "new.txt"), []byte("a\nb\n"), 0644)
+			},
+			want: []Change{
+				{Path: "docs/notes.txt", Status: Deleted, Deletions: 2},
+				{Path: "new.txt", Status: Added, Insertions: 2},
+			},
+		},
+		{
+			name: "binary",
+			edit: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "image.bin"), []byte{0, 1, 2}, 0644)
+			},
+			want: []Change{{Path: "image.bin", Status: Added, Binary: true}},
+		},
+		{
+			name: "changed in the project only",
+			edit: func(dir string) error { return nil },
+			project: func(dir string) error {
+				if err := os.Remove(filepath.Join(dir, "docs", "notes.txt")); err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+			},
+			want: nil,
+		},
+		{
+			name: "changed in both",
+			edit: func(dir string) error {
+				if err := os.WriteFile(filepath.Join(dir, "new.txt"), []byte("sandbox\n"), 0644); err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {\n\tprintln()\n}\n"), 0644)
+			},
+			project: func(dir string) error {
+				if err := os.WriteFile(filepath.Join(dir, "new.txt"), []byte("project\n"), 0644); err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+			},
+			want: []Change{
+				{Path: "main.go", Status: Modified, Insertions: 4, Conflict: true},
+				{Path: "new.txt", Status: Added, Insertions: 1, Deletions: 1, Conflict: true},
+			},
+		},
+		{
+			name: "ignored files left out",
+			edit: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "debug.log"), []byte("log\n"), 0644)
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := newProject(t)
+			s, err := New(project)
+			if err != nil {
+				t.Fatalf("New() error: %v", err)
+			}
+			defer s.Remove()
+
+			if err := tt.edit(s.Dir); err != nil {
+				t.Fatal(err)
+			}
+			if tt.project != nil {
+				if err := tt.project(project); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := s.Changes()
+			if err != nil {
+				t.Fatalf("Changes() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Changes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	project := newProject(t)
+	s, err := New(project)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	defer s.Remove()
+
+	if err := os.WriteFile(filepath.Join(s.Dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(s.Dir, "docs", "notes.txt")); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := s.Changes()
+	if err != nil {
+		t.Fatalf("Changes() error: %v", err)
+	}
+
+	// A file changed in the project since then stops the whole apply
+	if err := os.WriteFile(filepath.Join(project, "docs", "notes.txt"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Apply(changes); err == nil {
+		t.Fatal("Apply() with a file changed in the project succeeded")
+	}
+	if data, _ := os.ReadFile(filepath.Join(project, "main.go")); string(data) == "package main\n" {
+		t.Error("Apply() that failed changed main.go")
+	}
+
+	if err := os.WriteFile(filepath.Join(project, "docs", "notes.txt"), []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Apply(changes); err != nil {
+		t.Fatalf("Apply() error: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(project, "main.go")); string(data) != "package main\n" {
+		t.Errorf("main.go = %q after Apply()", data)
+	}
+	if _, err := os.Stat(filepath.Join(project, "docs", "notes.txt")); err == nil {
+		t.Error("docs/notes.txt not deleted by Apply()")
+	}
+}
+
+// newProject creates a project directory with a few files
+func newProject(t *testing.T) string {
+	t.Helper()
+	project := t.TempDir()
+	files := map[string]string{
+		".gitignore":     "*.log\n",
+		"main.go":        "package main\n\nfunc main() {\n}\n",
+		"docs/notes.txt": "one\ntwo\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(project, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return project
+}
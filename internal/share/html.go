@@ -0,0 +1,147 @@
+// Package share exports sessions as standalone HTML pages and uploads them
+package share
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/mark3labs/mcphost/internal/citations"
+	"github.com/mark3labs/mcphost/internal/transcript"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Session is the content of a shared page
+type Session struct {
+	Title  string
+	Model  string
+	Time   time.Time
+	Events []transcript.Event
+}
+
+// entry is a single block of the page. A tool entry combines a tool call with
+// its result.
+type entry struct {
+	Kind    string
+	Time    time.Time
+	Content template.HTML
+	Text    string
+	Tool    string
+	Args    string
+	Result  string
+	IsError bool
+	Pending bool
+}
+
+// markdown renders assistant messages. Raw HTML in messages is not rendered.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// RenderHTML renders the session as a standalone HTML page with inline CSS
+// and collapsible tool calls
+func RenderHTML(session Session) ([]byte, error) {
+	entries, err := buildEntries(session.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = pageTemplate.Execute(&buf, struct {
+		Session
+		Entries []entry
+	}{session, entries})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render page: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// buildEntries converts transcript events to page entries, pairing each tool
+// call with the result that follows it
+func buildEntries(events []transcript.Event) ([]entry, error) {
+	var entries []entry
+	pending := make(map[string]int)
+	for _, event := range events {
+		switch event.Type {
+		case "user":
+			entries = append(entries, entry{Kind: "user", Time: event.Time, Text: event.Content})
+		case "assistant", "final":
+			var buf bytes.Buffer
+			if err := markdown.Convert([]byte(citations.WithFooter(event.Content, event.Sources)), &buf); err != nil {
+				return nil, fmt.Errorf("failed to render message: %v", err)
+			}
+			// The HTML is generated by goldmark, which escapes raw HTML
+			entries = append(entries, entry{Kind: "assistant", Time: event.Time, Content: template.HTML(buf.String())})
+		case "tool_call":
+			pending[event.Tool] = len(entries)
+			entries = append(entries, entry{Kind: "tool", Time: event.Time, Tool: event.Tool, Args: event.Args, Pending: true})
+		case "tool_result":
+			if i, ok := pending[event.Tool]; ok {
+				delete(pending, event.Tool)
+				entries[i].Result = event.Content
+				entries[i].IsError = event.IsError
+				entries[i].Pending = false
+				continue
+			}
+			entries = append(entries, entry{Kind: "tool", Time: event.Time, Tool: event.Tool, Args: event.Args,
+				Result: event.Content, IsError: event.IsError})
+		case "error":
+			entries = append(entries, entry{Kind: "error", Time: event.Time, Text: event.Content})
+		}
+	}
+	return entries, nil
+}
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+  body { margin: 0; background: #f6f7f9; color: #1f2328; font: 15px/1.55 -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; }
+  main { max-width: 860px; margin: 0 auto; padding: 24px 16px 48px; }
+  header { border-bottom: 1px solid #d0d7de; margin-bottom: 24px; }
+  header h1 { font-size: 22px; margin: 0 0 4px; }
+  header p { color: #656d76; margin: 0 0 16px; }
+  .entry { background: #fff; border: 1px solid #d0d7de; border-radius: 8px; margin: 12px 0; padding: 12px 16px; }
+  .entry .meta { color: #656d76; font-size: 12px; margin-bottom: 6px; }
+  .user { border-left: 4px solid #8250df; }
+  .user .text { white-space: pre-wrap; }
+  .assistant { border-left: 4px solid #1a7f37; }
+  .assistant > :last-child { margin-bottom: 0; }
+  .error { border-left: 4px solid #cf222e; color: #cf222e; white-space: pre-wrap; }
+  details.tool { background: #fff; border: 1px solid #d0d7de; border-radius: 8px; margin: 8px 0 8px 24px; padding: 8px 12px; }
+  details.tool summary { cursor: pointer; font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 13px; }
+  details.tool.failed summary { color: #cf222e; }
+  pre, code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 13px; }
+  pre { background: #f6f8fa; border-radius: 6px; overflow-x: auto; padding: 8px 12px; white-space: pre-wrap; word-break: break-word; }
+  table { border-collapse: collapse; }
+  th, td { border: 1px solid #d0d7de; padding: 4px 8px; }
+</style>
+</head>
+<body>
+<main>
+<header>
+  <h1>{{.Title}}</h1>
+  <p>{{if .Model}}{{.Model}} · {{end}}{{.Time.Format "2 January 2006 15:04"}}</p>
+</header>
+{{range .Entries}}
+{{- if eq .Kind "user"}}
+<section class="entry user"><div class="meta">User · {{.Time.Format "15:04:05"}}</div><div class="text">{{.Text}}</div></section>
+{{- else if eq .Kind "assistant"}}
+<section class="entry assistant"><div class="meta">Assistant · {{.Time.Format "15:04:05"}}</div>{{.Content}}</section>
+{{- else if eq .Kind "tool"}}
+<details class="tool{{if .IsError}} failed{{end}}"><summary>{{if .IsError}}✗{{else}}⚙{{end}} {{.Tool}}</summary>
+{{- if .Args}}<div class="meta">Arguments</div><pre>{{.Args}}</pre>{{end}}
+{{- if .Pending}}<div class="meta">No result</div>{{else}}<div class="meta">Result</div><pre>{{.Result}}</pre>{{end}}
+</details>
+{{- else if eq .Kind "error"}}
+<section class="entry error">{{.Text}}</section>
+{{- end}}
+{{- end}}
+</main>
+</body>
+</html>
+`))
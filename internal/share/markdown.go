@@ -0,0 +1,85 @@
+package share
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcphost/internal/citations"
+)
+
+// RenderMarkdown renders the session as a Markdown document, with each tool
+// call and its result in a collapsible block
+func RenderMarkdown(session Session) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", session.Title)
+	var meta []string
+	if session.Model != "" {
+		meta = append(meta, "Model: "+session.Model)
+	}
+	if !session.Time.IsZero() {
+		meta = append(meta, session.Time.Format("2006-01-02 15:04:05"))
+	}
+	if len(meta) > 0 {
+		fmt.Fprintf(&b, "_%s_\n\n", strings.Join(meta, " · "))
+	}
+
+	// open is the tool whose call block awaits its result
+	open := ""
+	closeOpen := func() {
+		if open != "" {
+			b.WriteString("_No result._\n\n</details>\n\n")
+			open = ""
+		}
+	}
+	openTool := func(tool, args string) {
+		closeOpen()
+		open = tool
+		fmt.Fprintf(&b, "<details>\n<summary>Tool: %s</summary>\n\n", tool)
+		if args != "" {
+			b.WriteString(codeBlock("json", args))
+		}
+	}
+
+	for _, event := range session.Events {
+		switch event.Type {
+		case "user":
+			closeOpen()
+			fmt.Fprintf(&b, "## User\n\n%s\n\n", event.Content)
+		case "assistant", "final":
+			closeOpen()
+			header := "Assistant"
+			if event.Model != "" {
+				header = fmt.Sprintf("Assistant (%s)", event.Model)
+			}
+			fmt.Fprintf(&b, "## %s\n\n%s\n\n", header, strings.TrimRight(citations.WithFooter(event.Content, event.Sources), "\n"))
+		case "tool_call":
+			openTool(event.Tool, event.Args)
+		case "tool_result":
+			if open != event.Tool {
+				openTool(event.Tool, event.Args)
+			}
+			if event.IsError {
+				b.WriteString("**Error:**\n\n")
+			} else {
+				b.WriteString("**Result:**\n\n")
+			}
+			b.WriteString(codeBlock("", event.Content))
+			b.WriteString("</details>\n\n")
+			open = ""
+		case "error":
+			closeOpen()
+			fmt.Fprintf(&b, "> **Error:** %s\n\n", event.Content)
+		}
+	}
+	closeOpen()
+	return []byte(b.String())
+}
+
+// codeBlock fences text with more backticks than it contains in a row
+func codeBlock(lang, text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fmt.Sprintf("%s%s\n%s\n%s\n\n", fence, lang, strings.TrimRight(text, "\n"), fence)
+}
@@ -0,0 +1,100 @@
+package share
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// uploadTimeout bounds a single upload request
+const uploadTimeout = 30 * time.Second
+
+// gistsURL is the GitHub API endpoint for creating gists
+const gistsURL = "https://api.github.com/gists"
+
+// UploadGist uploads the page as a secret GitHub gist and returns its URL
+func UploadGist(ctx context.Context, token, filename, description string, page []byte) (string, error) {
+	body, err := json.Marshal(map[string]any{
+		"description": description,
+		"public":      false,
+		"files": map[string]any{
+			filename: map[string]string{"content": string(page)},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode gist: %v", err)
+	}
+
+	respBody, err := post(ctx, gistsURL, "application/json", body, map[string]string{
+		"Authorization": "Bearer " + token,
+		"Accept":        "application/vnd.github+json",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create gist: %v", err)
+	}
+
+	var gist struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.Unmarshal(respBody, &gist); err != nil || gist.HTMLURL == "" {
+		return "", fmt.Errorf("unexpected response from GitHub")
+	}
+	return gist.HTMLURL, nil
+}
+
+// Upload posts the page to an endpoint and returns the URL it responds with,
+// either as plain text or as a JSON object with a url field
+func Upload(ctx context.Context, endpoint string, page []byte) (string, error) {
+	respBody, err := post(ctx, endpoint, "text/html; charset=utf-8", page, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload to %s: %v", endpoint, err)
+	}
+
+	var result struct {
+		URL string `json:"url"`
+	}
+	if json.Unmarshal(respBody, &result) == nil && result.URL != "" {
+		return result.URL, nil
+	}
+
+	url := strings.TrimSpace(string(respBody))
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return "", fmt.Errorf("%s did not respond with a URL", endpoint)
+	}
+	return url, nil
+}
+
+// post sends body to url and returns the response body of a successful request
+func post(ctx context.Context, url, contentType string, body []byte, headers map[string]string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, uploadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	return respBody, nil
+}
@@ -0,0 +1,166 @@
+// Package snapshot records the state of a working directory as commits in
+// a shadow git repository, so that changes made by tools can be rolled
+// back. The project's own repository, if any, is never touched.
+package snapshot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcphost/internal/paths"
+)
+
+// ref is the branch of the shadow repository holding the snapshots
+const ref = "refs/heads/snapshots"
+
+// Snapshot is a recorded state of the working directory
+type Snapshot struct {
+	ID      string
+	Time    time.Time
+	Message string
+}
+
+// Store is the shadow repository of a working directory
+type Store struct {
+	gitDir   string
+	workTree string
+}
+
+// Dir returns the directory holding the shadow repositories
+func Dir() (string, error) {
+	return paths.Data("snapshots")
+}
+
+// Open returns the store of workTree, creating its shadow repository if
+// needed. Each directory has its own repository, named after a hash of its
+// path.
+func Open(workTree string) (*Store, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("snapshots need git: %v", err)
+	}
+	workTree, err := filepath.Abs(workTree)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(workTree))
+	s := &Store{gitDir: filepath.Join(dir, hex.EncodeToString(sum[:8])+".git"), workTree: workTree}
+
+	if _, err := os.Stat(filepath.Join(s.gitDir, "HEAD")); os.IsNotExist(err) {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create snapshot directory: %v", err)
+		}
+		if out, err := exec.Command("git", "init", "--quiet", "--bare", s.gitDir).CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("failed to create snapshot repository: %s", strings.TrimSpace(string(out)))
+		}
+	}
+	return s, nil
+}
+
+// Take records the working directory unless it is unchanged since the last
+// snapshot. It returns the ID of the new or unchanged snapshot and whether
+// one was created. Files ignored by the project's .gitignore are left out.
+func (s *Store) Take(message string) (string, bool, error) {
+	if _, err := s.git("add", "--all", "."); err != nil {
+		return "", false, err
+	}
+	tree, err := s.git("write-tree")
+	if err != nil {
+		return "", false, err
+	}
+
+	args := []string{"commit-tree", tree, "-m", message}
+	if parent, err := s.git("rev-parse", "--verify", "--quiet", ref); err == nil {
+		if parentTree, err := s.git("rev-parse", parent+"^{tree}"); err == nil && parentTree == tree {
+			return short(parent), false, nil
+		}
+		args = append(args, "-p", parent)
+	}
+	commit, err := s.git(args...)
+	if err != nil {
+		return "", false, err
+	}
+	if _, err := s.git("update-ref", ref, commit); err != nil {
+		return "", false, err
+	}
+	return short(commit), true, nil
+}
+
+// List returns the snapshots, newest first
+func (s *Store) List() ([]Snapshot, error) {
+	if _, err := s.git("rev-parse", "--verify", "--quiet", ref); err != nil {
+		return nil, nil
+	}
+	out, err := s.git("log", "--format=%H%x09%ct%x09%s", ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		unix, _ := strconv.ParseInt(fields[1], 10, 64)
+		snapshots = append(snapshots, Snapshot{ID: short(fields[0]), Time: time.Unix(unix, 0), Message: fields[2]})
+	}
+	return snapshots, nil
+}
+
+// Restore makes the working directory match a snapshot: changed files are
+// restored and files added since are deleted. The current state is recorded
+// first, so a restore can be undone. It returns the ID of that snapshot.
+func (s *Store) Restore(id string) (string, error) {
+	commit, err := s.git("rev-parse", "--verify", "--quiet", id+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("no snapshot %s", id)
+	}
+	current, _, err := s.Take("before restoring " + short(commit))
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.git("read-tree", "--reset", "-u", commit); err != nil {
+		return "", err
+	}
+	return current, nil
+}
+
+// git runs a git command on the shadow repository and returns its trimmed
+// output
+func (s *Store) git(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"--git-dir", s.gitDir, "--work-tree", s.workTree}, args...)...)
+	cmd.Dir = s.workTree
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=mcphost", "GIT_AUTHOR_EMAIL=mcphost@localhost",
+		"GIT_COMMITTER_NAME=mcphost", "GIT_COMMITTER_EMAIL=mcphost@localhost")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// short abbreviates a commit hash for display
+func short(commit string) string {
+	if len(commit) > 10 {
+		return commit[:10]
+	}
+	return commit
+}
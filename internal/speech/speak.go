@@ -0,0 +1,305 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+// maxSpeechInput is the most text the OpenAI speech API reads per request
+const maxSpeechInput = 4000
+
+// windowsSpeak reads stdin aloud with the .NET speech synthesizer
+const windowsSpeak = `Add-Type -AssemblyName System.Speech; $s = New-Object System.Speech.Synthesis.SpeechSynthesizer; if ($env:MCPHOST_VOICE) { $s.SelectVoice($env:MCPHOST_VOICE) }; $s.Speak([Console]::In.ReadToEnd())`
+
+// windowsPlay plays the WAV file in $env:MCPHOST_AUDIO
+const windowsPlay = `(New-Object Media.SoundPlayer $env:MCPHOST_AUDIO).PlaySync()`
+
+// Speaker reads text aloud in the background
+type Speaker struct {
+	config config.TextToSpeechConfig
+	// apiKey and baseURL are used by the openai backend
+	apiKey  string
+	baseURL string
+
+	// OnError, if set, is called with errors of reading text aloud
+	OnError func(error)
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewSpeaker checks the text-to-speech settings and returns a speaker.
+// apiKey and baseURL are the OpenAI credentials, used by the openai backend;
+// an empty baseURL is the OpenAI API.
+func NewSpeaker(cfg config.TextToSpeechConfig, apiKey, baseURL string) (*Speaker, error) {
+	switch cfg.Backend {
+	case "", "system":
+		if len(cfg.Command) == 0 {
+			cfg.Command = systemSynthesizer(cfg.Voice)
+		}
+		if len(cfg.Command) == 0 {
+			return nil, fmt.Errorf("no speech synthesizer found; install espeak-ng or set a command in text-to-speech settings")
+		}
+		if _, err := exec.LookPath(cfg.Command[0]); err != nil {
+			return nil, fmt.Errorf("speech synthesizer: %v", err)
+		}
+	case "openai":
+		if apiKey == "" {
+			return nil, fmt.Errorf("the openai text-to-speech backend needs an OpenAI API key. Use --openai-api-key flag or OPENAI_API_KEY environment variable")
+		}
+		if cfg.Model == "" {
+			cfg.Model = "tts-1"
+		}
+		if cfg.Voice == "" {
+			cfg.Voice = "alloy"
+		}
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+		if len(cfg.Play) == 0 {
+			cfg.Play = audioPlayer()
+		}
+		if len(cfg.Play) == 0 {
+			return nil, fmt.Errorf("no audio player found; install sox or set a play command in text-to-speech settings")
+		}
+		if _, err := exec.LookPath(cfg.Play[0]); err != nil {
+			return nil, fmt.Errorf("audio player: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown text-to-speech backend %q (expected system or openai)", cfg.Backend)
+	}
+	return &Speaker{config: cfg, apiKey: apiKey, baseURL: baseURL}, nil
+}
+
+// Speak reads text, written in Markdown, aloud in the background,
+// interrupting anything still being read. Code blocks aren't read.
+func (s *Speaker) Speak(text string) {
+	text = plainText(text)
+	if text == "" {
+		return
+	}
+
+	s.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	s.mu.Lock()
+	s.cancel, s.done = cancel, done
+	s.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		var err error
+		if s.config.Backend == "openai" {
+			err = s.speakOpenAI(ctx, text)
+		} else {
+			err = s.speakSystem(ctx, text)
+		}
+		if err != nil && ctx.Err() == nil && s.OnError != nil {
+			s.OnError(err)
+		}
+	}()
+}
+
+// Stop stops reading aloud
+func (s *Speaker) Stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// Wait waits until the text being read aloud has been read
+func (s *Speaker) Wait() {
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+func (s *Speaker) speakSystem(ctx context.Context, text string) error {
+	cmd := exec.CommandContext(ctx, s.config.Command[0], s.config.Command[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Env = append(os.Environ(), "MCPHOST_VOICE="+s.config.Voice)
+	return runQuiet(cmd, "speech synthesizer")
+}
+
+func (s *Speaker) speakOpenAI(ctx context.Context, text string) error {
+	for _, chunk := range splitText(text, maxSpeechInput) {
+		if err := s.speakOpenAIChunk(ctx, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Speaker) speakOpenAIChunk(ctx context.Context, text string) error {
+	body, err := json.Marshal(map[string]string{
+		"model":           s.config.Model,
+		"voice":           s.config.Voice,
+		"input":           text,
+		"response_format": "wav",
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.baseURL, "/")+"/audio/speech", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("speech request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("speech request failed: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	tmp, err := os.CreateTemp("", "mcphost-speech-*.wav")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("speech request failed: %v", err)
+	}
+
+	args := make([]string, len(s.config.Play))
+	for i, arg := range s.config.Play {
+		args[i] = strings.ReplaceAll(arg, "{file}", tmp.Name())
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), "MCPHOST_AUDIO="+tmp.Name())
+	return runQuiet(cmd, "audio player")
+}
+
+// runQuiet runs a command, returning its stderr output as the error if it
+// fails
+func runQuiet(cmd *exec.Cmd, name string) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %s", name, msg)
+		}
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// systemSynthesizer returns the command of the platform's speech
+// synthesizer, reading text on stdin, or nil if none is installed
+func systemSynthesizer(voice string) []string {
+	switch runtime.GOOS {
+	case "darwin":
+		if voice != "" {
+			return []string{"say", "-v", voice}
+		}
+		return []string{"say"}
+	case "windows":
+		return []string{"powershell", "-NoProfile", "-Command", windowsSpeak}
+	}
+	for _, name := range []string{"espeak-ng", "espeak"} {
+		if _, err := exec.LookPath(name); err == nil {
+			if voice != "" {
+				return []string{name, "--stdin", "-v", voice}
+			}
+			return []string{name, "--stdin"}
+		}
+	}
+	return nil
+}
+
+// audioPlayer returns the command of an installed WAV player, or nil if
+// none is found
+func audioPlayer() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"afplay", "{file}"}
+	case "windows":
+		return []string{"powershell", "-NoProfile", "-Command", windowsPlay}
+	}
+	for _, player := range [][]string{
+		{"paplay", "{file}"},
+		{"aplay", "-q", "{file}"},
+		{"play", "-q", "{file}"},
+		{"ffplay", "-nodisp", "-autoexit", "-loglevel", "quiet", "{file}"},
+	} {
+		if _, err := exec.LookPath(player[0]); err == nil {
+			return player
+		}
+	}
+	return nil
+}
+
+var (
+	codeBlock    = regexp.MustCompile("(?s)```.*?(```|$)")
+	markdownLink = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	markup       = regexp.MustCompile("(?m)^\\s{0,3}(#{1,6}|>|[-*+]|\\d+\\.)\\s+|[*_`~]+|^\\s*\\|?\\s*:?-{3,}.*$")
+	blankLines   = regexp.MustCompile(`\n{3,}`)
+)
+
+// plainText turns Markdown into text that reads well aloud: code blocks are
+// left out, links are replaced with their text, and markup is removed
+func plainText(markdown string) string {
+	text := codeBlock.ReplaceAllString(markdown, "")
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = markup.ReplaceAllString(text, "")
+	text = strings.ReplaceAll(text, "|", " ")
+	text = blankLines.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// splitText splits text into chunks of at most limit bytes, at paragraph or
+// sentence ends where possible
+func splitText(text string, limit int) []string {
+	var chunks []string
+	for len(text) > limit {
+		cut := strings.LastIndex(text[:limit], "\n\n")
+		if cut <= 0 {
+			cut = strings.LastIndex(text[:limit], ". ")
+			if cut > 0 {
+				cut++
+			}
+		}
+		if cut <= 0 {
+			cut = strings.LastIndex(text[:limit], " ")
+		}
+		if cut <= 0 {
+			cut = limit
+		}
+		chunks = append(chunks, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
@@ -0,0 +1,225 @@
+// Package speech records and transcribes spoken prompts, and reads answers
+// aloud, using external recorders and speech services.
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+// defaultRecord records 16 kHz mono audio with sox, starting when sound is
+// heard and stopping after two seconds of silence or a minute
+var defaultRecord = []string{"rec", "-q", "-c", "1", "-r", "16000", "-b", "16", "{file}",
+	"silence", "1", "0.1", "3%", "1", "2.0", "3%", "trim", "0", "60"}
+
+// ErrNothingHeard is returned by Record when no sound was recorded within
+// startTimeout
+var ErrNothingHeard = errors.New("nothing was heard")
+
+// startTimeout is how long Record waits for sound. rec's silence effect
+// writes nothing until someone speaks, so without it Record would wait
+// forever in a quiet room.
+var startTimeout = 10 * time.Second
+
+// wavHeaderSize is the size of a WAV file without samples
+const wavHeaderSize = 44
+
+// Listener records spoken prompts and transcribes them
+type Listener struct {
+	config config.SpeechToTextConfig
+	// apiKey and baseURL are used by the openai backend
+	apiKey  string
+	baseURL string
+}
+
+// NewListener checks the speech-to-text settings and returns a listener.
+// apiKey and baseURL are the OpenAI credentials, used by the openai backend;
+// an empty baseURL is the OpenAI API.
+func NewListener(cfg config.SpeechToTextConfig, apiKey, baseURL string) (*Listener, error) {
+	if len(cfg.Record) == 0 {
+		cfg.Record = defaultRecord
+	}
+	if _, err := exec.LookPath(cfg.Record[0]); err != nil {
+		if cfg.Record[0] == defaultRecord[0] {
+			return nil, fmt.Errorf("recording needs sox (rec), or a record command in speech-to-text settings: %v", err)
+		}
+		return nil, fmt.Errorf("record command: %v", err)
+	}
+
+	switch cfg.Backend {
+	case "", "openai":
+		if apiKey == "" {
+			return nil, fmt.Errorf("the openai speech-to-text backend needs an OpenAI API key. Use --openai-api-key flag or OPENAI_API_KEY environment variable")
+		}
+		if cfg.Model == "" {
+			cfg.Model = "whisper-1"
+		}
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+	case "whisper-cpp":
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("the whisper-cpp speech-to-text backend needs the path of a model file as model")
+		}
+		if cfg.Command == "" {
+			cfg.Command = "whisper-cli"
+		}
+		if _, err := exec.LookPath(cfg.Command); err != nil {
+			return nil, fmt.Errorf("whisper.cpp: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown speech-to-text backend %q (expected openai or whisper-cpp)", cfg.Backend)
+	}
+	return &Listener{config: cfg, apiKey: apiKey, baseURL: baseURL}, nil
+}
+
+// Record records a spoken prompt to a temporary WAV file and returns its
+// path. The caller removes the file. If the recorder has written no samples
+// after startTimeout, it is stopped and Record returns ErrNothingHeard.
+func (l *Listener) Record(ctx context.Context) (string, error) {
+	tmp, err := os.CreateTemp("", "mcphost-voice-*.wav")
+	if err != nil {
+		return "", err
+	}
+	file := tmp.Name()
+	tmp.Close()
+
+	args := make([]string, len(l.config.Record))
+	for i, arg := range l.config.Record {
+		args[i] = strings.ReplaceAll(arg, "{file}", file)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = &stderr
+	// Don't wait for children of a stopped recorder holding stderr open
+	cmd.WaitDelay = time.Second
+	if err := cmd.Start(); err != nil {
+		os.Remove(file)
+		return "", fmt.Errorf("recording failed: %v", err)
+	}
+
+	var silent atomic.Bool
+	timer := time.AfterFunc(startTimeout, func() {
+		if info, err := os.Stat(file); err != nil || info.Size() <= wavHeaderSize {
+			silent.Store(true)
+			cancel()
+		}
+	})
+	err = cmd.Wait()
+	timer.Stop()
+	if silent.Load() {
+		os.Remove(file)
+		return "", ErrNothingHeard
+	}
+	if err != nil {
+		os.Remove(file)
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("recording failed: %s", msg)
+		}
+		return "", fmt.Errorf("recording failed: %v", err)
+	}
+	return file, nil
+}
+
+// Transcribe returns the text spoken in a recording, which is empty if
+// nothing was said
+func (l *Listener) Transcribe(ctx context.Context, file string) (string, error) {
+	if info, err := os.Stat(file); err != nil || info.Size() == 0 {
+		return "", nil
+	}
+	if l.config.Backend == "whisper-cpp" {
+		return l.transcribeWhisperCpp(ctx, file)
+	}
+	return l.transcribeOpenAI(ctx, file)
+}
+
+func (l *Listener) transcribeOpenAI(ctx context.Context, file string) (string, error) {
+	audio, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filepath.Base(file))
+	if err != nil {
+		return "", err
+	}
+	part.Write(audio)
+	form.WriteField("model", l.config.Model)
+	if l.config.Language != "" {
+		form.WriteField("language", l.config.Language)
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(l.baseURL, "/")+"/audio/transcriptions", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+l.apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("transcription failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("transcription failed: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid transcription response: %v", err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+func (l *Listener) transcribeWhisperCpp(ctx context.Context, file string) (string, error) {
+	args := []string{"-m", l.config.Model, "-f", file, "--no-timestamps", "--no-prints"}
+	if l.config.Language != "" {
+		args = append(args, "-l", l.config.Language)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, l.config.Command, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("transcription failed: %s", msg)
+		}
+		return "", fmt.Errorf("transcription failed: %v", err)
+	}
+
+	// whisper.cpp marks silence and noise as [BLANK_AUDIO] and the like
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, " "), nil
+}
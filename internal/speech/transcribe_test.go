@@ -0,0 +1,55 @@
+package speech
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+func TestRecordStartTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("needs sh")
+	}
+	old := startTimeout
+	startTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { startTimeout = old })
+
+	tests := []struct {
+		name string
+		// script stands in for rec, with $0 the file to write
+		script  string
+		wantErr error
+	}{
+		{
+			name:    "nobody speaks",
+			script:  `head -c 44 /dev/zero > "$0"; sleep 5`,
+			wantErr: ErrNothingHeard,
+		},
+		{
+			name:   "speech before the timeout",
+			script: `head -c 1000 /dev/zero > "$0"; sleep 0.5`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Listener{config: config.SpeechToTextConfig{Record: []string{"sh", "-c", tt.script, "{file}"}}}
+			start := time.Now()
+			file, err := l.Record(context.Background())
+			if file != "" {
+				defer os.Remove(file)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Record() error = %v, want %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Errorf("Record() took %v", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Package tokens estimates how many tokens text uses with the tokenizers of
+// the supported providers, without loading the tokenizers themselves.
+package tokens
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Tokenizer describes the token density of a provider's tokenizer relative
+// to OpenAI's o200k_base, on which the base estimate is modelled
+type Tokenizer struct {
+	Provider string
+	Name     string
+	factor   float64
+}
+
+// Tokenizers are the tokenizers estimates can be made for
+var Tokenizers = []Tokenizer{
+	{Provider: "anthropic", Name: "Claude", factor: 1.15},
+	{Provider: "openai", Name: "o200k_base", factor: 1.0},
+	{Provider: "google", Name: "Gemini SentencePiece", factor: 0.95},
+	{Provider: "ollama", Name: "Llama 3 tiktoken", factor: 1.05},
+}
+
+// ForProvider returns the tokenizer for a provider, falling back to OpenAI's
+// for providers that are OpenAI-compatible or unknown
+func ForProvider(provider string) Tokenizer {
+	for _, t := range Tokenizers {
+		if t.Provider == provider {
+			return t
+		}
+	}
+	return Tokenizers[1]
+}
+
+// Estimate returns the estimated number of tokens in text
+func (t Tokenizer) Estimate(text string) int {
+	if text == "" {
+		return 0
+	}
+	return int(math.Ceil(baseEstimate(text) * t.factor))
+}
+
+// baseEstimate approximates a BPE tokenizer: words of up to six letters
+// are one token and longer words a token per six letters, digits group
+// in threes and punctuation in pairs, spaces attach to the following word,
+// and CJK characters are about a token each
+func baseEstimate(text string) float64 {
+	var tokens float64
+	letters, digits, punct := 0, 0, 0
+	flush := func() {
+		if letters > 0 {
+			tokens += float64(1 + (letters-1)/6)
+		}
+		if digits > 0 {
+			tokens += math.Ceil(float64(digits) / 3)
+		}
+		if punct > 0 {
+			tokens += math.Ceil(float64(punct) / 2)
+		}
+		letters, digits, punct = 0, 0, 0
+	}
+
+	newlines := 0
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flush()
+			tokens++
+		case unicode.IsLetter(r):
+			if digits > 0 || punct > 0 {
+				flush()
+			}
+			// Non-Latin scripts are split into more pieces
+			if r > unicode.MaxLatin1 {
+				letters += 2
+			} else {
+				letters++
+			}
+		case unicode.IsDigit(r):
+			if letters > 0 || punct > 0 {
+				flush()
+			}
+			digits++
+		case r == '\n':
+			flush()
+			newlines++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			if letters > 0 || digits > 0 {
+				flush()
+			}
+			punct++
+		}
+		if r != '\n' && newlines > 0 {
+			// Runs of newlines are merged into one token
+			tokens++
+			newlines = 0
+		}
+	}
+	flush()
+	if newlines > 0 {
+		tokens++
+	}
+	return tokens
+}
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// Provider returns the provider part of a provider:model string
+func Provider(model string) string {
+	provider, _, _ := strings.Cut(model, ":")
+	return provider
+}
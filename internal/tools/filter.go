@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/itchyny/gojq"
+)
+
+// resultFilter is a jq expression applied to a tool's JSON results before
+// they are given to the model, e.g. to drop noisy fields from API responses
+type resultFilter struct {
+	expr string
+	code *gojq.Code
+}
+
+// newResultFilter compiles a jq expression
+func newResultFilter(expr string) (*resultFilter, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jq expression %q: %v", expr, err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jq expression %q: %v", expr, err)
+	}
+	return &resultFilter{expr: expr, code: code}, nil
+}
+
+// apply filters a tool result. Results from MCP tools are serialized
+// CallToolResults, so the filter runs on each text content item that holds
+// JSON; other results are filtered as a whole. Content that isn't JSON, or
+// on which the filter fails, is left unchanged.
+func (f *resultFilter) apply(ctx context.Context, result string) string {
+	var callResult map[string]any
+	if err := json.Unmarshal([]byte(result), &callResult); err != nil {
+		return result
+	}
+
+	content, ok := callResult["content"].([]any)
+	if !ok {
+		filtered, err := f.run(ctx, callResult)
+		if err != nil {
+			return result
+		}
+		return filtered
+	}
+
+	for _, item := range content {
+		part, ok := item.(map[string]any)
+		if !ok || part["type"] != "text" {
+			continue
+		}
+		text, ok := part["text"].(string)
+		if !ok {
+			continue
+		}
+		var data any
+		if err := json.Unmarshal([]byte(text), &data); err != nil {
+			continue
+		}
+		if filtered, err := f.run(ctx, data); err == nil {
+			part["text"] = filtered
+		}
+	}
+
+	filtered, err := json.Marshal(callResult)
+	if err != nil {
+		return result
+	}
+	return string(filtered)
+}
+
+// run runs the filter on a JSON value. A single string output is returned
+// as is; other outputs are JSON encoded, one per line.
+func (f *resultFilter) run(ctx context.Context, input any) (string, error) {
+	var outputs []string
+	iter := f.code.RunWithContext(ctx, input)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			return "", fmt.Errorf("jq expression %q failed: %v", f.expr, err)
+		}
+		if s, ok := v.(string); ok {
+			outputs = append(outputs, s)
+			continue
+		}
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		outputs = append(outputs, string(encoded))
+	}
+	return strings.Join(outputs, "\n"), nil
+}
@@ -2,103 +2,387 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	einomcp "github.com/cloudwego/eino-ext/components/tool/mcp"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/schema"
-	einomcp "github.com/cloudwego/eino-ext/components/tool/mcp"
 	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/internal/builtin"
 	"github.com/mark3labs/mcphost/internal/config"
 )
 
+// clientCloseTimeout bounds how long a single MCP client may take to shut down
+// gracefully before its process group is killed.
+const clientCloseTimeout = 2 * time.Second
+
 // MCPToolManager manages MCP tools and clients
 type MCPToolManager struct {
-	clients map[string]client.MCPClient
-	tools   []tool.BaseTool
+	clients   map[string]client.MCPClient
+	processes map[string]*processGroup
+	logs      map[string]*serverLog
+	tools     []tool.BaseTool
+	// origins maps the names tools are offered under to their servers and
+	// MCP names
+	origins map[string]ToolOrigin
+	// servers are the initialize results of the started servers
+	servers map[string]ServerInfo
+	debug   bool
+
+	// mu guards clients, tools, origins, servers, processes and logs
+	mu        sync.Mutex
+	closeOnce sync.Once
+	closeErr  error
 }
 
 // NewMCPToolManager creates a new MCP tool manager
 func NewMCPToolManager() *MCPToolManager {
 	return &MCPToolManager{
-		clients: make(map[string]client.MCPClient),
-		tools:   make([]tool.BaseTool, 0),
+		clients:   make(map[string]client.MCPClient),
+		processes: make(map[string]*processGroup),
+		logs:      make(map[string]*serverLog),
+		tools:     make([]tool.BaseTool, 0),
+		origins:   make(map[string]ToolOrigin),
+		servers:   make(map[string]ServerInfo),
 	}
 }
 
+// ToolOrigin is the server and MCP name of a tool offered to the model
+type ToolOrigin struct {
+	Server string
+	Tool   string
+}
+
+// Origin returns the server and MCP name of the tool offered under name
+func (m *MCPToolManager) Origin(name string) (ToolOrigin, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	origin, ok := m.origins[name]
+	return origin, ok
+}
+
+// Origins returns the servers and MCP names of all loaded tools, keyed by
+// the names they are offered under
+func (m *MCPToolManager) Origins() map[string]ToolOrigin {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.origins)
+}
+
+// ServerInfo is what a server told about itself when it was initialized
+type ServerInfo struct {
+	Name            string
+	Version         string
+	ProtocolVersion string
+	// Capabilities are the features the server declared, e.g. tools or
+	// "resources (subscribe)"
+	Capabilities []string
+	// Instructions describe how to use the server's tools
+	Instructions string
+}
+
+// Servers returns the initialize results of the started servers, keyed by
+// server name
+func (m *MCPToolManager) Servers() map[string]ServerInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.servers)
+}
+
 // LoadTools loads tools from MCP servers based on configuration
 func (m *MCPToolManager) LoadTools(ctx context.Context, config *config.Config) error {
+	m.debug = config.Debug
+
 	for serverName, serverConfig := range config.MCPServers {
-		client, err := m.createMCPClient(ctx, serverName, serverConfig)
+		tools, err := m.loadServer(ctx, config, serverName, serverConfig)
 		if err != nil {
-			return fmt.Errorf("failed to create MCP client for %s: %v", serverName, err)
+			return err
 		}
+		m.mu.Lock()
+		m.tools = append(m.tools, tools...)
+		m.mu.Unlock()
+	}
 
-		m.clients[serverName] = client
+	return nil
+}
 
-		// Initialize the client
-		if err := m.initializeClient(ctx, client); err != nil {
-			return fmt.Errorf("failed to initialize MCP client for %s: %v", serverName, err)
-		}
+// AddServer starts a server of config that isn't running yet, e.g. one added
+// to a reloaded config, and returns its tools, which are added to the loaded
+// tools. If the server fails to start it is closed.
+func (m *MCPToolManager) AddServer(ctx context.Context, config *config.Config, serverName string) ([]tool.BaseTool, error) {
+	serverConfig, ok := config.MCPServers[serverName]
+	if !ok {
+		return nil, fmt.Errorf("server %s is not configured", serverName)
+	}
+	m.mu.Lock()
+	_, running := m.clients[serverName]
+	m.mu.Unlock()
+	if running {
+		return nil, fmt.Errorf("server %s is already running", serverName)
+	}
 
-		// Get allowed tools list for this server
-		var allowedTools []string
-		if len(serverConfig.AllowedTools) > 0 {
-			allowedTools = serverConfig.AllowedTools
-		} else {
-			// If no allowed tools specified, get all tools and filter out excluded ones
-			toolsResult, err := client.ListTools(ctx, mcp.ListToolsRequest{})
-			if err != nil {
-				return fmt.Errorf("failed to list tools from server %s: %v", serverName, err)
-			}
+	tools, err := m.loadServer(ctx, config, serverName, serverConfig)
+	if err != nil {
+		m.closeServer(serverName)
+		return nil, err
+	}
+	m.mu.Lock()
+	m.tools = append(slices.Clip(m.tools), tools...)
+	m.mu.Unlock()
+	return tools, nil
+}
 
-			for _, mcpTool := range toolsResult.Tools {
-				if !m.isToolExcluded(mcpTool.Name, serverConfig.ExcludedTools) {
-					allowedTools = append(allowedTools, mcpTool.Name)
-				}
+// loadServer starts a server and returns its tools, wrapped with their
+// server prefix, result filters and guardrails
+func (m *MCPToolManager) loadServer(ctx context.Context, config *config.Config, serverName string, serverConfig config.MCPServerConfig) ([]tool.BaseTool, error) {
+	client, err := m.createMCPClient(ctx, serverName, serverConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MCP client for %s: %v", serverName, err)
+	}
+
+	m.mu.Lock()
+	m.clients[serverName] = client
+	m.mu.Unlock()
+
+	// Initialize the client
+	initResult, err := m.initializeClient(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize MCP client for %s: %v", serverName, err)
+	}
+	m.mu.Lock()
+	m.servers[serverName] = newServerInfo(initResult)
+	m.mu.Unlock()
+
+	// Get allowed tools list for this server
+	var allowedTools []string
+	if len(serverConfig.AllowedTools) > 0 {
+		allowedTools = serverConfig.AllowedTools
+	} else {
+		// If no allowed tools specified, get all tools and filter out excluded ones
+		toolsResult, err := client.ListTools(ctx, mcp.ListToolsRequest{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tools from server %s: %v", serverName, err)
+		}
+
+		for _, mcpTool := range toolsResult.Tools {
+			if !m.isToolExcluded(mcpTool.Name, serverConfig.ExcludedTools) {
+				allowedTools = append(allowedTools, mcpTool.Name)
 			}
 		}
+	}
 
-		// Use eino's MCP tool adapter
-		mcpTools, err := einomcp.GetTools(ctx, &einomcp.Config{
-			Cli:          client,
-			ToolNameList: allowedTools,
-		})
+	// Use eino's MCP tool adapter
+	mcpTools, err := einomcp.GetTools(ctx, &einomcp.Config{
+		Cli:          client,
+		ToolNameList: allowedTools,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MCP tools from server %s: %v", serverName, err)
+	}
+
+	filters := make(map[string]*resultFilter, len(serverConfig.ResultFilters))
+	for toolName, expr := range serverConfig.ResultFilters {
+		filter, err := newResultFilter(expr)
 		if err != nil {
-			return fmt.Errorf("failed to get MCP tools from server %s: %v", serverName, err)
+			return nil, fmt.Errorf("invalid result filter for %s tool %s: %v", serverName, toolName, err)
 		}
+		filters[toolName] = filter
+	}
+
+	// Add tools directly - eino's MCP adapter should handle everything
+	var tools []tool.BaseTool
+	origins := make(map[string]ToolOrigin, len(mcpTools))
+	for _, mcpTool := range mcpTools {
+		if invokableTool, ok := mcpTool.(tool.InvokableTool); ok {
+			info, err := invokableTool.Info(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get tool info from server %s: %v", serverName, err)
+			}
+			filter, ok := filters[info.Name]
+			if !ok {
+				filter = filters["*"]
+			}
 
-		// Add tools directly - eino's MCP adapter should handle everything
-		for _, mcpTool := range mcpTools {
-			// Check if the tool already has a prefix, if not add server prefix
-			if invokableTool, ok := mcpTool.(tool.InvokableTool); ok {
-				wrappedTool := &PrefixedTool{
-					InvokableTool: invokableTool,
-					prefix:        serverName,
+			name := config.ToolName(serverName, info.Name)
+			if other, exists := origins[name]; exists {
+				return nil, fmt.Errorf("tools %s and %s of server %s are both named %s", other.Tool, info.Name, serverName, name)
+			}
+			origins[name] = ToolOrigin{Server: serverName, Tool: info.Name}
+
+			// Guardrail patterns may name the server, whatever its prefix
+			var guardrails []string
+			for _, guardrail := range config.Guardrails {
+				if guardrail.Matches(fmt.Sprintf("%s__%s", serverName, info.Name)) || guardrail.Matches(name) {
+					guardrails = append(guardrails, strings.TrimSpace(guardrail.Prompt))
 				}
-				m.tools = append(m.tools, wrappedTool)
-			} else {
-				return fmt.Errorf("tool from server %s does not implement InvokableTool interface", serverName)
 			}
+
+			tools = append(tools, newPrefixedTool(invokableTool, info, name, filter, guardrails))
+		} else {
+			return nil, fmt.Errorf("tool from server %s does not implement InvokableTool interface", serverName)
 		}
 	}
 
-	return nil
+	// Tool names must be unique across servers, which unprefixed or
+	// custom prefixed tools may not be
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name := range origins {
+		if other, exists := m.origins[name]; exists {
+			return nil, fmt.Errorf("tool %s of server %s has the same name as a tool of server %s; give one of them a different prefix", name, serverName, other.Server)
+		}
+	}
+	maps.Copy(m.origins, origins)
+
+	return tools, nil
 }
 
-// GetTools returns all loaded tools
+// GetTools returns all loaded tools. It is safe to call while tools run.
 func (m *MCPToolManager) GetTools() []tool.BaseTool {
-	return m.tools
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clip(m.tools)
 }
 
-// Close closes all MCP clients
+// GetServerLogs returns the most recent stderr output of a stdio MCP server
+func (m *MCPToolManager) GetServerLogs(serverName string) ([]string, bool) {
+	m.mu.Lock()
+	log, ok := m.logs[serverName]
+	m.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	return log.snapshot(), true
+}
+
+// Close closes all MCP clients and kills any stdio server processes that are
+// still running. It is safe to call Close more than once.
 func (m *MCPToolManager) Close() error {
-	for name, client := range m.clients {
-		if err := client.Close(); err != nil {
-			return fmt.Errorf("failed to close client %s: %v", name, err)
+	m.closeOnce.Do(func() {
+		var errs []error
+
+		m.mu.Lock()
+		clients := maps.Clone(m.clients)
+		m.mu.Unlock()
+		for name, client := range clients {
+			if err := closeWithTimeout(client, clientCloseTimeout); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close client %s: %v", name, err))
+			}
+		}
+
+		// Kill the process groups even after a clean close, since servers
+		// launched through wrappers like npx may leave children behind
+		m.mu.Lock()
+		for name, group := range m.processes {
+			if err := group.kill(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to kill processes for %s: %v", name, err))
+			}
 		}
+		m.mu.Unlock()
+
+		m.closeErr = errors.Join(errs...)
+	})
+	return m.closeErr
+}
+
+// closeServer closes the client of a server that failed to start and kills
+// its processes
+func (m *MCPToolManager) closeServer(serverName string) {
+	m.mu.Lock()
+	client := m.clients[serverName]
+	group := m.processes[serverName]
+	delete(m.clients, serverName)
+	delete(m.processes, serverName)
+	delete(m.servers, serverName)
+	m.mu.Unlock()
+
+	if client != nil {
+		closeWithTimeout(client, clientCloseTimeout)
+	}
+	if group != nil {
+		group.kill()
+	}
+}
+
+// startStdioClient starts an MCP server's command and returns a client
+// talking to it over the command's stdin and stdout. The command is started
+// here rather than by the transport so that it runs in its own process group.
+func startStdioClient(ctx context.Context, command string, env, args []string) (*client.Client, *processGroup, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Env = append(os.Environ(), env...)
+	group := newProcessGroup(cmd)
+
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create stdin pipe: %v", err)
+	}
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		closeFiles(stdinR, stdinW)
+		return nil, nil, fmt.Errorf("failed to create stdout pipe: %v", err)
+	}
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		closeFiles(stdinR, stdinW, stdoutR, stdoutW)
+		return nil, nil, fmt.Errorf("failed to create stderr pipe: %v", err)
+	}
+
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdinR, stdoutW, stderrW
+	err = cmd.Start()
+	// The child has its own copies of its ends of the pipes
+	closeFiles(stdinR, stdoutW, stderrW)
+	if err != nil {
+		closeFiles(stdinW, stdoutR, stderrR)
+		return nil, nil, fmt.Errorf("failed to start command: %v", err)
+	}
+	// Reap the process whenever it exits
+	go cmd.Wait()
+
+	if err := group.attach(); err != nil {
+		group.kill()
+		closeFiles(stdinW, stdoutR, stderrR)
+		return nil, nil, err
+	}
+
+	stdioTransport := transport.NewIO(stdoutR, stdinW, stderrR)
+	if err := stdioTransport.Start(ctx); err != nil {
+		group.kill()
+		closeFiles(stdinW, stdoutR, stderrR)
+		return nil, nil, fmt.Errorf("failed to start stdio transport: %v", err)
+	}
+	return client.NewClient(stdioTransport), group, nil
+}
+
+// closeFiles closes files, ignoring errors
+func closeFiles(files ...*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// closeWithTimeout closes a client, giving up after the given timeout
+func closeWithTimeout(c client.MCPClient, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Close()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %s", timeout)
 	}
-	return nil
 }
 
 // isToolExcluded checks if a tool is in the excluded list
@@ -112,9 +396,41 @@ func (m *MCPToolManager) isToolExcluded(toolName string, excludedTools []string)
 }
 
 func (m *MCPToolManager) createMCPClient(ctx context.Context, serverName string, serverConfig config.MCPServerConfig) (client.MCPClient, error) {
-	if serverConfig.Command != "" {
-		// STDIO client
-		return client.NewStdioMCPClient(serverConfig.Command, nil, serverConfig.Args...)
+	if serverConfig.Builtin != "" {
+		// Builtin server, running in-process
+		srv, err := builtin.NewServer(serverConfig.Builtin, serverConfig.Options)
+		if err != nil {
+			return nil, err
+		}
+		inProcessClient, err := client.NewInProcessClient(srv)
+		if err != nil {
+			return nil, err
+		}
+		if err := inProcessClient.Start(ctx); err != nil {
+			return nil, fmt.Errorf("failed to start builtin server: %v", err)
+		}
+		return inProcessClient, nil
+	} else if serverConfig.Command != "" {
+		// STDIO client, started in its own process group so it can be cleaned up
+		stdioClient, group, err := startStdioClient(ctx, serverConfig.Command, nil, serverConfig.Args)
+		if err != nil {
+			return nil, err
+		}
+		m.mu.Lock()
+		m.processes[serverName] = group
+		m.mu.Unlock()
+
+		// Capture stderr so it doesn't corrupt the terminal UI; it is only
+		// echoed inline in debug mode
+		if stderr, ok := client.GetStderr(stdioClient); ok {
+			log := newServerLog()
+			m.mu.Lock()
+			m.logs[serverName] = log
+			m.mu.Unlock()
+			go log.capture(serverName, stderr, m.debug)
+		}
+
+		return stdioClient, nil
 	} else if serverConfig.URL != "" {
 		// SSE client
 		sseClient, err := client.NewSSEMCPClient(serverConfig.URL)
@@ -130,43 +446,100 @@ func (m *MCPToolManager) createMCPClient(ctx context.Context, serverName string,
 		return sseClient, nil
 	}
 
-	return nil, fmt.Errorf("invalid server configuration for %s: must specify either command or url", serverName)
+	return nil, fmt.Errorf("invalid server configuration for %s: must specify command, url or builtin", serverName)
 }
 
-func (m *MCPToolManager) initializeClient(ctx context.Context, client client.MCPClient) error {
+func (m *MCPToolManager) initializeClient(ctx context.Context, client client.MCPClient) (*mcp.InitializeResult, error) {
 	initRequest := mcp.InitializeRequest{}
 	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
 	initRequest.Params.ClientInfo = mcp.Implementation{
 		Name:    "mcphost",
 		Version: "1.0.0",
 	}
+	// Sampling and roots are requests from the server to the client, which
+	// the MCP clients used here can't answer, so they aren't declared rather
+	// than leave servers waiting for replies that never come
+	initRequest.Params.Capabilities = mcp.ClientCapabilities{}
+
+	return client.Initialize(ctx, initRequest)
+}
+
+// newServerInfo describes a server from its initialize result
+func newServerInfo(result *mcp.InitializeResult) ServerInfo {
+	info := ServerInfo{
+		Name:            result.ServerInfo.Name,
+		Version:         result.ServerInfo.Version,
+		ProtocolVersion: result.ProtocolVersion,
+		Instructions:    strings.TrimSpace(result.Instructions),
+	}
+
+	caps := result.Capabilities
+	if caps.Tools != nil {
+		info.Capabilities = append(info.Capabilities, capability("tools", caps.Tools.ListChanged, false))
+	}
+	if caps.Resources != nil {
+		info.Capabilities = append(info.Capabilities, capability("resources", caps.Resources.ListChanged, caps.Resources.Subscribe))
+	}
+	if caps.Prompts != nil {
+		info.Capabilities = append(info.Capabilities, capability("prompts", caps.Prompts.ListChanged, false))
+	}
+	if caps.Logging != nil {
+		info.Capabilities = append(info.Capabilities, "logging")
+	}
+	for _, name := range slices.Sorted(maps.Keys(caps.Experimental)) {
+		info.Capabilities = append(info.Capabilities, name+" (experimental)")
+	}
+	return info
+}
 
-	_, err := client.Initialize(ctx, initRequest)
-	return err
+// capability names a server capability with its options, e.g.
+// "resources (subscribe, listChanged)"
+func capability(name string, listChanged, subscribe bool) string {
+	var options []string
+	if subscribe {
+		options = append(options, "subscribe")
+	}
+	if listChanged {
+		options = append(options, "listChanged")
+	}
+	if len(options) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, strings.Join(options, ", "))
 }
 
-// PrefixedTool wraps an eino tool to add a server prefix to its name
+// PrefixedTool wraps an eino tool to offer it under a name with its server's
+// prefix
 type PrefixedTool struct {
 	tool.InvokableTool
-	prefix string
+	// info is offered to the model. The wrapped tool's own info is left
+	// alone, since the MCP adapter calls the server by the name in it.
+	info   schema.ToolInfo
+	filter *resultFilter
+}
+
+// newPrefixedTool wraps a tool whose info is info, offering it as name with
+// the guardrails prepended to its description
+func newPrefixedTool(t tool.InvokableTool, info *schema.ToolInfo, name string, filter *resultFilter, guardrails []string) *PrefixedTool {
+	p := &PrefixedTool{InvokableTool: t, info: *info, filter: filter}
+	p.info.Name = name
+	if len(guardrails) > 0 {
+		p.info.Desc = strings.Join(guardrails, "\n\n") + "\n\n" + info.Desc
+	}
+	return p
 }
 
 // Info returns the tool information with prefixed name
 func (p *PrefixedTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
-	info, err := p.InvokableTool.Info(ctx)
-	if err != nil {
-		return nil, err
-	}
-	
-	// Add server prefix to tool name only if it doesn't already have one
-	if !hasPrefix(info.Name, p.prefix) {
-		info.Name = fmt.Sprintf("%s__%s", p.prefix, info.Name)
-	}
-	return info, nil
+	info := p.info
+	return &info, nil
 }
 
-// hasPrefix checks if the tool name already has the server prefix
-func hasPrefix(toolName, prefix string) bool {
-	expectedPrefix := prefix + "__"
-	return len(toolName) > len(expectedPrefix) && toolName[:len(expectedPrefix)] == expectedPrefix
+// InvokableRun runs the tool and applies the configured result filter, if any
+func (p *PrefixedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	result, err := p.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+	if err != nil || p.filter == nil {
+		return result, err
+	}
+	return p.filter.apply(ctx, result), nil
 }
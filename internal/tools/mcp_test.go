@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	einomcp "github.com/cloudwego/eino-ext/components/tool/mcp"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+// fakeTool returns the arguments it is run with
+type fakeTool struct {
+	info schema.ToolInfo
+}
+
+func (f *fakeTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &f.info, nil
+}
+
+func (f *fakeTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return argumentsInJSON, nil
+}
+
+func TestPrefixedToolInfo(t *testing.T) {
+	wrapped := &fakeTool{info: schema.ToolInfo{Name: "read_file", Desc: "Reads a file."}}
+	p := newPrefixedTool(wrapped, &wrapped.info, "fs__read_file", nil, []string{"Only read files in the project."})
+
+	for i := range 2 {
+		info, err := p.Info(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Name != "fs__read_file" || info.Desc != "Only read files in the project.\n\nReads a file." {
+			t.Errorf("Info call %d = %q: %q", i+1, info.Name, info.Desc)
+		}
+		// Callers changing the returned info don't change the tool
+		info.Desc = "changed"
+	}
+	if wrapped.info.Name != "read_file" || wrapped.info.Desc != "Reads a file." {
+		t.Errorf("wrapped tool info changed to %q: %q", wrapped.info.Name, wrapped.info.Desc)
+	}
+}
+
+// fakeClient offers one tool and records the names of the tools called
+type fakeClient struct {
+	client.MCPClient
+	tool   string
+	called []string
+}
+
+func (f *fakeClient) ListTools(ctx context.Context, request mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
+	return &mcp.ListToolsResult{Tools: []mcp.Tool{mcp.NewTool(f.tool)}}, nil
+}
+
+func (f *fakeClient) CallTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	f.called = append(f.called, request.Params.Name)
+	return mcp.NewToolResultText("ok"), nil
+}
+
+func TestPrefixedToolCallsServerName(t *testing.T) {
+	ctx := context.Background()
+	cli := &fakeClient{tool: "my.tool"}
+	mcpTools, err := einomcp.GetTools(ctx, &einomcp.Config{Cli: cli})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped := mcpTools[0].(tool.InvokableTool)
+	info, err := wrapped.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := (&config.Config{}).ToolName("srv", info.Name)
+	p := newPrefixedTool(wrapped, info, name, nil, nil)
+
+	offered, err := p.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offered.Name != "srv__my_tool" {
+		t.Errorf("offered as %q, want srv__my_tool", offered.Name)
+	}
+	if _, err := p.InvokableRun(ctx, "{}"); err != nil {
+		t.Fatal(err)
+	}
+	if len(cli.called) != 1 || cli.called[0] != "my.tool" {
+		t.Errorf("server called with %q, want my.tool", cli.called)
+	}
+}
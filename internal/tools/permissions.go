@@ -0,0 +1,197 @@
+package tools
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/mark3labs/mcphost/internal/builtin"
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+// PermissionPolicy decides which tool calls need user confirmation. Rules
+// are full tool names ("server__tool"), server wildcards ("server__*") or
+// "*" for every tool. Tool names and wildcards use the server's prefix and
+// the tool separator of the config; servers without a prefix have no
+// wildcard. It is safe for concurrent use.
+//
+// Tools of sensitive builtin servers, such as screenshot, need confirmation
+// even when confirmation mode is off, unless a rule naming their server
+// approves them; "*" doesn't.
+//
+// In read-only mode tools matching the read-only patterns are blocked
+// whatever the rules say.
+type PermissionPolicy struct {
+	mu        sync.RWMutex
+	confirm   bool
+	approved  map[string]struct{}
+	sensitive map[string]struct{}
+	// readOnly are the patterns of blocked tools; nil outside read-only mode
+	readOnly []string
+	// wildcards are the wildcard rules of the servers with a prefix
+	wildcards map[string]string
+	// origin looks up the server of a tool
+	origin func(toolName string) (ToolOrigin, bool)
+}
+
+// NewPermissionPolicy creates a policy from the autoApprove lists in the
+// config. When confirm is false no tool call ever needs confirmation. origin
+// looks up the server of a tool, e.g. MCPToolManager.Origin.
+func NewPermissionPolicy(cfg *config.Config, confirm bool, origin func(toolName string) (ToolOrigin, bool)) *PermissionPolicy {
+	p := &PermissionPolicy{
+		confirm:   confirm,
+		approved:  make(map[string]struct{}),
+		sensitive: make(map[string]struct{}),
+		wildcards: make(map[string]string),
+		origin:    origin,
+	}
+
+	if cfg != nil {
+		p.addServers(cfg)
+	}
+
+	return p
+}
+
+// addServers approves the autoApprove lists of the servers of cfg and
+// records their wildcards and which are sensitive
+func (p *PermissionPolicy) addServers(cfg *config.Config) {
+	for serverName, serverConfig := range cfg.MCPServers {
+		for _, toolName := range serverConfig.AutoApprove {
+			p.approved[cfg.ToolName(serverName, toolName)] = struct{}{}
+		}
+		if wildcard := cfg.ToolWildcard(serverName); wildcard != "" {
+			p.wildcards[serverName] = wildcard
+		} else {
+			delete(p.wildcards, serverName)
+		}
+		if serverConfig.Builtin != "" && builtin.Sensitive(serverConfig.Builtin) {
+			p.sensitive[serverName] = struct{}{}
+		}
+	}
+}
+
+// NeedsApproval reports whether a call to the given tool must be confirmed
+func (p *PermissionPolicy) NeedsApproval(toolName string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var origin ToolOrigin
+	found := false
+	if p.origin != nil {
+		origin, found = p.origin(toolName)
+	}
+	_, sensitive := p.sensitive[origin.Server]
+	sensitive = sensitive && found
+	if !p.confirm && !sensitive {
+		return false
+	}
+	if _, ok := p.approved["*"]; ok && !sensitive {
+		return false
+	}
+	if _, ok := p.approved[toolName]; ok {
+		return false
+	}
+	if wildcard, ok := p.wildcards[origin.Server]; found && ok {
+		if _, ok := p.approved[wildcard]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Blocked reports whether read-only mode blocks the given tool, and the
+// pattern it matches. Patterns are matched against the name the model sees,
+// the prefixed name (server__tool) and the tool's own name.
+func (p *PermissionPolicy) Blocked(toolName string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.readOnly == nil {
+		return "", false
+	}
+	names := []string{toolName}
+	if p.origin != nil {
+		if origin, ok := p.origin(toolName); ok {
+			names = append(names, origin.Server+"__"+origin.Tool, origin.Tool)
+		}
+	}
+	return config.MatchTool(p.readOnly, names...)
+}
+
+// SetReadOnly turns read-only mode on with the given patterns of tools to
+// block, or off if patterns is nil
+func (p *PermissionPolicy) SetReadOnly(patterns []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.readOnly = patterns
+}
+
+// ReadOnly reports whether read-only mode is on
+func (p *PermissionPolicy) ReadOnly() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.readOnly != nil
+}
+
+// Approve adds a rule so matching tools run without confirmation
+func (p *PermissionPolicy) Approve(rule string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.approved[rule] = struct{}{}
+}
+
+// Revoke removes a rule and reports whether it existed
+func (p *PermissionPolicy) Revoke(rule string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.approved[rule]; !ok {
+		return false
+	}
+	delete(p.approved, rule)
+	return true
+}
+
+// SetConfirm enables or disables confirmation for tools that aren't approved
+func (p *PermissionPolicy) SetConfirm(confirm bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.confirm = confirm
+}
+
+// Confirm reports whether confirmation mode is enabled
+func (p *PermissionPolicy) Confirm() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.confirm
+}
+
+// Rules returns the auto-approve rules in sorted order
+func (p *PermissionPolicy) Rules() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	rules := make([]string, 0, len(p.approved))
+	for rule := range p.approved {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+// Reload applies the autoApprove lists of a reloaded config: rules the
+// current config had and the reloaded one doesn't are revoked, and new ones
+// are approved. Other rules, such as those added with Approve, are kept.
+// Sensitive builtin servers new in the reloaded config need confirmation
+// like those loaded at start.
+func (p *PermissionPolicy) Reload(current, reloaded *config.Config) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for serverName, serverConfig := range current.MCPServers {
+		for _, toolName := range serverConfig.AutoApprove {
+			delete(p.approved, current.ToolName(serverName, toolName))
+		}
+	}
+	p.addServers(reloaded)
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package tools
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// processGroup tracks a stdio MCP server together with everything it spawns
+// (e.g. npx -> node) so the whole tree can be terminated on shutdown.
+type processGroup struct {
+	cmd *exec.Cmd
+}
+
+// newProcessGroup configures cmd to start in its own process group.
+func newProcessGroup(cmd *exec.Cmd) *processGroup {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return &processGroup{cmd: cmd}
+}
+
+// attach is a no-op on Unix; the process group is created at start.
+func (g *processGroup) attach() error {
+	return nil
+}
+
+// kill terminates every process in the group.
+func (g *processGroup) kill() error {
+	if g.cmd.Process == nil {
+		return nil
+	}
+
+	// A negative PID targets the whole process group
+	err := syscall.Kill(-g.cmd.Process.Pid, syscall.SIGKILL)
+	if err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+//go:build windows
+
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// processGroup tracks a stdio MCP server together with everything it spawns
+// (e.g. npx -> node) so the whole tree can be terminated on shutdown.
+type processGroup struct {
+	cmd *exec.Cmd
+	job windows.Handle
+}
+
+// newProcessGroup configures cmd to start in its own process group.
+func newProcessGroup(cmd *exec.Cmd) *processGroup {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: windows.CREATE_NEW_PROCESS_GROUP}
+	return &processGroup{cmd: cmd}
+}
+
+// attach assigns the started process to a job object that kills all of its
+// descendants when the job is terminated or its handle is closed.
+func (g *processGroup) attach() error {
+	if g.cmd.Process == nil {
+		return nil
+	}
+
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create job object: %v", err)
+	}
+
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+		BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{
+			LimitFlags: windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE,
+		},
+	}
+	if _, err := windows.SetInformationJobObject(
+		job,
+		windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)),
+		uint32(unsafe.Sizeof(info)),
+	); err != nil {
+		windows.CloseHandle(job)
+		return fmt.Errorf("failed to configure job object: %v", err)
+	}
+
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(g.cmd.Process.Pid))
+	if err != nil {
+		windows.CloseHandle(job)
+		return fmt.Errorf("failed to open process: %v", err)
+	}
+	defer windows.CloseHandle(process)
+
+	if err := windows.AssignProcessToJobObject(job, process); err != nil {
+		windows.CloseHandle(job)
+		return fmt.Errorf("failed to assign process to job object: %v", err)
+	}
+
+	g.job = job
+	return nil
+}
+
+// kill terminates every process in the job.
+func (g *processGroup) kill() error {
+	if g.job == 0 {
+		if g.cmd.Process == nil {
+			return nil
+		}
+		if err := g.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return err
+		}
+		return nil
+	}
+
+	err := windows.TerminateJobObject(g.job, 1)
+	windows.CloseHandle(g.job)
+	g.job = 0
+	return err
+}
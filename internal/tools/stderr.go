@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// maxServerLogLines is the number of stderr lines kept per server
+const maxServerLogLines = 500
+
+// serverLog is a ring buffer holding the most recent stderr output of a
+// stdio MCP server
+type serverLog struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+func newServerLog() *serverLog {
+	return &serverLog{lines: make([]string, maxServerLogLines)}
+}
+
+// add appends a line, overwriting the oldest one when the buffer is full
+func (l *serverLog) add(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lines[l.next] = line
+	l.next = (l.next + 1) % len(l.lines)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// snapshot returns the buffered lines, oldest first
+func (l *serverLog) snapshot() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.full {
+		return append([]string(nil), l.lines[:l.next]...)
+	}
+	out := make([]string, 0, len(l.lines))
+	out = append(out, l.lines[l.next:]...)
+	return append(out, l.lines[:l.next]...)
+}
+
+// capture reads r line by line into the log until EOF. When echo is true each
+// line is also written to stderr, prefixed with the server name.
+func (l *serverLog) capture(serverName string, r io.Reader, echo bool) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		l.add(line)
+		if echo {
+			fmt.Fprintf(os.Stderr, "[%s] %s\n", serverName, line)
+		}
+	}
+}
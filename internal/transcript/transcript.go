@@ -0,0 +1,269 @@
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcphost/internal/citations"
+)
+
+// Format is the on-disk transcript format
+type Format string
+
+const (
+	FormatText  Format = "text"
+	FormatJSONL Format = "jsonl"
+)
+
+// Event is a single transcript entry
+type Event struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Content string    `json:"content,omitempty"`
+	Tool    string    `json:"tool,omitempty"`
+	Args    string    `json:"args,omitempty"`
+	IsError bool      `json:"is_error,omitempty"`
+	Model   string    `json:"model,omitempty"`
+	// Sources are the pages and resources the tools of the turn returned,
+	// recorded with its answer
+	Sources []citations.Source `json:"sources,omitempty"`
+}
+
+// Writer appends transcript events to a file as they happen. Every event is
+// synced to disk immediately so a crashed session still leaves a record.
+// All methods are safe to call on a nil *Writer, which discards events.
+type Writer struct {
+	mu     sync.Mutex
+	out    io.Writer
+	format Format
+
+	// recording keeps a copy of every event in events
+	recording bool
+	events    []Event
+}
+
+// NewWriter creates a Writer that writes events to out in the given format.
+// A nil out writes nothing, which is useful together with Record.
+func NewWriter(out io.Writer, format Format) *Writer {
+	return &Writer{out: out, format: format}
+}
+
+// Record makes the writer keep every subsequent event in memory, e.g. so the
+// session can be shared
+func (w *Writer) Record() {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.recording = true
+}
+
+// Events returns the events recorded since Record was called
+func (w *Writer) Events() []Event {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Event(nil), w.events...)
+}
+
+// Open opens (or creates) a transcript file for appending. Files ending in
+// .jsonl or .json are written as JSON lines, anything else as plain text.
+func Open(path string) (*Writer, error) {
+	format := FormatText
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".json":
+		format = FormatJSONL
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript file: %v", err)
+	}
+
+	return NewWriter(file, format), nil
+}
+
+// Read parses a transcript written as JSON lines. Blank lines are skipped.
+func Read(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal([]byte(text), &event); err != nil {
+			return nil, fmt.Errorf("invalid transcript event on line %d: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %v", err)
+	}
+	return events, nil
+}
+
+// User records a user message
+func (w *Writer) User(content string) {
+	w.write(Event{Type: "user", Content: content})
+}
+
+// Assistant records an assistant message
+func (w *Writer) Assistant(content, model string) {
+	w.write(Event{Type: "assistant", Content: content, Model: model})
+}
+
+// Answer records the assistant's answer at the end of a turn, with the
+// sources the turn's tools returned
+func (w *Writer) Answer(content, model string, sources []citations.Source) {
+	w.write(Event{Type: "assistant", Content: content, Model: model, Sources: sources})
+}
+
+// Final records the final assistant response of a run
+func (w *Writer) Final(content, model string, sources []citations.Source) {
+	w.write(Event{Type: "final", Content: content, Model: model, Sources: sources})
+}
+
+// ToolCall records a tool call before it is executed
+func (w *Writer) ToolCall(tool, args string) {
+	w.write(Event{Type: "tool_call", Tool: tool, Args: args})
+}
+
+// ToolResult records the result of a tool call
+func (w *Writer) ToolResult(tool, args, result string, isError bool) {
+	w.write(Event{Type: "tool_result", Tool: tool, Args: args, Content: result, IsError: isError})
+}
+
+// Error records an error
+func (w *Writer) Error(err error) {
+	w.write(Event{Type: "error", Content: err.Error(), IsError: true})
+}
+
+// Variable records a /set variable being set, as name=value
+func (w *Writer) Variable(name, value string) {
+	w.write(Event{Type: "variable", Content: name + "=" + value})
+}
+
+// Unset records a /set variable being removed, as its name without a value
+func (w *Writer) Unset(name string) {
+	w.write(Event{Type: "variable", Content: name})
+}
+
+// Append records events that were recorded elsewhere, keeping their times
+func (w *Writer) Append(events ...Event) {
+	for _, event := range events {
+		w.write(event)
+	}
+}
+
+// Close closes the transcript file
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.out == nil {
+		return nil
+	}
+
+	var err error
+	if closer, ok := w.out.(io.Closer); ok && w.out != os.Stdout && w.out != os.Stderr {
+		err = closer.Close()
+	}
+	w.out = nil
+	return err
+}
+
+// write appends an event and syncs it to disk. Events without a time are
+// stamped with the current time.
+func (w *Writer) write(event Event) {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if w.recording {
+		w.events = append(w.events, event)
+	}
+
+	if w.out == nil {
+		return
+	}
+
+	var line string
+	if w.format == FormatJSONL {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		line = string(data) + "\n"
+	} else {
+		line = formatText(event)
+	}
+
+	// Transcript failures must never interrupt the session
+	if _, err := io.WriteString(w.out, line); err == nil {
+		if syncer, ok := w.out.(interface{ Sync() error }); ok {
+			syncer.Sync()
+		}
+	}
+}
+
+// formatText renders an event as a human-readable block
+func formatText(event Event) string {
+	timestamp := event.Time.Format(time.RFC3339)
+
+	var header string
+	switch event.Type {
+	case "user":
+		header = "User"
+	case "assistant", "final":
+		header = "Assistant"
+		if event.Model != "" {
+			header = fmt.Sprintf("Assistant (%s)", event.Model)
+		}
+	case "tool_call":
+		header = fmt.Sprintf("Tool call: %s %s", event.Tool, event.Args)
+	case "tool_result":
+		header = fmt.Sprintf("Tool result: %s", event.Tool)
+		if event.IsError {
+			header = fmt.Sprintf("Tool error: %s", event.Tool)
+		}
+	case "error":
+		header = "Error"
+	case "variable":
+		header = "Variable"
+	default:
+		header = event.Type
+	}
+
+	content := citations.WithFooter(event.Content, event.Sources)
+	if content == "" {
+		return fmt.Sprintf("[%s] %s\n\n", timestamp, header)
+	}
+	return fmt.Sprintf("[%s] %s\n%s\n\n", timestamp, header, content)
+}
@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/cloudwego/eino/schema"
+	"github.com/muesli/termenv"
 	"golang.org/x/term"
 )
 
@@ -18,26 +22,91 @@ var (
 	promptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
 )
 
+// resizeDebounce is how long the terminal size has to stay the same before
+// the messages already printed are redrawn at the new width
+const resizeDebounce = 150 * time.Millisecond
+
+// DisplayOptions controls how tool activity is rendered
+type DisplayOptions struct {
+	// SpinnerStyle selects the waiting animation (dots, line or none)
+	SpinnerStyle SpinnerStyle
+	// ShowToolArgs shows tool arguments while a tool is executing
+	ShowToolArgs bool
+	// Compact collapses each tool call and its result into a single line
+	Compact bool
+	// TimeFormat is the Go layout for message timestamps; empty hides them
+	TimeFormat string
+	// RenderMode selects fancy or simple terminal output
+	RenderMode RenderMode
+	// Output receives the rendered messages and prompts; nil means stdout
+	Output io.Writer
+	// Blocked, if set, reports whether read-only mode blocks a tool; failed
+	// calls of blocked tools are marked as such
+	Blocked func(toolName string) bool
+	// Complete, if set, completes the prompt being typed when tab is
+	// pressed, returning the new prompt. Simple mode has no completion.
+	Complete func(input string) string
+}
+
+// toolRecord is a completed tool call kept so its result can be expanded later
+type toolRecord struct {
+	name     string
+	args     string
+	result   string
+	isError  bool
+	duration time.Duration
+}
+
 // CLI handles the command line interface with improved message rendering
 type CLI struct {
 	messageRenderer  *MessageRenderer
 	messageContainer *MessageContainer
+	options          DisplayOptions
+	out              io.Writer
+	toolResults      []toolRecord
+	toolStart        time.Time
+	resized          atomic.Bool
+	lastResize       atomic.Int64 // when the terminal last reported a resize, in Unix nanoseconds
+	stale            bool         // printed messages were rendered at another width
+	displayed        int          // number of messages already printed
+	input            lineReader
+	suggestions      []string
 	width            int
 	height           int
+	clock            func() time.Time
 }
 
 // NewCLI creates a new CLI instance with message container
-func NewCLI() (*CLI, error) {
-	cli := &CLI{}
+func NewCLI(options DisplayOptions) (*CLI, error) {
+	// Spinners redraw their line in place, which simple mode avoids
+	if options.RenderMode == RenderSimple {
+		options.SpinnerStyle = SpinnerNone
+	}
+
+	cli := &CLI{options: options, out: options.Output}
+	if cli.out == nil {
+		cli.out = os.Stdout
+	}
+	cli.input.out = cli.out
+
+	// Styles are rendered for the terminal the output goes to
+	if file, ok := cli.out.(*os.File); ok && file != os.Stdout {
+		lipgloss.DefaultRenderer().SetOutput(termenv.NewOutput(file))
+	}
+
 	cli.updateSize()
 	cli.messageRenderer = NewMessageRenderer(cli.width)
+	cli.messageRenderer.SetTimeFormat(options.TimeFormat)
 	cli.messageContainer = NewMessageContainer(cli.width, cli.height-4) // Reserve space for input and help
+	cli.watchResize()
 
 	return cli, nil
 }
 
 // GetPrompt gets user input using the huh library with divider and padding
 func (c *CLI) GetPrompt() (string, error) {
+	c.refreshSize()
+
 	// Create a divider before the input
 	dividerStyle := lipgloss.NewStyle().
 		Width(c.width).
@@ -48,14 +117,21 @@ func (c *CLI) GetPrompt() (string, error) {
 		MarginBottom(1)
 
 	// Render the divider
-	fmt.Print(dividerStyle.Render(""))
+	fmt.Fprint(c.out, dividerStyle.Render(""))
+
+	if c.options.RenderMode == RenderSimple {
+		return c.input.readLine("> ")
+	}
 
 	var prompt string
-	err := huh.NewForm(huh.NewGroup(huh.NewText().
+	var field huh.Field = huh.NewText().
 		Title("Enter your prompt (Type /help for commands, Ctrl+C to quit)").
 		Value(&prompt).
-		CharLimit(5000)),
-	).WithWidth(c.width).
+		CharLimit(5000)
+	if c.options.Complete != nil {
+		field = &completingText{Text: field.(*huh.Text), value: &prompt, complete: c.options.Complete}
+	}
+	err := huh.NewForm(huh.NewGroup(field)).WithWidth(c.width).
 		WithTheme(huh.ThemeCharm()).
 		Run()
 
@@ -69,9 +145,142 @@ func (c *CLI) GetPrompt() (string, error) {
 	return prompt, nil
 }
 
+// completingText is a prompt field that completes the prompt when tab is
+// pressed, instead of submitting it
+type completingText struct {
+	*huh.Text
+	value    *string
+	complete func(string) string
+}
+
+// Update completes the prompt on tab and otherwise updates the text field
+func (t *completingText) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok && key.Type == tea.KeyTab {
+		if completed := t.complete(*t.value); completed != *t.value {
+			*t.value = completed
+			t.Text.Value(t.value)
+		}
+		return t, nil
+	}
+	_, cmd := t.Text.Update(msg)
+	return t, cmd
+}
+
+// ToolApproval is the user's answer to a tool confirmation prompt
+type ToolApproval int
+
+const (
+	ToolDenied ToolApproval = iota
+	ToolApprovedOnce
+	ToolApprovedAlways
+)
+
+// ConfirmToolCall asks the user whether a tool call may run
+func (c *CLI) ConfirmToolCall(toolName, toolArgs string) (ToolApproval, error) {
+	if c.options.RenderMode == RenderSimple {
+		fmt.Fprintf(c.out, "Allow %s to run?\n%s\n", toolName, c.messageRenderer.truncateText(toolArgs, c.width-4))
+		answer, err := c.input.readLine("[o]nce, [a]lways or [d]eny: ")
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return ToolDenied, nil
+			}
+			return ToolDenied, err
+		}
+		switch strings.ToLower(answer) {
+		case "o", "once", "y", "yes":
+			return ToolApprovedOnce, nil
+		case "a", "always":
+			return ToolApprovedAlways, nil
+		default:
+			return ToolDenied, nil
+		}
+	}
+
+	var choice string
+	err := huh.NewForm(huh.NewGroup(huh.NewSelect[string]().
+		Title(fmt.Sprintf("Allow %s to run?", toolName)).
+		Description(c.messageRenderer.truncateText(toolArgs, c.width-4)).
+		Options(
+			huh.NewOption("Allow once", "once"),
+			huh.NewOption("Always allow this tool", "always"),
+			huh.NewOption("Deny", "deny"),
+		).
+		Value(&choice)),
+	).WithWidth(c.width).
+		WithTheme(huh.ThemeCharm()).
+		Run()
+
+	if err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return ToolDenied, nil
+		}
+		return ToolDenied, err
+	}
+
+	switch choice {
+	case "once":
+		return ToolApprovedOnce, nil
+	case "always":
+		return ToolApprovedAlways, nil
+	default:
+		return ToolDenied, nil
+	}
+}
+
+// ConfirmShellCommand asks the user whether a command from a prompt escape may run
+func (c *CLI) ConfirmShellCommand(command string) bool {
+	if c.options.RenderMode == RenderSimple {
+		fmt.Fprintf(c.out, "Run this command and include its output?\n%s\n", c.messageRenderer.truncateText(command, c.width-4))
+		answer, err := c.input.readLine("[y/N]: ")
+		return err == nil && (strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes"))
+	}
+
+	run := false
+	err := huh.NewForm(huh.NewGroup(huh.NewConfirm().
+		Title("Run this command and include its output?").
+		Description(c.messageRenderer.truncateText(command, c.width-4)).
+		Affirmative("Run").
+		Negative("Skip").
+		Value(&run)),
+	).WithWidth(c.width).
+		WithTheme(huh.ThemeCharm()).
+		Run()
+
+	return err == nil && run
+}
+
+// WaitForStep pauses a run in step-by-step mode after done, e.g. "tool
+// fs__read_file", until the user presses enter. It returns what the user
+// typed, if anything, for the model. It returns io.EOF if the user aborts,
+// e.g. with Ctrl+D.
+func (c *CLI) WaitForStep(done string) (string, error) {
+	title := fmt.Sprintf("Paused after %s", done)
+	if c.options.RenderMode == RenderSimple {
+		fmt.Fprintln(c.out, title)
+		return c.input.readLine("Enter to continue, or a message for the model: ")
+	}
+
+	var answer string
+	err := huh.NewForm(huh.NewGroup(huh.NewInput().
+		Title(title).
+		Description("Press enter to continue, or type a message for the model").
+		Value(&answer)),
+	).WithWidth(c.width).
+		WithTheme(huh.ThemeCharm()).
+		Run()
+
+	if err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return "", io.EOF
+		}
+		return "", err
+	}
+	return strings.TrimSpace(answer), nil
+}
+
 // ShowSpinner displays a spinner with the given message and executes the action
 func (c *CLI) ShowSpinner(message string, action func() error) error {
-	spinner := NewSpinner(message)
+	spinner := c.NewSpinner(message)
 	spinner.Start()
 
 	err := action()
@@ -81,11 +290,58 @@ func (c *CLI) ShowSpinner(message string, action func() error) error {
 	return err
 }
 
+// SetClock makes messages show the times returned by clock instead of the
+// current time, e.g. when replaying a transcript
+func (c *CLI) SetClock(clock func() time.Time) {
+	c.clock = clock
+}
+
+// now returns the time shown for a new message
+func (c *CLI) now() time.Time {
+	if c.clock != nil {
+		return c.clock()
+	}
+	return time.Now()
+}
+
+// NewSpinner creates a spinner using the configured spinner style
+func (c *CLI) NewSpinner(message string) *Spinner {
+	return NewStyledSpinner(message, c.options.SpinnerStyle)
+}
+
 // DisplayUserMessage displays the user's message using the new renderer
 func (c *CLI) DisplayUserMessage(message string) {
-	msg := c.messageRenderer.RenderUserMessage(message, time.Now())
-	c.messageContainer.AddMessage(msg)
-	c.displayContainer()
+	now := c.now()
+	c.addMessage(func() UIMessage {
+		return c.messageRenderer.RenderUserMessage(message, now)
+	})
+}
+
+// DisplaySuggestions shows numbered follow-up prompts that can be sent by
+// typing their number as the next prompt
+func (c *CLI) DisplaySuggestions(suggestions []string) {
+	c.suggestions = suggestions
+	if len(suggestions) == 0 {
+		return
+	}
+
+	now := c.now()
+	c.addMessage(func() UIMessage {
+		return c.messageRenderer.RenderSuggestionsMessage(suggestions, now)
+	})
+}
+
+// SelectSuggestion returns the suggestion chosen by typing its number as the
+// prompt. Suggestions can only be selected by the prompt right after them.
+func (c *CLI) SelectSuggestion(input string) (string, bool) {
+	suggestions := c.suggestions
+	c.suggestions = nil
+
+	n, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || n < 1 || n > len(suggestions) {
+		return input, false
+	}
+	return suggestions[n-1], true
 }
 
 // DisplayAssistantMessage displays the assistant's message using the new renderer
@@ -95,28 +351,106 @@ func (c *CLI) DisplayAssistantMessage(message string) error {
 
 // DisplayAssistantMessageWithModel displays the assistant's message with model info
 func (c *CLI) DisplayAssistantMessageWithModel(message, modelName string) error {
-	msg := c.messageRenderer.RenderAssistantMessage(message, time.Now(), modelName)
-	c.messageContainer.AddMessage(msg)
-	c.displayContainer()
+	now := c.now()
+	c.addMessage(func() UIMessage {
+		return c.messageRenderer.RenderAssistantMessage(message, now, modelName)
+	})
 	return nil
 }
 
+// DisplayAnswer writes the final answer of a non-interactive run to stdout.
+// When the rest of the output goes elsewhere, the answer is rendered like
+// other messages if stdout is a terminal and written as plain text if it is
+// piped, so it can be processed reliably.
+func (c *CLI) DisplayAnswer(message, modelName string) {
+	if c.out == os.Stdout {
+		c.DisplayAssistantMessageWithModel(message, modelName)
+		return
+	}
+
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		msg := c.messageRenderer.RenderAssistantMessage(message, c.now(), modelName)
+		fmt.Fprint(os.Stdout, msg.Content+"\n\n")
+		return
+	}
+	fmt.Fprintln(os.Stdout, message)
+}
+
 // DisplayToolCallMessage displays a tool call in progress
 func (c *CLI) DisplayToolCallMessage(toolName, toolArgs string) {
-	msg := c.messageRenderer.RenderToolCallMessage(toolName, toolArgs, time.Now())
+	c.toolStart = c.now()
 
-	// Always display immediately - spinner management is handled externally
-	c.messageContainer.AddMessage(msg)
-	c.displayContainer()
+	// In compact mode the call is shown together with its result
+	if c.options.Compact {
+		return
+	}
+
+	if !c.options.ShowToolArgs {
+		toolArgs = ""
+	}
+	now := c.now()
+	c.addMessage(func() UIMessage {
+		return c.messageRenderer.RenderToolCallMessage(toolName, toolArgs, now)
+	})
 }
 
-// DisplayToolMessage displays a tool call message
+// DisplayToolMessage displays a tool result, collapsed to a one-line summary
+// that can be expanded with /expand
 func (c *CLI) DisplayToolMessage(toolName, toolArgs, toolResult string, isError bool) {
-	msg := c.messageRenderer.RenderToolMessage(toolName, toolArgs, toolResult, isError)
+	var duration time.Duration
+	if !c.toolStart.IsZero() {
+		duration = c.now().Sub(c.toolStart)
+		c.toolStart = time.Time{}
+	}
+
+	c.toolResults = append(c.toolResults, toolRecord{
+		name:     toolName,
+		args:     toolArgs,
+		result:   toolResult,
+		isError:  isError,
+		duration: duration,
+	})
+	index := len(c.toolResults)
+
+	if !c.options.ShowToolArgs {
+		toolArgs = ""
+	}
+	blocked := isError && c.options.Blocked != nil && c.options.Blocked(toolName)
 
 	// Always display immediately - spinner management is handled externally
-	c.messageContainer.AddMessage(msg)
-	c.displayContainer()
+	c.addMessage(func() UIMessage {
+		if blocked {
+			return c.messageRenderer.RenderBlockedToolMessage(index, toolName)
+		}
+		if c.options.Compact {
+			return c.messageRenderer.RenderCompactToolMessage(index, toolName, toolArgs, toolResult, isError, duration)
+		}
+		return c.messageRenderer.RenderCollapsedToolMessage(index, toolName, toolResult, isError, duration)
+	})
+}
+
+// ExpandToolResult displays the full output of the n-th tool result (1-based)
+func (c *CLI) ExpandToolResult(arg string) {
+	if len(c.toolResults) == 0 {
+		c.DisplayError(fmt.Errorf("no tool results to expand"))
+		return
+	}
+
+	// Default to the most recent result
+	n := len(c.toolResults)
+	if arg != "" {
+		parsed, err := strconv.Atoi(arg)
+		if err != nil || parsed < 1 || parsed > len(c.toolResults) {
+			c.DisplayError(fmt.Errorf("usage: /expand <n> where n is between 1 and %d", len(c.toolResults)))
+			return
+		}
+		n = parsed
+	}
+
+	tr := c.toolResults[n-1]
+	c.addMessage(func() UIMessage {
+		return c.messageRenderer.RenderExpandedToolMessage(tr.name, tr.args, tr.result, tr.isError)
+	})
 }
 
 // DisplayStreamingMessage displays streaming content
@@ -140,16 +474,26 @@ func (c *CLI) DisplayStreamingMessage(reader *schema.StreamReader[*schema.Messag
 
 // DisplayError displays an error message using the message component
 func (c *CLI) DisplayError(err error) {
-	msg := c.messageRenderer.RenderErrorMessage(err.Error(), time.Now())
-	c.messageContainer.AddMessage(msg)
-	c.displayContainer()
+	now := c.now()
+	c.addMessage(func() UIMessage {
+		return c.messageRenderer.RenderErrorMessage(err.Error(), now)
+	})
 }
 
 // DisplayInfo displays an informational message using the system message component
 func (c *CLI) DisplayInfo(message string) {
-	msg := c.messageRenderer.RenderSystemMessage(message, time.Now())
-	c.messageContainer.AddMessage(msg)
-	c.displayContainer()
+	now := c.now()
+	c.addMessage(func() UIMessage {
+		return c.messageRenderer.RenderSystemMessage(message, now)
+	})
+}
+
+// DisplayNotice displays a one-line notice about something mcphost did on
+// its own, such as dropping old messages from the context
+func (c *CLI) DisplayNotice(message string) {
+	c.addMessage(func() UIMessage {
+		return c.messageRenderer.RenderNoticeMessage(message)
+	})
 }
 
 // DisplayHelp displays help information in a message block
@@ -158,17 +502,47 @@ func (c *CLI) DisplayHelp() {
 
 - ` + "`/help`" + `: Show this help message
 - ` + "`/tools`" + `: List all available tools
-- ` + "`/servers`" + `: List configured MCP servers
-- ` + "`/history`" + `: Display conversation history
+- ` + "`/servers`" + `: List configured MCP servers with their capabilities and instructions
+- ` + "`/logs <server>`" + `: Show recent stderr output of a stdio MCP server
+- ` + "`/permissions`" + `: View or change which tools run without confirmation
+- ` + "`/system [edit]`" + `: Show the system prompt, or edit it in your editor
+- ` + "`/share [file|gist|endpoint]`" + `: Export the session as an HTML page
+- ` + "`/save-last <path>`" + `: Save the last answer to a file as Markdown
+- ` + "`/open`" + `: Open the last answer in the browser
+- ` + "`/tokens`" + `: Estimate the tokens used by the system prompt, tools and conversation
+- ` + "`/trace`" + `: Show the model and tool calls of the last answer as a tree
+- ` + "`/changes`" + `: List the files changed by tool calls
+- ` + "`/diff <file>`" + `: Show the changes tools made to a file
+- ` + "`/revert <file>...`" + `: Undo the changes tools made to files
+- ` + "`/voice`" + `: Speak the next prompt instead of typing it
+- ` + "`/speak [on|off]`" + `: Turn reading answers aloud on or off
+- ` + "`/history [full] [tools] [last <n>] [search <term>]`" + `: Display conversation history; full includes the messages dropped from the context by the message window, tools the tool calls of each answer
+- ` + "`/retry`" + `: Resend the last prompt, e.g. after a provider error
+- ` + "`/regenerate [temperature]`" + `: Discard the last answer and generate a new one
+- ` + "`/undo [n]`" + `: Remove the last n exchanges from the conversation (default: 1)
+- ` + "`/pin [n]`" + `: Keep message n of /history in context regardless of the message window (default: the latest)
+- ` + "`/unpin <n>`" + `: Unpin message n
+- ` + "`/pins [clear]`" + `: List pinned messages, or unpin them all
+- ` + "`/expand [n]`" + `: Show the full output of tool result n (default: the latest)
+- ` + "`/new [name]`" + `: Start a new conversation, keeping the current one
+- ` + "`/switch <name>`" + `: Switch to another conversation
+- ` + "`/list`" + `: List the conversations of this session
+- ` + "`/ask <provider:model> <prompt>`" + `: Send a single prompt to another model; ` + "`@model:<provider:model> <prompt>`" + ` does the same
+- ` + "`/set [name=value]`" + `: Set a variable of this conversation, used as {{name}} in later prompts, or list them
+- ` + "`/unset <name>`" + `: Remove a variable
+- ` + "`/bg <prompt>`" + `: Run a prompt in the background while you keep chatting
+- ` + "`/jobs`" + `: List background jobs
+- ` + "`/fg <id>`" + `: Show the result of a background job and add it to the conversation
 - ` + "`/quit`" + `: Exit the application
 - ` + "`Ctrl+C`" + `: Exit at any time
 
 You can also just type your message to chat with the AI assistant.`
 
 	// Display as a system message
-	msg := c.messageRenderer.RenderSystemMessage(help, time.Now())
-	c.messageContainer.AddMessage(msg)
-	c.displayContainer()
+	now := c.now()
+	c.addMessage(func() UIMessage {
+		return c.messageRenderer.RenderSystemMessage(help, now)
+	})
 }
 
 // DisplayTools displays available tools in a message block
@@ -185,13 +559,27 @@ func (c *CLI) DisplayTools(tools []string) {
 	}
 
 	// Display as a system message
-	msg := c.messageRenderer.RenderSystemMessage(content.String(), time.Now())
-	c.messageContainer.AddMessage(msg)
-	c.displayContainer()
+	now := c.now()
+	c.addMessage(func() UIMessage {
+		return c.messageRenderer.RenderSystemMessage(content.String(), now)
+	})
+}
+
+// ServerDetails describes an MCP server for /servers
+type ServerDetails struct {
+	Name string
+	// Running is false for configured servers that weren't started
+	Running bool
+	// Implementation is the server's own name and version, e.g.
+	// "filesystem 0.6.2"
+	Implementation string
+	Capabilities   []string
+	Instructions   string
 }
 
-// DisplayServers displays configured MCP servers in a message block
-func (c *CLI) DisplayServers(servers []string) {
+// DisplayServers displays configured MCP servers with what they declared
+// when they started in a message block
+func (c *CLI) DisplayServers(servers []ServerDetails) {
 	var content strings.Builder
 	content.WriteString("## Configured MCP Servers\n\n")
 
@@ -199,34 +587,146 @@ func (c *CLI) DisplayServers(servers []string) {
 		content.WriteString("No MCP servers are currently configured.")
 	} else {
 		for i, server := range servers {
-			content.WriteString(fmt.Sprintf("%d. `%s`\n", i+1, server))
+			content.WriteString(fmt.Sprintf("%d. `%s`", i+1, server.Name))
+			if !server.Running {
+				content.WriteString(" (not running)\n")
+				continue
+			}
+			if server.Implementation != "" {
+				content.WriteString(fmt.Sprintf(" (%s)", server.Implementation))
+			}
+			content.WriteString("\n")
+			if len(server.Capabilities) > 0 {
+				content.WriteString(fmt.Sprintf("   - Capabilities: %s\n", strings.Join(server.Capabilities, ", ")))
+			}
+			if server.Instructions != "" {
+				content.WriteString("   - Instructions:\n\n")
+				for _, line := range strings.Split(server.Instructions, "\n") {
+					content.WriteString("     > " + line + "\n")
+				}
+				content.WriteString("\n")
+			}
 		}
 	}
 
 	// Display as a system message
-	msg := c.messageRenderer.RenderSystemMessage(content.String(), time.Now())
-	c.messageContainer.AddMessage(msg)
-	c.displayContainer()
+	now := c.now()
+	c.addMessage(func() UIMessage {
+		return c.messageRenderer.RenderSystemMessage(content.String(), now)
+	})
 }
 
-// DisplayHistory displays conversation history using the message container
-func (c *CLI) DisplayHistory(messages []*schema.Message) {
+// DisplayPermissions displays the tool permission policy
+func (c *CLI) DisplayPermissions(confirm, readOnly bool, rules []string) {
+	var content strings.Builder
+	content.WriteString("## Tool Permissions\n\n")
+
+	if readOnly {
+		content.WriteString("Read-only mode is **on**: tools that look like they change something are blocked, even if listed below.\n\n")
+	}
+	if confirm {
+		content.WriteString("Confirmation mode is **on**: tools not listed below ask before running.\n\n")
+	} else {
+		content.WriteString("Confirmation mode is **off**: all tools run without asking.\n\n")
+	}
+
+	if len(rules) == 0 {
+		content.WriteString("No tools are auto-approved.\n")
+	} else {
+		content.WriteString("Auto-approved:\n\n")
+		for _, rule := range rules {
+			content.WriteString(fmt.Sprintf("- `%s`\n", rule))
+		}
+	}
+
+	content.WriteString("\nUse `/permissions allow <tool>`, `/permissions revoke <tool>` or `/permissions confirm on|off` to change the policy.")
+
+	// Display as a system message
+	now := c.now()
+	c.addMessage(func() UIMessage {
+		return c.messageRenderer.RenderSystemMessage(content.String(), now)
+	})
+}
+
+// DisplayServerLogs displays the captured stderr output of an MCP server
+func (c *CLI) DisplayServerLogs(server string, lines []string) {
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("## Logs for `%s`\n\n", server))
+
+	if len(lines) == 0 {
+		content.WriteString("No stderr output captured.")
+	} else {
+		content.WriteString("```\n")
+		content.WriteString(strings.Join(lines, "\n"))
+		content.WriteString("\n```")
+	}
+
+	// Display as a system message
+	now := c.now()
+	c.addMessage(func() UIMessage {
+		return c.messageRenderer.RenderSystemMessage(content.String(), now)
+	})
+}
+
+// HistoryEntry is a user or assistant message as /history shows it
+type HistoryEntry struct {
+	Message *schema.Message
+	// Number is the message's number for commands like /pin, or 0 for a
+	// message the message window dropped from the context
+	Number int
+	Pinned bool
+	// Time is when the message was sent or received; zero if unknown
+	Time time.Time
+	// Model is the model that generated an answer; empty if unknown
+	Model string
+	// ToolCalls are the tool calls made while an answer was generated
+	ToolCalls []HistoryToolCall
+}
+
+// HistoryToolCall is a tool call and its result
+type HistoryToolCall struct {
+	Name    string
+	Args    string
+	Result  string
+	IsError bool
+	Time    time.Time
+}
+
+// DisplayHistory displays conversation history using the message container.
+// Messages are labelled with their number, so they can be referred to by
+// commands like /pin, or as dropped, and pinned messages are marked. With
+// showTools the tool calls of each answer are shown before it.
+func (c *CLI) DisplayHistory(entries []HistoryEntry, showTools bool) {
 	// Create a temporary container for history
 	historyContainer := NewMessageContainer(c.width, c.height-4)
 
-	for _, msg := range messages {
-		switch msg.Role {
+	for _, entry := range entries {
+		label := "`dropped`"
+		if entry.Number > 0 {
+			label = fmt.Sprintf("`#%d`", entry.Number)
+		}
+		if entry.Pinned {
+			label += " 📌"
+		}
+		content := label + " " + entry.Message.Content
+
+		if showTools {
+			for _, call := range entry.ToolCalls {
+				historyContainer.AddMessage(c.messageRenderer.RenderToolCallMessage(call.Name, call.Args, call.Time))
+				historyContainer.AddMessage(c.messageRenderer.RenderToolMessage(call.Name, call.Args, call.Result, call.IsError))
+			}
+		}
+
+		switch entry.Message.Role {
 		case schema.User:
-			uiMsg := c.messageRenderer.RenderUserMessage(msg.Content, time.Now())
-			historyContainer.AddMessage(uiMsg)
+			historyContainer.AddMessage(c.messageRenderer.RenderUserMessage(content, entry.Time))
 		case schema.Assistant:
-			uiMsg := c.messageRenderer.RenderAssistantMessage(msg.Content, time.Now(), "")
-			historyContainer.AddMessage(uiMsg)
+			historyContainer.AddMessage(c.messageRenderer.RenderAssistantMessage(content, entry.Time, entry.Model))
 		}
 	}
 
-	fmt.Println("\nConversation History:")
-	fmt.Println(historyContainer.Render())
+	fmt.Fprintln(c.out, "\nConversation History:")
+	fmt.Fprintln(c.out, historyContainer.Render())
 }
 
 // IsSlashCommand checks if the input is a slash command
@@ -235,19 +735,31 @@ func (c *CLI) IsSlashCommand(input string) bool {
 }
 
 // HandleSlashCommand handles slash commands and returns true if handled
-func (c *CLI) HandleSlashCommand(input string, servers []string, tools []string, history []*schema.Message) bool {
-	switch input {
+func (c *CLI) HandleSlashCommand(input string, tools []string, history []*schema.Message) bool {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return false
+	}
+	command, args := fields[0], fields[1:]
+
+	switch command {
 	case "/help":
 		c.DisplayHelp()
 		return true
 	case "/tools":
 		c.DisplayTools(tools)
 		return true
-	case "/servers":
-		c.DisplayServers(servers)
-		return true
 	case "/history":
-		c.DisplayHistory(history)
+		var entries []HistoryEntry
+		for _, msg := range history {
+			if msg.Role == schema.User || msg.Role == schema.Assistant {
+				entries = append(entries, HistoryEntry{Message: msg, Number: len(entries) + 1})
+			}
+		}
+		c.DisplayHistory(entries, false)
+		return true
+	case "/expand":
+		c.ExpandToolResult(strings.Join(args, " "))
 		return true
 	case "/clear":
 		c.ClearMessages()
@@ -261,22 +773,94 @@ func (c *CLI) HandleSlashCommand(input string, servers []string, tools []string,
 	}
 }
 
-// ClearMessages clears all messages from the container
+// addMessage renders a message and adds it to the container. The render
+// function is kept so the message can be re-rendered after a resize.
+func (c *CLI) addMessage(render func() UIMessage) {
+	c.refreshSize()
+
+	msg := render()
+	msg.rerender = render
+	c.messageContainer.AddMessage(msg)
+	c.displayContainer()
+}
+
+// ClearMessages clears all messages from the container. Earlier output is
+// left in the terminal's scrollback.
 func (c *CLI) ClearMessages() {
 	c.messageContainer.Clear()
-	c.displayContainer()
+	c.displayed = 0
+	fmt.Fprint(c.out, c.messageContainer.Render())
 }
 
-// displayContainer renders and displays the message container
+// displayContainer appends the messages added since the last display below
+// the output already on screen. The screen is never cleared, so the
+// terminal's native scrollback keeps the whole conversation.
 func (c *CLI) displayContainer() {
-	// Clear screen and display messages
-	fmt.Print("\033[2J\033[H") // Clear screen and move cursor to top
-	fmt.Print(c.messageContainer.Render())
+	c.refreshSize()
+
+	fmt.Fprint(c.out, c.messageContainer.RenderFrom(c.displayed))
+	c.displayed = c.messageContainer.Len()
+}
+
+// refreshSize updates the renderer sizes if the terminal was resized since
+// the last render, so new messages are rendered at the new width. Once the
+// size has stopped changing for resizeDebounce, the messages already
+// printed are printed again at the new width, so dragging a window edge
+// redraws the screen once rather than at every step. It reports whether
+// the screen was redrawn.
+func (c *CLI) refreshSize() bool {
+	resized := c.resized.Swap(false)
+
+	width, height, err := term.GetSize(c.terminalFd())
+	if err == nil && (width != c.width || height != c.height) {
+		resized = true
+	}
+	if resized {
+		oldWidth := c.width
+		c.updateSize()
+		if c.width != oldWidth {
+			c.stale = true
+		}
+	}
+
+	if !c.stale || time.Since(time.Unix(0, c.lastResize.Load())) < resizeDebounce {
+		return false
+	}
+	c.stale = false
+	return c.redraw()
+}
+
+// redraw clears the screen and its scrollback and prints the displayed
+// messages again, re-rendered at the current width, so the scrollback
+// doesn't keep a copy wrapped at the old width. Simple render mode and
+// output that isn't a terminal are left alone.
+func (c *CLI) redraw() bool {
+	if c.displayed == 0 || !c.isTerminal() || c.options.RenderMode == RenderSimple {
+		return false
+	}
+	fmt.Fprint(c.out, "\033[H\033[2J\033[3J")
+	fmt.Fprint(c.out, c.messageContainer.RenderFrom(0))
+	c.displayed = c.messageContainer.Len()
+	return true
+}
+
+// isTerminal reports whether the output goes to a terminal
+func (c *CLI) isTerminal() bool {
+	file, ok := c.out.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
+
+// terminalFd returns the file descriptor of the terminal the output goes to
+func (c *CLI) terminalFd() int {
+	if file, ok := c.out.(*os.File); ok {
+		return int(file.Fd())
+	}
+	return int(os.Stdout.Fd())
 }
 
 // updateSize updates the CLI size based on terminal dimensions
 func (c *CLI) updateSize() {
-	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	width, height, err := term.GetSize(c.terminalFd())
 	if err != nil {
 		c.width = 80  // Fallback width
 		c.height = 24 // Fallback height
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 // MessageType represents the type of message
@@ -28,8 +29,19 @@ type UIMessage struct {
 	Height    int
 	Content   string
 	Timestamp time.Time
+
+	// rerender renders the message again at the renderer's current width
+	rerender func() UIMessage
+	// width is the container width the message was last rendered at
+	width int
 }
 
+// maxToolResultLines is the number of result lines shown when a tool result is rendered inline
+const maxToolResultLines = 10
+
+// maxToolArgsWidth is the display width at which formatted tool arguments are truncated
+const maxToolArgsWidth = 100
+
 // Color constants
 var (
 	primaryColor   = lipgloss.Color("#7C3AED") // Purple
@@ -41,18 +53,62 @@ var (
 	toolColor      = lipgloss.Color("#F59E0B") // Orange/Amber for tool calls
 )
 
+// Timestamp format presets for --time-format
+var timeFormatPresets = map[string]string{
+	"12h":  "02 Jan 2006 03:04 PM",
+	"24h":  "02 Jan 2006 15:04",
+	"iso":  "2006-01-02 15:04",
+	"us":   "Jan 2 2006 3:04 PM",
+	"eu":   "02.01.2006 15:04",
+	"time": "15:04",
+}
+
+// DefaultTimeFormat is the timestamp format preset used by default
+const DefaultTimeFormat = "12h"
+
+// ParseTimeFormat resolves a timestamp format preset (12h, 24h, iso, us, eu,
+// time) or a Go time layout such as "2006-01-02 15:04" to a layout
+func ParseTimeFormat(format string) (string, error) {
+	if layout, ok := timeFormatPresets[format]; ok {
+		return layout, nil
+	}
+	// A layout without any date or time elements formats to itself
+	if format == "" || (time.Time{}).Format(format) == format {
+		return "", fmt.Errorf("invalid time format %q (expected 12h, 24h, iso, us, eu, time or a Go time layout)", format)
+	}
+	return format, nil
+}
+
 // MessageRenderer handles rendering of messages with proper styling
 type MessageRenderer struct {
 	width int
+	// timeFormat is the Go layout for message timestamps; empty hides them
+	timeFormat string
 }
 
 // NewMessageRenderer creates a new message renderer
 func NewMessageRenderer(width int) *MessageRenderer {
 	return &MessageRenderer{
-		width: width,
+		width:      width,
+		timeFormat: timeFormatPresets[DefaultTimeFormat],
 	}
 }
 
+// SetTimeFormat sets the Go layout for message timestamps; an empty layout
+// hides timestamps
+func (r *MessageRenderer) SetTimeFormat(layout string) {
+	r.timeFormat = layout
+}
+
+// infoLine formats the label and timestamp shown below a message. A zero
+// timestamp, for a time that isn't known, isn't shown.
+func (r *MessageRenderer) infoLine(label string, timestamp time.Time) string {
+	if r.timeFormat == "" || timestamp.IsZero() {
+		return " " + label
+	}
+	return fmt.Sprintf(" %s (%s)", label, timestamp.Local().Format(r.timeFormat))
+}
+
 // SetWidth updates the renderer width
 func (r *MessageRenderer) SetWidth(width int) {
 	r.width = width
@@ -71,15 +127,13 @@ func (r *MessageRenderer) RenderUserMessage(content string, timestamp time.Time)
 		BorderStyle(lipgloss.ThickBorder()).
 		PaddingLeft(1)
 
-	// Format timestamp
-	timeStr := timestamp.Local().Format("02 Jan 2006 03:04 PM")
 	username := "You"
 
 	// Create info line
 	info := baseStyle.
 		Width(r.width - 1).
 		Foreground(mutedColor).
-		Render(fmt.Sprintf(" %s (%s)", username, timeStr))
+		Render(r.infoLine(username, timestamp))
 
 	// Render the message content
 	messageContent := r.renderMarkdown(content, r.width-2)
@@ -115,8 +169,7 @@ func (r *MessageRenderer) RenderAssistantMessage(content string, timestamp time.
 		BorderStyle(lipgloss.ThickBorder()).
 		PaddingLeft(1)
 
-	// Format timestamp and model info
-	timeStr := timestamp.Local().Format("02 Jan 2006 03:04 PM")
+	// Format model info
 	if modelName == "" {
 		modelName = "Assistant"
 	}
@@ -125,7 +178,7 @@ func (r *MessageRenderer) RenderAssistantMessage(content string, timestamp time.
 	info := baseStyle.
 		Width(r.width - 1).
 		Foreground(mutedColor).
-		Render(fmt.Sprintf(" %s (%s)", modelName, timeStr))
+		Render(r.infoLine(modelName, timestamp))
 
 	// Render the message content
 	messageContent := r.renderMarkdown(content, r.width-2)
@@ -169,14 +222,11 @@ func (r *MessageRenderer) RenderSystemMessage(content string, timestamp time.Tim
 		BorderStyle(lipgloss.ThickBorder()).
 		PaddingLeft(1)
 
-	// Format timestamp
-	timeStr := timestamp.Local().Format("02 Jan 2006 03:04 PM")
-
 	// Create info line with MCPHost label
 	info := baseStyle.
 		Width(r.width - 1).
 		Foreground(mutedColor).
-		Render(fmt.Sprintf(" MCPHost (%s)", timeStr))
+		Render(r.infoLine("MCPHost", timestamp))
 
 	// Render the message content with markdown
 	messageContent := r.renderMarkdown(content, r.width-2)
@@ -207,6 +257,59 @@ func (r *MessageRenderer) RenderSystemMessage(content string, timestamp time.Tim
 	}
 }
 
+// RenderNoticeMessage renders a muted one-line notice
+func (r *MessageRenderer) RenderNoticeMessage(notice string) UIMessage {
+	baseStyle := lipgloss.NewStyle()
+
+	style := baseStyle.
+		Width(r.width - 1).
+		BorderLeft(true).
+		BorderStyle(lipgloss.NormalBorder()).
+		PaddingLeft(1).
+		BorderForeground(mutedColor)
+
+	rendered := style.Render(
+		baseStyle.
+			Foreground(mutedColor).
+			Render(r.truncateText(notice, r.width-3)),
+	)
+
+	return UIMessage{
+		Type:    SystemMessage,
+		Content: rendered,
+		Height:  lipgloss.Height(rendered),
+	}
+}
+
+// RenderSuggestionsMessage renders numbered follow-up prompt suggestions
+func (r *MessageRenderer) RenderSuggestionsMessage(suggestions []string, timestamp time.Time) UIMessage {
+	baseStyle := lipgloss.NewStyle()
+
+	lines := []string{
+		baseStyle.
+			Foreground(mutedColor).
+			Italic(true).
+			Render("Suggested next prompts (type a number to send one):"),
+	}
+	for i, suggestion := range suggestions {
+		lines = append(lines, baseStyle.
+			Foreground(mutedColor).
+			Render(r.truncateText(fmt.Sprintf("  %d. %s", i+1, suggestion), r.width-2)))
+	}
+
+	rendered := baseStyle.
+		Width(r.width - 1).
+		PaddingLeft(1).
+		Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+
+	return UIMessage{
+		Type:      SystemMessage,
+		Content:   rendered,
+		Height:    lipgloss.Height(rendered),
+		Timestamp: timestamp,
+	}
+}
+
 // RenderErrorMessage renders an error message with proper styling
 func (r *MessageRenderer) RenderErrorMessage(errorMsg string, timestamp time.Time) UIMessage {
 	baseStyle := lipgloss.NewStyle()
@@ -220,14 +323,11 @@ func (r *MessageRenderer) RenderErrorMessage(errorMsg string, timestamp time.Tim
 		BorderStyle(lipgloss.ThickBorder()).
 		PaddingLeft(1)
 
-	// Format timestamp
-	timeStr := timestamp.Local().Format("02 Jan 2006 03:04 PM")
-
 	// Create info line with Error label
 	info := baseStyle.
 		Width(r.width - 1).
 		Foreground(mutedColor).
-		Render(fmt.Sprintf(" Error (%s)", timeStr))
+		Render(r.infoLine("Error", timestamp))
 
 	// Format error content with error styling
 	errorContent := baseStyle.
@@ -266,9 +366,6 @@ func (r *MessageRenderer) RenderToolCallMessage(toolName, toolArgs string, times
 		BorderStyle(lipgloss.ThickBorder()).
 		PaddingLeft(1)
 
-	// Format timestamp
-	timeStr := timestamp.Local().Format("02 Jan 2006 03:04 PM")
-
 	// Create header with tool icon and name
 	toolIcon := "🔧"
 	header := baseStyle.
@@ -289,7 +386,7 @@ func (r *MessageRenderer) RenderToolCallMessage(toolName, toolArgs string, times
 	info := baseStyle.
 		Width(r.width - 1).
 		Foreground(mutedColor).
-		Render(fmt.Sprintf(" Tool Call (%s)", timeStr))
+		Render(r.infoLine("Tool Call", timestamp))
 
 	// Combine parts
 	parts := []string{header}
@@ -312,6 +409,16 @@ func (r *MessageRenderer) RenderToolCallMessage(toolName, toolArgs string, times
 
 // RenderToolMessage renders a tool call message with proper styling
 func (r *MessageRenderer) RenderToolMessage(toolName, toolArgs, toolResult string, isError bool) UIMessage {
+	return r.renderToolMessage(toolName, toolArgs, toolResult, isError, maxToolResultLines)
+}
+
+// RenderExpandedToolMessage renders a tool call message with the full, untruncated result
+func (r *MessageRenderer) RenderExpandedToolMessage(toolName, toolArgs, toolResult string, isError bool) UIMessage {
+	return r.renderToolMessage(toolName, toolArgs, toolResult, isError, 0)
+}
+
+// renderToolMessage renders a tool message, truncating the result to maxLines (0 for no limit)
+func (r *MessageRenderer) renderToolMessage(toolName, toolArgs, toolResult string, isError bool, maxLines int) UIMessage {
 	baseStyle := lipgloss.NewStyle()
 
 	// Create the main message style with border
@@ -342,7 +449,7 @@ func (r *MessageRenderer) RenderToolMessage(toolName, toolArgs, toolResult strin
 			Render(fmt.Sprintf("Error: %s", toolResult))
 	} else {
 		// Format result based on tool type
-		resultContent = r.formatToolResult(toolName, toolResult, r.width-2)
+		resultContent = r.formatToolResult(toolName, toolResult, r.width-2, maxLines)
 	}
 
 	// Combine parts
@@ -364,6 +471,108 @@ func (r *MessageRenderer) RenderToolMessage(toolName, toolArgs, toolResult strin
 	}
 }
 
+// RenderCompactToolMessage renders a tool call and its result on a single line.
+// index is the number used to expand the result with /expand.
+func (r *MessageRenderer) RenderCompactToolMessage(index int, toolName, toolArgs, toolResult string, isError bool, duration time.Duration) UIMessage {
+	baseStyle := lipgloss.NewStyle()
+
+	color := toolColor
+	icon := "🔧"
+	if isError {
+		color = errorColor
+		icon = "❌"
+	}
+
+	call := toolName
+	if toolArgs != "" && toolArgs != "{}" {
+		call = fmt.Sprintf("%s(%s)", toolName, r.formatToolArgs(toolArgs))
+	}
+
+	// Summarize the result as its first non-empty line plus a line count
+	summary := "(no output)"
+	lines := strings.Split(strings.TrimSpace(toolResult), "\n")
+	if lines[0] != "" {
+		summary = lines[0]
+		if len(lines) > 1 {
+			summary = fmt.Sprintf("%s (+%d lines)", summary, len(lines)-1)
+		}
+	}
+
+	line := fmt.Sprintf("[%d] %s %s %s → %s", index, icon, call, formatDuration(duration), summary)
+	rendered := baseStyle.
+		Width(r.width - 1).
+		Foreground(color).
+		Render(r.truncateText(line, r.width-1))
+
+	return UIMessage{
+		Type:    ToolMessage,
+		Content: rendered,
+		Height:  lipgloss.Height(rendered),
+	}
+}
+
+// RenderBlockedToolMessage renders a one-line notice that read-only mode
+// blocked a tool call. index is the number used to expand it with /expand.
+func (r *MessageRenderer) RenderBlockedToolMessage(index int, toolName string) UIMessage {
+	baseStyle := lipgloss.NewStyle()
+
+	style := baseStyle.
+		Width(r.width - 1).
+		BorderLeft(true).
+		BorderStyle(lipgloss.ThickBorder()).
+		PaddingLeft(1).
+		BorderForeground(toolColor)
+
+	summary := fmt.Sprintf("🔒 [%d] %s blocked by read-only mode · /expand %d", index, toolName, index)
+
+	rendered := style.Render(
+		baseStyle.
+			Foreground(toolColor).
+			Render(r.truncateText(summary, r.width-3)),
+	)
+
+	return UIMessage{
+		Type:    ToolMessage,
+		Content: rendered,
+		Height:  lipgloss.Height(rendered),
+	}
+}
+
+// RenderCollapsedToolMessage renders a one-line summary of a tool result with
+// its duration and size. index is the number used to expand it with /expand.
+func (r *MessageRenderer) RenderCollapsedToolMessage(index int, toolName, toolResult string, isError bool, duration time.Duration) UIMessage {
+	baseStyle := lipgloss.NewStyle()
+
+	borderColor := mutedColor
+	status := "done"
+	if isError {
+		borderColor = errorColor
+		status = "failed"
+	}
+
+	style := baseStyle.
+		Width(r.width - 1).
+		BorderLeft(true).
+		BorderStyle(lipgloss.ThickBorder()).
+		PaddingLeft(1).
+		BorderForeground(borderColor)
+
+	summary := fmt.Sprintf("▸ [%d] %s %s · %s · %s · /expand %d",
+		index, toolName, status, formatDuration(duration), formatSize(len(toolResult)), index)
+
+	rendered := style.Render(
+		baseStyle.
+			Foreground(borderColor).
+			Render(r.truncateText(summary, r.width-3)),
+	)
+
+	return UIMessage{
+		Type:    ToolMessage,
+		Content: rendered,
+		Height:  lipgloss.Height(rendered),
+	}
+}
+
 // formatToolArgs formats tool arguments for display
 func (r *MessageRenderer) formatToolArgs(args string) string {
 	// Remove outer braces and clean up JSON formatting
@@ -380,22 +589,17 @@ func (r *MessageRenderer) formatToolArgs(args string) string {
 	}
 
 	// Truncate if too long
-	maxLen := 100
-	if len(args) > maxLen {
-		return args[:maxLen] + "..."
-	}
-
-	return args
+	return ansi.Truncate(args, maxToolArgsWidth, "...")
 }
 
-// formatToolResult formats tool results based on tool type
-func (r *MessageRenderer) formatToolResult(toolName, result string, width int) string {
+// formatToolResult formats tool results based on tool type, keeping at most
+// maxLines lines (0 for no limit)
+func (r *MessageRenderer) formatToolResult(toolName, result string, width, maxLines int) string {
 	baseStyle := lipgloss.NewStyle()
 
 	// Truncate very long results
-	maxLines := 10
 	lines := strings.Split(result, "\n")
-	if len(lines) > maxLines {
+	if maxLines > 0 && len(lines) > maxLines {
 		result = strings.Join(lines[:maxLines], "\n") + "\n... (truncated)"
 	}
 
@@ -412,6 +616,26 @@ func (r *MessageRenderer) formatToolResult(toolName, result string, width int) s
 		Render(result)
 }
 
+// formatDuration formats a tool execution time for display
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
+
+// formatSize formats a byte count for display
+func formatSize(n int) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	}
+}
+
 // truncateText truncates text to fit within the specified width
 func (r *MessageRenderer) truncateText(text string, maxWidth int) string {
 	// Replace newlines with spaces for single-line display
@@ -421,15 +645,13 @@ func (r *MessageRenderer) truncateText(text string, maxWidth int) string {
 		return text
 	}
 
-	// Simple truncation - could be improved with proper unicode handling
-	for i := len(text) - 1; i >= 0; i-- {
-		truncated := text[:i] + "..."
-		if lipgloss.Width(truncated) <= maxWidth {
-			return truncated
-		}
+	if maxWidth < lipgloss.Width("...") {
+		return "..."
 	}
 
-	return "..."
+	// Truncate by display width without splitting graphemes, so wide CJK
+	// characters and emoji don't overflow the borders
+	return ansi.Truncate(text, maxWidth, "...")
 }
 
 // renderMarkdown renders markdown content using glamour
@@ -454,16 +676,37 @@ func NewMessageContainer(width, height int) *MessageContainer {
 	}
 }
 
-// AddMessage adds a message to the container
+// AddMessage adds a message, rendered at the container's current width, to
+// the container
 func (c *MessageContainer) AddMessage(msg UIMessage) {
+	msg.width = c.width
 	c.messages = append(c.messages, msg)
 }
 
+// Len returns the number of messages in the container
+func (c *MessageContainer) Len() int {
+	return len(c.messages)
+}
+
 // Clear clears all messages from the container
 func (c *MessageContainer) Clear() {
 	c.messages = make([]UIMessage, 0)
 }
 
+// message returns the message at index i, re-rendering it first if it was
+// rendered at a different width. Messages are only re-rendered when they are
+// printed again after a resize.
+func (c *MessageContainer) message(i int) UIMessage {
+	msg := c.messages[i]
+	if msg.width != c.width && msg.rerender != nil {
+		updated := msg.rerender()
+		updated.rerender = msg.rerender
+		updated.width = c.width
+		c.messages[i] = updated
+	}
+	return c.messages[i]
+}
+
 // SetSize updates the container size
 func (c *MessageContainer) SetSize(width, height int) {
 	c.width = width
@@ -475,22 +718,19 @@ func (c *MessageContainer) Render() string {
 	if len(c.messages) == 0 {
 		return c.renderEmptyState()
 	}
+	return c.RenderFrom(0)
+}
 
-	baseStyle := lipgloss.NewStyle()
-	var parts []string
-
-	for _, msg := range c.messages {
-		parts = append(parts, msg.Content)
-		// Add spacing between messages
-		parts = append(parts, baseStyle.Width(c.width).Render(""))
+// RenderFrom renders the messages from index from onwards, each followed by
+// a blank line, so new messages can be appended below the ones already on
+// screen
+func (c *MessageContainer) RenderFrom(from int) string {
+	var b strings.Builder
+	for i := from; i < len(c.messages); i++ {
+		b.WriteString(c.message(i).Content)
+		b.WriteString("\n\n")
 	}
-
-	return baseStyle.
-		Width(c.width).
-		PaddingBottom(1).
-		Render(
-			lipgloss.JoinVertical(lipgloss.Top, parts...),
-		)
+	return b.String()
 }
 
 // renderEmptyState renders the initial empty state
@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// RenderMode selects how the interactive UI draws to the terminal
+type RenderMode string
+
+const (
+	// RenderAuto picks simple inside tmux, screen and dumb terminals and
+	// fancy everywhere else
+	RenderAuto RenderMode = "auto"
+	// RenderFancy uses animated spinners and interactive forms
+	RenderFancy RenderMode = "fancy"
+	// RenderSimple only appends plain lines, without spinners, forms or any
+	// cursor movement, so it can't corrupt terminal multiplexer panes
+	RenderSimple RenderMode = "simple"
+)
+
+// ParseRenderMode validates a render mode name, resolving auto (or empty) to
+// the mode detected for the current terminal
+func ParseRenderMode(name string) (RenderMode, error) {
+	switch RenderMode(name) {
+	case "", RenderAuto:
+		return detectRenderMode(), nil
+	case RenderFancy, RenderSimple:
+		return RenderMode(name), nil
+	default:
+		return "", fmt.Errorf("invalid render mode %q (expected auto, fancy or simple)", name)
+	}
+}
+
+// detectRenderMode returns simple when running inside tmux or screen, or on a
+// terminal without cursor control
+func detectRenderMode() RenderMode {
+	if os.Getenv("TMUX") != "" || os.Getenv("STY") != "" {
+		return RenderSimple
+	}
+	term := os.Getenv("TERM")
+	if term == "dumb" || strings.HasPrefix(term, "screen") || strings.HasPrefix(term, "tmux") {
+		return RenderSimple
+	}
+	return RenderFancy
+}
+
+// lineReader reads plain lines from stdin for the simple render mode
+type lineReader struct {
+	reader *bufio.Reader
+	// out receives the prompts; nil means stdout
+	out io.Writer
+}
+
+// readLine prints the prompt and reads one line of input. It returns io.EOF
+// when stdin is closed, e.g. by Ctrl+D.
+func (l *lineReader) readLine(prompt string) (string, error) {
+	if l.reader == nil {
+		l.reader = bufio.NewReader(os.Stdin)
+	}
+
+	out := l.out
+	if out == nil {
+		out = os.Stdout
+	}
+	fmt.Fprint(out, prompt)
+	line, err := l.reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
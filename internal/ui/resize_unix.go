@@ -0,0 +1,25 @@
+//go:build !windows
+
+package ui
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// watchResize marks the CLI as resized whenever the terminal sends SIGWINCH.
+// The messages are redrawn on the next display, so it never interleaves
+// with an active prompt or spinner.
+func (c *CLI) watchResize() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGWINCH)
+
+	go func() {
+		for range sigChan {
+			c.lastResize.Store(time.Now().UnixNano())
+			c.resized.Store(true)
+		}
+	}()
+}
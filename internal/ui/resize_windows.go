@@ -0,0 +1,7 @@
+//go:build windows
+
+package ui
+
+// watchResize is a no-op on Windows, which has no SIGWINCH; the terminal
+// size is compared on every display instead.
+func (c *CLI) watchResize() {}
@@ -10,13 +10,35 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+// SpinnerStyle selects the animation shown while waiting on the model or a tool
+type SpinnerStyle string
+
+const (
+	SpinnerDots SpinnerStyle = "dots"
+	SpinnerLine SpinnerStyle = "line"
+	SpinnerNone SpinnerStyle = "none"
+)
+
+// ParseSpinnerStyle validates a spinner style name, defaulting to dots when empty
+func ParseSpinnerStyle(name string) (SpinnerStyle, error) {
+	switch SpinnerStyle(name) {
+	case "", SpinnerDots:
+		return SpinnerDots, nil
+	case SpinnerLine, SpinnerNone:
+		return SpinnerStyle(name), nil
+	default:
+		return "", fmt.Errorf("invalid spinner style %q (expected dots, line or none)", name)
+	}
+}
+
 // Spinner wraps the bubbles spinner for both interactive and non-interactive mode
 type Spinner struct {
-	model  spinner.Model
-	done   chan struct{}
-	prog   *tea.Program
-	ctx    context.Context
-	cancel context.CancelFunc
+	model    spinner.Model
+	done     chan struct{}
+	prog     *tea.Program
+	ctx      context.Context
+	cancel   context.CancelFunc
+	disabled bool
 }
 
 // spinnerModel is the tea.Model for the spinner
@@ -105,8 +127,29 @@ func NewThemedSpinner(message string, color lipgloss.AdaptiveColor) *Spinner {
 	}
 }
 
+// NewStyledSpinner creates a new spinner with the given message and style.
+// SpinnerNone returns a spinner whose Start and Stop do nothing.
+func NewStyledSpinner(message string, style SpinnerStyle) *Spinner {
+	if style == SpinnerNone {
+		return &Spinner{disabled: true}
+	}
+
+	sp := NewSpinner(message)
+	if style == SpinnerLine {
+		sp.model.Spinner = spinner.Line
+		sp.prog = tea.NewProgram(spinnerModel{
+			spinner: sp.model,
+			message: message,
+		}, tea.WithOutput(os.Stderr), tea.WithoutCatchPanics())
+	}
+	return sp
+}
+
 // Start begins the spinner animation
 func (s *Spinner) Start() {
+	if s.disabled {
+		return
+	}
 	go func() {
 		defer close(s.done)
 		go func() {
@@ -122,6 +165,9 @@ func (s *Spinner) Start() {
 
 // Stop ends the spinner animation
 func (s *Spinner) Stop() {
+	if s.disabled {
+		return
+	}
 	s.cancel()
 	<-s.done
 }
@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamRedrawInterval limits how often a streamed answer is rendered again,
+// since rendering Markdown takes a while for long answers
+const streamRedrawInterval = 80 * time.Millisecond
+
+// AnswerStream shows an answer while it is generated, rendering the Markdown
+// received so far in place of the previous rendering. Once the answer is
+// complete, Finish replaces the live rendering with the answer displayed
+// like any other message, so it is re-rendered after a resize. The live
+// rendering only shows as many trailing lines as fit on the screen, so that
+// all of it can be erased. In simple render mode, or when the output isn't a
+// terminal, nothing is shown until Finish.
+type AnswerStream struct {
+	cli       *CLI
+	modelName string
+	live      bool
+
+	mu      sync.Mutex
+	content strings.Builder
+	started time.Time
+	// lines is the number of lines of the live rendering on screen
+	lines    int
+	lastDraw time.Time
+}
+
+// NewAnswerStream returns a stream for the answers of the model, which may
+// be used for several answers in turn
+func (c *CLI) NewAnswerStream(modelName string) *AnswerStream {
+	live := c.isTerminal() && c.options.RenderMode != RenderSimple
+	return &AnswerStream{cli: c, modelName: modelName, live: live}
+}
+
+// Write adds a chunk of the answer and renders the answer again, unless it
+// was rendered very recently
+func (s *AnswerStream) Write(chunk string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.content.Len() == 0 {
+		s.started = s.cli.now()
+	}
+	s.content.WriteString(chunk)
+	if !s.live || time.Since(s.lastDraw) < streamRedrawInterval {
+		return
+	}
+	s.redraw()
+}
+
+// Finish replaces the live rendering with the complete answer, displayed
+// as a message, and makes the stream ready for the next answer
+func (s *AnswerStream) Finish(answer string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.erase()
+	s.reset()
+	s.cli.DisplayAssistantMessageWithModel(answer, s.modelName)
+}
+
+// Discard erases the live rendering without displaying the answer, e.g.
+// when the model turned to tool calls or failed
+func (s *AnswerStream) Discard() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.erase()
+	s.reset()
+}
+
+// redraw replaces the live rendering with one of the content so far
+func (s *AnswerStream) redraw() {
+	// A redraw of the screen already removed the live rendering
+	if s.cli.refreshSize() {
+		s.lines = 0
+	}
+	msg := s.cli.messageRenderer.RenderAssistantMessage(s.content.String(), s.started, s.modelName)
+	lines := strings.Split(msg.Content, "\n")
+	// Lines that scroll off the screen can't be erased
+	if limit := s.cli.height - 2; limit > 0 && len(lines) > limit {
+		lines = lines[len(lines)-limit:]
+	}
+
+	s.erase()
+	fmt.Fprint(s.cli.out, strings.Join(lines, "\n")+"\n")
+	s.lines = len(lines)
+	s.lastDraw = time.Now()
+}
+
+// erase removes the live rendering from the screen
+func (s *AnswerStream) erase() {
+	if s.lines == 0 {
+		return
+	}
+	// Move to the start of the first line and clear to the end of the screen
+	fmt.Fprintf(s.cli.out, "\x1b[%dF\x1b[J", s.lines)
+	s.lines = 0
+}
+
+func (s *AnswerStream) reset() {
+	s.content.Reset()
+	s.lastDraw = time.Time{}
+}
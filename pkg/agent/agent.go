@@ -0,0 +1,25 @@
+// Package agent lets programs that embed MCPHost, by calling cmd.Execute
+// from their own main package, add message modifiers written in Go. A
+// modifier registered before cmd.Execute can be named in the
+// message-modifiers setting, like the built-in ones.
+package agent
+
+import (
+	"github.com/mark3labs/mcphost/internal/agent"
+)
+
+// MessageModifier changes the messages just before each model call, without
+// changing the conversation history
+type MessageModifier = agent.MessageModifier
+
+// RegisterMessageModifier makes a message modifier available by name,
+// replacing any modifier of the same name, including a built-in one
+func RegisterMessageModifier(name string, modifier MessageModifier) error {
+	return agent.RegisterMessageModifier(name, modifier)
+}
+
+// MessageModifiers returns the names of the registered message modifiers,
+// sorted
+func MessageModifiers() []string {
+	return agent.MessageModifiers()
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"log"
+	"time"
+
+	"github.com/mark3labs/mcphost/internal/models"
+)
+
+// Call is a model call as middleware sees it
+type Call = models.Call
+
+// GenerateHandler makes a model call and returns the whole response
+type GenerateHandler = models.GenerateHandler
+
+// StreamHandler makes a model call and returns the response as a stream
+type StreamHandler = models.StreamHandler
+
+// Middleware wraps the Generate and Stream calls of chat models. Either may
+// be nil to pass those calls through.
+type Middleware = models.Middleware
+
+// UsageCounter sums the token usage of model calls. It is safe for
+// concurrent use.
+type UsageCounter = models.UsageCounter
+
+// Use adds a middleware to the chain of the models created from then on.
+// It applies to the named providers, or to all of them if none are named.
+// Middleware added first is outermost: it sees a call first and its
+// response last.
+func Use(mw Middleware, providers ...string) error {
+	return models.Use(mw, providers...)
+}
+
+// LoggingMiddleware logs each model call: the number of messages, how long
+// the call took, or took to start streaming, and its token usage or error
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	return models.LoggingMiddleware(logger)
+}
+
+// RetryMiddleware retries model calls that fail, up to retries more times,
+// waiting delay before the first retry and twice as long before each
+// following one. Streams are retried only if they fail to start.
+func RetryMiddleware(retries int, delay time.Duration) Middleware {
+	return models.RetryMiddleware(retries, delay)
+}
+
+// CacheMiddleware answers a call from memory when an identical call was
+// answered before
+func CacheMiddleware() Middleware {
+	return models.CacheMiddleware()
+}
+
+// RedactMiddleware replaces each of the secrets, wherever it appears in the
+// messages sent to the model, with [redacted]
+func RedactMiddleware(secrets ...string) Middleware {
+	return models.RedactMiddleware(secrets...)
+}
+
+// UsageMiddleware adds the token usage each model call reports to counter
+func UsageMiddleware(counter *UsageCounter) Middleware {
+	return models.UsageMiddleware(counter)
+}
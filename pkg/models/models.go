@@ -0,0 +1,50 @@
+// Package models lets programs that embed MCPHost, by calling cmd.Execute
+// from their own main package, add LLM providers and model middleware
+// written in Go. A provider registered before cmd.Execute is used for model
+// strings starting with its name, like the built-in ones, and middleware
+// added before it wraps the model calls.
+package models
+
+import (
+	"github.com/mark3labs/mcphost/internal/models"
+)
+
+// Provider is an LLM backend. Check and List are optional; providers
+// without Check are checked by asking the model for a one-token response.
+type Provider = models.Provider
+
+// ProviderFactory creates a chat model for the part of the model string
+// after "provider:"
+type ProviderFactory = models.ProviderFactory
+
+// ProviderCheck verifies that a model exists and the provider accepts the
+// configured credentials
+type ProviderCheck = models.ProviderCheck
+
+// ProviderLister lists the models a provider offers
+type ProviderLister = models.ProviderLister
+
+// ProviderOption describes a setting a provider reads, such as an API key
+type ProviderOption = models.ProviderOption
+
+// ProviderConfig holds the settings a provider creates models from
+type ProviderConfig = models.ProviderConfig
+
+// ModelInfo describes a model a provider offers
+type ModelInfo = models.ModelInfo
+
+// Register adds a provider, replacing any provider of the same name,
+// including a built-in one
+func Register(p Provider) error {
+	return models.Register(p)
+}
+
+// Lookup returns the provider registered under name
+func Lookup(name string) (Provider, bool) {
+	return models.Lookup(name)
+}
+
+// Providers returns all registered providers sorted by name
+func Providers() []Provider {
+	return models.Providers()
+}